@@ -0,0 +1,250 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/urfave/cli"
+	. "github.com/zbroju/financoj/lib"
+	"github.com/zbroju/gsqlitehandler"
+	"os"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Attachments are listed with beginning of their hash only, which is enough to compare them
+const attachmentHashLen = 12
+
+// CmdAttachmentAdd attaches files to transaction (given by ID) or account (given by name)
+func CmdAttachmentAdd(c *cli.Context) error {
+	var err error
+
+	// Get loggers
+	printUserMsg, printError := GetLoggers()
+
+	// Check obligatory flags
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+	if len(c.Args()) == 0 {
+		return printError.Fail(errMissingAttachmentFile)
+	}
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	object, objectId, err := attachmentObject(fh, c)
+	if err != nil {
+		return printError.Fail(err)
+	}
+	if object == NotSetStringValue {
+		return printError.Fail(errMissingAttachmentObject)
+	}
+
+	// Attach files
+	for _, af := range c.Args() {
+		var data []byte
+		if data, err = os.ReadFile(af); err != nil {
+			return printError.Fail(err)
+		}
+		var a *Attachment
+		if a, err = AttachmentAdd(fh, object, objectId, af, data); err != nil {
+			return printError.Fail(err)
+		}
+		printUserMsg.Printf("attached %s (%s) to %s %d as attachment %d\n", a.Name, a.MIMEType, a.Object, a.ObjectId, a.Id)
+	}
+
+	return nil
+}
+
+// CmdAttachmentList lists attachments of transaction (given by ID) or account (given by name), or all of them
+func CmdAttachmentList(c *cli.Context) error {
+	var err error
+
+	// Get loggers
+	_, printError := GetLoggers()
+
+	// Check obligatory flags
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	object, objectId, err := attachmentObject(fh, c)
+	if err != nil {
+		return printError.Fail(err)
+	}
+
+	// Build formatting strings
+	var getNextAttachment func() *Attachment
+	if getNextAttachment, err = AttachmentList(fh, object, objectId); err != nil {
+		return printError.Fail(err)
+	}
+	lId := utf8.RuneCountInString(HAtId)
+	lObject := utf8.RuneCountInString(HAtObject)
+	lObjectId := utf8.RuneCountInString(HAtObjectId)
+	lName := utf8.RuneCountInString(HAtName)
+	lType := utf8.RuneCountInString(HAtType)
+	lSize := utf8.RuneCountInString(HAtSize)
+	lAdded := utf8.RuneCountInString(HAtAdded)
+	lHash := MaxLen(HAtHash, attachmentHashLen)
+	for a := getNextAttachment(); a != nil; a = getNextAttachment() {
+		lId = MaxLen(strconv.FormatInt(a.Id, 10), lId)
+		lObject = MaxLen(a.Object, lObject)
+		lObjectId = MaxLen(strconv.FormatInt(a.ObjectId, 10), lObjectId)
+		lName = MaxLen(a.Name, lName)
+		lType = MaxLen(a.MIMEType, lType)
+		lSize = MaxLen(strconv.FormatInt(a.Size, 10), lSize)
+		lAdded = MaxLen(a.Added.Format(DateFormat), lAdded)
+	}
+	lineH := LineFor(HFSForNumeric(lId), HFSForText(lObject), HFSForNumeric(lObjectId), HFSForText(lName), HFSForText(lType), HFSForNumeric(lSize), HFSForText(lAdded), HFSForText(lHash))
+	lineD := LineFor(DFSForID(lId), DFSForText(lObject), DFSForID(lObjectId), DFSForText(lName), DFSForText(lType), DFSForID(lSize), DFSForText(lAdded), DFSForText(lHash))
+
+	// Print attachments
+	if getNextAttachment, err = AttachmentList(fh, object, objectId); err != nil {
+		return printError.Fail(err)
+	}
+	fmt.Fprintf(os.Stdout, lineH, HAtId, HAtObject, HAtObjectId, HAtName, HAtType, HAtSize, HAtAdded, HAtHash)
+	for a := getNextAttachment(); a != nil; a = getNextAttachment() {
+		fmt.Fprintf(os.Stdout, lineD, a.Id, a.Object, a.ObjectId, a.Name, a.MIMEType, a.Size, a.Added.Format(DateFormat), a.Hash[:attachmentHashLen])
+	}
+
+	return nil
+}
+
+// CmdAttachmentExtract writes attachment to file given as argument (- for standard output),
+// or to file with its original name in the current directory (which must not exist)
+func CmdAttachmentExtract(c *cli.Context) error {
+	var err error
+
+	// Get loggers
+	printUserMsg, printError := GetLoggers()
+
+	// Check obligatory flags
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+	id, err := strconv.Atoi(c.Args().First())
+	if err != nil {
+		return printError.Fail(errMissingAttachmentID)
+	}
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	// Read attachment
+	var a *Attachment
+	if a, err = AttachmentForID(fh, id); err != nil {
+		return printError.Fail(err)
+	}
+	var data []byte
+	if data, err = AttachmentData(fh, a); err != nil {
+		return printError.Fail(err)
+	}
+
+	// Write it
+	var out *os.File
+	switch of := c.Args().Get(1); of {
+	case "-":
+		if _, err = os.Stdout.Write(data); err != nil {
+			return printError.Fail(err)
+		}
+		return nil
+	case NotSetStringValue:
+		out, err = os.OpenFile(a.Name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	default:
+		out, err = os.Create(of)
+	}
+	if err != nil {
+		return printError.Fail(err)
+	}
+	_, err = out.Write(data)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return printError.Fail(err)
+	}
+	printUserMsg.Printf("extracted attachment %d to %s\n", a.Id, out.Name())
+
+	return nil
+}
+
+// CmdAttachmentRemove removes attachment given as argument
+func CmdAttachmentRemove(c *cli.Context) error {
+	var err error
+
+	// Get loggers
+	printUserMsg, printError := GetLoggers()
+
+	// Check obligatory flags
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+	id, err := strconv.Atoi(c.Args().First())
+	if err != nil {
+		return printError.Fail(errMissingAttachmentID)
+	}
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	// Remove attachment
+	var a *Attachment
+	if a, err = AttachmentForID(fh, id); err != nil {
+		return printError.Fail(err)
+	}
+	if err = AttachmentRemove(fh, a); err != nil {
+		return printError.Fail(err)
+	}
+	printUserMsg.Printf("removed attachment %d (%s)\n", a.Id, a.Name)
+
+	return nil
+}
+
+// attachmentObject returns object given with flags: transaction with ID or account with name.
+// Object is not set if neither of them is given.
+func attachmentObject(fh *gsqlitehandler.SqliteDB, c *cli.Context) (object string, objectId int64, err error) {
+	id, an := c.Int(OptID), c.String(ObjAccount)
+	switch {
+	case id != NotSetIntValue && an != NotSetStringValue:
+		err = errors.New(errIncorrectAttachmentObject)
+	case id != NotSetIntValue:
+		var t *Transaction
+		if t, err = TransactionForID(fh, id); err == nil {
+			object, objectId = AttachmentTransaction, t.Id
+		}
+	case an != NotSetStringValue:
+		var a *Account
+		if a, err = AccountForName(fh, an); err == nil {
+			object, objectId = AttachmentAccount, a.Id
+		}
+	}
+
+	return object, objectId, err
+}
@@ -0,0 +1,90 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"fmt"
+	"github.com/urfave/cli"
+	. "github.com/zbroju/financoj/lib"
+	"os"
+	"strconv"
+	"unicode/utf8"
+)
+
+// CmdBackupList lists backups of data file, from the newest one
+func CmdBackupList(c *cli.Context) error {
+	var err error
+
+	// Get loggers
+	_, printError := GetLoggers()
+
+	// Check obligatory flags
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+
+	var s *BackupSettings
+	if s, err = GetBackupSettings(f); err != nil {
+		return printError.Fail(err)
+	}
+
+	// Build formatting strings
+	var getNextBackup func() *Backup
+	if getNextBackup, err = BackupList(f, s); err != nil {
+		return printError.Fail(err)
+	}
+	lName := utf8.RuneCountInString(HBkName)
+	lTime := utf8.RuneCountInString(HBkTime)
+	lSize := utf8.RuneCountInString(HBkSize)
+	for b := getNextBackup(); b != nil; b = getNextBackup() {
+		lName = MaxLen(b.Name, lName)
+		lTime = MaxLen(b.Time.Format(backupTimeFormat), lTime)
+		lSize = MaxLen(strconv.FormatInt(b.Size, 10), lSize)
+	}
+	lineH := LineFor(HFSForText(lName), HFSForText(lTime), HFSForNumeric(lSize))
+	lineD := LineFor(DFSForText(lName), DFSForText(lTime), DFSForID(lSize))
+
+	// Print backups
+	if getNextBackup, err = BackupList(f, s); err != nil {
+		return printError.Fail(err)
+	}
+	fmt.Fprintf(os.Stdout, lineH, HBkName, HBkTime, HBkSize)
+	for b := getNextBackup(); b != nil; b = getNextBackup() {
+		fmt.Fprintf(os.Stdout, lineD, b.Name, b.Time.Format(backupTimeFormat), b.Size)
+	}
+
+	return nil
+}
+
+// CmdBackupRestore replaces contents of data file with backup given as argument.
+// The current contents is backed up first, so restoring can be undone.
+func CmdBackupRestore(c *cli.Context) error {
+	var err error
+
+	// Get loggers
+	printUserMsg, printError := GetLoggers()
+
+	// Check obligatory flags
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+	name := c.Args().First()
+	if name == NotSetStringValue {
+		return printError.Fail(errMissingBackupName)
+	}
+
+	var s *BackupSettings
+	if s, err = GetBackupSettings(f); err != nil {
+		return printError.Fail(err)
+	}
+	if err = BackupRestore(f, s, name); err != nil {
+		return printError.Fail(err)
+	}
+	printUserMsg.Printf("restored %s from backup %s (previous contents was backed up)\n", f, name)
+
+	return nil
+}
@@ -0,0 +1,132 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"fmt"
+	"golang.org/x/term"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Chart width used when standard output is not a terminal
+const chartDefaultWidth = 80
+
+// Minimal width of chart bars area
+const chartMinBarsWidth = 10
+
+// Characters drawing eighths of chart bars growing to the right
+var chartBlocks = []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+
+const (
+	chartFullBlock = "█"
+	chartAxis      = "│"
+)
+
+// chartWidth returns width of the terminal
+func chartWidth() int {
+	if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+		return w
+	}
+
+	return chartDefaultWidth
+}
+
+// chartBar returns bar growing to the right, with length v/max of width w (padded with spaces to w)
+func chartBar(v, max float64, w int) string {
+	n := 0
+	if v > 0 && max > 0 {
+		n = int(math.Round(v / max * float64(w*8)))
+	}
+	bar := strings.Repeat(chartFullBlock, n/8) + chartBlocks[n%8]
+
+	return bar + strings.Repeat(" ", w-utf8.RuneCountInString(bar))
+}
+
+// chartBarLeft returns bar growing to the left, with length v/max of width w (padded with spaces to w).
+// It is drawn with full characters only, as there are no blocks filling fractions of characters from the right.
+func chartBarLeft(v, max float64, w int) string {
+	n := 0
+	if v > 0 && max > 0 {
+		n = int(math.Round(v / max * float64(w)))
+	}
+
+	return strings.Repeat(" ", w-n) + strings.Repeat(chartFullBlock, n)
+}
+
+// chartLabels returns width of labels and formatted values (with width of the longest one)
+func chartLabels(labels []string, values ...[]float64) (lL int, formatted [][]string, lV int) {
+	for _, l := range labels {
+		lL = MaxLen(l, lL)
+	}
+	for _, vs := range values {
+		var fs []string
+		for _, v := range vs {
+			s := strconv.FormatFloat(v, 'f', 2, 64)
+			lV = MaxLen(s, lV)
+			fs = append(fs, s)
+		}
+		formatted = append(formatted, fs)
+	}
+
+	return lL, formatted, lV
+}
+
+// printBarChart prints horizontal bar for every label, scaled to the terminal width.
+// Negative values grow to the left of the axis.
+func printBarChart(w io.Writer, labels []string, values []float64) {
+	lL, formatted, lV := chartLabels(labels, values)
+	area := chartWidth() - lL - lV - 2
+	if area < chartMinBarsWidth {
+		area = chartMinBarsWidth
+	}
+
+	var maxPos, maxNeg float64
+	for _, v := range values {
+		maxPos, maxNeg = math.Max(maxPos, v), math.Max(maxNeg, -v)
+	}
+	wNeg := 0
+	if maxNeg > 0 {
+		wNeg = int(math.Round(float64(area-1) * maxNeg / (maxPos + maxNeg)))
+	}
+	wPos := area - wNeg
+	if wNeg > 0 {
+		wPos--
+	}
+
+	for i, l := range labels {
+		fmt.Fprintf(w, "%-*s ", lL, l)
+		if wNeg > 0 {
+			fmt.Fprint(w, chartBarLeft(-values[i], maxNeg, wNeg), chartAxis)
+		}
+		fmt.Fprintf(w, "%s %*s\n", chartBar(values[i], maxPos, wPos), lV, formatted[0][i])
+	}
+}
+
+// printPairChart prints two horizontal bars side by side for every label (e.g. income and cost),
+// both scaled to the same maximum. Values are shown as absolute ones.
+func printPairChart(w io.Writer, labels []string, h1 string, v1 []float64, h2 string, v2 []float64) {
+	lL, formatted, lV := chartLabels(labels, v1, v2)
+	half := (chartWidth()-lL-3)/2 - lV - 1
+	if half < chartMinBarsWidth {
+		half = chartMinBarsWidth
+	}
+
+	var max float64
+	for i := range labels {
+		max = math.Max(max, math.Max(math.Abs(v1[i]), math.Abs(v2[i])))
+	}
+
+	fmt.Fprintf(w, "%-*s %-*s %s %s\n", lL, "", half+lV+1, h1, chartAxis, h2)
+	for i, l := range labels {
+		fmt.Fprintf(w, "%-*s %s %*s %s %s %*s\n", lL, l,
+			chartBar(math.Abs(v1[i]), max, half), lV, formatted[0][i], chartAxis,
+			chartBar(math.Abs(v2[i]), max, half), lV, formatted[1][i])
+	}
+}
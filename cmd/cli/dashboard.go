@@ -0,0 +1,26 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// Files of web dashboard (charts of reports taken from HTTP API), embedded in the binary so that it works offline
+//
+//go:embed web
+var dashboardFiles embed.FS
+
+// dashboardHandler serves web dashboard
+func dashboardHandler() http.Handler {
+	files, err := fs.Sub(dashboardFiles, "web")
+	if err != nil {
+		panic(err)
+	}
+
+	return http.FileServer(http.FS(files))
+}
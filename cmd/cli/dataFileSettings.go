@@ -0,0 +1,56 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"fmt"
+	"github.com/urfave/cli"
+	. "github.com/zbroju/financoj/lib"
+	"os"
+	"time"
+)
+
+// CmdDataFileSettings changes settings of data file given with flags, or shows them if none is given
+func CmdDataFileSettings(c *cli.Context) error {
+	var err error
+
+	// Get loggers
+	printUserMsg, printError := GetLoggers()
+
+	// Check obligatory flags
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	// Change settings
+	if fs := c.String(OptFiscalYearStart); fs != NotSetStringValue {
+		var m time.Month
+		if m, err = MonthParse(fs); err != nil {
+			return printError.Fail(err)
+		}
+		if err = FiscalYearStartSet(fh, m); err != nil {
+			return printError.Fail(err)
+		}
+		printUserMsg.Printf("fiscal year starts in %s\n", m)
+		return nil
+	}
+
+	// Show settings
+	var m time.Month
+	if m, err = FiscalYearStart(fh); err != nil {
+		return printError.Fail(err)
+	}
+	fmt.Fprintf(os.Stdout, "%s: %s\n", OptFiscalYearStart, m)
+
+	return nil
+}
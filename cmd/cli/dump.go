@@ -0,0 +1,101 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"github.com/urfave/cli"
+	. "github.com/zbroju/financoj/lib"
+	"os"
+)
+
+// CmdDataFileDump writes the whole data file as JSON to file (or to standard output)
+func CmdDataFileDump(c *cli.Context) error {
+	var err error
+
+	// Get loggers
+	printUserMsg, printError := GetLoggers()
+
+	// Check obligatory flags
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	// Dump data file
+	var d *Dump
+	if d, err = DumpGet(fh); err != nil {
+		return printError.Fail(err)
+	}
+	out := os.Stdout
+	if of := c.Args().First(); of != NotSetStringValue {
+		if out, err = os.Create(of); err != nil {
+			return printError.Fail(err)
+		}
+		defer out.Close()
+	}
+	if err = DumpWrite(d, out); err != nil {
+		return printError.Fail(err)
+	}
+	if out != os.Stdout {
+		printUserMsg.Printf("dumped data file to %s\n", out.Name())
+	}
+
+	return nil
+}
+
+// CmdDataFileRestore creates new data file with the contents of JSON dump.
+// The dump is checked before the file is created, so incorrect dumps leave nothing behind.
+func CmdDataFileRestore(c *cli.Context) error {
+	var err error
+
+	// Get loggers
+	printUserMsg, printError := GetLoggers()
+
+	// Check obligatory flags
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+	df := c.Args().First()
+	if df == NotSetStringValue {
+		return printError.Fail(errMissingDumpFile)
+	}
+
+	// Read dump
+	var d *Dump
+	var dfh *os.File
+	if dfh, err = os.Open(df); err != nil {
+		return printError.Fail(err)
+	}
+	d, err = DumpRead(dfh)
+	dfh.Close()
+	if err != nil {
+		return printError.Fail(err)
+	}
+
+	// Create new data file and restore dump
+	fh := dataFileHandler(f)
+	if err = CreateNewDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	if err = openDataFile(fh); err == nil {
+		err = DumpRestore(fh, d)
+		closeDataFile(fh)
+	}
+	if err != nil {
+		os.Remove(f)
+		return printError.Fail(err)
+	}
+	printUserMsg.Printf("restored %s to new file %s\n", df, f)
+
+	return nil
+}
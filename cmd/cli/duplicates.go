@@ -0,0 +1,201 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/urfave/cli"
+	. "github.com/zbroju/financoj/lib"
+	"github.com/zbroju/gsqlitehandler"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// CmdTransactionDuplicates lists candidate duplicate transactions (the same account and value, close dates and similar descriptions).
+// With merge or dismiss argument and two IDs the pair is merged (the transaction with higher ID is removed)
+// or remembered as not being duplicates. When asking the user is enabled, each candidate can be merged or dismissed in turn.
+func CmdTransactionDuplicates(c *cli.Context) error {
+	var err error
+
+	// Get loggers
+	printUserMsg, printError := GetLoggers()
+
+	// Check obligatory flags
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+	action := strings.ToLower(c.Args().First())
+	if action != NotSetStringValue && action != DuplicateActionMerge && action != DuplicateActionDismiss {
+		return printError.Fail(errIncorrectDuplicateAction)
+	}
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	// Merge or dismiss given pair
+	if action != NotSetStringValue {
+		var ids [2]int
+		if len(c.Args()) != 3 {
+			return printError.Fail(errMissingDuplicateIDs)
+		}
+		for i, s := range c.Args()[1:] {
+			if ids[i], err = strconv.Atoi(s); err != nil {
+				return printError.Fail(errMissingDuplicateIDs)
+			}
+		}
+		var d *Duplicate
+		if d, err = DuplicateForIDs(fh, ids[0], ids[1]); err != nil {
+			return printError.Fail(err)
+		}
+		if err = duplicateResolve(fh, d, action); err != nil {
+			return printError.Fail(err)
+		}
+		return nil
+	}
+
+	// Get criteria
+	var dateFrom, dateTo time.Time
+	if dateFrom, dateTo, err = datesRangeFlags(c, fh); err != nil {
+		return printError.Fail(err)
+	}
+	var account *Account
+	if as := c.String(ObjAccount); as != NotSetStringValue {
+		if account, err = AccountForName(fh, as); err != nil {
+			return printError.Fail(err)
+		}
+	}
+
+	var l []*Duplicate
+	if l, err = duplicateCandidates(fh, account, dateFrom, dateTo, c.Int(OptDays), c.Float64(OptSimilarity)); err != nil {
+		return printError.Fail(err)
+	}
+	if len(l) == 0 {
+		printUserMsg.Println("no candidate duplicates")
+		return nil
+	}
+
+	// Without the user only the list is shown
+	p := newPrompter(c)
+	if !p.enabled {
+		printDuplicates(l)
+		return nil
+	}
+	defer p.close()
+	removed := make(map[int64]bool)
+	for i, d := range l {
+		// Transaction removed while merging the previous candidate cannot be merged again
+		if removed[d.First.Id] || removed[d.Second.Id] {
+			continue
+		}
+		fmt.Fprintf(os.Stdout, "candidate %d of %d (similarity of descriptions %.0f%%):\n", i+1, len(l), d.Similarity*100)
+		printDuplicates([]*Duplicate{d})
+		for {
+			a := strings.ToLower(p.ask("merge, dismiss or skip? [m/d/S]", NotSetStringValue, nil))
+			switch a {
+			case "m", DuplicateActionMerge:
+				a = DuplicateActionMerge
+			case "d", DuplicateActionDismiss:
+				a = DuplicateActionDismiss
+			case "", "s", "skip":
+				a = NotSetStringValue
+			default:
+				printError.Println(errIncorrectDuplicateAction)
+				continue
+			}
+			if a != NotSetStringValue {
+				if err = duplicateResolve(fh, d, a); err != nil {
+					// Pair which cannot be merged can still be dismissed or skipped
+					if IsDataFileError(err) {
+						return printError.Fail(err)
+					}
+					printError.Println(err)
+					continue
+				}
+				if a == DuplicateActionMerge {
+					removed[d.Second.Id] = true
+				}
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
+// duplicateCandidates returns list of candidate duplicates
+func duplicateCandidates(fh *gsqlitehandler.SqliteDB, a *Account, df, dt time.Time, days int, similarity float64) (l []*Duplicate, err error) {
+	var getNextDuplicate func() *Duplicate
+	if getNextDuplicate, err = DuplicateList(fh, a, df, dt, days, similarity); err != nil {
+		return nil, err
+	}
+	for d := getNextDuplicate(); d != nil; d = getNextDuplicate() {
+		l = append(l, d)
+	}
+
+	return l, nil
+}
+
+// duplicateResolve merges or dismisses candidate duplicate
+func duplicateResolve(fh *gsqlitehandler.SqliteDB, d *Duplicate, action string) error {
+	printUserMsg, _ := GetLoggers()
+
+	switch action {
+	case DuplicateActionMerge:
+		if err := DuplicateMerge(fh, d); err != nil {
+			return err
+		}
+		printUserMsg.Printf("merged transaction %d into %d\n", d.Second.Id, d.First.Id)
+	case DuplicateActionDismiss:
+		if err := DuplicateDismiss(fh, d); err != nil {
+			return err
+		}
+		printUserMsg.Printf("transactions %d and %d will not be shown as duplicates again\n", d.First.Id, d.Second.Id)
+	default:
+		return errors.New(errIncorrectDuplicateAction)
+	}
+
+	return nil
+}
+
+// printDuplicates prints candidate duplicates as table, each pair separated with empty line
+func printDuplicates(l []*Duplicate) {
+	lId := utf8.RuneCountInString(HTId)
+	lDate := utf8.RuneCountInString(HTDate)
+	lAccount := utf8.RuneCountInString(HAName)
+	lValue := utf8.RuneCountInString(HTValue)
+	lCur := utf8.RuneCountInString(HACurrency)
+	lDesc := utf8.RuneCountInString(HTDescription)
+	for _, d := range l {
+		for _, t := range []*Transaction{d.First, d.Second} {
+			lId = MaxLen(strconv.FormatInt(t.Id, 10), lId)
+			lDate = MaxLen(t.Date.Format(DateFormat), lDate)
+			lAccount = MaxLen(t.Account.Name, lAccount)
+			lValue = MaxLen(strconv.FormatFloat(t.GetSValue(), 'f', 2, 64), lValue)
+			lCur = MaxLen(t.Account.Currency, lCur)
+			lDesc = MaxLen(t.Description, lDesc)
+		}
+	}
+	lineH := LineFor(HFSForNumeric(lId), HFSForText(lDate), HFSForText(lAccount), HFSForNumeric(lValue), HFSForText(lCur), HFSForText(lDesc))
+	lineD := LineFor(DFSForID(lId), DFSForText(lDate), DFSForText(lAccount), DFSForValue(lValue), DFSForText(lCur), DFSForText(lDesc))
+
+	fmt.Fprintf(os.Stdout, lineH, HTId, HTDate, HAName, HTValue, HACurrency, HTDescription)
+	for i, d := range l {
+		if i > 0 {
+			fmt.Fprintln(os.Stdout)
+		}
+		for _, t := range []*Transaction{d.First, d.Second} {
+			fmt.Fprintf(os.Stdout, lineD, t.Id, t.Date.Format(DateFormat), t.Account.Name, t.GetSValue(), t.Account.Currency, t.Description)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/urfave/cli"
+	. "github.com/zbroju/financoj/lib"
+	"golang.org/x/term"
+	"os"
+)
+
+// CmdDataFileEncrypt encrypts data file with new passphrase
+func CmdDataFileEncrypt(c *cli.Context) error {
+	// Get loggers
+	printUserMsg, printError := GetLoggers()
+
+	// Check obligatory flags
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+	if shell != nil && f == shell.file {
+		return printError.Fail(errShellRunning)
+	}
+
+	if err := DataFileEncrypt(f); err != nil {
+		return printError.Fail(err)
+	}
+	printUserMsg.Printf("encrypted file %s\n", f)
+
+	return nil
+}
+
+// CmdDataFileDecrypt decrypts data file
+func CmdDataFileDecrypt(c *cli.Context) error {
+	// Get loggers
+	printUserMsg, printError := GetLoggers()
+
+	// Check obligatory flags
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+	if shell != nil && f == shell.file {
+		return printError.Fail(errShellRunning)
+	}
+
+	if err := DataFileDecrypt(f); err != nil {
+		return printError.Fail(err)
+	}
+	printUserMsg.Printf("decrypted file %s\n", f)
+
+	return nil
+}
+
+// AskPassphrase asks the user for passphrase of encrypted data file without showing it,
+// twice if confirm is set. It is used as PassphrasePrompt.
+func AskPassphrase(file string, confirm bool) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, errors.New(errPassphraseNoTerminal)
+	}
+
+	fmt.Fprintf(os.Stderr, "passphrase for %s: ", file)
+	p, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil || !confirm {
+		return p, err
+	}
+	fmt.Fprintf(os.Stderr, "repeat passphrase: ")
+	r, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(p, r) {
+		return nil, errors.New(errPassphraseDiffers)
+	}
+
+	return p, nil
+}
@@ -0,0 +1,104 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"github.com/urfave/cli"
+	. "github.com/zbroju/financoj/lib"
+	"github.com/zbroju/gsqlitehandler"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// statementWriters maps formats of files to functions writing statements of accounts to them
+var statementWriters = map[string]func(io.Writer, *Account, *Statement) error{
+	FormatQIF: StatementWriteQIF,
+}
+
+// journalWriters maps formats of plain text accounting journals to functions writing the whole data file to them
+var journalWriters = map[string]func(*gsqlitehandler.SqliteDB, io.Writer) error{
+	FormatBeancount: JournalWriteBeancount,
+	FormatLedger:    JournalWriteLedger,
+}
+
+// CmdStatementExport writes transactions of the account to file in given format (or to standard output).
+// For plain text accounting journals the whole data file is written and the account is not needed.
+func CmdStatementExport(c *cli.Context) error {
+	var err error
+
+	// Get loggers
+	printUserMsg, printError := GetLoggers()
+
+	// Check obligatory flags
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+	format := strings.ToLower(c.String(OptFormat))
+	if format == NotSetStringValue {
+		return printError.Fail(errMissingFormatFlag)
+	}
+	writeStatement, isStatement := statementWriters[format]
+	writeJournal, isJournal := journalWriters[format]
+	if !isStatement && !isJournal {
+		return printError.Fail(errIncorrectFormat)
+	}
+	an := c.String(ObjAccount)
+	if an == NotSetStringValue && isStatement {
+		return printError.Fail(errMissingAccountFlag)
+	}
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	out := os.Stdout
+	if of := c.Args().First(); of != NotSetStringValue {
+		if out, err = os.Create(of); err != nil {
+			return printError.Fail(err)
+		}
+		defer out.Close()
+	}
+
+	// Export journal
+	if isJournal {
+		if err = writeJournal(fh, out); err != nil {
+			return printError.Fail(err)
+		}
+		if out != os.Stdout {
+			printUserMsg.Printf("exported data file to %s\n", out.Name())
+		}
+		return nil
+	}
+
+	// Create filters
+	var a *Account
+	if a, err = AccountForName(fh, an); err != nil {
+		return printError.Fail(err)
+	}
+	var df, dt time.Time
+	if df, dt, err = datesRangeFlags(c, fh); err != nil {
+		return printError.Fail(err)
+	}
+
+	// Export transactions
+	var s *Statement
+	if s, err = StatementForAccount(fh, a, df, dt); err != nil {
+		return printError.Fail(err)
+	}
+	if err = writeStatement(out, a, s); err != nil {
+		return printError.Fail(err)
+	}
+	if out != os.Stdout {
+		printUserMsg.Printf("exported %d transaction(s) of account '%s' to %s\n", len(s.Transactions), a.Name, out.Name())
+	}
+
+	return nil
+}
@@ -5,18 +5,37 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
+	"github.com/urfave/cli"
 	. "github.com/zbroju/financoj/lib"
+	"github.com/zbroju/gsqlitehandler"
 	"log"
 	"os"
 	"strings"
+	"time"
 	"unicode/utf8"
 )
 
+// Logger prints messages of the application
+type Logger struct {
+	*log.Logger
+}
+
+// errCommandFailed is returned by commands which failed, once the reason has been printed
+var errCommandFailed = errors.New("command failed")
+
+// Fail prints the message and returns error ending the command.
+// The application exits with failure status then, while shell goes on with the next command.
+func (l *Logger) Fail(v ...interface{}) error {
+	l.Println(v...)
+	return errCommandFailed
+}
+
 // GetLoggers returns two loggers for standard formatting of messages and errors
-func GetLoggers() (messageLogger *log.Logger, errorLogger *log.Logger) {
-	messageLogger = log.New(os.Stdout, fmt.Sprintf("%s: ", AppName), 0)
-	errorLogger = log.New(os.Stderr, fmt.Sprintf("%s: ", AppName), 0)
+func GetLoggers() (messageLogger *Logger, errorLogger *Logger) {
+	messageLogger = &Logger{log.New(os.Stdout, fmt.Sprintf("%s: ", AppName), 0)}
+	errorLogger = &Logger{log.New(os.Stderr, fmt.Sprintf("%s: ", AppName), 0)}
 
 	return
 }
@@ -41,6 +60,80 @@ func AccountTypeForString(s string) (t AccountType) {
 	return t
 }
 
+// MainCategoryTypeReportsForString returns flags of main category type for given comma separated list of reports
+func MainCategoryTypeReportsForString(s string) (incomeCost, budget, netValue bool, err error) {
+	for _, r := range strings.Split(s, ",") {
+		switch strings.TrimSpace(r) {
+		case "ic", "income-cost":
+			incomeCost = true
+		case "b", "budget":
+			budget = true
+		case "nv", "net-value":
+			netValue = true
+		case "none":
+		default:
+			return false, false, false, errors.New(errIncorrectReportsFlag)
+		}
+	}
+
+	return incomeCost, budget, netValue, nil
+}
+
+// MainCategoryTypeReportsString returns comma separated list of reports (reverse of MainCategoryTypeReportsForString)
+func MainCategoryTypeReportsString(incomeCost, budget, netValue bool) string {
+	var r []string
+	if incomeCost {
+		r = append(r, "income-cost")
+	}
+	if budget {
+		r = append(r, "budget")
+	}
+	if netValue {
+		r = append(r, "net-value")
+	}
+	if len(r) == 0 {
+		return "none"
+	}
+
+	return strings.Join(r, ",")
+}
+
+// datesRange returns dates range given with range expression and dates from and to (e.g. values of flags --range,
+// --date-from and --date-to), each of them parsed with DateRangeParse. Dates from and to override the bounds of range.
+// Zero dates mean the range is not limited. Years and quarters are the ones of data file opened with fh.
+func datesRange(fh *gsqlitehandler.SqliteDB, r, from, to string) (df, dt time.Time, err error) {
+	if r != NotSetStringValue {
+		if df, dt, err = DateRangeParse(fh, r); err != nil {
+			return df, dt, err
+		}
+	}
+	if from != NotSetStringValue {
+		if df, _, err = DateRangeParse(fh, from); err != nil {
+			return df, dt, err
+		}
+	}
+	if to != NotSetStringValue {
+		if _, dt, err = DateRangeParse(fh, to); err != nil {
+			return df, dt, err
+		}
+	}
+
+	return df, dt, nil
+}
+
+// datesRangeFlags returns dates range given with flags --range, --date-from and --date-to (see datesRange)
+func datesRangeFlags(c *cli.Context, fh *gsqlitehandler.SqliteDB) (df, dt time.Time, err error) {
+	return datesRange(fh, c.String(OptRange), c.String(OptDateFrom), c.String(OptDateTo))
+}
+
+// YesNo returns human readable string for a flag
+func YesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
 // getLineFor returns pre-formatted line formatting string for reporting
 func LineFor(fs ...string) string {
 	line := strings.Join(fs, FSSeparator) + "\n"
@@ -0,0 +1,227 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"fmt"
+	"github.com/urfave/cli"
+	. "github.com/zbroju/financoj/lib"
+	"github.com/zbroju/gsqlitehandler"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// CmdGoalAdd adds new savings goal saved in accounts or in transactions of category
+func CmdGoalAdd(c *cli.Context) error {
+	var err error
+
+	// Get loggers
+	printUserMsg, printError := GetLoggers()
+
+	// Check obligatory flags (file)
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	// Check obligatory flags (name, value, currency, last day and accounts or category), ask for missing ones if possible
+	g := GoalNew()
+	pr := newPrompter(c)
+	n := pr.String(c.String(ObjGoal), promptField{Label: HGName, Required: true})
+	if n == NotSetStringValue {
+		return printError.Fail(errMissingGoalFlag)
+	}
+	v := pr.Float(c.Float64(OptValue), promptField{Label: HGTarget, Required: true})
+	if v == NotSetFloatValue {
+		return printError.Fail(errMissingValueFlag)
+	}
+	cur := pr.String(c.String(OptCurrency), promptField{Label: HACurrency, Required: true, Candidates: currencyCompletion(fh)})
+	if cur == NotSetStringValue {
+		return printError.Fail(errMissingCurrencyFlag)
+	}
+	dt := pr.String(c.String(OptUntil), promptField{Label: HRDateTo, Required: true, Check: checkDate})
+	if dt == NotSetStringValue {
+		return printError.Fail(errMissingUntilFlag)
+	}
+	an := c.StringSlice(ObjAccount)
+	cn := c.String(ObjCategory)
+	if len(an) == 0 {
+		cn = pr.String(cn, promptField{Label: HCName, Candidates: categoryCompletion(fh), Check: checkCategory(fh)})
+	}
+	if len(an) == 0 && cn == NotSetStringValue {
+		return printError.Fail(errMissingGoalLinkFlag)
+	}
+
+	// Create the goal object
+	g.Name = n
+	g.Value = v
+	g.Currency = strings.ToUpper(cur)
+	if _, g.DateTo, err = DateRangeParse(fh, dt); err != nil {
+		return printError.Fail(err)
+	}
+	for _, s := range an {
+		var a *Account
+		if a, err = AccountForName(fh, s); err != nil {
+			return printError.Fail(err)
+		}
+		g.Accounts = append(g.Accounts, a)
+	}
+	if cn != NotSetStringValue {
+		if g.Category, err = CategoryForName(fh, cn); err != nil {
+			return printError.Fail(err)
+		}
+	}
+
+	// Add goal
+	if !pr.Confirm("new goal", goalSummary(fh, g)...) {
+		printUserMsg.Printf("nothing has been changed\n")
+		return nil
+	}
+	if err = GoalAdd(fh, g); err != nil {
+		return printError.Fail(err)
+	}
+
+	// Show summary
+	printUserMsg.Printf("add new goal with id = %d\n", g.Id)
+
+	return nil
+}
+
+// CmdGoalList prints savings goals on standard output
+func CmdGoalList(c *cli.Context) error {
+	var err error
+
+	// Get loggers
+	_, printError := GetLoggers()
+
+	// Check obligatory flags
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	var tree *CategoryTree
+	if tree, err = CategoryTreeGet(fh); err != nil {
+		return printError.Fail(err)
+	}
+
+	// Build formatting strings
+	var getNextGoal func() *Goal
+	if getNextGoal, err = GoalList(fh); err != nil {
+		return printError.Fail(err)
+	}
+	lId := utf8.RuneCountInString(HTId)
+	lName := utf8.RuneCountInString(HGName)
+	lTarget := utf8.RuneCountInString(HGTarget)
+	lCur := utf8.RuneCountInString(HACurrency)
+	lFrom := utf8.RuneCountInString(HRDateFrom)
+	lTo := utf8.RuneCountInString(HRDateTo)
+	lSaved := utf8.RuneCountInString(HGSavedIn)
+	for g := getNextGoal(); g != nil; g = getNextGoal() {
+		lId = MaxLen(strconv.FormatInt(g.Id, 10), lId)
+		lName = MaxLen(g.Name, lName)
+		lTarget = MaxLen(strconv.FormatFloat(g.Value, 'f', 2, 64), lTarget)
+		lCur = MaxLen(g.Currency, lCur)
+		lFrom = MaxLen(g.DateFrom.Format(DateFormat), lFrom)
+		lTo = MaxLen(g.DateTo.Format(DateFormat), lTo)
+		lSaved = MaxLen(goalSavedIn(tree, g), lSaved)
+	}
+	lineH := LineFor(HFSForNumeric(lId), HFSForText(lName), HFSForNumeric(lTarget), HFSForText(lCur), HFSForText(lFrom), HFSForText(lTo), HFSForText(lSaved))
+	lineD := LineFor(DFSForID(lId), DFSForText(lName), DFSForValue(lTarget), DFSForText(lCur), DFSForText(lFrom), DFSForText(lTo), DFSForText(lSaved))
+
+	// Print goals
+	if getNextGoal, err = GoalList(fh); err != nil {
+		return printError.Fail(err)
+	}
+	fmt.Fprintf(os.Stdout, lineH, HTId, HGName, HGTarget, HACurrency, HRDateFrom, HRDateTo, HGSavedIn)
+	for g := getNextGoal(); g != nil; g = getNextGoal() {
+		fmt.Fprintf(os.Stdout, lineD, g.Id, g.Name, g.Value, g.Currency, g.DateFrom.Format(DateFormat), g.DateTo.Format(DateFormat), goalSavedIn(tree, g))
+	}
+
+	return nil
+}
+
+// CmdGoalRemove removes savings goal
+func CmdGoalRemove(c *cli.Context) error {
+	var err error
+
+	// Get loggers
+	printUserMsg, printError := GetLoggers()
+
+	// Check obligatory flags
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+	id := c.Int(OptID)
+	if id == NotSetIntValue {
+		return printError.Fail(errMissingIDFlag)
+	}
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	var g *Goal
+	if g, err = GoalForID(fh, id); err != nil {
+		return printError.Fail(err)
+	}
+
+	// Remove the goal
+	if err = GoalRemove(fh, g); err != nil {
+		return printError.Fail(err)
+	}
+
+	// Show summary
+	printUserMsg.Printf("removed goal with id = %d\n", g.Id)
+
+	return nil
+}
+
+// goalSavedIn returns names of accounts or path of category which goal g is saved in
+func goalSavedIn(tree *CategoryTree, g *Goal) string {
+	if g.Category != nil {
+		return tree.Path(g.Category)
+	}
+	var l []string
+	for _, a := range g.Accounts {
+		l = append(l, a.Name)
+	}
+	return strings.Join(l, ", ")
+}
+
+// goalSummary returns label, value pairs describing goal g for Confirm
+func goalSummary(fh *gsqlitehandler.SqliteDB, g *Goal) []string {
+	s := NotSetStringValue
+	if tree, err := CategoryTreeGet(fh); err == nil {
+		s = goalSavedIn(tree, g)
+	}
+
+	return []string{
+		HGName, g.Name,
+		HGTarget, strconv.FormatFloat(g.Value, 'f', 2, 64) + " " + g.Currency,
+		HRDateTo, g.DateTo.Format(DateFormat),
+		HGSavedIn, s,
+	}
+}
@@ -0,0 +1,206 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"github.com/urfave/cli"
+	. "github.com/zbroju/financoj/lib"
+	"github.com/zbroju/gsqlitehandler"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+// statementReaders maps formats of files to functions reading statements from them
+var statementReaders = map[string]func(io.Reader) (*Statement, error){
+	FormatOFX:     StatementReadOFX,
+	FormatQFX:     StatementReadOFX,
+	FormatQIF:     StatementReadQIF,
+	FormatCAMT053: StatementReadCAMT053,
+	FormatMT940:   StatementReadMT940,
+}
+
+// journalReaders maps formats of plain text accounting journals to functions reading them
+var journalReaders = map[string]func(io.Reader) (*Journal, error){
+	FormatBeancount: JournalReadBeancount,
+	FormatLedger:    JournalReadLedger,
+}
+
+// CmdStatementImport adds transactions from bank statement file to the account.
+// Transactions imported before are skipped and the balance given by the bank is compared with the account balance.
+// Account and category are obligatory unless the file gives them for every transaction (e.g. QIF with account headers).
+// Plain text accounting journals are imported with their accounts, categories, exchange rates and budgets.
+func CmdStatementImport(c *cli.Context) error {
+	var err error
+
+	// Get loggers
+	printUserMsg, printError := GetLoggers()
+
+	// Check obligatory flags
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+	sf := c.Args().First()
+	if sf == NotSetStringValue {
+		return printError.Fail(errMissingStatementFile)
+	}
+	format := strings.ToLower(c.String(OptFormat))
+	if format == NotSetStringValue {
+		return printError.Fail(errMissingFormatFlag)
+	}
+	if _, ok := journalReaders[format]; ok {
+		return importJournal(f, sf, format)
+	}
+	readStatement, ok := statementReaders[format]
+	if !ok {
+		return printError.Fail(errIncorrectFormat)
+	}
+	an := c.String(ObjAccount)
+	cn := c.String(ObjCategory)
+
+	// Read statement
+	var s *Statement
+	var sfh *os.File
+	if sfh, err = os.Open(sf); err != nil {
+		return printError.Fail(err)
+	}
+	s, err = readStatement(sfh)
+	sfh.Close()
+	if err != nil {
+		return printError.Fail(err)
+	}
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	var a *Account
+	if an != NotSetStringValue {
+		if a, err = AccountForName(fh, an); err != nil {
+			return printError.Fail(err)
+		}
+	}
+	var cat *Category
+	if cn != NotSetStringValue {
+		if cat, err = CategoryForName(fh, cn); err != nil {
+			return printError.Fail(err)
+		}
+	}
+
+	// Import transactions
+	var added, skipped int
+	if added, skipped, err = StatementImport(fh, s, a, cat); err != nil {
+		return printError.Fail(err)
+	}
+	printUserMsg.Printf("imported %d transaction(s), skipped %d imported before\n", added, skipped)
+
+	// Warn about imported transactions which may have been entered by hand before
+	if added > 0 {
+		if err = warnStatementDuplicates(fh, s, a); err != nil {
+			return printError.Fail(err)
+		}
+	}
+
+	// Compare balances
+	if a != nil && !s.OpeningBalanceDate.IsZero() {
+		if err = compareStatementBalance(fh, a, "opening balance", s.OpeningBalance, s.OpeningBalanceDate); err != nil {
+			return printError.Fail(err)
+		}
+	}
+	if a != nil && !s.BalanceDate.IsZero() {
+		if err = compareStatementBalance(fh, a, "closing balance", s.Balance, s.BalanceDate); err != nil {
+			return printError.Fail(err)
+		}
+	}
+
+	return nil
+}
+
+// compareStatementBalance prints whether the balance of account a at the end of day d agrees with the one given by the bank
+func compareStatementBalance(fh *gsqlitehandler.SqliteDB, a *Account, name string, bank float64, d time.Time) error {
+	printUserMsg, _ := GetLoggers()
+
+	b, err := StatementAccountBalance(fh, a, d)
+	if err != nil {
+		return err
+	}
+	if diff := bank - b; math.Abs(diff) < 0.005 {
+		printUserMsg.Printf("%s on %s agrees with bank: %.2f %s\n", name, d.Format(DateFormat), b, a.Currency)
+	} else {
+		printUserMsg.Printf("%s on %s differs from bank: %.2f %s (bank: %.2f, difference: %.2f)\n", name, d.Format(DateFormat), b, a.Currency, bank, diff)
+	}
+
+	return nil
+}
+
+// warnStatementDuplicates prints candidate duplicates among transactions of account a (all accounts if nil)
+// within dates of statement s
+func warnStatementDuplicates(fh *gsqlitehandler.SqliteDB, s *Statement, a *Account) error {
+	printUserMsg, _ := GetLoggers()
+
+	var df, dt time.Time
+	for _, st := range s.Transactions {
+		if df.IsZero() || st.Date.Before(df) {
+			df = st.Date
+		}
+		if st.Date.After(dt) {
+			dt = st.Date
+		}
+	}
+	l, err := duplicateCandidates(fh, a, df, dt, DuplicateDays, DuplicateSimilarity)
+	if err != nil || len(l) == 0 {
+		return err
+	}
+	printUserMsg.Printf("found %d candidate duplicate(s), use %s command to merge or dismiss them:\n", len(l), CmdDuplicates)
+	printDuplicates(l)
+
+	return nil
+}
+
+// importJournal adds the contents of journal file jf in given format to data file f,
+// reporting the parts of journal which cannot be imported
+func importJournal(f, jf, format string) error {
+	var err error
+
+	// Get loggers
+	printUserMsg, printError := GetLoggers()
+
+	// Read journal
+	var j *Journal
+	var jfh *os.File
+	if jfh, err = os.Open(jf); err != nil {
+		return printError.Fail(err)
+	}
+	j, err = journalReaders[format](jfh)
+	jfh.Close()
+	if err != nil {
+		return printError.Fail(err)
+	}
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	// Import journal
+	var added, skipped int
+	if added, skipped, err = JournalImport(fh, j); err != nil {
+		return printError.Fail(err)
+	}
+	for _, w := range j.Warnings {
+		printUserMsg.Println(w)
+	}
+	printUserMsg.Printf("imported %d transaction(s), skipped %d imported before\n", added, skipped)
+
+	return nil
+}
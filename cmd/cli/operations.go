@@ -10,6 +10,7 @@ import (
 	. "github.com/zbroju/financoj/lib"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 	"unicode/utf8"
 )
@@ -22,13 +23,13 @@ func CmdCreateNewDataFile(c *cli.Context) error {
 	// Check the obligatory parameters and exit if missing
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
 
 	// Create new data file
-	fh := GetDataFileHandler(f)
+	fh := dataFileHandler(f)
 	if err := CreateNewDataFile(fh); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Show summary
@@ -44,36 +45,56 @@ func CmdCategoryAdd(c *cli.Context) error {
 	// Get loggers
 	printUserMsg, printError := GetLoggers()
 
-	// Check obligatory flags (file, name)
+	// Check obligatory flags (file)
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 
 	}
-	n := c.String(ObjCategory)
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err := openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	// Check obligatory flags (name, main category or parent), ask for missing ones if possible
+	pr := newPrompter(c)
+	n := pr.String(c.String(ObjCategory), promptField{Label: HCName, Required: true})
 	if n == NotSetStringValue {
-		printError.Fatalln(errMissingCategoryFlag)
+		return printError.Fail(errMissingCategoryFlag)
 	}
+	p := pr.String(c.String(OptCategoryParent), promptField{Label: HCParent, Candidates: categoryCompletion(fh), Check: checkCategory(fh)})
 	m := c.String(ObjMainCategory)
-	if m == NotSetStringValue {
-		printError.Fatalln(errMissingMainCategoryFlag)
+	if p == NotSetStringValue {
+		m = pr.String(m, promptField{Label: HMCName, Required: true, Candidates: mainCategoryCompletion(fh), Check: checkMainCategory(fh)})
+	}
+	if m == NotSetStringValue && p == NotSetStringValue {
+		return printError.Fail(errMissingMainOrParentFlag)
 	}
 
 	// Add new category
-	fh := GetDataFileHandler(f)
-	if err := fh.Open(); err != nil {
-		printError.Fatalln(err)
+	newCategory := &Category{Name: n, Status: ISOpen}
+	if p != NotSetStringValue {
+		var pc *Category
+		if pc, err = CategoryForName(fh, p); err != nil {
+			return printError.Fail(err)
+		}
+		newCategory.Main = pc.Main
+		newCategory.ParentId = pc.Id
+	} else {
+		if newCategory.Main, err = MainCategoryForName(fh, m); err != nil {
+			return printError.Fail(err)
+		}
 	}
-	defer fh.Close()
 
-	var mc *MainCategory
-	if mc, err = MainCategoryForName(fh, m); err != nil {
-		printError.Fatalln(err)
+	if !pr.Confirm("new category", HCName, n, HMCName, newCategory.Main.Name, HCParent, p) {
+		printUserMsg.Printf("nothing has been changed\n")
+		return nil
 	}
-
-	newCategory := &Category{Main: mc, Name: n, Status: ISOpen}
 	if err = CategoryAdd(fh, newCategory); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Show summary
@@ -89,43 +110,80 @@ func CmdCategoryEdit(c *cli.Context) error {
 	// Get loggers
 	printUserMsg, printError := GetLoggers()
 
-	// Check obligatory flags
+	// Check obligatory flags (file)
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 
 	}
-	id := c.Int(OptID)
-	if id == NotSetIntValue {
-		printError.Fatalln(errMissingIDFlag)
-	}
 
 	// Open data file
-	fh := GetDataFileHandler(f)
-	if err := fh.Open(); err != nil {
-		printError.Fatalln(err)
+	fh := dataFileHandler(f)
+	if err := openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	// Check obligatory flags (id), ask for missing one if possible
+	pr := newPrompter(c)
+	id := pr.Int(c.Int(OptID), promptField{Label: HCId, Required: true})
+	if id == NotSetIntValue {
+		return printError.Fail(errMissingIDFlag)
 	}
-	defer fh.Close()
 
 	// Prepare new values based on old ones
 	var cat *Category
 	if cat, err = CategoryForID(fh, id); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
+	}
+	var tree *CategoryTree
+	if tree, err = CategoryTreeGet(fh); err != nil {
+		return printError.Fail(err)
+	}
+	parentPath := NotSetStringValue
+	if cat.ParentId != int64(NotSetIntValue) {
+		var pc *Category
+		if pc, err = CategoryForID(fh, int(cat.ParentId)); err != nil {
+			return printError.Fail(err)
+		}
+		parentPath = tree.FullPath(pc)
 	}
-	if m := c.String(ObjMainCategory); m != NotSetStringValue {
+
+	n := pr.String(c.String(ObjCategory), promptField{Label: HCName, Default: cat.Name})
+	p := pr.String(c.String(OptCategoryParent), promptField{Label: HCParent, Default: parentPath, Candidates: categoryCompletion(fh), Check: checkCategory(fh)})
+	m := c.String(ObjMainCategory)
+	if pr.all && p == NotSetStringValue {
+		m = pr.String(m, promptField{Label: HMCName, Default: cat.Main.Name, Required: true, Candidates: mainCategoryCompletion(fh), Check: checkMainCategory(fh)})
+	}
+	if m != NotSetStringValue {
 		var mcat *MainCategory
 		if mcat, err = MainCategoryForName(fh, m); err != nil {
-			printError.Fatalln(err)
+			return printError.Fail(err)
 		}
 		cat.Main = mcat
+		cat.ParentId = int64(NotSetIntValue)
+		parentPath = NotSetStringValue
+	}
+	if p != NotSetStringValue {
+		var pc *Category
+		if pc, err = CategoryForName(fh, p); err != nil {
+			return printError.Fail(err)
+		}
+		cat.Main = pc.Main
+		cat.ParentId = pc.Id
+		parentPath = tree.FullPath(pc)
 	}
-	if n := c.String(ObjCategory); n != NotSetStringValue {
+	if n != NotSetStringValue {
 		cat.Name = n
 	}
 
 	// Execute the changes
+	if !pr.Confirm("changed category", HCId, strconv.Itoa(id), HCName, cat.Name, HMCName, cat.Main.Name, HCParent, parentPath) {
+		printUserMsg.Printf("nothing has been changed\n")
+		return nil
+	}
 	if err = CategoryEdit(fh, cat); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Show summary
@@ -144,29 +202,29 @@ func CmdCategoryRemove(c *cli.Context) error {
 	// Check obligatory flags
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 
 	}
 	id := c.Int(OptID)
 	if id == NotSetIntValue {
-		printError.Fatalln(errMissingIDFlag)
+		return printError.Fail(errMissingIDFlag)
 	}
 
 	// Open data file and get original main category
-	fh := GetDataFileHandler(f)
-	if err = fh.Open(); err != nil {
-		printError.Fatalln(err)
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	defer fh.Close()
+	defer closeDataFile(fh)
 
 	var cat *Category
 	if cat, err = CategoryForID(fh, id); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Remove the category
 	if err = CategoryRemove(fh, cat); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Show summary
@@ -186,41 +244,50 @@ func CmdCategoryList(c *cli.Context) error {
 	// Check obligatory flags
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
 
 	mn := c.String(ObjMainCategory)
 	cat := c.String(ObjCategory)
+	depth := c.Int(OptDepth)
 	s := ISOpen
 	if a := c.Bool(OptAll); a == true {
 		s = ISUnset
 	}
 
 	// Open data file
-	fh := GetDataFileHandler(f)
-	if err = fh.Open(); err != nil {
-		printError.Fatalln(err)
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	defer fh.Close()
+	defer closeDataFile(fh)
 
 	var mcat *MainCategory
 	if mn != NotSetStringValue {
 		if mcat, err = MainCategoryForName(fh, mn); err != nil {
-			printError.Fatalln(err)
+			return printError.Fail(err)
 		}
 	}
 
+	var tree *CategoryTree
+	if tree, err = CategoryTreeGet(fh); err != nil {
+		return printError.Fail(err)
+	}
+
 	// Build formatting strings
 	var getNextCategory func() *Category
 	if getNextCategory, err = CategoryList(fh, mcat, cat, s); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	lId, lType, lMCat, lCat, lStatus := utf8.RuneCountInString(HCId), utf8.RuneCountInString(HMCType), utf8.RuneCountInString(HMCName), utf8.RuneCountInString(HCName), utf8.RuneCountInString(HMCStatus)
 	for ct := getNextCategory(); ct != nil; ct = getNextCategory() {
+		if depth != NotSetIntValue && tree.Depth(ct) > depth {
+			continue
+		}
 		lId = MaxLen(strconv.FormatInt(ct.Id, 10), lId)
 		lType = MaxLen(ct.Main.MType.Name, lType)
 		lMCat = MaxLen(ct.Main.Name, lMCat)
-		lCat = MaxLen(ct.Name, lCat)
+		lCat = MaxLen(tree.Path(ct), lCat)
 		lStatus = MaxLen(ct.Status.String(), lStatus)
 	}
 	lineH := LineFor(HFSForNumeric(lId), HFSForText(lType), HFSForText(lMCat), HFSForText(lCat), HFSForText(lStatus))
@@ -228,11 +295,14 @@ func CmdCategoryList(c *cli.Context) error {
 
 	// Print categories
 	if getNextCategory, err = CategoryList(fh, mcat, cat, s); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	fmt.Fprintf(os.Stdout, lineH, HCId, HMCType, HMCName, HCName, HMCStatus)
 	for ct := getNextCategory(); ct != nil; ct = getNextCategory() {
-		fmt.Fprintf(os.Stdout, lineD, ct.Id, ct.Main.MType.Name, ct.Main.Name, ct.Name, ct.Status)
+		if depth != NotSetIntValue && tree.Depth(ct) > depth {
+			continue
+		}
+		fmt.Fprintf(os.Stdout, lineD, ct.Id, ct.Main.MType.Name, ct.Main.Name, tree.Path(ct), ct.Status)
 	}
 
 	return nil
@@ -245,39 +315,47 @@ func CmdMainCategoryAdd(c *cli.Context) error {
 	// Get loggers
 	printUserMsg, printError := GetLoggers()
 
-	// Check obligatory flags (file, name)
+	// Check obligatory flags (file)
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 
 	}
-	n := c.String(ObjMainCategory)
-	if n == NotSetStringValue {
-		printError.Fatalln(errMissingMainCategoryFlag)
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err := openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	tn := c.String(OptMainCategoryType)
+	defer closeDataFile(fh)
 
-	// Add new main category
-	fh := GetDataFileHandler(f)
-	if err := fh.Open(); err != nil {
-		printError.Fatalln(err)
+	// Check obligatory flags (name), ask for missing one if possible
+	pr := newPrompter(c)
+	n := pr.String(c.String(ObjMainCategory), promptField{Label: HMCName, Required: true})
+	if n == NotSetStringValue {
+		return printError.Fail(errMissingMainCategoryFlag)
 	}
-	defer fh.Close()
+	tn := pr.String(c.String(OptMainCategoryType), promptField{Label: HMCType, Candidates: mainCategoryTypeCompletion(fh), Check: checkMainCategoryType(fh)})
 
+	// Add new main category
 	var t *MainCategoryType
 	if tn != NotSetStringValue {
 		if t, err = MainCategoryTypeForName(fh, tn); err != nil {
-			printError.Fatalln(err)
+			return printError.Fail(err)
 		}
 	} else {
 		if t, err = MainCategoryTypeForID(fh, MCTCost); err != nil {
-			printError.Fatalln(err)
+			return printError.Fail(err)
 		}
 	}
 
 	m := &MainCategory{MType: t, Name: n, Status: ISOpen}
+	if !pr.Confirm("new main category", HMCName, m.Name, HMCType, t.Name) {
+		printUserMsg.Printf("nothing has been changed\n")
+		return nil
+	}
 	if err = MainCategoryAdd(fh, m); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Show summary
@@ -293,40 +371,48 @@ func CmdMainCategoryEdit(c *cli.Context) error {
 	// Get loggers
 	printUserMsg, printError := GetLoggers()
 
-	// Check obligatory flags
+	// Check obligatory flags (file)
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 
 	}
-	id := c.Int(OptID)
-	if id == NotSetIntValue {
-		printError.Fatalln(errMissingIDFlag)
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err := openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
+	defer closeDataFile(fh)
 
-	// Open data file and get original main category
-	fh := GetDataFileHandler(f)
-	if err := fh.Open(); err != nil {
-		printError.Fatalln(err)
+	// Check obligatory flags (id), ask for missing one if possible
+	pr := newPrompter(c)
+	id := pr.Int(c.Int(OptID), promptField{Label: HMCId, Required: true})
+	if id == NotSetIntValue {
+		return printError.Fail(errMissingIDFlag)
 	}
-	defer fh.Close()
 
+	// Get original main category
 	var mc *MainCategory
 	if mc, err = MainCategoryForID(fh, id); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Edit main category
-	if t := c.String(OptMainCategoryType); t != NotSetStringValue {
+	if t := pr.String(c.String(OptMainCategoryType), promptField{Label: HMCType, Default: mc.MType.Name, Candidates: mainCategoryTypeCompletion(fh), Check: checkMainCategoryType(fh)}); t != NotSetStringValue {
 		if mc.MType, err = MainCategoryTypeForName(fh, t); err != nil {
-			printError.Fatalln(err)
+			return printError.Fail(err)
 		}
 	}
-	if n := c.String(ObjMainCategory); n != NotSetStringValue {
+	if n := pr.String(c.String(ObjMainCategory), promptField{Label: HMCName, Default: mc.Name}); n != NotSetStringValue {
 		mc.Name = n
 	}
+	if !pr.Confirm("changed main category", HMCId, strconv.Itoa(id), HMCName, mc.Name, HMCType, mc.MType.Name) {
+		printUserMsg.Printf("nothing has been changed\n")
+		return nil
+	}
 	if err = MainCategoryEdit(fh, mc); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Show summary
@@ -345,28 +431,28 @@ func CmdMainCategoryRemove(c *cli.Context) error {
 	// Check obligatory flags
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
 	id := c.Int(OptID)
 	if id == NotSetIntValue {
-		printError.Fatalln(errMissingIDFlag)
+		return printError.Fail(errMissingIDFlag)
 	}
 
 	// Open data file and get original main category
-	fh := GetDataFileHandler(f)
-	if err = fh.Open(); err != nil {
-		printError.Fatalln(err)
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	defer fh.Close()
+	defer closeDataFile(fh)
 
 	var mc *MainCategory
 	if mc, err = MainCategoryForID(fh, id); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Remove the main category
 	if err = MainCategoryRemove(fh, mc); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Show summary
@@ -385,21 +471,21 @@ func CmdMainCategoryList(c *cli.Context) error {
 	// Check obligatory flags
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
 
 	// Open data file
-	fh := GetDataFileHandler(f)
-	if err = fh.Open(); err != nil {
-		printError.Fatalln(err)
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	defer fh.Close()
+	defer closeDataFile(fh)
 
 	// Create filters
 	var mct *MainCategoryType
 	if t := c.String(OptMainCategoryType); t != NotSetStringValue {
 		if mct, err = MainCategoryTypeForName(fh, t); err != nil {
-			printError.Fatalln(err)
+			return printError.Fail(err)
 		}
 	}
 	n := c.String(ObjMainCategory)
@@ -411,7 +497,7 @@ func CmdMainCategoryList(c *cli.Context) error {
 	// Build formatting strings
 	var getNextMainCategory func() *MainCategory
 	if getNextMainCategory, err = MainCategoryList(fh, mct, n, s); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	lId, lType, lName, lStatus := utf8.RuneCountInString(HMCId), utf8.RuneCountInString(HMCType), utf8.RuneCountInString(HMCName), utf8.RuneCountInString(HMCStatus)
 	for m := getNextMainCategory(); m != nil; m = getNextMainCategory() {
@@ -425,7 +511,7 @@ func CmdMainCategoryList(c *cli.Context) error {
 
 	// Print main categories
 	if getNextMainCategory, err = MainCategoryList(fh, mct, n, s); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	fmt.Fprintf(os.Stdout, lineH, HMCId, HMCType, HMCName, HMCStatus)
 	for m := getNextMainCategory(); m != nil; m = getNextMainCategory() {
@@ -435,6 +521,213 @@ func CmdMainCategoryList(c *cli.Context) error {
 	return nil
 }
 
+// CmdMainCategoryTypeAdd adds new main category type
+func CmdMainCategoryTypeAdd(c *cli.Context) error {
+	var err error
+
+	// Get loggers
+	printUserMsg, printError := GetLoggers()
+
+	// Check obligatory flags (file)
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	// Check obligatory flags (name), ask for missing one if possible
+	pr := newPrompter(c)
+	n := pr.String(c.String(OptMainCategoryType), promptField{Label: HMCTName, Required: true})
+	if n == NotSetStringValue {
+		return printError.Fail(errMissingMainCategoryTypeFlag)
+	}
+
+	// Build main category type
+	t := MainCategoryTypeNew()
+	t.Name = n
+	if fc := pr.Int(c.Int(OptFactor), promptField{Label: HMCTFactor, Default: strconv.Itoa(t.Factor), Check: checkFactor}); fc != NotSetIntValue {
+		t.Factor = fc
+	}
+	if r := pr.String(c.String(OptReports), promptField{Label: HMCTReports, Default: reportsCompletion()[0], Candidates: reportsCompletion, Check: checkReports}); r != NotSetStringValue {
+		if t.InIncomeCost, t.InBudget, t.InNetValue, err = MainCategoryTypeReportsForString(r); err != nil {
+			return printError.Fail(err)
+		}
+	}
+
+	// Add new main category type
+	if !pr.Confirm("new main category type", HMCTName, t.Name, HMCTFactor, strconv.Itoa(t.Factor), HMCTIncomeCost, YesNo(t.InIncomeCost), HMCTBudget, YesNo(t.InBudget), HMCTNetValue, YesNo(t.InNetValue)) {
+		printUserMsg.Printf("nothing has been changed\n")
+		return nil
+	}
+	if err = MainCategoryTypeAdd(fh, t); err != nil {
+		return printError.Fail(err)
+	}
+
+	// Show summary
+	printUserMsg.Printf("added new main category type: %s (factor: %d)\n", n, t.Factor)
+
+	return nil
+}
+
+// CmdMainCategoryTypeEdit updates main category type with new values
+func CmdMainCategoryTypeEdit(c *cli.Context) error {
+	var err error
+
+	// Get loggers
+	printUserMsg, printError := GetLoggers()
+
+	// Check obligatory flags (file)
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	// Check obligatory flags (id), ask for missing one if possible
+	pr := newPrompter(c)
+	id := pr.Int(c.Int(OptID), promptField{Label: HMCTId, Required: true})
+	if id == NotSetIntValue {
+		return printError.Fail(errMissingIDFlag)
+	}
+
+	// Get original main category type
+	var t *MainCategoryType
+	if t, err = MainCategoryTypeForID(fh, id); err != nil {
+		return printError.Fail(err)
+	}
+
+	// Edit main category type
+	if n := pr.String(c.String(OptMainCategoryType), promptField{Label: HMCTName, Default: t.Name}); n != NotSetStringValue {
+		t.Name = n
+	}
+	if fc := pr.Int(c.Int(OptFactor), promptField{Label: HMCTFactor, Default: strconv.Itoa(t.Factor), Check: checkFactor}); fc != NotSetIntValue {
+		t.Factor = fc
+	}
+	if r := pr.String(c.String(OptReports), promptField{Label: HMCTReports, Default: MainCategoryTypeReportsString(t.InIncomeCost, t.InBudget, t.InNetValue), Candidates: reportsCompletion, Check: checkReports}); r != NotSetStringValue {
+		if t.InIncomeCost, t.InBudget, t.InNetValue, err = MainCategoryTypeReportsForString(r); err != nil {
+			return printError.Fail(err)
+		}
+	}
+	if !pr.Confirm("changed main category type", HMCTId, strconv.Itoa(id), HMCTName, t.Name, HMCTFactor, strconv.Itoa(t.Factor), HMCTIncomeCost, YesNo(t.InIncomeCost), HMCTBudget, YesNo(t.InBudget), HMCTNetValue, YesNo(t.InNetValue)) {
+		printUserMsg.Printf("nothing has been changed\n")
+		return nil
+	}
+	if err = MainCategoryTypeEdit(fh, t); err != nil {
+		return printError.Fail(err)
+	}
+
+	// Show summary
+	printUserMsg.Printf("changed details of main category type with id = %d\n", id)
+
+	return nil
+}
+
+// CmdMainCategoryTypeRemove sets main category type status to ISClose
+func CmdMainCategoryTypeRemove(c *cli.Context) error {
+	var err error
+
+	// Get loggers
+	printUserMsg, printError := GetLoggers()
+
+	// Check obligatory flags
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+	id := c.Int(OptID)
+	if id == NotSetIntValue {
+		return printError.Fail(errMissingIDFlag)
+	}
+
+	// Open data file and get original main category type
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	var t *MainCategoryType
+	if t, err = MainCategoryTypeForID(fh, id); err != nil {
+		return printError.Fail(err)
+	}
+
+	// Remove the main category type
+	if err = MainCategoryTypeRemove(fh, t); err != nil {
+		return printError.Fail(err)
+	}
+
+	// Show summary
+	printUserMsg.Printf("removed main category type with id = %d\n", t.Id)
+
+	return nil
+}
+
+// CmdMainCategoryTypeList prints main category types on standard output
+func CmdMainCategoryTypeList(c *cli.Context) error {
+	var err error
+
+	// Get loggers
+	_, printError := GetLoggers()
+
+	// Check obligatory flags
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	// Create filters
+	s := ISOpen
+	if a := c.Bool(OptAll); a == true {
+		s = ISUnset
+	}
+
+	// Build formatting strings
+	var getNextMainCategoryType func() *MainCategoryType
+	if getNextMainCategoryType, err = MainCategoryTypeList(fh, s); err != nil {
+		return printError.Fail(err)
+	}
+	lId, lName, lFactor := utf8.RuneCountInString(HMCTId), utf8.RuneCountInString(HMCTName), utf8.RuneCountInString(HMCTFactor)
+	lIncomeCost, lBudget, lNetValue, lStatus := utf8.RuneCountInString(HMCTIncomeCost), utf8.RuneCountInString(HMCTBudget), utf8.RuneCountInString(HMCTNetValue), utf8.RuneCountInString(HMCTStatus)
+	for t := getNextMainCategoryType(); t != nil; t = getNextMainCategoryType() {
+		lId = MaxLen(strconv.Itoa(t.Id), lId)
+		lName = MaxLen(t.Name, lName)
+		lFactor = MaxLen(strconv.Itoa(t.Factor), lFactor)
+		lStatus = MaxLen(t.Status.String(), lStatus)
+	}
+	lineH := LineFor(HFSForNumeric(lId), HFSForText(lName), HFSForNumeric(lFactor), HFSForText(lIncomeCost), HFSForText(lBudget), HFSForText(lNetValue), HFSForText(lStatus))
+	lineD := LineFor(DFSForID(lId), DFSForText(lName), DFSForID(lFactor), DFSForText(lIncomeCost), DFSForText(lBudget), DFSForText(lNetValue), DFSForText(lStatus))
+
+	// Print main category types
+	if getNextMainCategoryType, err = MainCategoryTypeList(fh, s); err != nil {
+		return printError.Fail(err)
+	}
+	fmt.Fprintf(os.Stdout, lineH, HMCTId, HMCTName, HMCTFactor, HMCTIncomeCost, HMCTBudget, HMCTNetValue, HMCTStatus)
+	for t := getNextMainCategoryType(); t != nil; t = getNextMainCategoryType() {
+		fmt.Fprintf(os.Stdout, lineD, t.Id, t.Name, t.Factor, YesNo(t.InIncomeCost), YesNo(t.InBudget), YesNo(t.InNetValue), t.Status)
+	}
+
+	return nil
+}
+
 // CmdExchangeRateAdd adds new currency exchange rate
 func CmdExchangeRateAdd(c *cli.Context) error {
 	var err error
@@ -442,35 +735,43 @@ func CmdExchangeRateAdd(c *cli.Context) error {
 	// Get loggers
 	printUserMsg, printError := GetLoggers()
 
-	// Check obligatory flags (file, name)
+	// Check obligatory flags (file)
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 
 	}
-	curFrom := c.String(OptCurrency)
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err := openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	// Check obligatory flags (currencies, rate), ask for missing ones if possible
+	pr := newPrompter(c)
+	curFrom := pr.String(c.String(OptCurrency), promptField{Label: HCurF, Required: true, Candidates: currencyCompletion(fh)})
 	if curFrom == NotSetStringValue {
-		printError.Fatalln(errMissingCurrencyFlag)
+		return printError.Fail(errMissingCurrencyFlag)
 	}
-	curTo := c.String(OptCurrencyTo)
+	curTo := pr.String(c.String(OptCurrencyTo), promptField{Label: HCurT, Required: true, Candidates: currencyCompletion(fh)})
 	if curTo == NotSetStringValue {
-		printError.Fatalln(errMissingCurrencyToFlag)
+		return printError.Fail(errMissingCurrencyToFlag)
 	}
-	rate := c.Float64(ObjExchangeRate)
+	rate := pr.Float(c.Float64(ObjExchangeRate), promptField{Label: HCurRate, Required: true})
 	if rate == NotSetFloatValue {
-		printError.Fatalln(errMissingExchangeRateFlag)
+		return printError.Fail(errMissingExchangeRateFlag)
 	}
 
 	// Add currency exchange rate
-	fh := GetDataFileHandler(f)
-	if err := fh.Open(); err != nil {
-		printError.Fatalln(err)
-	}
-	defer fh.Close()
-
 	newCurrency := &ExchangeRate{CurrencyFrom: curFrom, CurrencyTo: curTo, Rate: rate}
+	if !pr.Confirm("new currency exchange rate", HCurF, curFrom, HCurT, curTo, HCurRate, strconv.FormatFloat(rate, 'f', -1, 64)) {
+		printUserMsg.Printf("nothing has been changed\n")
+		return nil
+	}
 	if err = ExchangeRateAdd(fh, newCurrency); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Show summary
@@ -485,41 +786,51 @@ func CmdExchangeRateEdit(c *cli.Context) error {
 	// Get loggers
 	printUserMsg, printError := GetLoggers()
 
-	// Check obligatory flags
+	// Check obligatory flags (file)
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
+
+	}
 
+	// Open data file
+	fh := dataFileHandler(f)
+	if err := openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	cf := c.String(OptCurrency)
+	defer closeDataFile(fh)
+
+	// Check obligatory flags (currencies), ask for missing ones if possible
+	pr := newPrompter(c)
+	cf := pr.String(c.String(OptCurrency), promptField{Label: HCurF, Required: true, Candidates: currencyCompletion(fh)})
 	if cf == NotSetStringValue {
-		printError.Fatalln(errMissingCurrencyFlag)
+		return printError.Fail(errMissingCurrencyFlag)
 	}
-	ct := c.String(OptCurrencyTo)
+	ct := pr.String(c.String(OptCurrencyTo), promptField{Label: HCurT, Required: true, Candidates: currencyCompletion(fh)})
 	if ct == NotSetStringValue {
-		printError.Fatalln(errMissingCurrencyToFlag)
-	}
-	r := c.Float64(ObjExchangeRate)
-	if r == NotSetFloatValue {
-		printError.Fatalln(errMissingExchangeRateFlag)
+		return printError.Fail(errMissingCurrencyToFlag)
 	}
 
-	// Open data file and get original main category
-	fh := GetDataFileHandler(f)
-	if err := fh.Open(); err != nil {
-		printError.Fatalln(err)
-	}
-	defer fh.Close()
-
+	// Get original exchange rate
 	var e *ExchangeRate
 	if e, err = ExchangeRateForCurrencies(fh, cf, ct); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
+	}
+
+	// Check obligatory flags (rate), ask for missing one if possible
+	r := pr.Float(c.Float64(ObjExchangeRate), promptField{Label: HCurRate, Default: strconv.FormatFloat(e.Rate, 'f', -1, 64), Required: true})
+	if r == NotSetFloatValue {
+		return printError.Fail(errMissingExchangeRateFlag)
 	}
 
 	// Edit exchange rate
 	e.Rate = r
+	if !pr.Confirm("changed currency exchange rate", HCurF, e.CurrencyFrom, HCurT, e.CurrencyTo, HCurRate, strconv.FormatFloat(e.Rate, 'f', -1, 64)) {
+		printUserMsg.Printf("nothing has been changed\n")
+		return nil
+	}
 	if err = ExchangeRateEdit(fh, e); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Show summary
@@ -538,20 +849,20 @@ func CmdExchangeRateList(c *cli.Context) error {
 	// Check obligatory flags
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
 
 	// Open data file
-	fh := GetDataFileHandler(f)
-	if err = fh.Open(); err != nil {
-		printError.Fatalln(err)
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	defer fh.Close()
+	defer closeDataFile(fh)
 
 	// Build formatting strings
 	var getNextCurrency func() *ExchangeRate
 	if getNextCurrency, err = ExchangeRateList(fh); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	lCurF, lCurT, lRate := utf8.RuneCountInString(HCurF), utf8.RuneCountInString(HCurT), utf8.RuneCountInString(HCurRate)
 	for cur := getNextCurrency(); cur != nil; cur = getNextCurrency() {
@@ -564,7 +875,7 @@ func CmdExchangeRateList(c *cli.Context) error {
 
 	// Print currencies
 	if getNextCurrency, err = ExchangeRateList(fh); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	fmt.Fprintf(os.Stdout, lineH, HCurF, HCurT, HCurRate)
 	for cur := getNextCurrency(); cur != nil; cur = getNextCurrency() {
@@ -584,33 +895,33 @@ func CmdExchangeRateRemove(c *cli.Context) error {
 	// Check obligatory flags
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 
 	}
 	j := c.String(OptCurrency)
 	if j == NotSetStringValue {
-		printError.Fatalln(errMissingCurrencyFlag)
+		return printError.Fail(errMissingCurrencyFlag)
 	}
 	k := c.String(OptCurrencyTo)
 	if k == NotSetStringValue {
-		printError.Fatalln(errMissingCurrencyToFlag)
+		return printError.Fail(errMissingCurrencyToFlag)
 	}
 
 	// Open data file and get original main category
-	fh := GetDataFileHandler(f)
-	if err = fh.Open(); err != nil {
-		printError.Fatalln(err)
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	defer fh.Close()
+	defer closeDataFile(fh)
 
 	var cur *ExchangeRate
 	if cur, err = ExchangeRateForCurrencies(fh, j, k); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Remove the exchange rate
 	if err = ExchangeRateRemove(fh, cur); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Show summary
@@ -625,39 +936,47 @@ func CmdAccountAdd(c *cli.Context) error {
 	// Get loggers
 	printUserMsg, printError := GetLoggers()
 
-	// Check obligatory flags (file, name)
+	// Check obligatory flags (file)
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 
 	}
-	n := c.String(ObjAccount)
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err := openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	// Check obligatory flags (name, currency, type), ask for missing ones if possible
+	pr := newPrompter(c)
+	n := pr.String(c.String(ObjAccount), promptField{Label: HAName, Required: true})
 	if n == NotSetStringValue {
-		printError.Fatalln(errMissingAccountFlag)
+		return printError.Fail(errMissingAccountFlag)
 	}
-	j := c.String(OptCurrency)
+	j := pr.String(c.String(OptCurrency), promptField{Label: HACurrency, Required: true, Candidates: currencyCompletion(fh)})
 	if j == NotSetStringValue {
-		printError.Fatalln(errMissingCurrencyFlag)
+		return printError.Fail(errMissingCurrencyFlag)
 	}
-	t := AccountTypeForString(c.String(OptAccountType))
+	t := AccountTypeForString(pr.String(c.String(OptAccountType), promptField{Label: HAType, Default: accountTypeName(ATTransactional), Candidates: accountTypeCompletion, Check: checkAccountType}))
 	if t == ATUnknown {
-		printError.Fatalln(errIncorrectAccountType)
+		return printError.Fail(errIncorrectAccountType)
 	}
 
 	// Other flags
-	d := c.String(OptDescription)
-	i := c.String(OptInstitution)
+	d := pr.String(c.String(OptDescription), promptField{Label: HADescription})
+	i := pr.String(c.String(OptInstitution), promptField{Label: HAInstitution})
 
 	// Add new account
-	fh := GetDataFileHandler(f)
-	if err := fh.Open(); err != nil {
-		printError.Fatalln(err)
-	}
-	defer fh.Close()
-
 	a := &Account{Name: n, Description: d, Institution: i, Currency: j, AType: t, Status: ISOpen}
+	if !pr.Confirm("new account", HAName, a.Name, HACurrency, a.Currency, HAType, a.AType.String(), HADescription, a.Description, HAInstitution, a.Institution) {
+		printUserMsg.Printf("nothing has been changed\n")
+		return nil
+	}
 	if err := AccountAdd(fh, a); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Show summary
@@ -677,7 +996,7 @@ func CmdAccountList(c *cli.Context) error {
 	// Check obligatory flags
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
 
 	// Parse other flags
@@ -690,7 +1009,7 @@ func CmdAccountList(c *cli.Context) error {
 		atype = ATUnset
 	} else {
 		if atype = AccountTypeForString(t); atype == ATUnknown {
-			printError.Fatalln(errIncorrectAccountType)
+			return printError.Fail(errIncorrectAccountType)
 		}
 	}
 	status := ISOpen
@@ -699,16 +1018,16 @@ func CmdAccountList(c *cli.Context) error {
 	}
 
 	// Open data file
-	fh := GetDataFileHandler(f)
-	if err = fh.Open(); err != nil {
-		printError.Fatalln(err)
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	defer fh.Close()
+	defer closeDataFile(fh)
 
 	// Build formatting strings
 	var getNextAccount func() *Account
 	if getNextAccount, err = AccountList(fh, name, description, institution, currency, atype, status); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	lId := utf8.RuneCountInString(HAId)
 	lN := utf8.RuneCountInString(HAName)
@@ -731,7 +1050,7 @@ func CmdAccountList(c *cli.Context) error {
 
 	// Print accounts
 	if getNextAccount, err = AccountList(fh, name, description, institution, currency, atype, status); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	fmt.Fprintf(os.Stdout, lineH, HAId, HAName, HAType, HACurrency, HAInstitution, HAStatus, HADescription)
 	for a := getNextAccount(); a != nil; a = getNextAccount() {
@@ -748,53 +1067,60 @@ func CmdAccountEdit(c *cli.Context) error {
 	// Get loggers
 	printUserMsg, printError := GetLoggers()
 
-	// Check obligatory flags
+	// Check obligatory flags (file)
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 
 	}
-	id := c.Int(OptID)
-	if id == NotSetIntValue {
-		printError.Fatalln(errMissingIDFlag)
-	}
 
 	// Open data file
-	fh := GetDataFileHandler(f)
-	if err := fh.Open(); err != nil {
-		printError.Fatalln(err)
+	fh := dataFileHandler(f)
+	if err := openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	// Check obligatory flags (id), ask for missing one if possible
+	pr := newPrompter(c)
+	id := pr.Int(c.Int(OptID), promptField{Label: HAId, Required: true})
+	if id == NotSetIntValue {
+		return printError.Fail(errMissingIDFlag)
 	}
-	defer fh.Close()
 
 	// Prepare new values based on old ones
 	var a *Account
 	if a, err = AccountForID(fh, id); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
-	if n := c.String(ObjAccount); n != NotSetStringValue {
+	if n := pr.String(c.String(ObjAccount), promptField{Label: HAName, Default: a.Name}); n != NotSetStringValue {
 		a.Name = n
 	}
-	if d := c.String(OptDescription); d != NotSetStringValue {
+	if d := pr.String(c.String(OptDescription), promptField{Label: HADescription, Default: a.Description}); d != NotSetStringValue || pr.all {
 		a.Description = d
 	}
-	if i := c.String(OptInstitution); i != NotSetStringValue {
+	if i := pr.String(c.String(OptInstitution), promptField{Label: HAInstitution, Default: a.Institution}); i != NotSetStringValue || pr.all {
 		a.Institution = i
 	}
-	if j := c.String(OptCurrency); j != NotSetStringValue {
+	if j := pr.String(c.String(OptCurrency), promptField{Label: HACurrency, Default: a.Currency, Candidates: currencyCompletion(fh)}); j != NotSetStringValue {
 		a.Currency = j
 	}
-	if ts := c.String(OptAccountType); ts != NotSetStringValue {
+	if ts := pr.String(c.String(OptAccountType), promptField{Label: HAType, Default: accountTypeName(a.AType), Candidates: accountTypeCompletion, Check: checkAccountType}); ts != NotSetStringValue {
 		if at := AccountTypeForString(ts); at == ATUnknown {
-			printError.Fatalln(errIncorrectAccountType)
+			return printError.Fail(errIncorrectAccountType)
 		} else {
 			a.AType = at
 		}
 	}
 
 	// Execute the changes
+	if !pr.Confirm("changed account", HAId, strconv.Itoa(id), HAName, a.Name, HACurrency, a.Currency, HAType, a.AType.String(), HADescription, a.Description, HAInstitution, a.Institution) {
+		printUserMsg.Printf("nothing has been changed\n")
+		return nil
+	}
 	if err = AccountEdit(fh, a); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Show summary
@@ -813,28 +1139,28 @@ func CmdAccountRemove(c *cli.Context) error {
 	// Check obligatory flags
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
 	id := c.Int(OptID)
 	if id == NotSetIntValue {
-		printError.Fatalln(errMissingIDFlag)
+		return printError.Fail(errMissingIDFlag)
 	}
 
 	// Open data file and get original main category
-	fh := GetDataFileHandler(f)
-	if err = fh.Open(); err != nil {
-		printError.Fatalln(err)
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	defer fh.Close()
+	defer closeDataFile(fh)
 
 	var a *Account
 	if a, err = AccountForID(fh, id); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Remove the account
 	if err = AccountRemove(fh, a); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Show summary
@@ -850,54 +1176,62 @@ func CmdTransactionAdd(c *cli.Context) error {
 	// Get loggers
 	printUserMsg, printError := GetLoggers()
 
-	// Check obligatory flags
+	// Check obligatory flags (file)
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
-	d := c.String(OptDescription)
-	if d == NotSetStringValue {
-		printError.Fatalln(errMissingDescriptionFlag)
-	}
-	v := c.Float64(OptValue)
-	if v == NotSetFloatValue {
-		printError.Fatalln(errMissingValueFlag)
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err := openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	an := c.String(ObjAccount)
+	defer closeDataFile(fh)
+
+	// Check obligatory flags (account, category, value, description), ask for missing ones if possible
+	t := TransactionNew()
+	pr := newPrompter(c)
+	td := pr.String(c.String(OptDate), promptField{Label: HTDate, Default: t.Date.Format(DateFormat), Check: checkDate})
+	an := pr.String(c.String(ObjAccount), promptField{Label: HAName, Required: true, Candidates: accountCompletion(fh), Check: checkAccount(fh)})
 	if an == NotSetStringValue {
-		printError.Fatalln(errMissingAccountFlag)
+		return printError.Fail(errMissingAccountFlag)
 	}
-	cn := c.String(ObjCategory)
+	cn := pr.String(c.String(ObjCategory), promptField{Label: HCName, Required: true, Candidates: categoryCompletion(fh), Check: checkCategory(fh)})
 	if cn == NotSetStringValue {
-		printError.Fatalln(errMissingCategoryFlag)
+		return printError.Fail(errMissingCategoryFlag)
 	}
-
-	// Open data file
-	fh := GetDataFileHandler(f)
-	if err := fh.Open(); err != nil {
-		printError.Fatalln(err)
+	v := pr.Float(c.Float64(OptValue), promptField{Label: HTValue, Required: true})
+	if v == NotSetFloatValue {
+		return printError.Fail(errMissingValueFlag)
+	}
+	d := pr.String(c.String(OptDescription), promptField{Label: HTDescription, Required: true})
+	if d == NotSetStringValue {
+		return printError.Fail(errMissingDescriptionFlag)
 	}
-	defer fh.Close()
 
 	// Create the transaction object
-	t := TransactionNew()
-	if td := c.String(OptDate); td != NotSetStringValue {
-		if t.Date, err = time.Parse(DateFormat, td); err != nil {
-			printError.Fatalln(err)
+	if td != NotSetStringValue {
+		if t.Date, err = DateParse(fh, td); err != nil {
+			return printError.Fail(err)
 		}
 	}
 	if t.Category, err = CategoryForName(fh, cn); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	if t.Account, err = AccountForName(fh, an); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	t.Value = v
 	t.Description = d
 
 	// Add transaction
+	if !pr.Confirm("new transaction", transactionSummary(fh, t)...) {
+		printUserMsg.Printf("nothing has been changed\n")
+		return nil
+	}
 	if err = TransactionAdd(fh, t); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Show summary
@@ -916,57 +1250,90 @@ func CmdTransactionList(c *cli.Context) error {
 	// Check obligatory flags
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
 
 	// Open data file
-	fh := GetDataFileHandler(f)
-	if err = fh.Open(); err != nil {
-		printError.Fatalln(err)
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	defer fh.Close()
+	defer closeDataFile(fh)
 
 	// Get filtering criteria
-	var dateFrom time.Time
-	if ds := c.String(OptDateFrom); ds != NotSetStringValue {
-		if dateFrom, err = time.Parse(DateFormat, ds); err != nil {
-			printError.Fatalln(err)
-		}
-	} else {
-		dateFrom = time.Time{}
-	}
-	var dateTo time.Time
-	if ds := c.String(OptDateTo); ds != NotSetStringValue {
-		if dateTo, err = time.Parse(DateFormat, ds); err != nil {
-			printError.Fatalln(err)
-		}
-	} else {
-		dateTo = time.Time{}
+	var dateFrom, dateTo time.Time
+	if dateFrom, dateTo, err = datesRangeFlags(c, fh); err != nil {
+		return printError.Fail(err)
 	}
 	var account *Account
 	if as := c.String(ObjAccount); as != NotSetStringValue {
 		if account, err = AccountForName(fh, as); err != nil {
-			printError.Fatalln(err)
+			return printError.Fail(err)
 		}
 	}
 	description := c.String(OptDescription)
 	var category *Category
 	if cs := c.String(ObjCategory); cs != NotSetStringValue {
 		if category, err = CategoryForName(fh, cs); err != nil {
-			printError.Fatalln(err)
+			return printError.Fail(err)
 		}
 	}
 	var mainCategory *MainCategory
 	if ms := c.String(ObjMainCategory); ms != NotSetStringValue {
 		if mainCategory, err = MainCategoryForName(fh, ms); err != nil {
-			printError.Fatalln(err)
+			return printError.Fail(err)
 		}
 	}
 
+	var tree *CategoryTree
+	if tree, err = CategoryTreeGet(fh); err != nil {
+		return printError.Fail(err)
+	}
+
+	// Running balance is shown for one account (in its currency) or in given currency,
+	// starting from balance before the first day of the list. With other filters it is running total
+	// of the filtered transactions only.
+	currency := strings.ToUpper(c.String(OptCurrency))
+	showBalance := account != nil || currency != NotSetStringValue
+	var balance float64
+	if showBalance {
+		if balance, err = TransactionBalanceBefore(fh, dateFrom, account, description, category, mainCategory, currency); err != nil {
+			return printError.Fail(err)
+		}
+	}
+	hBalance := HTBalance
+	if description != NotSetStringValue || category != nil || mainCategory != nil {
+		hBalance = HTTotal
+	}
+	if currency != NotSetStringValue {
+		hBalance += " " + currency
+	}
+	rates := make(map[string]float64)
+	var rateErr error
+	runningBalance := func(t *Transaction) float64 {
+		if currency == NotSetStringValue {
+			balance += t.GetSValue()
+			return balance
+		}
+		r, ok := rates[t.Account.Currency]
+		if !ok {
+			e, err := ExchangeRateForCurrencies(fh, t.Account.Currency, currency)
+			if err != nil {
+				rateErr = err
+				return balance
+			}
+			r = e.Rate
+			rates[t.Account.Currency] = r
+		}
+		balance += t.GetSValue() * r
+		return balance
+	}
+	var balances []float64
+
 	// Build formatting strings
 	var getNextTransaction func() *Transaction
 	if getNextTransaction, err = TransactionList(fh, dateFrom, dateTo, account, description, category, mainCategory); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	lId := utf8.RuneCountInString(HTId)
 	lDate := utf8.RuneCountInString(HTDate)
@@ -976,34 +1343,66 @@ func CmdTransactionList(c *cli.Context) error {
 	lCat := utf8.RuneCountInString(HCName)
 	lValue := utf8.RuneCountInString(HTValue)
 	lCur := utf8.RuneCountInString(HACurrency)
+	lBalance := utf8.RuneCountInString(hBalance)
+	lAtt := utf8.RuneCountInString(HTAttachments)
 	lDesc := utf8.RuneCountInString(HTDescription)
 
 	for t := getNextTransaction(); t != nil; t = getNextTransaction() {
+		if showBalance {
+			balances = append(balances, runningBalance(t))
+			lBalance = MaxLen(strconv.FormatFloat(balances[len(balances)-1], 'f', 2, 64), lBalance)
+		}
 		lId = MaxLen(strconv.FormatInt(t.Id, 10), lId)
 		lDate = MaxLen(t.Date.Format(DateFormat), lDate)
 		lAccount = MaxLen(t.Account.Name, lAccount)
 		lType = MaxLen(t.Category.Main.MType.Name, lType)
 		lMCat = MaxLen(t.Category.Main.Name, lMCat)
-		lCat = MaxLen(t.Category.Name, lCat)
+		lCat = MaxLen(tree.Path(t.Category), lCat)
 		lValue = MaxLen(strconv.FormatFloat(t.GetSValue(), 'f', 2, 64), lValue)
 		lCur = MaxLen(t.Account.Currency, lCur)
+		lAtt = MaxLen(transactionAttachments(t), lAtt)
 		lDesc = MaxLen(t.Description, lDesc)
 	}
-	lineH := LineFor(HFSForNumeric(lId), HFSForText(lDate), HFSForText(lAccount), HFSForText(lType), HFSForText(lMCat), HFSForText(lCat), HFSForNumeric(lValue), HFSForText(lCur), HFSForText(lDesc))
-	lineD := LineFor(DFSForID(lId), DFSForText(lDate), DFSForText(lAccount), DFSForText(lType), DFSForText(lMCat), DFSForText(lCat), DFSForValue(lValue), DFSForText(lCur), DFSForText(lDesc))
+	if rateErr != nil {
+		return printError.Fail(rateErr)
+	}
+	fsH := []string{HFSForNumeric(lId), HFSForText(lDate), HFSForText(lAccount), HFSForText(lType), HFSForText(lMCat), HFSForText(lCat), HFSForNumeric(lValue), HFSForText(lCur)}
+	fsD := []string{DFSForID(lId), DFSForText(lDate), DFSForText(lAccount), DFSForText(lType), DFSForText(lMCat), DFSForText(lCat), DFSForValue(lValue), DFSForText(lCur)}
+	if showBalance {
+		fsH = append(fsH, HFSForNumeric(lBalance))
+		fsD = append(fsD, DFSForValue(lBalance))
+	}
+	lineH := LineFor(append(fsH, HFSForNumeric(lAtt), HFSForText(lDesc))...)
+	lineD := LineFor(append(fsD, HFSForNumeric(lAtt), DFSForText(lDesc))...)
 
 	// Print transactions
 	if getNextTransaction, err = TransactionList(fh, dateFrom, dateTo, account, description, category, mainCategory); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
-	fmt.Fprintf(os.Stdout, lineH, HTId, HTDate, HAName, HMCType, HMCName, HCName, HTValue, HACurrency, HTDescription)
-	for t := getNextTransaction(); t != nil; t = getNextTransaction() {
-		fmt.Fprintf(os.Stdout, lineD, t.Id, t.Date.Format(DateFormat), t.Account.Name, t.Category.Main.MType.Name, t.Category.Main.Name, t.Category.Name, t.GetSValue(), t.Account.Currency, t.Description)
+	h := []interface{}{HTId, HTDate, HAName, HMCType, HMCName, HCName, HTValue, HACurrency}
+	if showBalance {
+		h = append(h, hBalance)
+	}
+	fmt.Fprintf(os.Stdout, lineH, append(h, HTAttachments, HTDescription)...)
+	for i, t := 0, getNextTransaction(); t != nil; i, t = i+1, getNextTransaction() {
+		d := []interface{}{t.Id, t.Date.Format(DateFormat), t.Account.Name, t.Category.Main.MType.Name, t.Category.Main.Name, tree.Path(t.Category), t.GetSValue(), t.Account.Currency}
+		if showBalance {
+			d = append(d, balances[i])
+		}
+		fmt.Fprintf(os.Stdout, lineD, append(d, transactionAttachments(t), t.Description)...)
 	}
 
 	return nil
 }
 
+// transactionAttachments returns number of files attached to transaction, or nothing if there are none
+func transactionAttachments(t *Transaction) string {
+	if t.Attachments == 0 {
+		return NotSetStringValue
+	}
+	return strconv.Itoa(t.Attachments)
+}
+
 // CmdTransactionEdit updates transaction with new values
 func CmdTransactionEdit(c *cli.Context) error {
 	var err error
@@ -1011,54 +1410,66 @@ func CmdTransactionEdit(c *cli.Context) error {
 	// Get loggers
 	printUserMsg, printError := GetLoggers()
 
-	// Check obligatory flags
+	// Check obligatory flags (file)
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 
 	}
-	id := c.Int(OptID)
-	if id == NotSetIntValue {
-		printError.Fatalln(errMissingIDFlag)
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err := openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
+	defer closeDataFile(fh)
 
-	// Open data file and get original main category
-	fh := GetDataFileHandler(f)
-	if err := fh.Open(); err != nil {
-		printError.Fatalln(err)
+	// Check obligatory flags (id), ask for missing one if possible
+	pr := newPrompter(c)
+	id := pr.Int(c.Int(OptID), promptField{Label: HTId, Required: true})
+	if id == NotSetIntValue {
+		return printError.Fail(errMissingIDFlag)
 	}
-	defer fh.Close()
 
+	// Get original transaction
 	var t *Transaction
 	if t, err = TransactionForID(fh, id); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
+	}
+	var tree *CategoryTree
+	if tree, err = CategoryTreeGet(fh); err != nil {
+		return printError.Fail(err)
 	}
 
 	// Edit transaction
-	if ds := c.String(OptDate); ds != NotSetStringValue {
-		if t.Date, err = time.Parse(DateFormat, ds); err != nil {
-			printError.Fatalln(err)
+	if ds := pr.String(c.String(OptDate), promptField{Label: HTDate, Default: t.Date.Format(DateFormat), Check: checkDate}); ds != NotSetStringValue {
+		if t.Date, err = DateParse(fh, ds); err != nil {
+			return printError.Fail(err)
 		}
 	}
-	if cs := c.String(ObjCategory); cs != NotSetStringValue {
-		if t.Category, err = CategoryForName(fh, cs); err != nil {
-			printError.Fatalln(err)
+	if as := pr.String(c.String(ObjAccount), promptField{Label: HAName, Default: t.Account.Name, Candidates: accountCompletion(fh), Check: checkAccount(fh)}); as != NotSetStringValue {
+		if t.Account, err = AccountForName(fh, as); err != nil {
+			return printError.Fail(err)
 		}
 	}
-	if as := c.String(ObjAccount); as != NotSetStringValue {
-		if t.Account, err = AccountForName(fh, as); err != nil {
-			printError.Fatalln(err)
+	if cs := pr.String(c.String(ObjCategory), promptField{Label: HCName, Default: tree.FullPath(t.Category), Candidates: categoryCompletion(fh), Check: checkCategory(fh)}); cs != NotSetStringValue {
+		if t.Category, err = CategoryForName(fh, cs); err != nil {
+			return printError.Fail(err)
 		}
 	}
-	if vf := c.Float64(OptValue); vf != NotSetFloatValue {
+	if vf := pr.Float(c.Float64(OptValue), promptField{Label: HTValue, Default: strconv.FormatFloat(t.Value, 'f', -1, 64)}); vf != NotSetFloatValue {
 		t.Value = vf
 	}
-	if descr := c.String(OptDescription); descr != NotSetStringValue {
+	if descr := pr.String(c.String(OptDescription), promptField{Label: HTDescription, Default: t.Description}); descr != NotSetStringValue {
 		t.Description = descr
 	}
 
+	if !pr.Confirm("changed transaction", append([]string{HTId, strconv.Itoa(id)}, transactionSummary(fh, t)...)...) {
+		printUserMsg.Printf("nothing has been changed\n")
+		return nil
+	}
 	if err = TransactionEdit(fh, t); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Show summary
@@ -1077,28 +1488,28 @@ func CmdTransactionRemove(c *cli.Context) error {
 	// Check obligatory flags
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
 	id := c.Int(OptID)
 	if id == NotSetIntValue {
-		printError.Fatalln(errMissingIDFlag)
+		return printError.Fail(errMissingIDFlag)
 	}
 
 	// Open data file and get original main category
-	fh := GetDataFileHandler(f)
-	if err = fh.Open(); err != nil {
-		printError.Fatalln(err)
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	defer fh.Close()
+	defer closeDataFile(fh)
 
 	var t *Transaction
 	if t, err = TransactionForID(fh, id); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Remove the transaction
 	if err = TransactionRemove(fh, t); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Show summary
@@ -1114,48 +1525,56 @@ func CmdBudgetAdd(c *cli.Context) error {
 	// Get loggers
 	printUserMsg, printError := GetLoggers()
 
-	// Check obligatory flags (file, name)
+	// Check obligatory flags (file)
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
+	}
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err := openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	p := c.String(OptPeriod)
+	defer closeDataFile(fh)
+
+	// Check obligatory flags (period, category, value, currency), ask for missing ones if possible
+	pr := newPrompter(c)
+	p := pr.String(c.String(OptPeriod), promptField{Label: HBPeriod, Default: time.Now().Format("2006-01"), Required: true, Check: checkPeriod})
 	if p == NotSetStringValue {
-		printError.Fatalln(errMissingPeriodFlag)
+		return printError.Fail(errMissingPeriodFlag)
 	}
-	cat := c.String(ObjCategory)
+	cat := pr.String(c.String(ObjCategory), promptField{Label: HCName, Required: true, Candidates: categoryCompletion(fh), Check: checkCategory(fh)})
 	if cat == NotSetStringValue {
-		printError.Fatalln(errMissingCategoryFlag)
+		return printError.Fail(errMissingCategoryFlag)
 	}
-	v := c.Float64(OptValue)
+	v := pr.Float(c.Float64(OptValue), promptField{Label: HBLimit, Required: true})
 	if v == NotSetFloatValue {
-		printError.Fatalln(errMissingValueFlag)
+		return printError.Fail(errMissingValueFlag)
 	}
-	cur := c.String(OptCurrency)
+	cur := pr.String(c.String(OptCurrency), promptField{Label: HBCurrency, Required: true, Candidates: currencyCompletion(fh)})
 	if cur == NotSetStringValue {
-		printError.Fatalln(errMissingCurrencyFlag)
-	}
-
-	// Open data file and validate parameters
-	fh := GetDataFileHandler(f)
-	if err := fh.Open(); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(errMissingCurrencyFlag)
 	}
-	defer fh.Close()
 
+	// Validate parameters
 	b := BudgetNew()
 	if b.Period, err = BPeriodParseYM(p); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	if b.Category, err = CategoryForName(fh, cat); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	b.Value = v
 	b.Currency = cur
 
 	// Add new budget
+	if !pr.Confirm("new budget", budgetSummary(fh, b)...) {
+		printUserMsg.Printf("nothing has been changed\n")
+		return nil
+	}
 	if err = BudgetAdd(fh, b); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Show summary
@@ -1174,41 +1593,41 @@ func CmdBudgetRemove(c *cli.Context) error {
 	// Check obligatory flags
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 
 	}
 	ps := c.String(OptPeriod)
 	if ps == NotSetStringValue {
-		printError.Fatalln(errMissingPeriodFlag)
+		return printError.Fail(errMissingPeriodFlag)
 	}
 	cs := c.String(ObjCategory)
 	if cs == NotSetStringValue {
-		printError.Fatalln(errMissingCategoryFlag)
+		return printError.Fail(errMissingCategoryFlag)
 	}
 
 	// Open data file and validate parameters
-	fh := GetDataFileHandler(f)
-	if err := fh.Open(); err != nil {
-		printError.Fatalln(err)
+	fh := dataFileHandler(f)
+	if err := openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	defer fh.Close()
+	defer closeDataFile(fh)
 
 	var p *BPeriod
 	if p, err = BPeriodParseYM(ps); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	var cat *Category
 	if cat, err = CategoryForName(fh, cs); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Find the budget and remove it
 	var b *Budget
 	if b, err = BudgetGet(fh, p, cat); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	if err = BudgetRemove(fh, b); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Show summary
@@ -1224,51 +1643,59 @@ func CmdBudgetEdit(c *cli.Context) error {
 	// Get loggers
 	printUserMsg, printError := GetLoggers()
 
-	// Check obligatory flags
+	// Check obligatory flags (file)
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
-	ps := c.String(OptPeriod)
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err := openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	// Check obligatory flags (period, category), ask for missing ones if possible
+	pr := newPrompter(c)
+	ps := pr.String(c.String(OptPeriod), promptField{Label: HBPeriod, Default: time.Now().Format("2006-01"), Required: true, Check: checkPeriod})
 	if ps == NotSetStringValue {
-		printError.Fatalln(errMissingPeriodFlag)
+		return printError.Fail(errMissingPeriodFlag)
 	}
-	cs := c.String(ObjCategory)
+	cs := pr.String(c.String(ObjCategory), promptField{Label: HCName, Required: true, Candidates: categoryCompletion(fh), Check: checkCategory(fh)})
 	if cs == NotSetStringValue {
-		printError.Fatalln(errMissingCategoryFlag)
-	}
-
-	// Open data file and validate parameters
-	fh := GetDataFileHandler(f)
-	if err := fh.Open(); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(errMissingCategoryFlag)
 	}
-	defer fh.Close()
 
+	// Validate parameters
 	var p *BPeriod
 	if p, err = BPeriodParseYM(ps); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	var cat *Category
 	if cat, err = CategoryForName(fh, cs); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
-	// Find the budget and remove it
+	// Find the budget and change it
 	var b *Budget
 	if b, err = BudgetGet(fh, p, cat); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
-	if v := c.Float64(OptValue); v != NotSetFloatValue {
+	if v := pr.Float(c.Float64(OptValue), promptField{Label: HBLimit, Default: strconv.FormatFloat(b.Value, 'f', -1, 64)}); v != NotSetFloatValue {
 		b.Value = v
 	}
-	if cur := c.String(OptCurrency); cur != NotSetStringValue {
+	if cur := pr.String(c.String(OptCurrency), promptField{Label: HBCurrency, Default: b.Currency, Candidates: currencyCompletion(fh)}); cur != NotSetStringValue {
 		b.Currency = cur
 	}
 
 	// Edit budget
+	if !pr.Confirm("changed budget", budgetSummary(fh, b)...) {
+		printUserMsg.Printf("nothing has been changed\n")
+		return nil
+	}
 	if err = BudgetEdit(fh, b); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Show summary
@@ -1287,32 +1714,34 @@ func CmdBudgetList(c *cli.Context) error {
 	// Check obligatory flags
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
 
 	// Open data file
-	fh := GetDataFileHandler(f)
-	if err = fh.Open(); err != nil {
-		printError.Fatalln(err)
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	defer fh.Close()
+	defer closeDataFile(fh)
 
 	// Get filtering criteria
 	var p *BPeriod
-	if p, err = BPeriodParseYOrYM(OptPeriod); err != nil {
-		printError.Fatalln(err)
+	if ps := c.String(OptPeriod); ps != NotSetStringValue {
+		if p, err = BPeriodParseYOrYM(fh, ps); err != nil {
+			return printError.Fail(err)
+		}
 	}
 	var ct *Category
 	if cs := c.String(ObjCategory); cs != NotSetStringValue {
 		if ct, err = CategoryForName(fh, cs); err != nil {
-			printError.Fatalln(err)
+			return printError.Fail(err)
 		}
 	}
 
 	// Build formatting strings
 	var getNextBudget func() *Budget
 	if getNextBudget, err = BudgetList(fh, p, ct); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	lP := utf8.RuneCountInString(HBPeriod)
 	lT := utf8.RuneCountInString(HMCType)
@@ -1334,7 +1763,7 @@ func CmdBudgetList(c *cli.Context) error {
 
 	// Print budgets
 	if getNextBudget, err = BudgetList(fh, p, ct); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	fmt.Fprintf(os.Stdout, lineH, HBPeriod, HMCType, HMCName, HCName, HBLimit, HBCurrency)
 	for b := getNextBudget(); b != nil; b = getNextBudget() {
@@ -1351,53 +1780,58 @@ func CmdCompoundTransferAdd(c *cli.Context) error {
 	// Get loggers
 	printUserMsg, printError := GetLoggers()
 
-	// Check obligatory flags
+	// Check obligatory flags (file)
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
-	af := c.String(ObjAccount)
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err := openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	// Check obligatory flags (accounts, value, description), ask for missing ones if possible
+	d := time.Now()
+	pr := newPrompter(c)
+	td := pr.String(c.String(OptDate), promptField{Label: HTDate, Default: d.Format(DateFormat), Check: checkDate})
+	af := pr.String(c.String(ObjAccount), promptField{Label: HAName, Required: true, Candidates: accountCompletion(fh), Check: checkAccount(fh)})
 	if af == NotSetStringValue {
-		printError.Fatalln(errMissingAccountFlag)
+		return printError.Fail(errMissingAccountFlag)
 	}
-	at := c.String(OptAccountTo)
+	at := pr.String(c.String(OptAccountTo), promptField{Label: HAAccountTo, Required: true, Candidates: accountCompletion(fh), Check: checkAccount(fh)})
 	if at == NotSetStringValue {
-		printError.Fatalln(errMissingAccountFlag)
+		return printError.Fail(errMissingAccountFlag)
 	}
-	v := c.Float64(OptValue)
+	v := pr.Float(c.Float64(OptValue), promptField{Label: HTValue, Required: true})
 	if v == NotSetFloatValue {
-		printError.Fatalln(errMissingValueFlag)
+		return printError.Fail(errMissingValueFlag)
 	}
-	desc := c.String(OptDescription)
+	desc := pr.String(c.String(OptDescription), promptField{Label: HTDescription, Required: true})
 	if desc == NotSetStringValue {
-		printError.Fatalln(errMissingDescriptionFlag)
+		return printError.Fail(errMissingDescriptionFlag)
 	}
-
-	// Open data file
-	fh := GetDataFileHandler(f)
-	if err := fh.Open(); err != nil {
-		printError.Fatalln(err)
-	}
-	defer fh.Close()
+	r := pr.Float(c.Float64(ObjExchangeRate), promptField{Label: HCurRate})
 
 	// Parse necessary parameters
-	d := time.Now()
-	if td := c.String(OptDate); td != NotSetStringValue {
-		if d, err = time.Parse(DateFormat, td); err != nil {
-			printError.Fatalln(err)
+	if td != NotSetStringValue {
+		if d, err = DateParse(fh, td); err != nil {
+			return printError.Fail(err)
 		}
 	}
 	var accFrom, accTo *Account
 	if accFrom, err = AccountForName(fh, af); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	if accTo, err = AccountForName(fh, at); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	var er *ExchangeRate
-	if r := c.Float64(ObjExchangeRate); r == NotSetFloatValue {
+	if r == NotSetFloatValue {
 		if er, err = ExchangeRateForCurrencies(fh, accFrom.Currency, accTo.Currency); err != nil {
-			printError.Fatalln(err)
+			return printError.Fail(err)
 		}
 	} else {
 		er = new(ExchangeRate)
@@ -1407,8 +1841,12 @@ func CmdCompoundTransferAdd(c *cli.Context) error {
 	}
 
 	// Add transaction
+	if !pr.Confirm("new transfer", HTDate, d.Format(DateFormat), HAName, accFrom.Name, HAAccountTo, accTo.Name, HTValue, strconv.FormatFloat(v, 'f', 2, 64)+" "+accFrom.Currency, HTDescription, desc, HCurRate, strconv.FormatFloat(er.Rate, 'f', -1, 64)) {
+		printUserMsg.Printf("nothing has been changed\n")
+		return nil
+	}
 	if err = CompoundTransferAdd(fh, d, accFrom, accTo, v, desc, er); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Show summary
@@ -1424,61 +1862,66 @@ func CmdCompoundInternalCostAdd(c *cli.Context) error {
 	// Get loggers
 	printUserMsg, printError := GetLoggers()
 
-	// Check obligatory flags
+	// Check obligatory flags (file)
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
-	cs := c.String(ObjCategory)
-	if cs == NotSetStringValue {
-		printError.Fatalln(errMissingCategoryFlag)
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err := openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	ac := c.String(ObjAccount)
+	defer closeDataFile(fh)
+
+	// Check obligatory flags (category, accounts, value, description), ask for missing ones if possible
+	d := time.Now()
+	pr := newPrompter(c)
+	td := pr.String(c.String(OptDate), promptField{Label: HTDate, Default: d.Format(DateFormat), Check: checkDate})
+	ac := pr.String(c.String(ObjAccount), promptField{Label: HAName, Required: true, Candidates: accountCompletion(fh), Check: checkAccount(fh)})
 	if ac == NotSetStringValue {
-		printError.Fatalln(errMissingAccountFlag)
+		return printError.Fail(errMissingAccountFlag)
 	}
-	at := c.String(OptAccountTo)
+	at := pr.String(c.String(OptAccountTo), promptField{Label: HAAccountTo, Required: true, Candidates: accountCompletion(fh), Check: checkAccount(fh)})
 	if at == NotSetStringValue {
-		printError.Fatalln(errMissingAccountFlag)
+		return printError.Fail(errMissingAccountFlag)
+	}
+	cs := pr.String(c.String(ObjCategory), promptField{Label: HCName, Required: true, Candidates: categoryCompletion(fh), Check: checkCategory(fh)})
+	if cs == NotSetStringValue {
+		return printError.Fail(errMissingCategoryFlag)
 	}
-	v := c.Float64(OptValue)
+	v := pr.Float(c.Float64(OptValue), promptField{Label: HTValue, Required: true})
 	if v == NotSetFloatValue {
-		printError.Fatalln(errMissingValueFlag)
+		return printError.Fail(errMissingValueFlag)
 	}
-	desc := c.String(OptDescription)
+	desc := pr.String(c.String(OptDescription), promptField{Label: HTDescription, Required: true})
 	if desc == NotSetStringValue {
-		printError.Fatalln(errMissingDescriptionFlag)
+		return printError.Fail(errMissingDescriptionFlag)
 	}
-
-	// Open data file
-	fh := GetDataFileHandler(f)
-	if err := fh.Open(); err != nil {
-		printError.Fatalln(err)
-	}
-	defer fh.Close()
+	r := pr.Float(c.Float64(ObjExchangeRate), promptField{Label: HCurRate})
 
 	// Parse necessary parameters
-	d := time.Now()
-	if td := c.String(OptDate); td != NotSetStringValue {
-		if d, err = time.Parse(DateFormat, td); err != nil {
-			printError.Fatalln(err)
+	if td != NotSetStringValue {
+		if d, err = DateParse(fh, td); err != nil {
+			return printError.Fail(err)
 		}
 	}
 	var cat *Category
 	if cat, err = CategoryForName(fh, cs); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	var accCost, accTransfer *Account
 	if accCost, err = AccountForName(fh, ac); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	if accTransfer, err = AccountForName(fh, at); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	var er *ExchangeRate
-	if r := c.Float64(ObjExchangeRate); r == NotSetFloatValue {
+	if r == NotSetFloatValue {
 		if er, err = ExchangeRateForCurrencies(fh, accCost.Currency, accTransfer.Currency); err != nil {
-			printError.Fatalln(err)
+			return printError.Fail(err)
 		}
 	} else {
 		er = new(ExchangeRate)
@@ -1488,8 +1931,12 @@ func CmdCompoundInternalCostAdd(c *cli.Context) error {
 	}
 
 	// Add transaction
+	if !pr.Confirm("new internal cost", HTDate, d.Format(DateFormat), HAName, accCost.Name, HAAccountTo, accTransfer.Name, HCName, cs, HTValue, strconv.FormatFloat(v, 'f', 2, 64)+" "+accCost.Currency, HTDescription, desc, HCurRate, strconv.FormatFloat(er.Rate, 'f', -1, 64)) {
+		printUserMsg.Printf("nothing has been changed\n")
+		return nil
+	}
 	if err = CompoundInternalCostAdd(fh, d, cat, accCost, accTransfer, v, desc, er); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Show summary
@@ -1505,61 +1952,69 @@ func CmdCompoundTransactionSplit(c *cli.Context) error {
 	// Get loggers
 	printUserMsg, printError := GetLoggers()
 
-	// Check obligatory flags
+	// Check obligatory flags (file)
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
-	}
-	a := c.String(ObjAccount)
-	if a == NotSetStringValue {
-		printError.Fatalln(errMissingAccountFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
-	v := c.Float64(OptValue)
-	if v == NotSetFloatValue {
-		printError.Fatalln(errMissingValueFlag)
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err := openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	desc := c.String(OptDescription)
-	if desc == NotSetStringValue {
-		printError.Fatalln(errMissingDescriptionFlag)
+	defer closeDataFile(fh)
+
+	// Check obligatory flags (account, value, description, categories), ask for missing ones if possible
+	d := time.Now()
+	pr := newPrompter(c)
+	td := pr.String(c.String(OptDate), promptField{Label: HTDate, Default: d.Format(DateFormat), Check: checkDate})
+	a := pr.String(c.String(ObjAccount), promptField{Label: HAName, Required: true, Candidates: accountCompletion(fh), Check: checkAccount(fh)})
+	if a == NotSetStringValue {
+		return printError.Fail(errMissingAccountFlag)
 	}
-	c1 := c.String(ObjCategory)
+	c1 := pr.String(c.String(ObjCategory), promptField{Label: HCName, Required: true, Candidates: categoryCompletion(fh), Check: checkCategory(fh)})
 	if c1 == NotSetStringValue {
-		printError.Fatalln(errMissingCategoryFlag)
+		return printError.Fail(errMissingCategoryFlag)
 	}
-	c2 := c.String(OptCategorySplit)
+	c2 := pr.String(c.String(OptCategorySplit), promptField{Label: HCSplit, Required: true, Candidates: categoryCompletion(fh), Check: checkCategory(fh)})
 	if c2 == NotSetStringValue {
-		printError.Fatalln(errMissingCategorySplitFlag)
+		return printError.Fail(errMissingCategorySplitFlag)
 	}
-
-	// Open data file
-	fh := GetDataFileHandler(f)
-	if err := fh.Open(); err != nil {
-		printError.Fatalln(err)
+	v := pr.Float(c.Float64(OptValue), promptField{Label: HTValue, Required: true})
+	if v == NotSetFloatValue {
+		return printError.Fail(errMissingValueFlag)
+	}
+	desc := pr.String(c.String(OptDescription), promptField{Label: HTDescription, Required: true})
+	if desc == NotSetStringValue {
+		return printError.Fail(errMissingDescriptionFlag)
 	}
-	defer fh.Close()
 
 	// Parse necessary parameters
-	d := time.Now()
-	if td := c.String(OptDate); td != NotSetStringValue {
-		if d, err = time.Parse(DateFormat, td); err != nil {
-			printError.Fatalln(err)
+	if td != NotSetStringValue {
+		if d, err = DateParse(fh, td); err != nil {
+			return printError.Fail(err)
 		}
 	}
 	var acc *Account
 	if acc, err = AccountForName(fh, a); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	var cat1, cat2 *Category
 	if cat1, err = CategoryForName(fh, c1); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	if cat2, err = CategoryForName(fh, c2); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Add transaction
+	if !pr.Confirm("new split transaction", HTDate, d.Format(DateFormat), HAName, acc.Name, HCName, c1, HCSplit, c2, HTValue, strconv.FormatFloat(v, 'f', 2, 64)+" "+acc.Currency, HTDescription, desc) {
+		printUserMsg.Printf("nothing has been changed\n")
+		return nil
+	}
 	if err = CompoundSplitAdd(fh, d, acc, v, desc, cat1, cat2); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 
 	// Show summary
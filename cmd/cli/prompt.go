@@ -0,0 +1,474 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/peterh/liner"
+	"github.com/urfave/cli"
+	. "github.com/zbroju/financoj/lib"
+	"github.com/zbroju/gsqlitehandler"
+	"golang.org/x/term"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// promptField describes a value asked from the user
+type promptField struct {
+	Label      string               // heading of the value
+	Default    string               // value proposed when nothing was given in flags
+	Required   bool                 // the value cannot be left empty
+	Candidates func() []string      // names offered in completion (tab key), read only when needed
+	Check      func(s string) error // validation of the answer, asked again until it passes
+}
+
+// prompter asks the user for the values missing in command line flags.
+// It is enabled with --interactive flag or automatically when standard input is a terminal.
+// With --interactive flag all the values are asked for, with flags values given as defaults.
+type prompter struct {
+	enabled  bool
+	all      bool
+	terminal bool
+	asked    bool
+	canceled bool
+	line     *liner.State
+	errLog   *Logger
+}
+
+// newPrompter returns prompter set up according to command flags and standard input
+func newPrompter(c *cli.Context) *prompter {
+	_, printError := GetLoggers()
+	p := &prompter{all: c.Bool(OptInteractive), terminal: term.IsTerminal(int(os.Stdin.Fd())), errLog: printError}
+	p.enabled = p.all || p.terminal
+
+	return p
+}
+
+// String returns value v or, if the value is missing (or the prompter asks for all values), the answer of the user
+func (p *prompter) String(v string, f promptField) string {
+	if p.canceled {
+		return NotSetStringValue
+	}
+	if v != NotSetStringValue && !p.all {
+		return v
+	}
+	if v == NotSetStringValue && !(p.enabled && (f.Required || p.all)) {
+		return v
+	}
+
+	def := f.Default
+	if v != NotSetStringValue {
+		def = v
+	}
+	for {
+		// Empty answer means default value, while NullDataValue clears it
+		a := p.ask(strings.ToLower(f.Label), def, f.Candidates)
+		switch a {
+		case NotSetStringValue:
+			a = def
+		case NullDataValue:
+			a = NotSetStringValue
+		}
+		if p.canceled {
+			return NotSetStringValue
+		}
+		if a == NotSetStringValue {
+			if !f.Required {
+				return a
+			}
+			p.errLog.Println(errEmptyAnswer)
+			continue
+		}
+		if f.Check != nil {
+			if err := f.Check(a); err != nil {
+				p.errLog.Println(err)
+				continue
+			}
+		}
+		return a
+	}
+}
+
+// Float returns the same as String, but for float values
+func (p *prompter) Float(v float64, f promptField) float64 {
+	s := NotSetStringValue
+	if v != NotSetFloatValue {
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	f.Check = checkAll(checkFloat, f.Check)
+	if s = p.String(s, f); s == NotSetStringValue {
+		return NotSetFloatValue
+	}
+	v, _ = strconv.ParseFloat(s, 64)
+
+	return v
+}
+
+// Int returns the same as String, but for integer values
+func (p *prompter) Int(v int, f promptField) int {
+	s := NotSetStringValue
+	if v != NotSetIntValue {
+		s = strconv.Itoa(v)
+	}
+	f.Check = checkAll(checkInt, f.Check)
+	if s = p.String(s, f); s == NotSetStringValue {
+		return NotSetIntValue
+	}
+	v, _ = strconv.Atoi(s)
+
+	return v
+}
+
+// Confirm shows summary of values (given as label, value pairs) and asks the user to confirm them.
+// The summary is shown only if anything was asked before.
+func (p *prompter) Confirm(title string, rows ...string) bool {
+	defer p.close()
+	if !p.asked {
+		return true
+	}
+
+	l := 0
+	for i := 0; i < len(rows); i += 2 {
+		l = MaxLen(rows[i], l)
+	}
+	fmt.Fprintf(os.Stdout, "%s:\n", title)
+	for i := 0; i+1 < len(rows); i += 2 {
+		fmt.Fprintf(os.Stdout, "  "+HFSForText(l)+FSSeparator+"%s\n", rows[i], rows[i+1])
+	}
+
+	a := p.ask("confirm? [Y/n]", NotSetStringValue, nil)
+	if p.canceled {
+		return false
+	}
+	switch strings.ToLower(a) {
+	case "", "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// ask shows prompt with editable default value and returns the answer.
+// Once the user cancels it (e.g. with Ctrl-C), nothing more is asked and empty answers are returned.
+func (p *prompter) ask(label, def string, candidates func() []string) string {
+	if p.canceled {
+		return NotSetStringValue
+	}
+	if p.line == nil {
+		p.line = liner.NewLiner()
+		p.line.SetCtrlCAborts(true)
+	}
+	p.asked = true
+
+	var names []string
+	p.line.SetCompleter(func(s string) []string {
+		if candidates == nil {
+			return nil
+		}
+		if names == nil {
+			names = candidates()
+		}
+		return fuzzyMatches(s, names)
+	})
+	a, err := p.line.PromptWithSuggestion(label+": ", def, -1)
+	// Terminal is restored after each question, so that the command can exit anytime.
+	// The state is kept for redirected input, because it reads ahead from the input.
+	if err != nil || p.terminal {
+		p.close()
+	}
+	if err != nil {
+		p.errLog.Println(errInteractiveCancelled)
+		p.canceled = true
+		return NotSetStringValue
+	}
+
+	return strings.TrimSpace(a)
+}
+
+// close restores the terminal
+func (p *prompter) close() {
+	if p.line != nil {
+		p.line.Close()
+		p.line = nil
+	}
+}
+
+// transactionSummary returns label, value pairs describing transaction t for Confirm
+func transactionSummary(fh *gsqlitehandler.SqliteDB, t *Transaction) []string {
+	cn := t.Category.Name
+	if tree, err := CategoryTreeGet(fh); err == nil {
+		cn = tree.FullPath(t.Category)
+	}
+
+	return []string{
+		HTDate, t.Date.Format(DateFormat),
+		HAName, t.Account.Name,
+		HCName, cn,
+		HTValue, strconv.FormatFloat(t.GetSValue(), 'f', 2, 64) + " " + t.Account.Currency,
+		HTDescription, t.Description,
+	}
+}
+
+// budgetSummary returns label, value pairs describing budget b for Confirm
+func budgetSummary(fh *gsqlitehandler.SqliteDB, b *Budget) []string {
+	cn := b.Category.Name
+	if tree, err := CategoryTreeGet(fh); err == nil {
+		cn = tree.FullPath(b.Category)
+	}
+
+	return []string{
+		HBPeriod, b.Period.String(),
+		HCName, cn,
+		HBLimit, strconv.FormatFloat(b.Value, 'f', 2, 64),
+		HBCurrency, b.Currency,
+	}
+}
+
+// fuzzyMatches returns candidates containing all the characters of s (in the same order, case insensitive).
+// Candidates starting with s go first, then the ones containing s and at the end the rest of matches.
+func fuzzyMatches(s string, candidates []string) []string {
+	type match struct {
+		name  string
+		score int
+	}
+
+	s = strings.ToLower(s)
+	var ms []match
+	for _, c := range candidates {
+		lc := strings.ToLower(c)
+		switch {
+		case strings.HasPrefix(lc, s):
+			ms = append(ms, match{c, 0})
+		case strings.Contains(lc, s):
+			ms = append(ms, match{c, 1})
+		case isSubsequence(s, lc):
+			ms = append(ms, match{c, 2})
+		}
+	}
+	sort.SliceStable(ms, func(i, j int) bool { return ms[i].score < ms[j].score })
+
+	names := make([]string, len(ms))
+	for i, m := range ms {
+		names[i] = m.name
+	}
+
+	return names
+}
+
+// isSubsequence checks if all the runes of s appear in t in the same order
+func isSubsequence(s, t string) bool {
+	for _, r := range t {
+		if s == NotSetStringValue {
+			break
+		}
+		if fr, size := utf8.DecodeRuneInString(s); fr == r {
+			s = s[size:]
+		}
+	}
+
+	return s == NotSetStringValue
+}
+
+// accountCompletion returns completion with names of open accounts
+func accountCompletion(fh *gsqlitehandler.SqliteDB) func() []string {
+	return func() (names []string) {
+		getNextAccount, err := AccountList(fh, NotSetStringValue, NotSetStringValue, NotSetStringValue, NotSetStringValue, ATUnset, ISOpen)
+		if err != nil {
+			return nil
+		}
+		for a := getNextAccount(); a != nil; a = getNextAccount() {
+			names = append(names, a.Name)
+		}
+
+		return names
+	}
+}
+
+// categoryCompletion returns completion with full paths (with main category) of open categories
+func categoryCompletion(fh *gsqlitehandler.SqliteDB) func() []string {
+	return func() (names []string) {
+		tree, err := CategoryTreeGet(fh)
+		if err != nil {
+			return nil
+		}
+		getNextCategory, err := CategoryList(fh, nil, NotSetStringValue, ISOpen)
+		if err != nil {
+			return nil
+		}
+		for c := getNextCategory(); c != nil; c = getNextCategory() {
+			names = append(names, tree.FullPath(c))
+		}
+
+		return names
+	}
+}
+
+// mainCategoryCompletion returns completion with names of open main categories
+func mainCategoryCompletion(fh *gsqlitehandler.SqliteDB) func() []string {
+	return func() (names []string) {
+		getNextMainCategory, err := MainCategoryList(fh, nil, NotSetStringValue, ISOpen)
+		if err != nil {
+			return nil
+		}
+		for m := getNextMainCategory(); m != nil; m = getNextMainCategory() {
+			names = append(names, m.Name)
+		}
+
+		return names
+	}
+}
+
+// mainCategoryTypeCompletion returns completion with names of open main category types
+func mainCategoryTypeCompletion(fh *gsqlitehandler.SqliteDB) func() []string {
+	return func() (names []string) {
+		getNextMainCategoryType, err := MainCategoryTypeList(fh, ISOpen)
+		if err != nil {
+			return nil
+		}
+		for t := getNextMainCategoryType(); t != nil; t = getNextMainCategoryType() {
+			names = append(names, t.Name)
+		}
+
+		return names
+	}
+}
+
+// currencyCompletion returns completion with currencies used in accounts and exchange rates
+func currencyCompletion(fh *gsqlitehandler.SqliteDB) func() []string {
+	return func() (names []string) {
+		used := make(map[string]bool)
+		add := func(c string) {
+			if !used[c] {
+				used[c] = true
+				names = append(names, c)
+			}
+		}
+
+		if getNextAccount, err := AccountList(fh, NotSetStringValue, NotSetStringValue, NotSetStringValue, NotSetStringValue, ATUnset, ISOpen); err == nil {
+			for a := getNextAccount(); a != nil; a = getNextAccount() {
+				add(a.Currency)
+			}
+		}
+		if getNextRate, err := ExchangeRateList(fh); err == nil {
+			for e := getNextRate(); e != nil; e = getNextRate() {
+				add(e.CurrencyFrom)
+				add(e.CurrencyTo)
+			}
+		}
+		sort.Strings(names)
+
+		return names
+	}
+}
+
+// accountTypeCompletion returns completion with the names accepted by AccountTypeForString
+func accountTypeCompletion() []string {
+	return []string{"operational", "savings", "properties", "investment", "loans"}
+}
+
+// accountTypeName returns name of account type accepted by AccountTypeForString
+func accountTypeName(t AccountType) string {
+	if names := accountTypeCompletion(); t >= ATTransactional && int(t) <= len(names) {
+		return names[t-ATTransactional]
+	}
+	return NotSetStringValue
+}
+
+// reportsCompletion returns completion with the names accepted by MainCategoryTypeReportsForString
+func reportsCompletion() []string {
+	return []string{"income-cost,budget,net-value", "income-cost", "budget", "net-value", "none"}
+}
+
+// checkAll returns check running all the given (non nil) checks
+func checkAll(checks ...func(string) error) func(string) error {
+	return func(s string) error {
+		for _, c := range checks {
+			if c == nil {
+				continue
+			}
+			if err := c(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// check* functions validate the answers given by the user
+func checkFloat(s string) error {
+	_, err := strconv.ParseFloat(s, 64)
+	return err
+}
+
+func checkInt(s string) error {
+	_, err := strconv.Atoi(s)
+	return err
+}
+
+func checkDate(s string) error {
+	_, err := DateParse(nil, s)
+	return err
+}
+
+func checkPeriod(s string) error {
+	_, err := BPeriodParseYM(s)
+	return err
+}
+
+func checkGranularity(s string) error {
+	_, err := GranularityParse(s)
+	return err
+}
+
+func checkAccountType(s string) error {
+	if AccountTypeForString(s) == ATUnknown {
+		return errors.New(errIncorrectAccountType)
+	}
+	return nil
+}
+
+func checkReports(s string) error {
+	_, _, _, err := MainCategoryTypeReportsForString(s)
+	return err
+}
+
+func checkFactor(s string) error {
+	if s != "1" && s != "-1" {
+		return errors.New(errIncorrectFactor)
+	}
+	return nil
+}
+
+func checkAccount(fh *gsqlitehandler.SqliteDB) func(string) error {
+	return func(s string) error {
+		_, err := AccountForName(fh, s)
+		return err
+	}
+}
+
+func checkCategory(fh *gsqlitehandler.SqliteDB) func(string) error {
+	return func(s string) error {
+		_, err := CategoryForName(fh, s)
+		return err
+	}
+}
+
+func checkMainCategory(fh *gsqlitehandler.SqliteDB) func(string) error {
+	return func(s string) error {
+		_, err := MainCategoryForName(fh, s)
+		return err
+	}
+}
+
+func checkMainCategoryType(fh *gsqlitehandler.SqliteDB) func(string) error {
+	return func(s string) error {
+		_, err := MainCategoryTypeForName(fh, s)
+		return err
+	}
+}
@@ -0,0 +1,241 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"fmt"
+	"github.com/urfave/cli"
+	. "github.com/zbroju/financoj/lib"
+	"github.com/zbroju/gsqlitehandler"
+	"os"
+	"strconv"
+	"unicode/utf8"
+)
+
+// CmdRecurringAdd adds new recurring transaction
+func CmdRecurringAdd(c *cli.Context) error {
+	var err error
+
+	// Get loggers
+	printUserMsg, printError := GetLoggers()
+
+	// Check obligatory flags (file)
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	// Check obligatory flags (account, category, value, description), ask for missing ones if possible
+	r := RecurringNew()
+	pr := newPrompter(c)
+	an := pr.String(c.String(ObjAccount), promptField{Label: HAName, Required: true, Candidates: accountCompletion(fh), Check: checkAccount(fh)})
+	if an == NotSetStringValue {
+		return printError.Fail(errMissingAccountFlag)
+	}
+	cn := pr.String(c.String(ObjCategory), promptField{Label: HCName, Required: true, Candidates: categoryCompletion(fh), Check: checkCategory(fh)})
+	if cn == NotSetStringValue {
+		return printError.Fail(errMissingCategoryFlag)
+	}
+	v := pr.Float(c.Float64(OptValue), promptField{Label: HTValue, Required: true})
+	if v == NotSetFloatValue {
+		return printError.Fail(errMissingValueFlag)
+	}
+	d := pr.String(c.String(OptDescription), promptField{Label: HTDescription, Required: true})
+	if d == NotSetStringValue {
+		return printError.Fail(errMissingDescriptionFlag)
+	}
+	df := pr.String(c.String(OptDate), promptField{Label: HRDateFrom, Default: r.DateFrom.Format(DateFormat), Check: checkDate})
+	dt := pr.String(c.String(OptUntil), promptField{Label: HRDateTo, Check: checkDate})
+	g := pr.String(c.String(OptGranularity), promptField{Label: HRGranularity, Default: r.Granularity.String(), Check: checkGranularity})
+	every := pr.Int(c.Int(OptEvery), promptField{Label: HREvery, Default: strconv.Itoa(r.Every)})
+
+	// Create the recurring transaction object
+	if r.Account, err = AccountForName(fh, an); err != nil {
+		return printError.Fail(err)
+	}
+	if r.Category, err = CategoryForName(fh, cn); err != nil {
+		return printError.Fail(err)
+	}
+	r.Value = v
+	r.Description = d
+	if df != NotSetStringValue {
+		if r.DateFrom, err = DateParse(fh, df); err != nil {
+			return printError.Fail(err)
+		}
+	}
+	if dt != NotSetStringValue {
+		if _, r.DateTo, err = DateRangeParse(fh, dt); err != nil {
+			return printError.Fail(err)
+		}
+	}
+	if r.Granularity, err = GranularityParse(g); err != nil {
+		return printError.Fail(err)
+	}
+	r.Every = every
+
+	// Add recurring transaction
+	if !pr.Confirm("new recurring transaction", recurringSummary(fh, r)...) {
+		printUserMsg.Printf("nothing has been changed\n")
+		return nil
+	}
+	if err = RecurringAdd(fh, r); err != nil {
+		return printError.Fail(err)
+	}
+
+	// Show summary
+	printUserMsg.Printf("add new recurring transaction with id = %d\n", r.Id)
+
+	return nil
+}
+
+// CmdRecurringList prints recurring transactions on standard output
+func CmdRecurringList(c *cli.Context) error {
+	var err error
+
+	// Get loggers
+	_, printError := GetLoggers()
+
+	// Check obligatory flags
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	// Get filtering criteria
+	var account *Account
+	if as := c.String(ObjAccount); as != NotSetStringValue {
+		if account, err = AccountForName(fh, as); err != nil {
+			return printError.Fail(err)
+		}
+	}
+
+	// Build formatting strings
+	var getNextRecurring func() *Recurring
+	if getNextRecurring, err = RecurringList(fh, account); err != nil {
+		return printError.Fail(err)
+	}
+	lId := utf8.RuneCountInString(HTId)
+	lAccount := utf8.RuneCountInString(HAName)
+	lCat := utf8.RuneCountInString(HCName)
+	lValue := utf8.RuneCountInString(HTValue)
+	lCur := utf8.RuneCountInString(HACurrency)
+	lFrom := utf8.RuneCountInString(HRDateFrom)
+	lTo := utf8.RuneCountInString(HRDateTo)
+	lRepeat := utf8.RuneCountInString(HRRepeat)
+	lDesc := utf8.RuneCountInString(HTDescription)
+
+	for r := getNextRecurring(); r != nil; r = getNextRecurring() {
+		lId = MaxLen(strconv.FormatInt(r.Id, 10), lId)
+		lAccount = MaxLen(r.Account.Name, lAccount)
+		lCat = MaxLen(r.Category.Name, lCat)
+		lValue = MaxLen(strconv.FormatFloat(r.GetSValue(), 'f', 2, 64), lValue)
+		lCur = MaxLen(r.Account.Currency, lCur)
+		lFrom = MaxLen(r.DateFrom.Format(DateFormat), lFrom)
+		lTo = MaxLen(recurringDateTo(r), lTo)
+		lRepeat = MaxLen(recurringRepeat(r), lRepeat)
+		lDesc = MaxLen(r.Description, lDesc)
+	}
+	lineH := LineFor(HFSForNumeric(lId), HFSForText(lAccount), HFSForText(lCat), HFSForNumeric(lValue), HFSForText(lCur), HFSForText(lFrom), HFSForText(lTo), HFSForText(lRepeat), HFSForText(lDesc))
+	lineD := LineFor(DFSForID(lId), DFSForText(lAccount), DFSForText(lCat), DFSForValue(lValue), DFSForText(lCur), DFSForText(lFrom), DFSForText(lTo), DFSForText(lRepeat), DFSForText(lDesc))
+
+	// Print recurring transactions
+	if getNextRecurring, err = RecurringList(fh, account); err != nil {
+		return printError.Fail(err)
+	}
+	fmt.Fprintf(os.Stdout, lineH, HTId, HAName, HCName, HTValue, HACurrency, HRDateFrom, HRDateTo, HRRepeat, HTDescription)
+	for r := getNextRecurring(); r != nil; r = getNextRecurring() {
+		fmt.Fprintf(os.Stdout, lineD, r.Id, r.Account.Name, r.Category.Name, r.GetSValue(), r.Account.Currency, r.DateFrom.Format(DateFormat), recurringDateTo(r), recurringRepeat(r), r.Description)
+	}
+
+	return nil
+}
+
+// CmdRecurringRemove removes recurring transaction (transactions added already are kept)
+func CmdRecurringRemove(c *cli.Context) error {
+	var err error
+
+	// Get loggers
+	printUserMsg, printError := GetLoggers()
+
+	// Check obligatory flags
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+	id := c.Int(OptID)
+	if id == NotSetIntValue {
+		return printError.Fail(errMissingIDFlag)
+	}
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	var r *Recurring
+	if r, err = RecurringForID(fh, id); err != nil {
+		return printError.Fail(err)
+	}
+
+	// Remove the recurring transaction
+	if err = RecurringRemove(fh, r); err != nil {
+		return printError.Fail(err)
+	}
+
+	// Show summary
+	printUserMsg.Printf("removed recurring transaction with id = %d\n", r.Id)
+
+	return nil
+}
+
+// recurringDateTo returns the last date of recurring transaction, or nothing if it has no end
+func recurringDateTo(r *Recurring) string {
+	if r.DateTo.IsZero() {
+		return NotSetStringValue
+	}
+	return r.DateTo.Format(DateFormat)
+}
+
+// recurringRepeat returns how often recurring transaction is repeated, e.g. 'month' or '2 week'
+func recurringRepeat(r *Recurring) string {
+	if r.Every == 1 {
+		return r.Granularity.String()
+	}
+	return fmt.Sprintf("%d %s", r.Every, r.Granularity)
+}
+
+// recurringSummary returns label, value pairs describing recurring transaction r for Confirm
+func recurringSummary(fh *gsqlitehandler.SqliteDB, r *Recurring) []string {
+	cn := r.Category.Name
+	if tree, err := CategoryTreeGet(fh); err == nil {
+		cn = tree.FullPath(r.Category)
+	}
+
+	return []string{
+		HAName, r.Account.Name,
+		HCName, cn,
+		HTValue, strconv.FormatFloat(r.GetSValue(), 'f', 2, 64) + " " + r.Account.Currency,
+		HTDescription, r.Description,
+		HRDateFrom, r.DateFrom.Format(DateFormat),
+		HRDateTo, recurringDateTo(r),
+		HRRepeat, recurringRepeat(r),
+	}
+}
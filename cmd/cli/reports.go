@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"github.com/urfave/cli"
 	. "github.com/zbroju/financoj/lib"
+	"github.com/zbroju/gsqlitehandler"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -24,30 +26,42 @@ func RepAccountBalance(c *cli.Context) error {
 	// Check obligatory flags
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
 
 	// Open data file
-	fh := GetDataFileHandler(f)
-	if err = fh.Open(); err != nil {
-		printError.Fatalln(err)
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	defer fh.Close()
+	defer closeDataFile(fh)
 
 	// Create filters
 	var bDate time.Time
 	if td := c.String(OptDate); td != NotSetStringValue {
-		if bDate, err = time.Parse(DateFormat, td); err != nil {
-			printError.Fatalln(err)
+		if bDate, err = DateParse(fh, td); err != nil {
+			return printError.Fail(err)
 		}
 	} else {
 		bDate = time.Now()
 	}
 
+	// Print report
+	if err = printAccountBalance(os.Stdout, fh, bDate); err != nil {
+		return printError.Fail(err)
+	}
+
+	return nil
+}
+
+// printAccountBalance writes accounts balance report on given date to w
+func printAccountBalance(w io.Writer, fh *gsqlitehandler.SqliteDB, bDate time.Time) error {
+	var err error
+
 	// Build formatting strings
 	var getNextEntry func() *AccountBalanceReportEntry
 	if getNextEntry, err = ReportAccountBalance(fh, bDate); err != nil {
-		printError.Fatalln(err)
+		return err
 	}
 	lA := utf8.RuneCountInString(HAName)
 	lV := utf8.RuneCountInString(HTValue)
@@ -60,18 +74,18 @@ func RepAccountBalance(c *cli.Context) error {
 	lineD := LineFor(NotSetStringValue, DFSForText(lA), DFSForValue(lV), DFSForText(lC))
 
 	// Print report
-	fmt.Fprintf(os.Stdout, "Accounts balance on %s:\n", bDate.Format(DateFormat))
+	fmt.Fprintf(w, "Accounts balance on %s:\n", bDate.Format(DateFormat))
 
 	if getNextEntry, err = ReportAccountBalance(fh, bDate); err != nil {
-		printError.Fatalln(err)
+		return err
 	}
 	var currentType string
 	for e := getNextEntry(); e != nil; e = getNextEntry() {
 		if currentType != e.Account.AType.String() {
 			currentType = e.Account.AType.String()
-			fmt.Fprintf(os.Stdout, "\n%s\n", currentType)
+			fmt.Fprintf(w, "\n%s\n", currentType)
 		}
-		fmt.Fprintf(os.Stdout, lineD, e.Account.Name, e.Value, e.Account.Currency)
+		fmt.Fprintf(w, lineD, e.Account.Name, e.Value, e.Account.Currency)
 	}
 
 	return nil
@@ -86,53 +100,41 @@ func RepTransactionBalance(c *cli.Context) error {
 	// Check obligatory flags
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
 	cur := c.String(OptCurrency)
 	if cur == NotSetStringValue {
-		printError.Fatalln(errMissingCurrencyFlag)
+		return printError.Fail(errMissingCurrencyFlag)
 	}
 
 	// Open data file
-	fh := GetDataFileHandler(f)
-	if err = fh.Open(); err != nil {
-		printError.Fatalln(err)
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	defer fh.Close()
+	defer closeDataFile(fh)
 
 	// Create filters
-	var df time.Time
-	if ds := c.String(OptDateFrom); ds != NotSetStringValue {
-		if df, err = time.Parse(DateFormat, ds); err != nil {
-			printError.Fatalln(err)
-		}
-	} else {
-		df = time.Time{}
-	}
-	var dt time.Time
-	if ds := c.String(OptDateTo); ds != NotSetStringValue {
-		if dt, err = time.Parse(DateFormat, ds); err != nil {
-			printError.Fatalln(err)
-		}
-	} else {
-		dt = time.Time{}
+	var df, dt time.Time
+	if df, dt, err = datesRangeFlags(c, fh); err != nil {
+		return printError.Fail(err)
 	}
 	var a *Account
 	if as := c.String(ObjAccount); as != NotSetStringValue {
 		if a, err = AccountForName(fh, as); err != nil {
-			printError.Fatalln(err)
+			return printError.Fail(err)
 		}
 	}
 	var cat *Category
 	if cs := c.String(ObjCategory); cs != NotSetStringValue {
 		if cat, err = CategoryForName(fh, cs); err != nil {
-			printError.Fatalln(err)
+			return printError.Fail(err)
 		}
 	}
 	var mcat *MainCategory
 	if ms := c.String(ObjMainCategory); ms != NotSetStringValue {
 		if mcat, err = MainCategoryForName(fh, ms); err != nil {
-			printError.Fatalln(err)
+			return printError.Fail(err)
 		}
 	}
 	desc := c.String(OptDescription)
@@ -140,7 +142,7 @@ func RepTransactionBalance(c *cli.Context) error {
 	// Build formatting strings
 	var getNextEntry func() *TransactionBalanceReportEntry
 	if getNextEntry, err = ReportTransactionBalance(fh, cur, df, dt, a, cat, mcat, desc); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	lD := utf8.RuneCountInString(HTDate)
 	lMC := utf8.RuneCountInString(HMCName)
@@ -166,7 +168,7 @@ func RepTransactionBalance(c *cli.Context) error {
 	fmt.Fprint(os.Stdout, "\n")
 	fmt.Fprintf(os.Stdout, lineH, HTDate, HMCName, HCName, HAName, HTValue, HTDescription)
 	if getNextEntry, err = ReportTransactionBalance(fh, cur, df, dt, a, cat, mcat, desc); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	for e := getNextEntry(); e != nil; e = getNextEntry() {
 		fmt.Fprintf(os.Stdout, lineD, e.Transaction.Date.Format(DateFormat), e.Transaction.Category.Main.Name, e.Transaction.Category.Name, e.Transaction.Account.Name, e.Balance, e.Transaction.Description)
@@ -186,60 +188,53 @@ func RepCategoryBalance(c *cli.Context) error {
 	// Check obligatory flags
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
 	cur := c.String(OptCurrency)
 	if cur == NotSetStringValue {
-		printError.Fatalln(errMissingCurrencyFlag)
+		return printError.Fail(errMissingCurrencyFlag)
 	}
 
 	// Open data file
-	fh := GetDataFileHandler(f)
-	if err = fh.Open(); err != nil {
-		printError.Fatalln(err)
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	defer fh.Close()
+	defer closeDataFile(fh)
 
 	// Create filters
-	var df time.Time
-	if ds := c.String(OptDateFrom); ds != NotSetStringValue {
-		if df, err = time.Parse(DateFormat, ds); err != nil {
-			printError.Fatalln(err)
-		}
-	} else {
-		df = time.Time{}
-	}
-	var dt time.Time
-	if ds := c.String(OptDateTo); ds != NotSetStringValue {
-		if dt, err = time.Parse(DateFormat, ds); err != nil {
-			printError.Fatalln(err)
-		}
-	} else {
-		dt = time.Time{}
+	var df, dt time.Time
+	if df, dt, err = datesRangeFlags(c, fh); err != nil {
+		return printError.Fail(err)
 	}
 	var a *Account
 	if as := c.String(ObjAccount); as != NotSetStringValue {
 		if a, err = AccountForName(fh, as); err != nil {
-			printError.Fatalln(err)
+			return printError.Fail(err)
 		}
 	}
 	var cat *Category
 	if cs := c.String(ObjCategory); cs != NotSetStringValue {
 		if cat, err = CategoryForName(fh, cs); err != nil {
-			printError.Fatalln(err)
+			return printError.Fail(err)
 		}
 	}
 	var mcat *MainCategory
 	if ms := c.String(ObjMainCategory); ms != NotSetStringValue {
 		if mcat, err = MainCategoryForName(fh, ms); err != nil {
-			printError.Fatalln(err)
+			return printError.Fail(err)
 		}
 	}
+	depth := c.Int(OptDepth)
+	var tree *CategoryTree
+	if tree, err = CategoryTreeGet(fh); err != nil {
+		return printError.Fail(err)
+	}
 
 	// Build formatting strings
 	var getNextEntry func() *CategoryBalanceReportEntry
-	if getNextEntry, err = ReportCategoryBalance(fh, cur, df, dt, a, cat, mcat); err != nil {
-		printError.Fatalln(err)
+	if getNextEntry, err = ReportCategoryBalance(fh, cur, df, dt, a, cat, mcat, depth); err != nil {
+		return printError.Fail(err)
 	}
 	lMT := utf8.RuneCountInString(HMCType)
 	lM := utf8.RuneCountInString(HMCName)
@@ -250,7 +245,7 @@ func RepCategoryBalance(c *cli.Context) error {
 	for e := getNextEntry(); e != nil; e = getNextEntry() {
 		lMT = MaxLen(e.Category.Main.MType.Name, lMT)
 		lM = MaxLen(e.Category.Main.Name, lM)
-		lC = MaxLen(e.Category.Name, lC)
+		lC = MaxLen(tree.Path(e.Category), lC)
 		if currentType != e.Category.Main.MType.Name {
 			lV = MaxLen(strconv.FormatFloat(sumValue, 'f', 2, 64), lV)
 			sumValue = 0
@@ -267,8 +262,8 @@ func RepCategoryBalance(c *cli.Context) error {
 	// Print report
 	fmt.Fprintf(os.Stdout, "Categories balance (in %s):\n", strings.ToUpper(cur))
 
-	if getNextEntry, err = ReportCategoryBalance(fh, cur, df, dt, a, cat, mcat); err != nil {
-		printError.Fatalln(err)
+	if getNextEntry, err = ReportCategoryBalance(fh, cur, df, dt, a, cat, mcat, depth); err != nil {
+		return printError.Fail(err)
 	}
 	currentType = NotSetStringValue
 	var subtotalValue, totalValue float64
@@ -285,7 +280,7 @@ func RepCategoryBalance(c *cli.Context) error {
 			beginning = false
 			subtotalValue = NotSetFloatValue
 		}
-		fmt.Fprintf(os.Stdout, lineD, e.Category.Main.Name, e.Category.Name, e.Balance)
+		fmt.Fprintf(os.Stdout, lineD, e.Category.Main.Name, tree.Path(e.Category), e.Balance)
 		subtotalValue += e.Balance
 		totalValue += e.Balance
 	}
@@ -296,7 +291,8 @@ func RepCategoryBalance(c *cli.Context) error {
 	return nil
 }
 
-func RepCategoryBalanceMonthly(c *cli.Context) error {
+// RepCategoryBalanceTime shows balance of category in periods given with --granularity
+func RepCategoryBalanceTime(c *cli.Context) error {
 	var err error
 
 	// Get loggers
@@ -305,50 +301,49 @@ func RepCategoryBalanceMonthly(c *cli.Context) error {
 	// Check obligatory flags
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
 	cur := c.String(OptCurrency)
 	if cur == NotSetStringValue {
-		printError.Fatalln(errMissingCurrencyFlag)
+		return printError.Fail(errMissingCurrencyFlag)
 	}
 	cs := c.String(ObjCategory)
 	if cs == NotSetStringValue {
-		printError.Fatalln(errMissingCategoryFlag)
+		return printError.Fail(errMissingCategoryFlag)
+	}
+	var g Granularity
+	if g, err = GranularityParse(c.String(OptGranularity)); err != nil {
+		return printError.Fail(err)
 	}
 
 	// Open data file
-	fh := GetDataFileHandler(f)
-	if err = fh.Open(); err != nil {
-		printError.Fatalln(err)
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	defer fh.Close()
+	defer closeDataFile(fh)
 
 	// Create filters
 	var cat *Category
 	if cat, err = CategoryForName(fh, cs); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
-	var df time.Time
-	if ds := c.String(OptDateFrom); ds != NotSetStringValue {
-		if df, err = time.Parse(DateFormat, ds); err != nil {
-			printError.Fatalln(err)
+	if depth := c.Int(OptDepth); depth != NotSetIntValue {
+		var tree *CategoryTree
+		if tree, err = CategoryTreeGet(fh); err != nil {
+			return printError.Fail(err)
 		}
-	} else {
-		df = time.Time{}
+		cat = tree.Ancestor(cat, depth)
 	}
-	var dt time.Time
-	if ds := c.String(OptDateTo); ds != NotSetStringValue {
-		if dt, err = time.Parse(DateFormat, ds); err != nil {
-			printError.Fatalln(err)
-		}
-	} else {
-		dt = time.Time{}
+	var df, dt time.Time
+	if df, dt, err = datesRangeFlags(c, fh); err != nil {
+		return printError.Fail(err)
 	}
 
 	// Build formatting strings
 	var getNextEntry func() *BalanceTimeReportEntry
-	if getNextEntry, err = ReportCategoriesBalanceMonthly(fh, cur, cat, df, dt); err != nil {
-		printError.Fatalln(err)
+	if getNextEntry, err = ReportCategoriesBalanceTime(fh, cur, cat, g, df, dt); err != nil {
+		return printError.Fail(err)
 	}
 	lP := utf8.RuneCountInString(HBPeriod)
 	lV := utf8.RuneCountInString(HTValue)
@@ -360,87 +355,20 @@ func RepCategoryBalanceMonthly(c *cli.Context) error {
 	lineD := LineFor(DFSForText(lP), DFSForValue(lV))
 
 	// Print report
-	fmt.Fprintf(os.Stdout, "Category '%s' balance monthly (in %s):\n\n", cat.Name, strings.ToUpper(cur))
-	fmt.Fprintf(os.Stdout, lineH, HBPeriod, HTValue)
-	if getNextEntry, err = ReportCategoriesBalanceMonthly(fh, cur, cat, df, dt); err != nil {
-		printError.Fatalln(err)
-	}
-	for e := getNextEntry(); e != nil; e = getNextEntry() {
-		fmt.Fprintf(os.Stdout, lineD, e.Period, e.Value)
-	}
-
-	return nil
-}
-
-func RepCategoryBalanceYearly(c *cli.Context) error {
-	var err error
-
-	// Get loggers
-	_, printError := GetLoggers()
-
-	// Check obligatory flags
-	f := c.String(OptFile)
-	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
-	}
-	cur := c.String(OptCurrency)
-	if cur == NotSetStringValue {
-		printError.Fatalln(errMissingCurrencyFlag)
-	}
-	cs := c.String(ObjCategory)
-	if cs == NotSetStringValue {
-		printError.Fatalln(errMissingCategoryFlag)
-	}
-
-	// Open data file
-	fh := GetDataFileHandler(f)
-	if err = fh.Open(); err != nil {
-		printError.Fatalln(err)
-	}
-	defer fh.Close()
-
-	// Create filters
-	var cat *Category
-	if cat, err = CategoryForName(fh, cs); err != nil {
-		printError.Fatalln(err)
-	}
-	var df time.Time
-	if ds := c.String(OptDateFrom); ds != NotSetStringValue {
-		if df, err = time.Parse(DateFormat, ds); err != nil {
-			printError.Fatalln(err)
+	fmt.Fprintf(os.Stdout, "Category '%s' balance by %s (in %s):\n\n", cat.Name, g, strings.ToUpper(cur))
+	if getNextEntry, err = ReportCategoriesBalanceTime(fh, cur, cat, g, df, dt); err != nil {
+		return printError.Fail(err)
+	}
+	if c.Bool(OptChart) {
+		var periods []string
+		var values []float64
+		for e := getNextEntry(); e != nil; e = getNextEntry() {
+			periods, values = append(periods, e.Period.String()), append(values, e.Value)
 		}
-	} else {
-		df = time.Time{}
-	}
-	var dt time.Time
-	if ds := c.String(OptDateTo); ds != NotSetStringValue {
-		if dt, err = time.Parse(DateFormat, ds); err != nil {
-			printError.Fatalln(err)
-		}
-	} else {
-		dt = time.Time{}
-	}
-
-	// Build formatting strings
-	var getNextEntry func() *BalanceTimeReportEntry
-	if getNextEntry, err = ReportCategoriesBalanceYearly(fh, cur, cat, df, dt); err != nil {
-		printError.Fatalln(err)
-	}
-	lP := utf8.RuneCountInString(HBPeriod)
-	lV := utf8.RuneCountInString(HTValue)
-	for e := getNextEntry(); e != nil; e = getNextEntry() {
-		lP = MaxLen(e.Period.String(), lP)
-		lV = MaxLen(strconv.FormatFloat(e.Value, 'f', 2, 64), lV)
+		printBarChart(os.Stdout, periods, values)
+		return nil
 	}
-	lineH := LineFor(HFSForText(lP), HFSForNumeric(lV))
-	lineD := LineFor(DFSForText(lP), DFSForValue(lV))
-
-	// Print report
-	fmt.Fprintf(os.Stdout, "Category '%s' balance yearly (in %s):\n\n", cat.Name, strings.ToUpper(cur))
 	fmt.Fprintf(os.Stdout, lineH, HBPeriod, HTValue)
-	if getNextEntry, err = ReportCategoriesBalanceYearly(fh, cur, cat, df, dt); err != nil {
-		printError.Fatalln(err)
-	}
 	for e := getNextEntry(); e != nil; e = getNextEntry() {
 		fmt.Fprintf(os.Stdout, lineD, e.Period, e.Value)
 	}
@@ -457,54 +385,42 @@ func RepMainCategoryBalance(c *cli.Context) error {
 	// Check obligatory flags
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
 	cur := c.String(OptCurrency)
 	if cur == NotSetStringValue {
-		printError.Fatalln(errMissingCurrencyFlag)
+		return printError.Fail(errMissingCurrencyFlag)
 	}
 
 	// Open data file
-	fh := GetDataFileHandler(f)
-	if err = fh.Open(); err != nil {
-		printError.Fatalln(err)
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	defer fh.Close()
+	defer closeDataFile(fh)
 
 	// Create filters
-	var df time.Time
-	if ds := c.String(OptDateFrom); ds != NotSetStringValue {
-		if df, err = time.Parse(DateFormat, ds); err != nil {
-			printError.Fatalln(err)
-		}
-	} else {
-		df = time.Time{}
-	}
-	var dt time.Time
-	if ds := c.String(OptDateTo); ds != NotSetStringValue {
-		if dt, err = time.Parse(DateFormat, ds); err != nil {
-			printError.Fatalln(err)
-		}
-	} else {
-		dt = time.Time{}
+	var df, dt time.Time
+	if df, dt, err = datesRangeFlags(c, fh); err != nil {
+		return printError.Fail(err)
 	}
 	var a *Account
 	if as := c.String(ObjAccount); as != NotSetStringValue {
 		if a, err = AccountForName(fh, as); err != nil {
-			printError.Fatalln(err)
+			return printError.Fail(err)
 		}
 	}
 	var mcat *MainCategory
 	if ms := c.String(ObjMainCategory); ms != NotSetStringValue {
 		if mcat, err = MainCategoryForName(fh, ms); err != nil {
-			printError.Fatalln(err)
+			return printError.Fail(err)
 		}
 	}
 
 	// Build formatting strings
 	var getNextEntry func() *MainCategoryBalanceReportEntry
 	if getNextEntry, err = ReportMainCategoryBalance(fh, cur, df, dt, a, mcat); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	lMT := utf8.RuneCountInString(HMCType)
 	lM := utf8.RuneCountInString(HMCName)
@@ -533,7 +449,7 @@ func RepMainCategoryBalance(c *cli.Context) error {
 	fmt.Fprintf(os.Stdout, "Categories balance (in %s):\n", strings.ToUpper(cur))
 
 	if getNextEntry, err = ReportMainCategoryBalance(fh, cur, df, dt, a, mcat); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	currentType = NotSetStringValue
 	subtotalValue, totalValue = NotSetFloatValue, NotSetFloatValue
@@ -561,7 +477,8 @@ func RepMainCategoryBalance(c *cli.Context) error {
 	return nil
 }
 
-func RepMainCategoryBalanceMonthly(c *cli.Context) error {
+// RepMainCategoryBalanceTime shows balance of main category in periods given with --granularity
+func RepMainCategoryBalanceTime(c *cli.Context) error {
 	var err error
 
 	// Get loggers
@@ -570,50 +487,42 @@ func RepMainCategoryBalanceMonthly(c *cli.Context) error {
 	// Check obligatory flags
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
 	cur := c.String(OptCurrency)
 	if cur == NotSetStringValue {
-		printError.Fatalln(errMissingCurrencyFlag)
+		return printError.Fail(errMissingCurrencyFlag)
 	}
 	ms := c.String(ObjMainCategory)
 	if ms == NotSetStringValue {
-		printError.Fatalln(errMissingMainCategoryFlag)
+		return printError.Fail(errMissingMainCategoryFlag)
+	}
+	var g Granularity
+	if g, err = GranularityParse(c.String(OptGranularity)); err != nil {
+		return printError.Fail(err)
 	}
 
 	// Open data file
-	fh := GetDataFileHandler(f)
-	if err = fh.Open(); err != nil {
-		printError.Fatalln(err)
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	defer fh.Close()
+	defer closeDataFile(fh)
 
 	// Create filters
 	var mc *MainCategory
 	if mc, err = MainCategoryForName(fh, ms); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
-	var df time.Time
-	if ds := c.String(OptDateFrom); ds != NotSetStringValue {
-		if df, err = time.Parse(DateFormat, ds); err != nil {
-			printError.Fatalln(err)
-		}
-	} else {
-		df = time.Time{}
-	}
-	var dt time.Time
-	if ds := c.String(OptDateTo); ds != NotSetStringValue {
-		if dt, err = time.Parse(DateFormat, ds); err != nil {
-			printError.Fatalln(err)
-		}
-	} else {
-		dt = time.Time{}
+	var df, dt time.Time
+	if df, dt, err = datesRangeFlags(c, fh); err != nil {
+		return printError.Fail(err)
 	}
 
 	// Build formatting strings
 	var getNextEntry func() *BalanceTimeReportEntry
-	if getNextEntry, err = ReportMainCategoriesBalanceMonthly(fh, cur, mc, df, dt); err != nil {
-		printError.Fatalln(err)
+	if getNextEntry, err = ReportMainCategoriesBalanceTime(fh, cur, mc, g, df, dt); err != nil {
+		return printError.Fail(err)
 	}
 	lP := utf8.RuneCountInString(HBPeriod)
 	lV := utf8.RuneCountInString(HTValue)
@@ -625,87 +534,20 @@ func RepMainCategoryBalanceMonthly(c *cli.Context) error {
 	lineD := LineFor(DFSForText(lP), DFSForValue(lV))
 
 	// Print report
-	fmt.Fprintf(os.Stdout, "Main category '%s' balance monthly (in %s):\n\n", mc.Name, strings.ToUpper(cur))
-	fmt.Fprintf(os.Stdout, lineH, HBPeriod, HTValue)
-	if getNextEntry, err = ReportMainCategoriesBalanceMonthly(fh, cur, mc, df, dt); err != nil {
-		printError.Fatalln(err)
-	}
-	for e := getNextEntry(); e != nil; e = getNextEntry() {
-		fmt.Fprintf(os.Stdout, lineD, e.Period, e.Value)
-	}
-
-	return nil
-}
-
-func RepMainCategoryBalanceYearly(c *cli.Context) error {
-	var err error
-
-	// Get loggers
-	_, printError := GetLoggers()
-
-	// Check obligatory flags
-	f := c.String(OptFile)
-	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
-	}
-	cur := c.String(OptCurrency)
-	if cur == NotSetStringValue {
-		printError.Fatalln(errMissingCurrencyFlag)
-	}
-	ms := c.String(ObjMainCategory)
-	if ms == NotSetStringValue {
-		printError.Fatalln(errMissingMainCategoryFlag)
-	}
-
-	// Open data file
-	fh := GetDataFileHandler(f)
-	if err = fh.Open(); err != nil {
-		printError.Fatalln(err)
-	}
-	defer fh.Close()
-
-	// Create filters
-	var mc *MainCategory
-	if mc, err = MainCategoryForName(fh, ms); err != nil {
-		printError.Fatalln(err)
-	}
-	var df time.Time
-	if ds := c.String(OptDateFrom); ds != NotSetStringValue {
-		if df, err = time.Parse(DateFormat, ds); err != nil {
-			printError.Fatalln(err)
+	fmt.Fprintf(os.Stdout, "Main category '%s' balance by %s (in %s):\n\n", mc.Name, g, strings.ToUpper(cur))
+	if getNextEntry, err = ReportMainCategoriesBalanceTime(fh, cur, mc, g, df, dt); err != nil {
+		return printError.Fail(err)
+	}
+	if c.Bool(OptChart) {
+		var periods []string
+		var values []float64
+		for e := getNextEntry(); e != nil; e = getNextEntry() {
+			periods, values = append(periods, e.Period.String()), append(values, e.Value)
 		}
-	} else {
-		df = time.Time{}
-	}
-	var dt time.Time
-	if ds := c.String(OptDateTo); ds != NotSetStringValue {
-		if dt, err = time.Parse(DateFormat, ds); err != nil {
-			printError.Fatalln(err)
-		}
-	} else {
-		dt = time.Time{}
-	}
-
-	// Build formatting strings
-	var getNextEntry func() *BalanceTimeReportEntry
-	if getNextEntry, err = ReportMainCategoriesBalanceYearly(fh, cur, mc, df, dt); err != nil {
-		printError.Fatalln(err)
-	}
-	lP := utf8.RuneCountInString(HBPeriod)
-	lV := utf8.RuneCountInString(HTValue)
-	for e := getNextEntry(); e != nil; e = getNextEntry() {
-		lP = MaxLen(e.Period.String(), lP)
-		lV = MaxLen(strconv.FormatFloat(e.Value, 'f', 2, 64), lV)
+		printBarChart(os.Stdout, periods, values)
+		return nil
 	}
-	lineH := LineFor(HFSForText(lP), HFSForNumeric(lV))
-	lineD := LineFor(DFSForText(lP), DFSForValue(lV))
-
-	// Print report
-	fmt.Fprintf(os.Stdout, "Main category '%s' balance yearly (in %s):\n\n", mc.Name, strings.ToUpper(cur))
 	fmt.Fprintf(os.Stdout, lineH, HBPeriod, HTValue)
-	if getNextEntry, err = ReportMainCategoriesBalanceYearly(fh, cur, mc, df, dt); err != nil {
-		printError.Fatalln(err)
-	}
 	for e := getNextEntry(); e != nil; e = getNextEntry() {
 		fmt.Fprintf(os.Stdout, lineD, e.Period, e.Value)
 	}
@@ -722,25 +564,25 @@ func RepAssetsSummary(c *cli.Context) error {
 	// Check obligatory flags
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
 	cur := c.String(OptCurrency)
 	if cur == NotSetStringValue {
-		printError.Fatalln(errMissingCurrencyFlag)
+		return printError.Fail(errMissingCurrencyFlag)
 	}
 
 	// Open data file
-	fh := GetDataFileHandler(f)
-	if err = fh.Open(); err != nil {
-		printError.Fatalln(err)
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	defer fh.Close()
+	defer closeDataFile(fh)
 
 	// Create filters
 	var onDate time.Time
 	if ds := c.String(OptDate); ds != NotSetStringValue {
-		if onDate, err = time.Parse(DateFormat, ds); err != nil {
-			printError.Fatalln(err)
+		if onDate, err = DateParse(fh, ds); err != nil {
+			return printError.Fail(err)
 		}
 	} else {
 		onDate = time.Now()
@@ -749,7 +591,7 @@ func RepAssetsSummary(c *cli.Context) error {
 	// Build formatting strings
 	var getNextEntry func() *AssetsSummaryReportEntry
 	if getNextEntry, err = ReportAssetsSummary(fh, cur, onDate); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	lA := utf8.RuneCountInString(HAName)
 	lV := utf8.RuneCountInString(HTValue)
@@ -774,7 +616,7 @@ func RepAssetsSummary(c *cli.Context) error {
 	fmt.Fprintf(os.Stdout, "Assets summary on %s (in %s):\n", onDate.Format(DateFormat), strings.ToUpper(cur))
 
 	if getNextEntry, err = ReportAssetsSummary(fh, cur, onDate); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	currentType = NotSetStringValue
 	subtotalValue, totalValue = NotSetFloatValue, NotSetFloatValue
@@ -810,36 +652,54 @@ func RepBudgetCategories(c *cli.Context) error {
 	// Check obligatory flags
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
 
 	// Open data file
-	fh := GetDataFileHandler(f)
-	if err = fh.Open(); err != nil {
-		printError.Fatalln(err)
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	defer fh.Close()
+	defer closeDataFile(fh)
 
 	// Create filters
 	var p *BPeriod
 	if ps := c.String(OptPeriod); ps != NotSetStringValue {
-		if p, err = BPeriodParseYOrYM(ps); err != nil {
-			printError.Fatalln(err)
+		if p, err = BPeriodParseYOrYM(fh, ps); err != nil {
+			return printError.Fail(err)
 		}
 	} else {
 		if p, err = BPeriodCurrent(); err != nil {
-			printError.Fatalln(err)
+			return printError.Fail(err)
 		}
 	}
 	currency := c.String(OptCurrency)
 	if currency == NotSetStringValue {
-		printError.Fatalln(errMissingCurrencyFlag)
+		return printError.Fail(errMissingCurrencyFlag)
+	}
+	depth := c.Int(OptDepth)
+
+	// Print report
+	if err = printBudgetCategories(os.Stdout, fh, p, currency, depth); err != nil {
+		return printError.Fail(err)
+	}
+
+	return nil
+}
+
+// printBudgetCategories writes budget report for categories in given period to w
+func printBudgetCategories(w io.Writer, fh *gsqlitehandler.SqliteDB, p *BPeriod, currency string, depth int) error {
+	var err error
+
+	var tree *CategoryTree
+	if tree, err = CategoryTreeGet(fh); err != nil {
+		return err
 	}
 
 	// Build formatting strings
 	var getNextEntry func() *BudgetCategoriesReportEntry
-	if getNextEntry, err = ReportBudgetCategories(fh, p, currency); err != nil {
-		printError.Fatalln(err)
+	if getNextEntry, err = ReportBudgetCategories(fh, p, currency, depth); err != nil {
+		return err
 	}
 	lMT := utf8.RuneCountInString(HMCType)
 	lMN := utf8.RuneCountInString(HMCName)
@@ -852,7 +712,7 @@ func RepBudgetCategories(c *cli.Context) error {
 	for e := getNextEntry(); e != nil; e = getNextEntry() {
 		lMT = MaxLen(e.Category.Main.MType.Name, lMT)
 		lMN = MaxLen(e.Category.Main.Name, lMN)
-		lCN = MaxLen(e.Category.Name, lCN)
+		lCN = MaxLen(tree.Path(e.Category), lCN)
 		lBL = MaxLen(strconv.FormatFloat(e.Limit, 'f', 2, 64), lBL)
 		lTV = MaxLen(strconv.FormatFloat(e.Actual, 'f', 2, 64), lTV)
 		lD = MaxLen(strconv.FormatFloat(e.Difference, 'f', 2, 64), lD)
@@ -878,10 +738,10 @@ func RepBudgetCategories(c *cli.Context) error {
 	lineS := LineFor(DFSForText(2*utf8.RuneCountInString(FSSeparator)+lMN+lCN), DFSForValue(lBL), DFSForValue(lTV), DFSForValue(lD))
 
 	// Print report
-	fmt.Fprintf(os.Stdout, "Budget report for %s (in %s):\n", p, strings.ToUpper(currency))
+	fmt.Fprintf(w, "Budget report for %s (in %s):\n", p, strings.ToUpper(currency))
 
-	if getNextEntry, err = ReportBudgetCategories(fh, p, currency); err != nil {
-		printError.Fatalln(err)
+	if getNextEntry, err = ReportBudgetCategories(fh, p, currency, depth); err != nil {
+		return err
 	}
 	currentType = NotSetStringValue
 	var subtotalLimit, subtotalValue, subtotalDifference, totalLimit, totalValue, totalDifference float64
@@ -889,18 +749,18 @@ func RepBudgetCategories(c *cli.Context) error {
 	for e := getNextEntry(); e != nil; e = getNextEntry() {
 		if currentType != e.Category.Main.MType.Name {
 			if !beginning {
-				fmt.Fprintf(os.Stdout, lineS, currentType, subtotalLimit, subtotalValue, subtotalDifference)
+				fmt.Fprintf(w, lineS, currentType, subtotalLimit, subtotalValue, subtotalDifference)
 			}
 			currentType = e.Category.Main.MType.Name
-			fmt.Fprintf(os.Stdout, "\n%s\n", currentType)
-			fmt.Fprintf(os.Stdout, lineH, HMCName, HCName, HBLimit, HTValue, HBDifference)
+			fmt.Fprintf(w, "\n%s\n", currentType)
+			fmt.Fprintf(w, lineH, HMCName, HCName, HBLimit, HTValue, HBDifference)
 
 			beginning = false
 			subtotalLimit = NotSetFloatValue
 			subtotalValue = NotSetFloatValue
 			subtotalDifference = NotSetFloatValue
 		}
-		fmt.Fprintf(os.Stdout, lineD, e.Category.Main.Name, e.Category.Name, e.Limit, e.Actual, e.Difference)
+		fmt.Fprintf(w, lineD, e.Category.Main.Name, tree.Path(e.Category), e.Limit, e.Actual, e.Difference)
 		subtotalLimit += e.Limit
 		subtotalValue += e.Actual
 		subtotalDifference += e.Difference
@@ -908,9 +768,9 @@ func RepBudgetCategories(c *cli.Context) error {
 		totalValue += e.Actual
 		totalDifference += e.Difference
 	}
-	fmt.Fprintf(os.Stdout, lineS, currentType, subtotalLimit, subtotalValue, subtotalDifference)
-	fmt.Fprint(os.Stdout, "\n")
-	fmt.Fprintf(os.Stdout, lineS, "TOTAL", totalLimit, totalValue, totalDifference)
+	fmt.Fprintf(w, lineS, currentType, subtotalLimit, subtotalValue, subtotalDifference)
+	fmt.Fprint(w, "\n")
+	fmt.Fprintf(w, lineS, "TOTAL", totalLimit, totalValue, totalDifference)
 
 	return nil
 }
@@ -924,36 +784,36 @@ func RepBudgetMainCategories(c *cli.Context) error {
 	// Check obligatory flags
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
 
 	// Open data file
-	fh := GetDataFileHandler(f)
-	if err = fh.Open(); err != nil {
-		printError.Fatalln(err)
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	defer fh.Close()
+	defer closeDataFile(fh)
 
 	// Create filters
 	var p *BPeriod
 	if ps := c.String(OptPeriod); ps != NotSetStringValue {
-		if p, err = BPeriodParseYOrYM(ps); err != nil {
-			printError.Fatalln(err)
+		if p, err = BPeriodParseYOrYM(fh, ps); err != nil {
+			return printError.Fail(err)
 		}
 	} else {
 		if p, err = BPeriodCurrent(); err != nil {
-			printError.Fatalln(err)
+			return printError.Fail(err)
 		}
 	}
 	currency := c.String(OptCurrency)
 	if currency == NotSetStringValue {
-		printError.Fatalln(errMissingCurrencyFlag)
+		return printError.Fail(errMissingCurrencyFlag)
 	}
 
 	// Build formatting strings
 	var getNextEntry func() *BudgetMainCategoryReportEntry
 	if getNextEntry, err = ReportBudgetMainCategories(fh, p, currency); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	lMT := utf8.RuneCountInString(HMCType)
 	lMN := utf8.RuneCountInString(HMCName)
@@ -992,7 +852,7 @@ func RepBudgetMainCategories(c *cli.Context) error {
 	// Print report
 	fmt.Fprintf(os.Stdout, "Budget report for %s (in %s):\n", p, strings.ToUpper(currency))
 	if getNextEntry, err = ReportBudgetMainCategories(fh, p, currency); err != nil {
-		printError.Fatalln(err)
+		return printError.Fail(err)
 	}
 	currentType = NotSetStringValue
 	var subtotalLimit, subtotalValue, subtotalDifference, totalLimit, totalValue, totalDifference float64
@@ -1025,7 +885,8 @@ func RepBudgetMainCategories(c *cli.Context) error {
 	return nil
 }
 
-func RepNetValueMonthly(c *cli.Context) error {
+// RepNetValue shows net value at the end of periods given with --granularity
+func RepNetValue(c *cli.Context) error {
 	var err error
 
 	// Get loggers
@@ -1034,41 +895,37 @@ func RepNetValueMonthly(c *cli.Context) error {
 	// Check obligatory flags
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
 	cur := c.String(OptCurrency)
 	if cur == NotSetStringValue {
-		printError.Fatalln(errMissingCurrencyFlag)
+		return printError.Fail(errMissingCurrencyFlag)
+	}
+	var g Granularity
+	if g, err = GranularityParse(c.String(OptGranularity)); err != nil {
+		return printError.Fail(err)
 	}
 
 	// Open data file
-	fh := GetDataFileHandler(f)
-	if err = fh.Open(); err != nil {
-		printError.Fatalln(err)
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	defer fh.Close()
+	defer closeDataFile(fh)
 
 	// Create filters
 	var dateFrom, dateTo time.Time
-	if ds := c.String(OptDateFrom); ds != NotSetStringValue {
-		if dateFrom, err = time.Parse(DateFormat, ds); err != nil {
-			printError.Fatalln(err)
-		}
-	} else {
-		dateFrom = time.Time{}
+	if dateFrom, dateTo, err = datesRangeFlags(c, fh); err != nil {
+		return printError.Fail(err)
 	}
-	if ds := c.String(OptDateTo); ds != NotSetStringValue {
-		if dateTo, err = time.Parse(DateFormat, ds); err != nil {
-			printError.Fatalln(err)
-		}
-	} else {
+	if dateTo.IsZero() {
 		dateTo = time.Now()
 	}
 
 	// Build formatting strings
-	var getNextEntry func() *NetValueMonthlyReportEntry
-	if getNextEntry, err = ReportNetValueMonthly(fh, cur, dateFrom, dateTo); err != nil {
-		printError.Fatalln(err)
+	var getNextEntry func() *NetValueReportEntry
+	if getNextEntry, err = ReportNetValue(fh, cur, g, dateFrom, dateTo); err != nil {
+		return printError.Fail(err)
 	}
 	lP := utf8.RuneCountInString(HBPeriod)
 	lV := utf8.RuneCountInString(HNV)
@@ -1083,25 +940,37 @@ func RepNetValueMonthly(c *cli.Context) error {
 
 	// Print report
 	if dateFrom.IsZero() {
-		fmt.Fprintf(os.Stdout, "Net value until %s (in %s):\n", dateTo.Format(DateFormat), cur)
+		fmt.Fprintf(os.Stdout, "Net value by %s until %s (in %s):\n", g, dateTo.Format(DateFormat), cur)
 	} else {
-		fmt.Fprintf(os.Stdout, "Net value between %s and %s (in %s):\n", dateFrom.Format(DateFormat), dateTo.Format(DateFormat), cur)
+		fmt.Fprintf(os.Stdout, "Net value by %s between %s and %s (in %s):\n", g, dateFrom.Format(DateFormat), dateTo.Format(DateFormat), cur)
+	}
+	if !c.Bool(OptChart) {
+		fmt.Fprintf(os.Stdout, LineH, HBPeriod, HNV)
 	}
-	fmt.Fprintf(os.Stdout, LineH, HBPeriod, HNV)
 
-	if getNextEntry, err = ReportNetValueMonthly(fh, cur, dateFrom, dateTo); err != nil {
-		printError.Fatalln(err)
+	if getNextEntry, err = ReportNetValue(fh, cur, g, dateFrom, dateTo); err != nil {
+		return printError.Fail(err)
 	}
 	totalValue = NotSetFloatValue
+	var periods []string
+	var values []float64
 	for e := getNextEntry(); e != nil; e = getNextEntry() {
 		totalValue += e.Value
-		fmt.Fprintf(os.Stdout, LineD, e.Period.String(), totalValue)
+		if c.Bool(OptChart) {
+			periods, values = append(periods, e.Period.String()), append(values, totalValue)
+		} else {
+			fmt.Fprintf(os.Stdout, LineD, e.Period.String(), totalValue)
+		}
+	}
+	if c.Bool(OptChart) {
+		printBarChart(os.Stdout, periods, values)
 	}
 	//FIXME: wrong net value if date_from is given
 	return nil
 }
 
-func RepIncomeVsCostMonthly(c *cli.Context) error {
+// RepIncomeVsCost shows income, cost and their difference in periods given with --granularity
+func RepIncomeVsCost(c *cli.Context) error {
 	var err error
 
 	// Get loggers
@@ -1110,42 +979,34 @@ func RepIncomeVsCostMonthly(c *cli.Context) error {
 	// Check obligatory flags
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
 	cur := c.String(OptCurrency)
 	if cur == NotSetStringValue {
-		printError.Fatalln(errMissingCurrencyFlag)
+		return printError.Fail(errMissingCurrencyFlag)
+	}
+	var g Granularity
+	if g, err = GranularityParse(c.String(OptGranularity)); err != nil {
+		return printError.Fail(err)
 	}
 
 	// Open data file
-	fh := GetDataFileHandler(f)
-	if err = fh.Open(); err != nil {
-		printError.Fatalln(err)
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	defer fh.Close()
+	defer closeDataFile(fh)
 
 	// Create filters
-	var df time.Time
-	if ds := c.String(OptDateFrom); ds != NotSetStringValue {
-		if df, err = time.Parse(DateFormat, ds); err != nil {
-			printError.Fatalln(err)
-		}
-	} else {
-		df = time.Time{}
-	}
-	var dt time.Time
-	if ds := c.String(OptDateTo); ds != NotSetStringValue {
-		if dt, err = time.Parse(DateFormat, ds); err != nil {
-			printError.Fatalln(err)
-		}
-	} else {
-		dt = time.Time{}
+	var df, dt time.Time
+	if df, dt, err = datesRangeFlags(c, fh); err != nil {
+		return printError.Fail(err)
 	}
 
 	// Build formatting strings
 	var getNextEntry func() *IncomeVsCostReportEntry
-	if getNextEntry, err = ReportIncomeVsCostMonthly(fh, cur, df, dt); err != nil {
-		printError.Fatalln(err)
+	if getNextEntry, err = ReportIncomeVsCost(fh, cur, g, df, dt); err != nil {
+		return printError.Fail(err)
 	}
 	lP := utf8.RuneCountInString(HBPeriod)
 	lI := utf8.RuneCountInString(HIncome)
@@ -1161,11 +1022,20 @@ func RepIncomeVsCostMonthly(c *cli.Context) error {
 	lineD := LineFor(DFSForText(lP), DFSForValue(lI), DFSForValue(lC), DFSForValue(lD))
 
 	// Print report
-	fmt.Fprintf(os.Stdout, "Income vs Cost monthly (in %s):\n\n", strings.ToUpper(cur))
-	fmt.Fprintf(os.Stdout, lineH, HBPeriod, HIncome, HCost, HDifference)
-	if getNextEntry, err = ReportIncomeVsCostMonthly(fh, cur, df, dt); err != nil {
-		printError.Fatalln(err)
+	fmt.Fprintf(os.Stdout, "Income vs Cost by %s (in %s):\n\n", g, strings.ToUpper(cur))
+	if getNextEntry, err = ReportIncomeVsCost(fh, cur, g, df, dt); err != nil {
+		return printError.Fail(err)
+	}
+	if c.Bool(OptChart) {
+		var periods []string
+		var income, cost []float64
+		for e := getNextEntry(); e != nil; e = getNextEntry() {
+			periods, income, cost = append(periods, e.Period.String()), append(income, e.Income), append(cost, e.Cost)
+		}
+		printPairChart(os.Stdout, periods, HIncome, income, HCost, cost)
+		return nil
 	}
+	fmt.Fprintf(os.Stdout, lineH, HBPeriod, HIncome, HCost, HDifference)
 	for e := getNextEntry(); e != nil; e = getNextEntry() {
 		fmt.Fprintf(os.Stdout, lineD, e.Period, e.Income, e.Cost, e.Income+e.Cost)
 	}
@@ -1173,7 +1043,8 @@ func RepIncomeVsCostMonthly(c *cli.Context) error {
 	return nil
 }
 
-func RepIncomeVsCostYearly(c *cli.Context) error {
+// RepForecast prints balances of accounts forecast day by day, with days when any operational account is below zero marked
+func RepForecast(c *cli.Context) error {
 	var err error
 
 	// Get loggers
@@ -1182,64 +1053,139 @@ func RepIncomeVsCostYearly(c *cli.Context) error {
 	// Check obligatory flags
 	f := c.String(OptFile)
 	if f == NotSetStringValue {
-		printError.Fatalln(errMissingFileFlag)
+		return printError.Fail(errMissingFileFlag)
 	}
-	cur := c.String(OptCurrency)
-	if cur == NotSetStringValue {
-		printError.Fatalln(errMissingCurrencyFlag)
+	us := c.String(OptUntil)
+	if us == NotSetStringValue {
+		return printError.Fail(errMissingUntilFlag)
 	}
 
 	// Open data file
-	fh := GetDataFileHandler(f)
-	if err = fh.Open(); err != nil {
-		printError.Fatalln(err)
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
 	}
-	defer fh.Close()
+	defer closeDataFile(fh)
 
 	// Create filters
-	var df time.Time
-	if ds := c.String(OptDateFrom); ds != NotSetStringValue {
-		if df, err = time.Parse(DateFormat, ds); err != nil {
-			printError.Fatalln(err)
-		}
-	} else {
-		df = time.Time{}
+	var until time.Time
+	if _, until, err = DateRangeParse(fh, us); err != nil {
+		return printError.Fail(err)
 	}
-	var dt time.Time
-	if ds := c.String(OptDateTo); ds != NotSetStringValue {
-		if dt, err = time.Parse(DateFormat, ds); err != nil {
-			printError.Fatalln(err)
+	var account *Account
+	if as := c.String(ObjAccount); as != NotSetStringValue {
+		if account, err = AccountForName(fh, as); err != nil {
+			return printError.Fail(err)
 		}
-	} else {
-		dt = time.Time{}
 	}
 
 	// Build formatting strings
-	var getNextEntry func() *IncomeVsCostReportEntry
-	if getNextEntry, err = ReportIncomeVsCostYearly(fh, cur, df, dt); err != nil {
-		printError.Fatalln(err)
+	var accounts []*Account
+	var getNextEntry func() *ForecastReportEntry
+	if accounts, getNextEntry, err = ReportForecast(fh, account, until); err != nil {
+		return printError.Fail(err)
 	}
-	lP := utf8.RuneCountInString(HBPeriod)
-	lI := utf8.RuneCountInString(HIncome)
-	lC := utf8.RuneCountInString(HCost)
-	lD := utf8.RuneCountInString(HDifference)
+	var entries []*ForecastReportEntry
 	for e := getNextEntry(); e != nil; e = getNextEntry() {
-		lP = MaxLen(e.Period.String(), lP)
-		lI = MaxLen(strconv.FormatFloat(e.Income, 'f', 2, 64), lI)
-		lC = MaxLen(strconv.FormatFloat(e.Cost, 'f', 2, 64), lC)
-		lD = MaxLen(strconv.FormatFloat(e.Income+e.Cost, 'f', 2, 64), lD)
+		entries = append(entries, e)
 	}
-	lineH := LineFor(HFSForText(lP), HFSForNumeric(lI), HFSForNumeric(lC), HFSForNumeric(lD))
-	lineD := LineFor(DFSForText(lP), DFSForValue(lI), DFSForValue(lC), DFSForValue(lD))
+	lD := utf8.RuneCountInString(HTDate)
+	for _, e := range entries {
+		lD = MaxLen(e.Date.Format(DateFormat), lD)
+	}
+	lA := make([]int, len(accounts))
+	for i, a := range accounts {
+		lA[i] = utf8.RuneCountInString(a.Name + " " + a.Currency)
+		for _, e := range entries {
+			lA[i] = MaxLen(strconv.FormatFloat(e.Balances[i], 'f', 2, 64), lA[i])
+		}
+	}
+	fsH := []string{HFSForText(lD)}
+	fsD := []string{DFSForText(lD)}
+	for _, l := range lA {
+		fsH = append(fsH, HFSForNumeric(l))
+		fsD = append(fsD, DFSForValue(l))
+	}
+	lineH := LineFor(append(fsH, HFSForText(utf8.RuneCountInString(HFNegative)))...)
+	lineD := LineFor(append(fsD, DFSForText(utf8.RuneCountInString(HFNegative)))...)
 
 	// Print report
-	fmt.Fprintf(os.Stdout, "Income vs Cost yearly (in %s):\n\n", strings.ToUpper(cur))
-	fmt.Fprintf(os.Stdout, lineH, HBPeriod, HIncome, HCost, HDifference)
-	if getNextEntry, err = ReportIncomeVsCostYearly(fh, cur, df, dt); err != nil {
-		printError.Fatalln(err)
+	fmt.Fprintf(os.Stdout, "Forecast of accounts balance until %s:\n", until.Format(DateFormat))
+	h := []interface{}{HTDate}
+	for _, a := range accounts {
+		h = append(h, a.Name+" "+a.Currency)
+	}
+	fmt.Fprintf(os.Stdout, lineH, append(h, HFNegative)...)
+	for _, e := range entries {
+		d := []interface{}{e.Date.Format(DateFormat)}
+		for _, b := range e.Balances {
+			d = append(d, b)
+		}
+		n := NotSetStringValue
+		if e.Negative {
+			n = "!"
+		}
+		fmt.Fprintf(os.Stdout, lineD, append(d, n)...)
 	}
+
+	return nil
+}
+
+// RepGoals prints progress of savings goals in given currency
+func RepGoals(c *cli.Context) error {
+	var err error
+
+	// Get loggers
+	_, printError := GetLoggers()
+
+	// Check obligatory flags
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+	cur := c.String(OptCurrency)
+	if cur == NotSetStringValue {
+		return printError.Fail(errMissingCurrencyFlag)
+	}
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+
+	// Build formatting strings
+	var getNextEntry func() *GoalsReportEntry
+	if getNextEntry, err = ReportGoals(fh, cur); err != nil {
+		return printError.Fail(err)
+	}
+	lN := utf8.RuneCountInString(HGName)
+	lD := utf8.RuneCountInString(HRDateTo)
+	lT := utf8.RuneCountInString(HGTarget)
+	lC := utf8.RuneCountInString(HGCurrent)
+	lP := utf8.RuneCountInString(HGPercent)
+	lM := utf8.RuneCountInString(HGMonthly)
+	lO := utf8.RuneCountInString(HGOnTrack)
 	for e := getNextEntry(); e != nil; e = getNextEntry() {
-		fmt.Fprintf(os.Stdout, lineD, e.Period, e.Income, e.Cost, e.Income+e.Cost)
+		lN = MaxLen(e.Goal.Name, lN)
+		lD = MaxLen(e.Goal.DateTo.Format(DateFormat), lD)
+		lT = MaxLen(strconv.FormatFloat(e.Target, 'f', 2, 64), lT)
+		lC = MaxLen(strconv.FormatFloat(e.Current, 'f', 2, 64), lC)
+		lP = MaxLen(strconv.FormatFloat(e.Percent, 'f', 2, 64), lP)
+		lM = MaxLen(strconv.FormatFloat(e.Monthly, 'f', 2, 64), lM)
+	}
+	lineH := LineFor(HFSForText(lN), HFSForText(lD), HFSForNumeric(lT), HFSForNumeric(lC), HFSForNumeric(lP), HFSForNumeric(lM), HFSForText(lO))
+	lineD := LineFor(DFSForText(lN), DFSForText(lD), DFSForValue(lT), DFSForValue(lC), DFSForValue(lP), DFSForValue(lM), DFSForText(lO))
+
+	// Print report
+	fmt.Fprintf(os.Stdout, "Savings goals on %s (in %s):\n", time.Now().Format(DateFormat), strings.ToUpper(cur))
+	if getNextEntry, err = ReportGoals(fh, cur); err != nil {
+		return printError.Fail(err)
+	}
+	fmt.Fprintf(os.Stdout, lineH, HGName, HRDateTo, HGTarget, HGCurrent, HGPercent, HGMonthly, HGOnTrack)
+	for e := getNextEntry(); e != nil; e = getNextEntry() {
+		fmt.Fprintf(os.Stdout, lineD, e.Goal.Name, e.Goal.DateTo.Format(DateFormat), e.Target, e.Current, e.Percent, e.Monthly, YesNo(e.OnTrack))
 	}
 
 	return nil
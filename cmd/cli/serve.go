@@ -0,0 +1,1333 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/urfave/cli"
+	. "github.com/zbroju/financoj/lib"
+	"github.com/zbroju/gsqlitehandler"
+	"math"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Path of HTTP API. Objects are served under their names (e.g. /api/account/3), reports under /api/report/<name>.
+const apiPath = "/api/"
+
+// apiError is an error of API request with HTTP status code returned to the client
+type apiError struct {
+	status int
+	msg    string
+}
+
+func (e *apiError) Error() string {
+	return e.msg
+}
+
+// apiErr returns error with given HTTP status code, unless err is a failure of data file (then it is internal server error)
+func apiErr(status int, err error) error {
+	if IsDataFileError(err) {
+		status = http.StatusInternalServerError
+	}
+	return &apiError{status: status, msg: err.Error()}
+}
+
+// apiBadRequest returns error of incorrect request with message msg
+func apiBadRequest(msg string) error {
+	return &apiError{status: http.StatusBadRequest, msg: msg}
+}
+
+// apiRequest is a request to API object (or report) with values given in JSON body or in URL query.
+// Names of the values are the same as long names of command line options (e.g. account, category, value).
+type apiRequest struct {
+	fh     *gsqlitehandler.SqliteDB // data file the dates and periods are parsed for
+	method string
+	keys   []string // parts of the path after object name (e.g. id)
+	values map[string]interface{}
+	err    error
+}
+
+// fail records error of the value with name n (the first one is returned to the client)
+func (r *apiRequest) fail(n string) {
+	if r.err == nil {
+		r.err = apiBadRequest(fmt.Sprintf(errAPIIncorrectValue, n))
+	}
+}
+
+// IsSet checks if the value with name n was given in the request
+func (r *apiRequest) IsSet(n string) bool {
+	_, ok := r.values[n]
+	return ok
+}
+
+// String returns the value with name n as string
+func (r *apiRequest) String(n string) string {
+	switch v := r.values[n].(type) {
+	case nil:
+		return NotSetStringValue
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		r.fail(n)
+		return NotSetStringValue
+	}
+}
+
+// Float64 returns the value with name n as number
+func (r *apiRequest) Float64(n string) float64 {
+	switch v := r.values[n].(type) {
+	case nil:
+		return NotSetFloatValue
+	case float64:
+		return v
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			r.fail(n)
+		}
+		return f
+	default:
+		r.fail(n)
+		return NotSetFloatValue
+	}
+}
+
+// Int returns the value with name n as integer number
+func (r *apiRequest) Int(n string) int {
+	f := r.Float64(n)
+	if f != math.Trunc(f) {
+		r.fail(n)
+	}
+	return int(f)
+}
+
+// Bool returns the value with name n as boolean
+func (r *apiRequest) Bool(n string) bool {
+	switch v := r.values[n].(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			r.fail(n)
+		}
+		return b
+	default:
+		r.fail(n)
+		return false
+	}
+}
+
+// Date returns the value with name n as date (zero time if it is missing)
+func (r *apiRequest) Date(n string) time.Time {
+	var d time.Time
+	if s := r.String(n); s != NotSetStringValue {
+		var err error
+		if d, err = DateParse(r.fh, s); err != nil {
+			r.fail(n)
+		}
+	}
+	return d
+}
+
+// DatesRange returns dates range given with values range, date-from and date-to (see datesRange)
+func (r *apiRequest) DatesRange() (df, dt time.Time) {
+	for _, n := range []string{OptRange, OptDateFrom, OptDateTo} {
+		if s := r.String(n); s != NotSetStringValue {
+			if _, _, err := DateRangeParse(r.fh, s); err != nil {
+				r.fail(n)
+				return df, dt
+			}
+		}
+	}
+	df, dt, _ = datesRange(r.fh, r.String(OptRange), r.String(OptDateFrom), r.String(OptDateTo))
+
+	return df, dt
+}
+
+// id returns ID given in the path of object
+func (r *apiRequest) id() (int, error) {
+	if len(r.keys) != 1 {
+		return NotSetIntValue, &apiError{status: http.StatusNotFound, msg: errAPIPathUnknown}
+	}
+	id, err := strconv.Atoi(r.keys[0])
+	if err != nil {
+		return NotSetIntValue, apiBadRequest(errAPIIncorrectID)
+	}
+	return id, nil
+}
+
+// apiObject handles requests for one kind of objects
+type apiObject func(fh *gsqlitehandler.SqliteDB, r *apiRequest) (status int, body interface{}, err error)
+
+// apiObjects maps path of API to objects
+var apiObjects = map[string]apiObject{
+	ObjAccount:                  apiAccount,
+	ObjCategory:                 apiCategory,
+	ObjMainCategory:             apiMainCategory,
+	ObjMainCategoryType:         apiMainCategoryType,
+	ObjExchangeRate:             apiExchangeRate,
+	ObjTransaction:              apiTransaction,
+	ObjBudget:                   apiBudget,
+	ObjCompoundTransfer:         apiCompoundTransfer,
+	ObjCompoundInternalCost:     apiCompoundInternalCost,
+	ObjCompoundTransactionSplit: apiCompoundTransactionSplit,
+	CmdReport:                   apiReport,
+}
+
+// apiHandler serves HTTP API for data file. Requests are handled one by one.
+// Default currency is used in reports and new objects when the request does not give any.
+// Only requests sent to the address the server listens on are accepted (see apiCheckOrigin).
+type apiHandler struct {
+	fh       *gsqlitehandler.SqliteDB
+	currency string
+	listen   string
+	lock     sync.Mutex
+}
+
+// ServeHTTP satisfies http.Handler interface
+func (h *apiHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	// Data file is backed up before the first change made by the request
+	var status int
+	var body interface{}
+	err := BackupOnNextChange(h.fh)
+	if err == nil {
+		status, body, err = h.serve(req)
+	}
+	if err != nil {
+		status = http.StatusInternalServerError
+		if e, ok := err.(*apiError); ok {
+			status = e.status
+		}
+		body = apiMessage{Error: err.Error()}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// serve parses the request and passes it to the object
+func (h *apiHandler) serve(req *http.Request) (int, interface{}, error) {
+	if err := h.checkOrigin(req); err != nil {
+		return 0, nil, err
+	}
+	if !strings.HasPrefix(req.URL.Path, apiPath) {
+		return 0, nil, &apiError{status: http.StatusNotFound, msg: errAPIPathUnknown}
+	}
+	path := strings.Split(strings.Trim(strings.TrimPrefix(req.URL.Path, apiPath), "/"), "/")
+	o, ok := apiObjects[path[0]]
+	if !ok {
+		return 0, nil, &apiError{status: http.StatusNotFound, msg: errAPIPathUnknown}
+	}
+
+	r := &apiRequest{fh: h.fh, method: req.Method, keys: path[1:], values: make(map[string]interface{})}
+	if h.currency != NotSetStringValue && (req.Method == http.MethodPost || path[0] == CmdReport) {
+		r.values[OptCurrency] = h.currency
+	}
+	for k, v := range req.URL.Query() {
+		r.values[k] = v[0]
+	}
+	if req.Method == http.MethodPost || req.Method == http.MethodPut || req.Method == http.MethodPatch {
+		// Other content types can be sent by any web page without asking the server first (CORS preflight)
+		if t, _, err := mime.ParseMediaType(req.Header.Get("Content-Type")); err != nil || t != "application/json" {
+			return 0, nil, &apiError{status: http.StatusUnsupportedMediaType, msg: errAPIContentType}
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return 0, nil, apiBadRequest(errAPIIncorrectBody)
+		}
+		for k, v := range body {
+			r.values[k] = v
+		}
+	}
+
+	return o(h.fh, r)
+}
+
+// checkOrigin refuses requests which can come from other web pages visited by the user:
+// the ones sent by pages from other origins, and the ones with host name other than the address
+// the server listens on (pages of other hosts may resolve their names to it, i.e. DNS rebinding).
+// Localhost and loopback addresses are accepted if the server listens on one of them,
+// and any IP address if it listens on all interfaces (with the same port in both cases).
+func (h *apiHandler) checkOrigin(req *http.Request) error {
+	if !apiHostAllowed(req.Host, h.listen) {
+		return &apiError{status: http.StatusForbidden, msg: errAPIHostNotAllowed}
+	}
+	if o := req.Header.Get("Origin"); o != NotSetStringValue && o != "http://"+req.Host {
+		return &apiError{status: http.StatusForbidden, msg: errAPIOriginNotAllowed}
+	}
+
+	return nil
+}
+
+// apiHostAllowed checks if host (taken from the request) is the address listen the server listens on
+func apiHostAllowed(host, listen string) bool {
+	if strings.EqualFold(host, listen) {
+		return true
+	}
+	lh, lp, err := net.SplitHostPort(listen)
+	if err != nil {
+		return false
+	}
+	h, p, err := net.SplitHostPort(host)
+	if err != nil || p != lp {
+		return false
+	}
+	ip, lip := net.ParseIP(h), net.ParseIP(lh)
+	switch {
+	case lh == NotSetStringValue || lip != nil && lip.IsUnspecified():
+		return strings.EqualFold(h, "localhost") || ip != nil
+	case strings.EqualFold(lh, "localhost") || lip != nil && lip.IsLoopback():
+		return strings.EqualFold(h, "localhost") || ip != nil && ip.IsLoopback()
+	}
+
+	return false
+}
+
+// apiMethodNotAllowed is returned for methods not supported by objects
+var apiMethodNotAllowed = &apiError{status: http.StatusMethodNotAllowed, msg: errAPIMethodNotAllowed}
+
+// apiMessage is a response with message (the same as printed by command line) or error
+type apiMessage struct {
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// CmdHTTPServer serves HTTP API with JSON requests and responses for given data file, and web dashboard using it
+func CmdHTTPServer(c *cli.Context) error {
+	var err error
+
+	// Get loggers
+	printUserMsg, printError := GetLoggers()
+
+	// Check obligatory flags
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+	l := c.String(OptListen)
+	if l == NotSetStringValue {
+		return printError.Fail(errMissingListenFlag)
+	}
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+	KeepDataFileUnlocked(fh)
+
+	// Serve API and dashboard
+	mux := http.NewServeMux()
+	mux.Handle(apiPath, &apiHandler{fh: fh, currency: c.String(OptCurrency), listen: l})
+	mux.Handle("/", dashboardHandler())
+	printUserMsg.Printf("serving %s at http://%s/ (API at %s)\n", f, l, apiPath)
+	if err = http.ListenAndServe(l, mux); err != nil {
+		return printError.Fail(err)
+	}
+
+	return nil
+}
+
+// apiAccountEntry represents account in API responses
+type apiAccountEntry struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Bank        string `json:"bank"`
+	Currency    string `json:"currency"`
+	Type        string `json:"type"`
+	Status      string `json:"status"`
+}
+
+func apiAccountEntryFor(a *Account) *apiAccountEntry {
+	return &apiAccountEntry{ID: a.Id, Name: a.Name, Description: a.Description, Bank: a.Institution, Currency: a.Currency, Type: a.AType.String(), Status: a.Status.String()}
+}
+
+// apiAccount lists, adds, shows, edits and removes accounts
+func apiAccount(fh *gsqlitehandler.SqliteDB, r *apiRequest) (int, interface{}, error) {
+	var err error
+	var a *Account
+
+	if len(r.keys) == 0 {
+		switch r.method {
+		case http.MethodGet:
+			var at AccountType = ATUnset
+			if ts := r.String(OptAccountType); ts != NotSetStringValue {
+				if at = AccountTypeForString(ts); at == ATUnknown {
+					return 0, nil, apiBadRequest(errIncorrectAccountType)
+				}
+			}
+			s := ISOpen
+			if r.Bool(OptAll) {
+				s = ISUnset
+			}
+			n, d, i, j := r.String(ObjAccount), r.String(OptDescription), r.String(OptInstitution), r.String(OptCurrency)
+			if r.err != nil {
+				return 0, nil, r.err
+			}
+			var getNextAccount func() *Account
+			if getNextAccount, err = AccountList(fh, n, d, i, j, at, s); err != nil {
+				return 0, nil, apiErr(http.StatusInternalServerError, err)
+			}
+			accounts := []*apiAccountEntry{}
+			for a = getNextAccount(); a != nil; a = getNextAccount() {
+				accounts = append(accounts, apiAccountEntryFor(a))
+			}
+			return http.StatusOK, accounts, nil
+
+		case http.MethodPost:
+			a = &Account{Name: r.String(ObjAccount), Description: r.String(OptDescription), Institution: r.String(OptInstitution), Currency: r.String(OptCurrency), AType: ATTransactional, Status: ISOpen}
+			if ts := r.String(OptAccountType); ts != NotSetStringValue {
+				a.AType = AccountTypeForString(ts)
+			}
+			switch {
+			case r.err != nil:
+				return 0, nil, r.err
+			case a.Name == NotSetStringValue:
+				return 0, nil, apiBadRequest(errMissingAccountFlag)
+			case a.Currency == NotSetStringValue:
+				return 0, nil, apiBadRequest(errMissingCurrencyFlag)
+			case a.AType == ATUnknown:
+				return 0, nil, apiBadRequest(errIncorrectAccountType)
+			}
+			if err = AccountAdd(fh, a); err != nil {
+				return 0, nil, apiErr(http.StatusConflict, err)
+			}
+			return apiReread(http.StatusCreated, apiAccount, fh, a.Id)
+		}
+		return 0, nil, apiMethodNotAllowed
+	}
+
+	id, err := r.id()
+	if err != nil {
+		return 0, nil, err
+	}
+	if a, err = AccountForID(fh, id); err != nil {
+		return 0, nil, apiErr(http.StatusNotFound, err)
+	}
+
+	switch r.method {
+	case http.MethodGet:
+		return http.StatusOK, apiAccountEntryFor(a), nil
+
+	case http.MethodPut, http.MethodPatch:
+		if n := r.String(ObjAccount); n != NotSetStringValue {
+			a.Name = n
+		}
+		if r.IsSet(OptDescription) {
+			a.Description = r.String(OptDescription)
+		}
+		if r.IsSet(OptInstitution) {
+			a.Institution = r.String(OptInstitution)
+		}
+		if j := r.String(OptCurrency); j != NotSetStringValue {
+			a.Currency = j
+		}
+		if ts := r.String(OptAccountType); ts != NotSetStringValue {
+			if a.AType = AccountTypeForString(ts); a.AType == ATUnknown {
+				return 0, nil, apiBadRequest(errIncorrectAccountType)
+			}
+		}
+		if r.err != nil {
+			return 0, nil, r.err
+		}
+		if err = AccountEdit(fh, a); err != nil {
+			return 0, nil, apiErr(http.StatusConflict, err)
+		}
+		return apiReread(http.StatusOK, apiAccount, fh, a.Id)
+
+	case http.MethodDelete:
+		if err = AccountRemove(fh, a); err != nil {
+			return 0, nil, apiErr(http.StatusConflict, err)
+		}
+		return http.StatusOK, apiMessage{Message: fmt.Sprintf("removed account with id = %d", a.Id)}, nil
+	}
+
+	return 0, nil, apiMethodNotAllowed
+}
+
+// apiReread returns object with given id as it has been saved in data file
+func apiReread(status int, o apiObject, fh *gsqlitehandler.SqliteDB, id interface{}) (int, interface{}, error) {
+	_, body, err := o(fh, &apiRequest{fh: fh, method: http.MethodGet, keys: []string{fmt.Sprint(id)}})
+	if err != nil {
+		return 0, nil, err
+	}
+	return status, body, nil
+}
+
+// apiCategoryEntry represents category in API responses
+type apiCategoryEntry struct {
+	ID           int64  `json:"id"`
+	Name         string `json:"name"`
+	Path         string `json:"path"`
+	MainCategory string `json:"main_category"`
+	Type         string `json:"type"`
+	ParentID     int64  `json:"parent_id"`
+	Status       string `json:"status"`
+}
+
+func apiCategoryEntryFor(tree *CategoryTree, c *Category) *apiCategoryEntry {
+	return &apiCategoryEntry{ID: c.Id, Name: c.Name, Path: tree.FullPath(c), MainCategory: c.Main.Name, Type: c.Main.MType.Name, ParentID: c.ParentId, Status: c.Status.String()}
+}
+
+// apiCategory lists, adds, shows, edits and removes categories
+func apiCategory(fh *gsqlitehandler.SqliteDB, r *apiRequest) (int, interface{}, error) {
+	var err error
+	var cat *Category
+
+	var tree *CategoryTree
+	if tree, err = CategoryTreeGet(fh); err != nil {
+		return 0, nil, apiErr(http.StatusInternalServerError, err)
+	}
+
+	if len(r.keys) == 0 {
+		switch r.method {
+		case http.MethodGet:
+			var mcat *MainCategory
+			if mn := r.String(ObjMainCategory); mn != NotSetStringValue {
+				if mcat, err = MainCategoryForName(fh, mn); err != nil {
+					return 0, nil, apiErr(http.StatusBadRequest, err)
+				}
+			}
+			s := ISOpen
+			if r.Bool(OptAll) {
+				s = ISUnset
+			}
+			n := r.String(ObjCategory)
+			if r.err != nil {
+				return 0, nil, r.err
+			}
+			var getNextCategory func() *Category
+			if getNextCategory, err = CategoryList(fh, mcat, n, s); err != nil {
+				return 0, nil, apiErr(http.StatusInternalServerError, err)
+			}
+			categories := []*apiCategoryEntry{}
+			for cat = getNextCategory(); cat != nil; cat = getNextCategory() {
+				categories = append(categories, apiCategoryEntryFor(tree, cat))
+			}
+			return http.StatusOK, categories, nil
+
+		case http.MethodPost:
+			n, p, m := r.String(ObjCategory), r.String(OptCategoryParent), r.String(ObjMainCategory)
+			switch {
+			case r.err != nil:
+				return 0, nil, r.err
+			case n == NotSetStringValue:
+				return 0, nil, apiBadRequest(errMissingCategoryFlag)
+			case m == NotSetStringValue && p == NotSetStringValue:
+				return 0, nil, apiBadRequest(errMissingMainOrParentFlag)
+			}
+			cat = &Category{Name: n, Status: ISOpen}
+			if p != NotSetStringValue {
+				var pc *Category
+				if pc, err = CategoryForName(fh, p); err != nil {
+					return 0, nil, apiErr(http.StatusBadRequest, err)
+				}
+				cat.Main = pc.Main
+				cat.ParentId = pc.Id
+			} else if cat.Main, err = MainCategoryForName(fh, m); err != nil {
+				return 0, nil, apiErr(http.StatusBadRequest, err)
+			}
+			if err = CategoryAdd(fh, cat); err != nil {
+				return 0, nil, apiErr(http.StatusConflict, err)
+			}
+			return apiReread(http.StatusCreated, apiCategory, fh, cat.Id)
+		}
+		return 0, nil, apiMethodNotAllowed
+	}
+
+	id, err := r.id()
+	if err != nil {
+		return 0, nil, err
+	}
+	if cat, err = CategoryForID(fh, id); err != nil {
+		return 0, nil, apiErr(http.StatusNotFound, err)
+	}
+
+	switch r.method {
+	case http.MethodGet:
+		return http.StatusOK, apiCategoryEntryFor(tree, cat), nil
+
+	case http.MethodPut, http.MethodPatch:
+		if m := r.String(ObjMainCategory); m != NotSetStringValue {
+			if cat.Main, err = MainCategoryForName(fh, m); err != nil {
+				return 0, nil, apiErr(http.StatusBadRequest, err)
+			}
+			cat.ParentId = int64(NotSetIntValue)
+		}
+		if p := r.String(OptCategoryParent); p != NotSetStringValue {
+			var pc *Category
+			if pc, err = CategoryForName(fh, p); err != nil {
+				return 0, nil, apiErr(http.StatusBadRequest, err)
+			}
+			cat.Main = pc.Main
+			cat.ParentId = pc.Id
+		}
+		if n := r.String(ObjCategory); n != NotSetStringValue {
+			cat.Name = n
+		}
+		if r.err != nil {
+			return 0, nil, r.err
+		}
+		if err = CategoryEdit(fh, cat); err != nil {
+			return 0, nil, apiErr(http.StatusConflict, err)
+		}
+		return apiReread(http.StatusOK, apiCategory, fh, cat.Id)
+
+	case http.MethodDelete:
+		if err = CategoryRemove(fh, cat); err != nil {
+			return 0, nil, apiErr(http.StatusConflict, err)
+		}
+		return http.StatusOK, apiMessage{Message: fmt.Sprintf("removed category with id = %d", cat.Id)}, nil
+	}
+
+	return 0, nil, apiMethodNotAllowed
+}
+
+// apiMainCategoryEntry represents main category in API responses
+type apiMainCategoryEntry struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Status string `json:"status"`
+}
+
+func apiMainCategoryEntryFor(m *MainCategory) *apiMainCategoryEntry {
+	return &apiMainCategoryEntry{ID: m.Id, Name: m.Name, Type: m.MType.Name, Status: m.Status.String()}
+}
+
+// apiMainCategory lists, adds, shows, edits and removes main categories
+func apiMainCategory(fh *gsqlitehandler.SqliteDB, r *apiRequest) (int, interface{}, error) {
+	var err error
+	var mc *MainCategory
+
+	if len(r.keys) == 0 {
+		switch r.method {
+		case http.MethodGet:
+			var mct *MainCategoryType
+			if t := r.String(OptMainCategoryType); t != NotSetStringValue {
+				if mct, err = MainCategoryTypeForName(fh, t); err != nil {
+					return 0, nil, apiErr(http.StatusBadRequest, err)
+				}
+			}
+			s := ISOpen
+			if r.Bool(OptAll) {
+				s = ISUnset
+			}
+			n := r.String(ObjMainCategory)
+			if r.err != nil {
+				return 0, nil, r.err
+			}
+			var getNextMainCategory func() *MainCategory
+			if getNextMainCategory, err = MainCategoryList(fh, mct, n, s); err != nil {
+				return 0, nil, apiErr(http.StatusInternalServerError, err)
+			}
+			mainCategories := []*apiMainCategoryEntry{}
+			for mc = getNextMainCategory(); mc != nil; mc = getNextMainCategory() {
+				mainCategories = append(mainCategories, apiMainCategoryEntryFor(mc))
+			}
+			return http.StatusOK, mainCategories, nil
+
+		case http.MethodPost:
+			mc = &MainCategory{Name: r.String(ObjMainCategory), Status: ISOpen}
+			tn := r.String(OptMainCategoryType)
+			switch {
+			case r.err != nil:
+				return 0, nil, r.err
+			case mc.Name == NotSetStringValue:
+				return 0, nil, apiBadRequest(errMissingMainCategoryFlag)
+			}
+			if tn != NotSetStringValue {
+				mc.MType, err = MainCategoryTypeForName(fh, tn)
+			} else {
+				mc.MType, err = MainCategoryTypeForID(fh, MCTCost)
+			}
+			if err != nil {
+				return 0, nil, apiErr(http.StatusBadRequest, err)
+			}
+			if err = MainCategoryAdd(fh, mc); err != nil {
+				return 0, nil, apiErr(http.StatusConflict, err)
+			}
+			return apiReread(http.StatusCreated, apiMainCategory, fh, mc.Id)
+		}
+		return 0, nil, apiMethodNotAllowed
+	}
+
+	id, err := r.id()
+	if err != nil {
+		return 0, nil, err
+	}
+	if mc, err = MainCategoryForID(fh, id); err != nil {
+		return 0, nil, apiErr(http.StatusNotFound, err)
+	}
+
+	switch r.method {
+	case http.MethodGet:
+		return http.StatusOK, apiMainCategoryEntryFor(mc), nil
+
+	case http.MethodPut, http.MethodPatch:
+		if t := r.String(OptMainCategoryType); t != NotSetStringValue {
+			if mc.MType, err = MainCategoryTypeForName(fh, t); err != nil {
+				return 0, nil, apiErr(http.StatusBadRequest, err)
+			}
+		}
+		if n := r.String(ObjMainCategory); n != NotSetStringValue {
+			mc.Name = n
+		}
+		if r.err != nil {
+			return 0, nil, r.err
+		}
+		if err = MainCategoryEdit(fh, mc); err != nil {
+			return 0, nil, apiErr(http.StatusConflict, err)
+		}
+		return apiReread(http.StatusOK, apiMainCategory, fh, mc.Id)
+
+	case http.MethodDelete:
+		if err = MainCategoryRemove(fh, mc); err != nil {
+			return 0, nil, apiErr(http.StatusConflict, err)
+		}
+		return http.StatusOK, apiMessage{Message: fmt.Sprintf("removed main category with id = %d", mc.Id)}, nil
+	}
+
+	return 0, nil, apiMethodNotAllowed
+}
+
+// apiMainCategoryTypeEntry represents main category type in API responses
+type apiMainCategoryTypeEntry struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Factor     int    `json:"factor"`
+	IncomeCost bool   `json:"income_cost"`
+	Budget     bool   `json:"budget"`
+	NetValue   bool   `json:"net_value"`
+	Status     string `json:"status"`
+}
+
+func apiMainCategoryTypeEntryFor(t *MainCategoryType) *apiMainCategoryTypeEntry {
+	return &apiMainCategoryTypeEntry{ID: t.Id, Name: t.Name, Factor: t.Factor, IncomeCost: t.InIncomeCost, Budget: t.InBudget, NetValue: t.InNetValue, Status: t.Status.String()}
+}
+
+// apiMainCategoryTypeValues sets factor and reports of main category type t given in request
+func apiMainCategoryTypeValues(r *apiRequest, t *MainCategoryType) error {
+	var err error
+
+	if r.IsSet(OptFactor) {
+		if err = checkFactor(r.String(OptFactor)); err != nil {
+			return apiBadRequest(err.Error())
+		}
+		t.Factor = r.Int(OptFactor)
+	}
+	if rs := r.String(OptReports); rs != NotSetStringValue {
+		if t.InIncomeCost, t.InBudget, t.InNetValue, err = MainCategoryTypeReportsForString(rs); err != nil {
+			return apiBadRequest(err.Error())
+		}
+	}
+
+	return r.err
+}
+
+// apiMainCategoryType lists, adds, shows, edits and removes main category types
+func apiMainCategoryType(fh *gsqlitehandler.SqliteDB, r *apiRequest) (int, interface{}, error) {
+	var err error
+	var t *MainCategoryType
+
+	if len(r.keys) == 0 {
+		switch r.method {
+		case http.MethodGet:
+			s := ISOpen
+			if r.Bool(OptAll) {
+				s = ISUnset
+			}
+			if r.err != nil {
+				return 0, nil, r.err
+			}
+			var getNextMainCategoryType func() *MainCategoryType
+			if getNextMainCategoryType, err = MainCategoryTypeList(fh, s); err != nil {
+				return 0, nil, apiErr(http.StatusInternalServerError, err)
+			}
+			types := []*apiMainCategoryTypeEntry{}
+			for t = getNextMainCategoryType(); t != nil; t = getNextMainCategoryType() {
+				types = append(types, apiMainCategoryTypeEntryFor(t))
+			}
+			return http.StatusOK, types, nil
+
+		case http.MethodPost:
+			t = MainCategoryTypeNew()
+			if t.Name = r.String(OptMainCategoryType); t.Name == NotSetStringValue && r.err == nil {
+				return 0, nil, apiBadRequest(errMissingMainCategoryTypeFlag)
+			}
+			if err = apiMainCategoryTypeValues(r, t); err != nil {
+				return 0, nil, err
+			}
+			if err = MainCategoryTypeAdd(fh, t); err != nil {
+				return 0, nil, apiErr(http.StatusConflict, err)
+			}
+			return apiReread(http.StatusCreated, apiMainCategoryType, fh, t.Id)
+		}
+		return 0, nil, apiMethodNotAllowed
+	}
+
+	id, err := r.id()
+	if err != nil {
+		return 0, nil, err
+	}
+	if t, err = MainCategoryTypeForID(fh, id); err != nil {
+		return 0, nil, apiErr(http.StatusNotFound, err)
+	}
+
+	switch r.method {
+	case http.MethodGet:
+		return http.StatusOK, apiMainCategoryTypeEntryFor(t), nil
+
+	case http.MethodPut, http.MethodPatch:
+		if n := r.String(OptMainCategoryType); n != NotSetStringValue {
+			t.Name = n
+		}
+		if err = apiMainCategoryTypeValues(r, t); err != nil {
+			return 0, nil, err
+		}
+		if err = MainCategoryTypeEdit(fh, t); err != nil {
+			return 0, nil, apiErr(http.StatusConflict, err)
+		}
+		return apiReread(http.StatusOK, apiMainCategoryType, fh, t.Id)
+
+	case http.MethodDelete:
+		if err = MainCategoryTypeRemove(fh, t); err != nil {
+			return 0, nil, apiErr(http.StatusConflict, err)
+		}
+		return http.StatusOK, apiMessage{Message: fmt.Sprintf("removed main category type with id = %d", t.Id)}, nil
+	}
+
+	return 0, nil, apiMethodNotAllowed
+}
+
+// apiExchangeRateEntry represents currency exchange rate in API responses
+type apiExchangeRateEntry struct {
+	CurrencyFrom string  `json:"currency"`
+	CurrencyTo   string  `json:"currency_to"`
+	Rate         float64 `json:"rate"`
+}
+
+func apiExchangeRateEntryFor(e *ExchangeRate) *apiExchangeRateEntry {
+	return &apiExchangeRateEntry{CurrencyFrom: e.CurrencyFrom, CurrencyTo: e.CurrencyTo, Rate: e.Rate}
+}
+
+// apiExchangeRate lists, adds, shows, edits and removes currency exchange rates.
+// Single exchange rate is identified by both currencies in the path (e.g. /api/rate/EUR/SEK).
+func apiExchangeRate(fh *gsqlitehandler.SqliteDB, r *apiRequest) (int, interface{}, error) {
+	var err error
+	var e *ExchangeRate
+
+	if len(r.keys) == 0 {
+		switch r.method {
+		case http.MethodGet:
+			var getNextExchangeRate func() *ExchangeRate
+			if getNextExchangeRate, err = ExchangeRateList(fh); err != nil {
+				return 0, nil, apiErr(http.StatusInternalServerError, err)
+			}
+			rates := []*apiExchangeRateEntry{}
+			for e = getNextExchangeRate(); e != nil; e = getNextExchangeRate() {
+				rates = append(rates, apiExchangeRateEntryFor(e))
+			}
+			return http.StatusOK, rates, nil
+
+		case http.MethodPost:
+			e = &ExchangeRate{CurrencyFrom: r.String(OptCurrency), CurrencyTo: r.String(OptCurrencyTo), Rate: r.Float64(ObjExchangeRate)}
+			switch {
+			case r.err != nil:
+				return 0, nil, r.err
+			case e.CurrencyFrom == NotSetStringValue:
+				return 0, nil, apiBadRequest(errMissingCurrencyFlag)
+			case e.CurrencyTo == NotSetStringValue:
+				return 0, nil, apiBadRequest(errMissingCurrencyToFlag)
+			case e.Rate == NotSetFloatValue:
+				return 0, nil, apiBadRequest(errMissingExchangeRateFlag)
+			}
+			if err = ExchangeRateAdd(fh, e); err != nil {
+				return 0, nil, apiErr(http.StatusConflict, err)
+			}
+			return apiReread(http.StatusCreated, apiExchangeRate, fh, e.CurrencyFrom+"/"+e.CurrencyTo)
+		}
+		return 0, nil, apiMethodNotAllowed
+	}
+
+	if len(r.keys) == 1 && strings.Contains(r.keys[0], "/") {
+		r.keys = strings.SplitN(r.keys[0], "/", 2)
+	}
+	if len(r.keys) != 2 {
+		return 0, nil, &apiError{status: http.StatusNotFound, msg: errAPIPathUnknown}
+	}
+	if e, err = ExchangeRateForCurrencies(fh, r.keys[0], r.keys[1]); err != nil {
+		return 0, nil, apiErr(http.StatusNotFound, err)
+	}
+
+	switch r.method {
+	case http.MethodGet:
+		return http.StatusOK, apiExchangeRateEntryFor(e), nil
+
+	case http.MethodPut, http.MethodPatch:
+		if e.Rate = r.Float64(ObjExchangeRate); r.err != nil {
+			return 0, nil, r.err
+		}
+		if e.Rate == NotSetFloatValue {
+			return 0, nil, apiBadRequest(errMissingExchangeRateFlag)
+		}
+		if err = ExchangeRateEdit(fh, e); err != nil {
+			return 0, nil, apiErr(http.StatusConflict, err)
+		}
+		return http.StatusOK, apiExchangeRateEntryFor(e), nil
+
+	case http.MethodDelete:
+		if err = ExchangeRateRemove(fh, e); err != nil {
+			return 0, nil, apiErr(http.StatusConflict, err)
+		}
+		return http.StatusOK, apiMessage{Message: fmt.Sprintf("removed currency exchange rate for %s and %s", e.CurrencyFrom, e.CurrencyTo)}, nil
+	}
+
+	return 0, nil, apiMethodNotAllowed
+}
+
+// apiTransactionEntry represents transaction in API responses.
+// Value is given as typed by the user, signed value takes into account the type of main category.
+type apiTransactionEntry struct {
+	ID           int64   `json:"id"`
+	Date         string  `json:"date"`
+	Account      string  `json:"account"`
+	Currency     string  `json:"currency"`
+	Category     string  `json:"category"`
+	MainCategory string  `json:"main_category"`
+	Type         string  `json:"type"`
+	Value        float64 `json:"value"`
+	SignedValue  float64 `json:"signed_value"`
+	Description  string  `json:"description"`
+}
+
+func apiTransactionEntryFor(tree *CategoryTree, t *Transaction) *apiTransactionEntry {
+	return &apiTransactionEntry{ID: t.Id, Date: t.Date.Format(DateFormat), Account: t.Account.Name, Currency: t.Account.Currency, Category: tree.FullPath(t.Category),
+		MainCategory: t.Category.Main.Name, Type: t.Category.Main.MType.Name, Value: t.Value, SignedValue: t.GetSValue(), Description: t.Description}
+}
+
+// apiTransactionFilters returns account, category and main category given by names in request
+func apiTransactionFilters(fh *gsqlitehandler.SqliteDB, r *apiRequest) (a *Account, c *Category, m *MainCategory, err error) {
+	if as := r.String(ObjAccount); as != NotSetStringValue {
+		if a, err = AccountForName(fh, as); err != nil {
+			return nil, nil, nil, apiErr(http.StatusBadRequest, err)
+		}
+	}
+	if cs := r.String(ObjCategory); cs != NotSetStringValue {
+		if c, err = CategoryForName(fh, cs); err != nil {
+			return nil, nil, nil, apiErr(http.StatusBadRequest, err)
+		}
+	}
+	if ms := r.String(ObjMainCategory); ms != NotSetStringValue {
+		if m, err = MainCategoryForName(fh, ms); err != nil {
+			return nil, nil, nil, apiErr(http.StatusBadRequest, err)
+		}
+	}
+
+	return a, c, m, nil
+}
+
+// apiTransaction lists, adds, shows, edits and removes transactions
+func apiTransaction(fh *gsqlitehandler.SqliteDB, r *apiRequest) (int, interface{}, error) {
+	var err error
+	var t *Transaction
+
+	var tree *CategoryTree
+	if tree, err = CategoryTreeGet(fh); err != nil {
+		return 0, nil, apiErr(http.StatusInternalServerError, err)
+	}
+
+	if len(r.keys) == 0 {
+		switch r.method {
+		case http.MethodGet:
+			df, dt := r.DatesRange()
+			d := r.String(OptDescription)
+			if r.err != nil {
+				return 0, nil, r.err
+			}
+			a, c, m, err := apiTransactionFilters(fh, r)
+			if err != nil {
+				return 0, nil, err
+			}
+			var getNextTransaction func() *Transaction
+			if getNextTransaction, err = TransactionList(fh, df, dt, a, d, c, m); err != nil {
+				return 0, nil, apiErr(http.StatusInternalServerError, err)
+			}
+			transactions := []*apiTransactionEntry{}
+			for t = getNextTransaction(); t != nil; t = getNextTransaction() {
+				transactions = append(transactions, apiTransactionEntryFor(tree, t))
+			}
+			return http.StatusOK, transactions, nil
+
+		case http.MethodPost:
+			t = TransactionNew()
+			if r.IsSet(OptDate) {
+				t.Date = r.Date(OptDate)
+			}
+			t.Value, t.Description = r.Float64(OptValue), r.String(OptDescription)
+			an, cn := r.String(ObjAccount), r.String(ObjCategory)
+			switch {
+			case r.err != nil:
+				return 0, nil, r.err
+			case an == NotSetStringValue:
+				return 0, nil, apiBadRequest(errMissingAccountFlag)
+			case cn == NotSetStringValue:
+				return 0, nil, apiBadRequest(errMissingCategoryFlag)
+			case t.Value == NotSetFloatValue:
+				return 0, nil, apiBadRequest(errMissingValueFlag)
+			case t.Description == NotSetStringValue:
+				return 0, nil, apiBadRequest(errMissingDescriptionFlag)
+			}
+			if t.Account, err = AccountForName(fh, an); err != nil {
+				return 0, nil, apiErr(http.StatusBadRequest, err)
+			}
+			if t.Category, err = CategoryForName(fh, cn); err != nil {
+				return 0, nil, apiErr(http.StatusBadRequest, err)
+			}
+			if err = TransactionAdd(fh, t); err != nil {
+				return 0, nil, apiErr(http.StatusConflict, err)
+			}
+			return apiReread(http.StatusCreated, apiTransaction, fh, t.Id)
+		}
+		return 0, nil, apiMethodNotAllowed
+	}
+
+	id, err := r.id()
+	if err != nil {
+		return 0, nil, err
+	}
+	if t, err = TransactionForID(fh, id); err != nil {
+		return 0, nil, apiErr(http.StatusNotFound, err)
+	}
+
+	switch r.method {
+	case http.MethodGet:
+		return http.StatusOK, apiTransactionEntryFor(tree, t), nil
+
+	case http.MethodPut, http.MethodPatch:
+		if r.IsSet(OptDate) {
+			t.Date = r.Date(OptDate)
+		}
+		if v := r.Float64(OptValue); v != NotSetFloatValue {
+			t.Value = v
+		}
+		if d := r.String(OptDescription); d != NotSetStringValue {
+			t.Description = d
+		}
+		if r.err != nil {
+			return 0, nil, r.err
+		}
+		if as := r.String(ObjAccount); as != NotSetStringValue {
+			if t.Account, err = AccountForName(fh, as); err != nil {
+				return 0, nil, apiErr(http.StatusBadRequest, err)
+			}
+		}
+		if cs := r.String(ObjCategory); cs != NotSetStringValue {
+			if t.Category, err = CategoryForName(fh, cs); err != nil {
+				return 0, nil, apiErr(http.StatusBadRequest, err)
+			}
+		}
+		if err = TransactionEdit(fh, t); err != nil {
+			return 0, nil, apiErr(http.StatusConflict, err)
+		}
+		return apiReread(http.StatusOK, apiTransaction, fh, t.Id)
+
+	case http.MethodDelete:
+		if err = TransactionRemove(fh, t); err != nil {
+			return 0, nil, apiErr(http.StatusConflict, err)
+		}
+		return http.StatusOK, apiMessage{Message: fmt.Sprintf("removed transaction with id = %d", t.Id)}, nil
+	}
+
+	return 0, nil, apiMethodNotAllowed
+}
+
+// apiBudgetEntry represents budget in API responses
+type apiBudgetEntry struct {
+	Period       string  `json:"period"`
+	Category     string  `json:"category"`
+	MainCategory string  `json:"main_category"`
+	Type         string  `json:"type"`
+	Value        float64 `json:"value"`
+	Currency     string  `json:"currency"`
+}
+
+func apiBudgetEntryFor(tree *CategoryTree, b *Budget) *apiBudgetEntry {
+	return &apiBudgetEntry{Period: b.Period.String(), Category: tree.FullPath(b.Category), MainCategory: b.Category.Main.Name, Type: b.Category.Main.MType.Name, Value: b.Value, Currency: b.Currency}
+}
+
+// apiBudget lists, adds, shows, edits and removes budgets.
+// Single budget is identified by period and category in the path (e.g. /api/budget/2016-05/Food:Groceries).
+func apiBudget(fh *gsqlitehandler.SqliteDB, r *apiRequest) (int, interface{}, error) {
+	var err error
+	var b *Budget
+
+	var tree *CategoryTree
+	if tree, err = CategoryTreeGet(fh); err != nil {
+		return 0, nil, apiErr(http.StatusInternalServerError, err)
+	}
+
+	if len(r.keys) == 0 {
+		switch r.method {
+		case http.MethodGet:
+			var p *BPeriod
+			if ps := r.String(OptPeriod); ps != NotSetStringValue {
+				if p, err = BPeriodParseYOrYM(fh, ps); err != nil {
+					return 0, nil, apiBadRequest(err.Error())
+				}
+			}
+			var c *Category
+			if cs := r.String(ObjCategory); cs != NotSetStringValue {
+				if c, err = CategoryForName(fh, cs); err != nil {
+					return 0, nil, apiErr(http.StatusBadRequest, err)
+				}
+			}
+			if r.err != nil {
+				return 0, nil, r.err
+			}
+			var getNextBudget func() *Budget
+			if getNextBudget, err = BudgetList(fh, p, c); err != nil {
+				return 0, nil, apiErr(http.StatusInternalServerError, err)
+			}
+			budgets := []*apiBudgetEntry{}
+			for b = getNextBudget(); b != nil; b = getNextBudget() {
+				budgets = append(budgets, apiBudgetEntryFor(tree, b))
+			}
+			return http.StatusOK, budgets, nil
+
+		case http.MethodPost:
+			b = BudgetNew()
+			ps, cs := r.String(OptPeriod), r.String(ObjCategory)
+			b.Value, b.Currency = r.Float64(OptValue), r.String(OptCurrency)
+			switch {
+			case r.err != nil:
+				return 0, nil, r.err
+			case ps == NotSetStringValue:
+				return 0, nil, apiBadRequest(errMissingPeriodFlag)
+			case cs == NotSetStringValue:
+				return 0, nil, apiBadRequest(errMissingCategoryFlag)
+			case b.Value == NotSetFloatValue:
+				return 0, nil, apiBadRequest(errMissingValueFlag)
+			case b.Currency == NotSetStringValue:
+				return 0, nil, apiBadRequest(errMissingCurrencyFlag)
+			}
+			if b.Period, err = BPeriodParseYM(ps); err != nil {
+				return 0, nil, apiBadRequest(err.Error())
+			}
+			if b.Category, err = CategoryForName(fh, cs); err != nil {
+				return 0, nil, apiErr(http.StatusBadRequest, err)
+			}
+			if err = BudgetAdd(fh, b); err != nil {
+				return 0, nil, apiErr(http.StatusConflict, err)
+			}
+			return apiReread(http.StatusCreated, apiBudget, fh, b.Period.String()+"/"+strconv.FormatInt(b.Category.Id, 10))
+		}
+		return 0, nil, apiMethodNotAllowed
+	}
+
+	if len(r.keys) == 1 && strings.Contains(r.keys[0], "/") {
+		r.keys = strings.SplitN(r.keys[0], "/", 2)
+	}
+	if len(r.keys) != 2 {
+		return 0, nil, &apiError{status: http.StatusNotFound, msg: errAPIPathUnknown}
+	}
+	var p *BPeriod
+	if p, err = BPeriodParseYM(r.keys[0]); err != nil {
+		return 0, nil, &apiError{status: http.StatusNotFound, msg: err.Error()}
+	}
+	var c *Category
+	if id, err := strconv.Atoi(r.keys[1]); err == nil {
+		c, err = CategoryForID(fh, id)
+	} else {
+		c, err = CategoryForName(fh, r.keys[1])
+	}
+	if err != nil {
+		return 0, nil, apiErr(http.StatusNotFound, err)
+	}
+	if b, err = BudgetGet(fh, p, c); err != nil {
+		return 0, nil, apiErr(http.StatusNotFound, err)
+	}
+
+	switch r.method {
+	case http.MethodGet:
+		return http.StatusOK, apiBudgetEntryFor(tree, b), nil
+
+	case http.MethodPut, http.MethodPatch:
+		if v := r.Float64(OptValue); v != NotSetFloatValue {
+			b.Value = v
+		}
+		if cur := r.String(OptCurrency); cur != NotSetStringValue {
+			b.Currency = cur
+		}
+		if r.err != nil {
+			return 0, nil, r.err
+		}
+		if err = BudgetEdit(fh, b); err != nil {
+			return 0, nil, apiErr(http.StatusConflict, err)
+		}
+		return apiReread(http.StatusOK, apiBudget, fh, b.Period.String()+"/"+strconv.FormatInt(b.Category.Id, 10))
+
+	case http.MethodDelete:
+		if err = BudgetRemove(fh, b); err != nil {
+			return 0, nil, apiErr(http.StatusConflict, err)
+		}
+		return http.StatusOK, apiMessage{Message: fmt.Sprintf("removed budget for period: %s and category: %s", b.Period, b.Category.Name)}, nil
+	}
+
+	return 0, nil, apiMethodNotAllowed
+}
+
+// apiCompoundValues returns date, value and description of compound transaction given in request
+func apiCompoundValues(r *apiRequest) (d time.Time, v float64, desc string, err error) {
+	d = time.Now()
+	if r.IsSet(OptDate) {
+		d = r.Date(OptDate)
+	}
+	v, desc = r.Float64(OptValue), r.String(OptDescription)
+	switch {
+	case r.err != nil:
+		return d, v, desc, r.err
+	case v == NotSetFloatValue:
+		return d, v, desc, apiBadRequest(errMissingValueFlag)
+	case desc == NotSetStringValue:
+		return d, v, desc, apiBadRequest(errMissingDescriptionFlag)
+	}
+
+	return d, v, desc, nil
+}
+
+// apiCompoundAccounts returns both accounts of compound transaction and exchange rate between them
+// (given in request or saved in data file)
+func apiCompoundAccounts(fh *gsqlitehandler.SqliteDB, r *apiRequest) (af, at *Account, e *ExchangeRate, err error) {
+	an, atn, rate := r.String(ObjAccount), r.String(OptAccountTo), r.Float64(ObjExchangeRate)
+	switch {
+	case r.err != nil:
+		return nil, nil, nil, r.err
+	case an == NotSetStringValue || atn == NotSetStringValue:
+		return nil, nil, nil, apiBadRequest(errMissingAccountFlag)
+	}
+	if af, err = AccountForName(fh, an); err != nil {
+		return nil, nil, nil, apiErr(http.StatusBadRequest, err)
+	}
+	if at, err = AccountForName(fh, atn); err != nil {
+		return nil, nil, nil, apiErr(http.StatusBadRequest, err)
+	}
+	if rate == NotSetFloatValue {
+		if e, err = ExchangeRateForCurrencies(fh, af.Currency, at.Currency); err != nil {
+			return nil, nil, nil, apiErr(http.StatusBadRequest, err)
+		}
+	} else {
+		e = &ExchangeRate{CurrencyFrom: af.Currency, CurrencyTo: at.Currency, Rate: rate}
+	}
+
+	return af, at, e, nil
+}
+
+// apiCompoundTransfer adds transfer between two accounts
+func apiCompoundTransfer(fh *gsqlitehandler.SqliteDB, r *apiRequest) (int, interface{}, error) {
+	if r.method != http.MethodPost || len(r.keys) > 0 {
+		return 0, nil, apiMethodNotAllowed
+	}
+
+	d, v, desc, err := apiCompoundValues(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	af, at, e, err := apiCompoundAccounts(fh, r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err = CompoundTransferAdd(fh, d, af, at, v, desc, e); err != nil {
+		return 0, nil, apiErr(http.StatusConflict, err)
+	}
+
+	return http.StatusCreated, apiMessage{Message: "add new transfer"}, nil
+}
+
+// apiCompoundInternalCost adds cost paid from one account and transfer to the other one
+func apiCompoundInternalCost(fh *gsqlitehandler.SqliteDB, r *apiRequest) (int, interface{}, error) {
+	if r.method != http.MethodPost || len(r.keys) > 0 {
+		return 0, nil, apiMethodNotAllowed
+	}
+
+	d, v, desc, err := apiCompoundValues(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	cs := r.String(ObjCategory)
+	if cs == NotSetStringValue {
+		return 0, nil, apiBadRequest(errMissingCategoryFlag)
+	}
+	ac, at, e, err := apiCompoundAccounts(fh, r)
+	if err != nil {
+		return 0, nil, err
+	}
+	var c *Category
+	if c, err = CategoryForName(fh, cs); err != nil {
+		return 0, nil, apiErr(http.StatusBadRequest, err)
+	}
+	if err = CompoundInternalCostAdd(fh, d, c, ac, at, v, desc, e); err != nil {
+		return 0, nil, apiErr(http.StatusConflict, err)
+	}
+
+	return http.StatusCreated, apiMessage{Message: "add new internal cost"}, nil
+}
+
+// apiCompoundTransactionSplit adds transaction split into two categories
+func apiCompoundTransactionSplit(fh *gsqlitehandler.SqliteDB, r *apiRequest) (int, interface{}, error) {
+	if r.method != http.MethodPost || len(r.keys) > 0 {
+		return 0, nil, apiMethodNotAllowed
+	}
+
+	d, v, desc, err := apiCompoundValues(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	an, c1, c2 := r.String(ObjAccount), r.String(ObjCategory), r.String(OptCategorySplit)
+	switch {
+	case r.err != nil:
+		return 0, nil, r.err
+	case an == NotSetStringValue:
+		return 0, nil, apiBadRequest(errMissingAccountFlag)
+	case c1 == NotSetStringValue:
+		return 0, nil, apiBadRequest(errMissingCategoryFlag)
+	case c2 == NotSetStringValue:
+		return 0, nil, apiBadRequest(errMissingCategorySplitFlag)
+	}
+	var a *Account
+	if a, err = AccountForName(fh, an); err != nil {
+		return 0, nil, apiErr(http.StatusBadRequest, err)
+	}
+	var cat1, cat2 *Category
+	if cat1, err = CategoryForName(fh, c1); err != nil {
+		return 0, nil, apiErr(http.StatusBadRequest, err)
+	}
+	if cat2, err = CategoryForName(fh, c2); err != nil {
+		return 0, nil, apiErr(http.StatusBadRequest, err)
+	}
+	if err = CompoundSplitAdd(fh, d, a, v, desc, cat1, cat2); err != nil {
+		return 0, nil, apiErr(http.StatusConflict, err)
+	}
+
+	return http.StatusCreated, apiMessage{Message: "add split transaction"}, nil
+}
@@ -0,0 +1,448 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package cli
+
+import (
+	. "github.com/zbroju/financoj/lib"
+	"github.com/zbroju/gsqlitehandler"
+	"net/http"
+	"time"
+)
+
+// apiReportFunc returns entries of report for criteria given in request
+type apiReportFunc func(fh *gsqlitehandler.SqliteDB, r *apiRequest) (entries interface{}, err error)
+
+// apiReports maps names of reports (the same as in command line) to functions building them
+var apiReports = map[string]apiReportFunc{
+	ObjReportAccountBalance:             apiReportAccountBalance,
+	ObjReportAssetsSummary:              apiReportAssetsSummary,
+	ObjReportTransactionBalance:         apiReportTransactionBalance,
+	ObjReportCategoryBalance:            apiReportCategoryBalance,
+	ObjReportCategoryBalanceMonthly:     apiReportCategoryBalanceTime(GranularityMonth),
+	ObjReportCategoryBalanceYearly:      apiReportCategoryBalanceTime(GranularityYear),
+	ObjReportMainCategoryBalance:        apiReportMainCategoryBalance,
+	ObjReportMainCategoryBalanceMonthly: apiReportMainCategoryBalanceTime(GranularityMonth),
+	ObjReportMainCategoryBalanceYearly:  apiReportMainCategoryBalanceTime(GranularityYear),
+	ObjReportBudgetCategories:           apiReportBudgetCategories,
+	ObjReportBudgetMainCategories:       apiReportBudgetMainCategories,
+	ObjReportNetValueMonthly:            apiReportNetValue,
+	ObjReportIncomeVsCostMonthly:        apiReportIncomeVsCost(GranularityMonth),
+	ObjReportIncomeVsCostYearly:         apiReportIncomeVsCost(GranularityYear),
+}
+
+// apiReport returns report with name given in the path (e.g. /api/report/net-value?currency=EUR)
+func apiReport(fh *gsqlitehandler.SqliteDB, r *apiRequest) (int, interface{}, error) {
+	if len(r.keys) != 1 {
+		return 0, nil, &apiError{status: http.StatusNotFound, msg: errAPIPathUnknown}
+	}
+	rep, ok := apiReports[r.keys[0]]
+	if !ok {
+		return 0, nil, &apiError{status: http.StatusNotFound, msg: errAPIPathUnknown}
+	}
+	if r.method != http.MethodGet {
+		return 0, nil, apiMethodNotAllowed
+	}
+
+	entries, err := rep(fh, r)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return http.StatusOK, entries, nil
+}
+
+// apiReportCriteria returns currency and dates range given in request. Currency is obligatory.
+func apiReportCriteria(r *apiRequest) (currency string, df, dt time.Time, err error) {
+	currency = r.String(OptCurrency)
+	df, dt = r.DatesRange()
+	switch {
+	case r.err != nil:
+		return currency, df, dt, r.err
+	case currency == NotSetStringValue:
+		return currency, df, dt, apiBadRequest(errMissingCurrencyFlag)
+	}
+
+	return currency, df, dt, nil
+}
+
+// apiReportGranularity returns granularity given in request or default one d
+func apiReportGranularity(r *apiRequest, d Granularity) Granularity {
+	if !r.IsSet(OptGranularity) {
+		return d
+	}
+	g, err := GranularityParse(r.String(OptGranularity))
+	if err != nil {
+		r.fail(OptGranularity)
+	}
+
+	return g
+}
+
+// apiReportDate returns date given in request or today
+func apiReportDate(r *apiRequest) (time.Time, error) {
+	d := time.Now()
+	if r.IsSet(OptDate) {
+		d = r.Date(OptDate)
+	}
+
+	return d, r.err
+}
+
+// apiReportPeriod returns budget period given in request or the current one
+func apiReportPeriod(r *apiRequest) (p *BPeriod, err error) {
+	if ps := r.String(OptPeriod); ps != NotSetStringValue {
+		p, err = BPeriodParseYOrYM(r.fh, ps)
+	} else {
+		p, err = BPeriodCurrent()
+	}
+	if err != nil {
+		return nil, apiBadRequest(err.Error())
+	}
+
+	return p, r.err
+}
+
+// apiReportErr returns error of building report: incorrect criteria (e.g. missing exchange rates) or failure of data file
+func apiReportErr(err error) error {
+	return apiErr(http.StatusBadRequest, err)
+}
+
+// apiAccountBalanceEntry represents one line of accounts balance report
+type apiAccountBalanceEntry struct {
+	Account  string  `json:"account"`
+	Currency string  `json:"currency"`
+	Value    float64 `json:"value"`
+}
+
+func apiReportAccountBalance(fh *gsqlitehandler.SqliteDB, r *apiRequest) (interface{}, error) {
+	d, err := apiReportDate(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var getNextEntry func() *AccountBalanceReportEntry
+	if getNextEntry, err = ReportAccountBalance(fh, d); err != nil {
+		return nil, apiReportErr(err)
+	}
+	entries := []*apiAccountBalanceEntry{}
+	for e := getNextEntry(); e != nil; e = getNextEntry() {
+		entries = append(entries, &apiAccountBalanceEntry{Account: e.Account.Name, Currency: e.Account.Currency, Value: e.Value})
+	}
+
+	return entries, nil
+}
+
+// apiAssetsSummaryEntry represents one line of assets summary report
+type apiAssetsSummaryEntry struct {
+	Account string  `json:"account"`
+	Type    string  `json:"type"`
+	Balance float64 `json:"balance"`
+}
+
+func apiReportAssetsSummary(fh *gsqlitehandler.SqliteDB, r *apiRequest) (interface{}, error) {
+	cur := r.String(OptCurrency)
+	d, err := apiReportDate(r)
+	if err != nil {
+		return nil, err
+	}
+	if cur == NotSetStringValue {
+		return nil, apiBadRequest(errMissingCurrencyFlag)
+	}
+
+	var getNextEntry func() *AssetsSummaryReportEntry
+	if getNextEntry, err = ReportAssetsSummary(fh, cur, d); err != nil {
+		return nil, apiReportErr(err)
+	}
+	entries := []*apiAssetsSummaryEntry{}
+	for e := getNextEntry(); e != nil; e = getNextEntry() {
+		entries = append(entries, &apiAssetsSummaryEntry{Account: e.Account.Name, Type: e.Account.AType.String(), Balance: e.Balance})
+	}
+
+	return entries, nil
+}
+
+// apiTransactionBalanceEntry represents one line of transactions balance report (balance is given in report currency)
+type apiTransactionBalanceEntry struct {
+	*apiTransactionEntry
+	Balance float64 `json:"balance"`
+}
+
+func apiReportTransactionBalance(fh *gsqlitehandler.SqliteDB, r *apiRequest) (interface{}, error) {
+	desc := r.String(OptDescription)
+	cur, df, dt, err := apiReportCriteria(r)
+	if err != nil {
+		return nil, err
+	}
+	a, c, m, err := apiTransactionFilters(fh, r)
+	if err != nil {
+		return nil, err
+	}
+	var tree *CategoryTree
+	if tree, err = CategoryTreeGet(fh); err != nil {
+		return nil, apiReportErr(err)
+	}
+
+	var getNextEntry func() *TransactionBalanceReportEntry
+	if getNextEntry, err = ReportTransactionBalance(fh, cur, df, dt, a, c, m, desc); err != nil {
+		return nil, apiReportErr(err)
+	}
+	entries := []*apiTransactionBalanceEntry{}
+	for e := getNextEntry(); e != nil; e = getNextEntry() {
+		entries = append(entries, &apiTransactionBalanceEntry{apiTransactionEntry: apiTransactionEntryFor(tree, e.Transaction), Balance: e.Balance})
+	}
+
+	return entries, nil
+}
+
+// apiCategoryBalanceEntry represents one line of categories balance report
+type apiCategoryBalanceEntry struct {
+	Category     string  `json:"category"`
+	MainCategory string  `json:"main_category"`
+	Type         string  `json:"type"`
+	Balance      float64 `json:"balance"`
+}
+
+func apiReportCategoryBalance(fh *gsqlitehandler.SqliteDB, r *apiRequest) (interface{}, error) {
+	depth := r.Int(OptDepth)
+	cur, df, dt, err := apiReportCriteria(r)
+	if err != nil {
+		return nil, err
+	}
+	a, c, m, err := apiTransactionFilters(fh, r)
+	if err != nil {
+		return nil, err
+	}
+	var tree *CategoryTree
+	if tree, err = CategoryTreeGet(fh); err != nil {
+		return nil, apiReportErr(err)
+	}
+
+	var getNextEntry func() *CategoryBalanceReportEntry
+	if getNextEntry, err = ReportCategoryBalance(fh, cur, df, dt, a, c, m, depth); err != nil {
+		return nil, apiReportErr(err)
+	}
+	entries := []*apiCategoryBalanceEntry{}
+	for e := getNextEntry(); e != nil; e = getNextEntry() {
+		entries = append(entries, &apiCategoryBalanceEntry{Category: tree.FullPath(e.Category), MainCategory: e.Category.Main.Name, Type: e.Category.Main.MType.Name, Balance: e.Balance})
+	}
+
+	return entries, nil
+}
+
+// apiMainCategoryBalanceEntry represents one line of main categories balance report
+type apiMainCategoryBalanceEntry struct {
+	MainCategory string  `json:"main_category"`
+	Type         string  `json:"type"`
+	Balance      float64 `json:"balance"`
+}
+
+func apiReportMainCategoryBalance(fh *gsqlitehandler.SqliteDB, r *apiRequest) (interface{}, error) {
+	cur, df, dt, err := apiReportCriteria(r)
+	if err != nil {
+		return nil, err
+	}
+	a, _, m, err := apiTransactionFilters(fh, r)
+	if err != nil {
+		return nil, err
+	}
+
+	var getNextEntry func() *MainCategoryBalanceReportEntry
+	if getNextEntry, err = ReportMainCategoryBalance(fh, cur, df, dt, a, m); err != nil {
+		return nil, apiReportErr(err)
+	}
+	entries := []*apiMainCategoryBalanceEntry{}
+	for e := getNextEntry(); e != nil; e = getNextEntry() {
+		entries = append(entries, &apiMainCategoryBalanceEntry{MainCategory: e.MainCategory.Name, Type: e.MainCategory.MType.Name, Balance: e.Balance})
+	}
+
+	return entries, nil
+}
+
+// apiPeriodValueEntry represents one line of reports with values for periods
+type apiPeriodValueEntry struct {
+	Period string  `json:"period"`
+	Value  float64 `json:"value"`
+}
+
+// apiPeriodValueEntries returns all entries of balance in time report
+func apiPeriodValueEntries(getNextEntry func() *BalanceTimeReportEntry) []*apiPeriodValueEntry {
+	entries := []*apiPeriodValueEntry{}
+	for e := getNextEntry(); e != nil; e = getNextEntry() {
+		entries = append(entries, &apiPeriodValueEntry{Period: e.Period.String(), Value: e.Value})
+	}
+
+	return entries
+}
+
+// apiReportCategoryBalanceTime returns balance of category in periods (of granularity d unless given in request)
+func apiReportCategoryBalanceTime(d Granularity) apiReportFunc {
+	return func(fh *gsqlitehandler.SqliteDB, r *apiRequest) (interface{}, error) {
+		cs, g := r.String(ObjCategory), apiReportGranularity(r, d)
+		cur, df, dt, err := apiReportCriteria(r)
+		if err != nil {
+			return nil, err
+		}
+		if cs == NotSetStringValue {
+			return nil, apiBadRequest(errMissingCategoryFlag)
+		}
+		var c *Category
+		if c, err = CategoryForName(fh, cs); err != nil {
+			return nil, apiErr(http.StatusBadRequest, err)
+		}
+		if depth := r.Int(OptDepth); depth != NotSetIntValue {
+			var t *CategoryTree
+			if t, err = CategoryTreeGet(fh); err != nil {
+				return nil, apiReportErr(err)
+			}
+			c = t.Ancestor(c, depth)
+		}
+
+		var getNextEntry func() *BalanceTimeReportEntry
+		if getNextEntry, err = ReportCategoriesBalanceTime(fh, cur, c, g, df, dt); err != nil {
+			return nil, apiReportErr(err)
+		}
+
+		return apiPeriodValueEntries(getNextEntry), nil
+	}
+}
+
+// apiReportMainCategoryBalanceTime returns balance of main category in periods (of granularity d unless given in request)
+func apiReportMainCategoryBalanceTime(d Granularity) apiReportFunc {
+	return func(fh *gsqlitehandler.SqliteDB, r *apiRequest) (interface{}, error) {
+		ms, g := r.String(ObjMainCategory), apiReportGranularity(r, d)
+		cur, df, dt, err := apiReportCriteria(r)
+		if err != nil {
+			return nil, err
+		}
+		if ms == NotSetStringValue {
+			return nil, apiBadRequest(errMissingMainCategoryFlag)
+		}
+		var m *MainCategory
+		if m, err = MainCategoryForName(fh, ms); err != nil {
+			return nil, apiErr(http.StatusBadRequest, err)
+		}
+
+		var getNextEntry func() *BalanceTimeReportEntry
+		if getNextEntry, err = ReportMainCategoriesBalanceTime(fh, cur, m, g, df, dt); err != nil {
+			return nil, apiReportErr(err)
+		}
+
+		return apiPeriodValueEntries(getNextEntry), nil
+	}
+}
+
+// apiBudgetCategoryEntry represents one line of budget report for categories
+type apiBudgetCategoryEntry struct {
+	Category     string  `json:"category"`
+	MainCategory string  `json:"main_category"`
+	Limit        float64 `json:"limit"`
+	Actual       float64 `json:"actual"`
+	Difference   float64 `json:"difference"`
+}
+
+func apiReportBudgetCategories(fh *gsqlitehandler.SqliteDB, r *apiRequest) (interface{}, error) {
+	cur, depth := r.String(OptCurrency), r.Int(OptDepth)
+	p, err := apiReportPeriod(r)
+	if err != nil {
+		return nil, err
+	}
+	if cur == NotSetStringValue {
+		return nil, apiBadRequest(errMissingCurrencyFlag)
+	}
+	var tree *CategoryTree
+	if tree, err = CategoryTreeGet(fh); err != nil {
+		return nil, apiReportErr(err)
+	}
+
+	var getNextEntry func() *BudgetCategoriesReportEntry
+	if getNextEntry, err = ReportBudgetCategories(fh, p, cur, depth); err != nil {
+		return nil, apiReportErr(err)
+	}
+	entries := []*apiBudgetCategoryEntry{}
+	for e := getNextEntry(); e != nil; e = getNextEntry() {
+		entries = append(entries, &apiBudgetCategoryEntry{Category: tree.FullPath(e.Category), MainCategory: e.Category.Main.Name, Limit: e.Limit, Actual: e.Actual, Difference: e.Difference})
+	}
+
+	return entries, nil
+}
+
+// apiBudgetMainCategoryEntry represents one line of budget report for main categories
+type apiBudgetMainCategoryEntry struct {
+	MainCategory string  `json:"main_category"`
+	Limit        float64 `json:"limit"`
+	Actual       float64 `json:"actual"`
+	Difference   float64 `json:"difference"`
+}
+
+func apiReportBudgetMainCategories(fh *gsqlitehandler.SqliteDB, r *apiRequest) (interface{}, error) {
+	cur := r.String(OptCurrency)
+	p, err := apiReportPeriod(r)
+	if err != nil {
+		return nil, err
+	}
+	if cur == NotSetStringValue {
+		return nil, apiBadRequest(errMissingCurrencyFlag)
+	}
+
+	var getNextEntry func() *BudgetMainCategoryReportEntry
+	if getNextEntry, err = ReportBudgetMainCategories(fh, p, cur); err != nil {
+		return nil, apiReportErr(err)
+	}
+	entries := []*apiBudgetMainCategoryEntry{}
+	for e := getNextEntry(); e != nil; e = getNextEntry() {
+		entries = append(entries, &apiBudgetMainCategoryEntry{MainCategory: e.MainCategory.Name, Limit: e.Limit, Actual: e.Actual, Difference: e.Difference})
+	}
+
+	return entries, nil
+}
+
+func apiReportNetValue(fh *gsqlitehandler.SqliteDB, r *apiRequest) (interface{}, error) {
+	g := apiReportGranularity(r, GranularityMonth)
+	cur, df, dt, err := apiReportCriteria(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var getNextEntry func() *NetValueReportEntry
+	if getNextEntry, err = ReportNetValue(fh, cur, g, df, dt); err != nil {
+		return nil, apiReportErr(err)
+	}
+	entries := []*apiPeriodValueEntry{}
+	for e := getNextEntry(); e != nil; e = getNextEntry() {
+		entries = append(entries, &apiPeriodValueEntry{Period: e.Period.String(), Value: e.Value})
+	}
+
+	return entries, nil
+}
+
+// apiIncomeVsCostEntry represents one line of income vs cost report
+type apiIncomeVsCostEntry struct {
+	Period     string  `json:"period"`
+	Income     float64 `json:"income"`
+	Cost       float64 `json:"cost"`
+	Difference float64 `json:"difference"`
+}
+
+// apiReportIncomeVsCost returns income vs cost report in periods (of granularity d unless given in request)
+func apiReportIncomeVsCost(d Granularity) apiReportFunc {
+	return func(fh *gsqlitehandler.SqliteDB, r *apiRequest) (interface{}, error) {
+		g := apiReportGranularity(r, d)
+		cur, df, dt, err := apiReportCriteria(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var getNextEntry func() *IncomeVsCostReportEntry
+		if getNextEntry, err = ReportIncomeVsCost(fh, cur, g, df, dt); err != nil {
+			return nil, apiReportErr(err)
+		}
+		entries := []*apiIncomeVsCostEntry{}
+		for e := getNextEntry(); e != nil; e = getNextEntry() {
+			entries = append(entries, &apiIncomeVsCostEntry{Period: e.Period.String(), Income: e.Income, Cost: e.Cost, Difference: e.Income + e.Cost})
+		}
+
+		return entries, nil
+	}
+}
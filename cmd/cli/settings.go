@@ -6,20 +6,32 @@ package cli
 
 // Settings
 const (
-	FSSeparator   = "  "
-	NullDataValue = "-"
+	FSSeparator      = "  "
+	NullDataValue    = "-"
+	backupTimeFormat = "2006-01-02 15:04:05"
 )
 
 // Headings for displaying data and reports
 const (
-	HCId   = "ID"
-	HCName = "CATEGORY"
+	HCId     = "ID"
+	HCName   = "CATEGORY"
+	HCParent = "PARENT"
+	HCSplit  = "SPLIT CATEGORY"
 
 	HMCId     = "ID"
 	HMCType   = "TYPE"
 	HMCName   = "MAINCAT"
 	HMCStatus = "STATUS"
 
+	HMCTId         = "ID"
+	HMCTName       = "TYPE"
+	HMCTFactor     = "FACTOR"
+	HMCTIncomeCost = "INCOME/COST"
+	HMCTBudget     = "BUDGET"
+	HMCTNetValue   = "NET VALUE"
+	HMCTStatus     = "STATUS"
+	HMCTReports    = "REPORTS"
+
 	HCurF    = "CUR_FR"
 	HCurT    = "CUR_TO"
 	HCurRate = "EX.RATE"
@@ -31,11 +43,31 @@ const (
 	HACurrency    = "CUR"
 	HAType        = "TYPE"
 	HAStatus      = "STATUS"
+	HAAccountTo   = "ACCOUNT TO"
 
 	HTId          = "ID"
 	HTDate        = "DATE"
 	HTValue       = "VALUE"
 	HTDescription = "DESCRIPTION"
+	HTAttachments = "ATT"
+	HTBalance     = "BALANCE"
+	HTTotal       = "TOTAL"
+
+	HRDateFrom    = "FROM"
+	HRDateTo      = "UNTIL"
+	HRGranularity = "PERIOD"
+	HREvery       = "EVERY"
+	HRRepeat      = "REPEAT"
+
+	HFNegative = "NEGATIVE"
+
+	HGName    = "GOAL"
+	HGTarget  = "TARGET"
+	HGCurrent = "CURRENT"
+	HGPercent = "DONE %"
+	HGMonthly = "MONTHLY"
+	HGOnTrack = "ON TRACK"
+	HGSavedIn = "SAVED IN"
 
 	HBPeriod     = "PERIOD"
 	HBLimit      = "LIMIT"
@@ -47,23 +79,69 @@ const (
 	HDifference = "DIFFERENCE"
 
 	HNV = "NET VALUE"
+
+	HBkName = "BACKUP"
+	HBkTime = "TIME"
+	HBkSize = "SIZE"
+
+	HAtId       = "ID"
+	HAtObject   = "OBJECT"
+	HAtObjectId = "OBJECT ID"
+	HAtName     = "NAME"
+	HAtType     = "TYPE"
+	HAtSize     = "SIZE"
+	HAtAdded    = "ADDED"
+	HAtHash     = "SHA-256"
 )
 
 // Errors
 const (
-	errMissingFileFlag          = "missing information about data file"
-	errMissingIDFlag            = "missing ID"
-	errMissingCategoryFlag      = "missing category name"
-	errMissingCategorySplitFlag = "missing category split name"
-	errMissingMainCategoryFlag  = "missing main category name"
-	errMissingCurrencyFlag      = "missing currency (from) name"
-	errMissingCurrencyToFlag    = "missing currency_to name"
-	errMissingExchangeRateFlag  = "missing exchange rate"
-	errMissingAccountFlag       = "missing account name"
-	errIncorrectAccountType     = "incorrect account type"
-	errMissingDescriptionFlag   = "missing description"
-	errMissingValueFlag         = "missing value"
-	errMissingPeriodFlag        = "missing period"
+	errMissingFileFlag             = "missing information about data file"
+	errMissingIDFlag               = "missing ID"
+	errMissingCategoryFlag         = "missing category name"
+	errMissingCategorySplitFlag    = "missing category split name"
+	errMissingMainCategoryFlag     = "missing main category name"
+	errMissingMainOrParentFlag     = "missing main category or parent category name"
+	errMissingCurrencyFlag         = "missing currency (from) name"
+	errMissingCurrencyToFlag       = "missing currency_to name"
+	errMissingExchangeRateFlag     = "missing exchange rate"
+	errMissingAccountFlag          = "missing account name"
+	errIncorrectAccountType        = "incorrect account type"
+	errMissingDescriptionFlag      = "missing description"
+	errMissingValueFlag            = "missing value"
+	errMissingPeriodFlag           = "missing period"
+	errMissingUntilFlag            = "missing last day"
+	errMissingGoalFlag             = "missing goal name"
+	errMissingGoalLinkFlag         = "missing accounts or category which goal is saved in"
+	errMissingMainCategoryTypeFlag = "missing main category type name"
+	errIncorrectReportsFlag        = "incorrect reports (allowed: income-cost/ic, budget/b, net-value/nv, none)"
+	errIncorrectFactor             = "incorrect factor (allowed: 1, -1)"
+	errEmptyAnswer                 = "the value cannot be empty"
+	errInteractiveCancelled        = "cancelled by user"
+	errShellRunning                = "shell is already running"
+	errShellQuote                  = "missing closing quote"
+	errMissingListenFlag           = "missing address to listen on"
+	errMissingFormatFlag           = "missing format of file"
+	errMissingStatementFile        = "missing statement file name"
+	errIncorrectFormat             = "incorrect format of file"
+	errAPIPathUnknown              = "unknown path"
+	errAPIMethodNotAllowed         = "method not allowed"
+	errAPIIncorrectBody            = "request body must be JSON object"
+	errAPIContentType              = "request body must be sent as application/json"
+	errAPIHostNotAllowed           = "host not allowed"
+	errAPIOriginNotAllowed         = "origin not allowed"
+	errAPIIncorrectValue           = "incorrect value of %s"
+	errAPIIncorrectID              = "incorrect ID"
+	errIncorrectDuplicateAction    = "incorrect action (allowed: merge, dismiss)"
+	errMissingDuplicateIDs         = "missing IDs of two transactions"
+	errMissingDumpFile             = "missing dump file name"
+	errMissingBackupName           = "missing backup name"
+	errPassphraseNoTerminal        = "missing passphrase of encrypted data file (set FINANCOJ_PASSPHRASE, FINANCOJ_KEYFILE or KEY_FILE in config file)"
+	errPassphraseDiffers           = "passphrases differ"
+	errMissingAttachmentFile       = "missing name of file to attach"
+	errMissingAttachmentObject     = "missing transaction ID or account name"
+	errIncorrectAttachmentObject   = "give either transaction ID or account name"
+	errMissingAttachmentID         = "missing or incorrect attachment ID"
 )
 
 // Commands, objects and options
@@ -80,6 +158,20 @@ const (
 	CmdListAlias   = "L"
 	CmdReport      = "report"
 	CmdReportAlias = "R"
+	CmdTui         = "tui"
+	CmdShell       = "shell"
+	CmdServe       = "serve"
+	CmdImport      = "import"
+	CmdExport      = "export"
+	CmdDuplicates  = "duplicates"
+	CmdDump        = "dump"
+	CmdRestore     = "restore"
+	CmdBackup      = "backup"
+	CmdEncrypt     = "encrypt"
+	CmdDecrypt     = "decrypt"
+	CmdAttachment  = "attachment"
+	CmdExtract     = "extract"
+	CmdSettings    = "settings"
 
 	OptFile                  = "file"
 	OptFileAlias             = "f"
@@ -107,21 +199,43 @@ const (
 	OptDateAlias             = "d"
 	OptDateFrom              = "date-from"
 	OptDateTo                = "date-to"
+	OptRange                 = "range"
+	OptGranularity           = "granularity"
+	OptUntil                 = "until"
+	OptEvery                 = "every"
+	OptFiscalYearStart       = "fiscal-year-start"
 	OptPeriod                = "period"
 	OptPeriodAlias           = "e"
+	OptCategoryParent        = "parent"
+	OptCategoryParentAlias   = "u"
+	OptDepth                 = "depth"
+	OptFactor                = "factor"
+	OptReports               = "reports"
+	OptInteractive           = "interactive"
+	OptListen                = "listen"
+	OptChart                 = "chart"
+	OptFormat                = "format"
+	OptDays                  = "days"
+	OptSimilarity            = "similarity"
 
-	ObjAccount           = "account"
-	ObjAccountAlias      = "a"
-	ObjCategory          = "category"
-	ObjCategoryAlias     = "c"
-	ObjMainCategory      = "main_category"
-	ObjMainCategoryAlias = "m"
-	ObjExchangeRate      = "rate"
-	ObjExchangeRateAlias = "r"
-	ObjTransaction       = "transaction"
-	ObjTransactionAlias  = "t"
-	ObjBudget            = "budget"
-	ObjBudgetAlias       = "b"
+	ObjAccount               = "account"
+	ObjAccountAlias          = "a"
+	ObjCategory              = "category"
+	ObjCategoryAlias         = "c"
+	ObjMainCategory          = "main_category"
+	ObjMainCategoryAlias     = "m"
+	ObjMainCategoryType      = "main_category_type"
+	ObjMainCategoryTypeAlias = "o"
+	ObjExchangeRate          = "rate"
+	ObjExchangeRateAlias     = "r"
+	ObjTransaction           = "transaction"
+	ObjTransactionAlias      = "t"
+	ObjBudget                = "budget"
+	ObjBudgetAlias           = "b"
+	ObjRecurring             = "recurring"
+	ObjRecurringAlias        = "rt"
+	ObjGoal                  = "goal"
+	ObjGoalAlias             = "g"
 
 	ObjCompoundTransfer              = "transfer"
 	ObjCompoundTransferAlias         = "T"
@@ -158,4 +272,25 @@ const (
 	ObjReportIncomeVsCostMonthlyAlias        = "icm"
 	ObjReportIncomeVsCostYearly              = "income-cost-yearly"
 	ObjReportIncomeVsCostYearlyAlias         = "icy"
+	ObjReportForecast                        = "forecast"
+	ObjReportForecastAlias                   = "fc"
+	ObjReportGoals                           = "goals"
+	ObjReportGoalsAlias                      = "gl"
+)
+
+// Formats of imported and exported files
+const (
+	FormatOFX       = "ofx"
+	FormatQFX       = "qfx"
+	FormatQIF       = "qif"
+	FormatCAMT053   = "camt053"
+	FormatMT940     = "mt940"
+	FormatBeancount = "beancount"
+	FormatLedger    = "ledger"
+)
+
+// Actions on candidate duplicates
+const (
+	DuplicateActionMerge   = "merge"
+	DuplicateActionDismiss = "dismiss"
 )
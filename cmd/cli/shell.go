@@ -0,0 +1,402 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/peterh/liner"
+	"github.com/urfave/cli"
+	. "github.com/zbroju/financoj/lib"
+	"github.com/zbroju/gsqlitehandler"
+	"golang.org/x/term"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// File keeping history of lines entered in shell
+const shellHistoryFile = ".financoj_history"
+
+// Commands of shell itself (the other lines are run as fin commands)
+const (
+	shellBegin    = "begin"
+	shellCommit   = "commit"
+	shellRollback = "rollback"
+	shellExit     = "exit"
+	shellQuit     = "quit"
+)
+
+// shellSession keeps data file open for all the commands run inside shell
+type shellSession struct {
+	fh   *gsqlitehandler.SqliteDB
+	file string
+}
+
+// Running shell session (nil outside shell)
+var shell *shellSession
+
+// dataFileHandler returns handler of data file f, shared by all the commands run inside shell
+func dataFileHandler(f string) *gsqlitehandler.SqliteDB {
+	if shell != nil && f == shell.file {
+		return shell.fh
+	}
+	return GetDataFileHandler(f)
+}
+
+// openDataFile opens data file unless it has been already opened by shell.
+// The data file is backed up before the first change made by the command.
+func openDataFile(fh *gsqlitehandler.SqliteDB) error {
+	if shell == nil || fh != shell.fh {
+		if err := OpenDataFile(fh); err != nil {
+			return err
+		}
+	}
+	return BackupOnNextChange(fh)
+}
+
+// closeDataFile closes data file unless it is kept open by shell
+func closeDataFile(fh *gsqlitehandler.SqliteDB) {
+	if shell != nil && fh == shell.fh {
+		return
+	}
+	CloseDataFile(fh)
+}
+
+// CmdInteractiveShell runs interactive session accepting the same commands as fin with data file kept open.
+// Changes made by several commands can be grouped with begin and then saved with commit or cancelled with rollback.
+func CmdInteractiveShell(c *cli.Context) error {
+	var err error
+
+	// Get loggers
+	printUserMsg, printError := GetLoggers()
+
+	// Check obligatory flags
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+	if shell != nil {
+		return printError.Fail(errShellRunning)
+	}
+
+	// Open data file
+	fh := GetDataFileHandler(f)
+	if err = OpenDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer CloseDataFile(fh)
+	KeepDataFileUnlocked(fh)
+
+	shell = &shellSession{fh: fh, file: f}
+	defer func() { shell = nil }()
+
+	// Read and run commands
+	in := newShellInput(c.App)
+	defer in.close()
+	for {
+		prompt := AppName + "> "
+		if DataFileInTransaction(fh) {
+			prompt = AppName + "*> "
+		}
+		line, err := in.readLine(prompt)
+		if err == liner.ErrPromptAborted {
+			continue
+		}
+		if err != nil {
+			if in.terminal {
+				fmt.Println()
+			}
+			break
+		}
+
+		args, err := shellSplit(line)
+		if err != nil {
+			printError.Println(err)
+			continue
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		switch args[0] {
+		case shellExit, shellQuit:
+			return shellEnd(fh, printUserMsg, printError)
+		case shellBegin:
+			err = BeginDataFileTransaction(fh)
+		case shellCommit:
+			err = CommitDataFileTransaction(fh)
+		case shellRollback:
+			err = RollbackDataFileTransaction(fh)
+		default:
+			shellRun(c.App, args)
+		}
+		if err != nil {
+			printError.Println(err)
+		}
+	}
+
+	return shellEnd(fh, printUserMsg, printError)
+}
+
+// shellEnd cancels changes which have not been committed before leaving shell
+func shellEnd(fh *gsqlitehandler.SqliteDB, printUserMsg, printError *Logger) error {
+	if DataFileInTransaction(fh) {
+		if err := RollbackDataFileTransaction(fh); err != nil {
+			printError.Println(err)
+			return nil
+		}
+		printUserMsg.Printf("changes not committed have been rolled back\n")
+	}
+
+	return nil
+}
+
+// shellRun runs fin command with given arguments. Errors are printed by the command itself.
+// Commands accepting data file work on the file of shell unless another one is given.
+func shellRun(app *cli.App, args []string) {
+	if cmd := shellCommand(app, args); cmd != nil && len(cmd.Subcommands) == 0 && shellHasFlag(cmd, OptFile) && !shellFileGiven(args) {
+		args = append(args, "--"+OptFile, shell.file)
+	}
+
+	app.Run(append([]string{app.Name}, args...))
+}
+
+// shellHasFlag checks if command cmd accepts option opt
+func shellHasFlag(cmd *cli.Command, opt string) bool {
+	for _, fl := range cmd.Flags {
+		for _, n := range strings.Split(fl.GetName(), ",") {
+			if strings.TrimSpace(n) == opt {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// shellFileGiven checks if arguments contain data file option
+func shellFileGiven(args []string) bool {
+	for _, a := range args {
+		a = strings.SplitN(strings.TrimLeft(a, "-"), "=", 2)[0]
+		if a == OptFile || a == OptFileAlias {
+			return true
+		}
+	}
+
+	return false
+}
+
+// shellSplit splits line into arguments separated with spaces.
+// Arguments containing spaces can be put in single or double quotes.
+func shellSplit(line string) (args []string, err error) {
+	var arg bytes.Buffer
+	var quote rune
+	inArg := false
+
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			arg.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New(errShellQuote)
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+
+	return args, nil
+}
+
+// shellInput reads lines of shell with history and completion when standard input is a terminal
+type shellInput struct {
+	terminal bool
+	history  bytes.Buffer
+	app      *cli.App
+	reader   *bufio.Reader
+}
+
+// newShellInput returns input reading lines from standard input, with history loaded from the history file
+func newShellInput(app *cli.App) *shellInput {
+	in := &shellInput{terminal: term.IsTerminal(int(os.Stdin.Fd())), app: app}
+	if !in.terminal {
+		in.reader = bufio.NewReader(os.Stdin)
+		return in
+	}
+
+	if hf, err := os.Open(path.Join(os.Getenv("HOME"), shellHistoryFile)); err == nil {
+		in.history.ReadFrom(hf)
+		hf.Close()
+	}
+
+	return in
+}
+
+// readLine shows prompt and returns the line entered by the user.
+// The terminal is restored before returning, so that commands can use it (e.g. tui or prompting for values).
+func (in *shellInput) readLine(prompt string) (string, error) {
+	if !in.terminal {
+		line, err := in.reader.ReadString('\n')
+		if err == io.EOF && line != NotSetStringValue {
+			err = nil
+		}
+		return strings.TrimSpace(line), err
+	}
+
+	l := liner.NewLiner()
+	defer l.Close()
+	l.SetCtrlCAborts(true)
+	l.SetTabCompletionStyle(liner.TabPrints)
+	l.SetWordCompleter(in.complete)
+	l.ReadHistory(bytes.NewReader(in.history.Bytes()))
+
+	line, err := l.Prompt(prompt)
+	if err != nil {
+		return NotSetStringValue, err
+	}
+	if line = strings.TrimSpace(line); line != NotSetStringValue {
+		l.AppendHistory(line)
+		in.history.Reset()
+		l.WriteHistory(&in.history)
+	}
+
+	return line, nil
+}
+
+// close saves history of entered lines
+func (in *shellInput) close() {
+	if !in.terminal {
+		return
+	}
+	if hf, err := os.Create(path.Join(os.Getenv("HOME"), shellHistoryFile)); err == nil {
+		in.history.WriteTo(hf)
+		hf.Close()
+	}
+}
+
+// complete returns completion of the word under cursor: names of commands, objects, options
+// and, after options requiring them, names of accounts, categories etc.
+func (in *shellInput) complete(line string, pos int) (head string, completions []string, tail string) {
+	start := strings.LastIndexAny(line[:pos], " \t") + 1
+	head, word, tail := line[:start], line[start:pos], line[pos:]
+	args, err := shellSplit(head)
+	if err != nil {
+		return head, nil, tail
+	}
+
+	var names []string
+	cmd := shellCommand(in.app, args)
+
+	switch {
+	case len(args) == 0:
+		names = append(names, shellBegin, shellCommit, shellRollback, shellExit)
+		for _, cm := range in.app.Commands {
+			names = append(names, cm.Name)
+		}
+	case cmd != nil && len(cmd.Subcommands) > 0:
+		for _, sc := range cmd.Subcommands {
+			names = append(names, sc.Name)
+		}
+	case cmd != nil && strings.HasPrefix(word, "-"):
+		for _, fl := range cmd.Flags {
+			for _, n := range strings.Split(fl.GetName(), ",") {
+				if n = strings.TrimSpace(n); len(n) > 1 {
+					names = append(names, "--"+n)
+				}
+			}
+		}
+	case !strings.HasPrefix(args[len(args)-1], "-"):
+		return head, nil, tail
+	default:
+		values := shellFlagValues(strings.TrimLeft(args[len(args)-1], "-"))
+		if values == nil {
+			return head, nil, tail
+		}
+		for _, v := range fuzzyMatches(word, values()) {
+			if strings.ContainsAny(v, " \t") {
+				v = "'" + v + "'"
+			}
+			completions = append(completions, v)
+		}
+		return head, completions, tail
+	}
+
+	for _, n := range names {
+		if strings.HasPrefix(n, word) {
+			completions = append(completions, n)
+		}
+	}
+	sort.Strings(completions)
+
+	return head, completions, tail
+}
+
+// shellCommand returns the most nested command (or subcommand) given in the first arguments
+func shellCommand(app *cli.App, args []string) (cmd *cli.Command) {
+	cmds := app.Commands
+	for _, a := range args {
+		found := shellFindCommand(cmds, a)
+		if found == nil {
+			break
+		}
+		cmd, cmds = found, found.Subcommands
+	}
+
+	return cmd
+}
+
+// shellFindCommand returns command with name or alias n
+func shellFindCommand(cmds []cli.Command, n string) *cli.Command {
+	for i := range cmds {
+		if cmds[i].HasName(n) {
+			return &cmds[i]
+		}
+	}
+
+	return nil
+}
+
+// shellFlagValues returns completion of the values of option opt (long or short name)
+func shellFlagValues(opt string) func() []string {
+	fh := shell.fh
+	switch opt {
+	case ObjAccount, ObjAccountAlias, OptAccountTo:
+		return accountCompletion(fh)
+	case ObjCategory, ObjCategoryAlias, OptCategorySplit, OptCategorySplitAlias, OptCategoryParent, OptCategoryParentAlias:
+		return categoryCompletion(fh)
+	case ObjMainCategory, ObjMainCategoryAlias:
+		return mainCategoryCompletion(fh)
+	case OptMainCategoryType, OptMainCategoryTypeAlias:
+		return mainCategoryTypeCompletion(fh)
+	case OptCurrency, OptCurrencyAlias, OptCurrencyTo, OptCurrencyToAlias:
+		return currencyCompletion(fh)
+	case OptAccountType, OptAccountTypeAlias:
+		return accountTypeCompletion
+	case OptReports:
+		return reportsCompletion
+	}
+
+	return nil
+}
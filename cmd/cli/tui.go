@@ -0,0 +1,756 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jroimartin/gocui"
+	"github.com/urfave/cli"
+	. "github.com/zbroju/financoj/lib"
+	"github.com/zbroju/gsqlitehandler"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Names of the views of terminal user interface
+const (
+	tuiViewAccounts     = "accounts"
+	tuiViewHeader       = "header"
+	tuiViewTransactions = "transactions"
+	tuiViewReport       = "report"
+	tuiViewStatus       = "status"
+	tuiViewFilter       = "filter"
+	tuiViewForm         = "form"
+)
+
+// Reports which can be shown in terminal user interface
+const (
+	tuiReportAccountBalance = iota
+	tuiReportBudgetCategories
+	tuiReportsNo
+)
+
+// Help lines shown in the status bar
+const (
+	tuiHelpMain   = "Tab: switch pane  Up/Down: move  a: add  e/Enter: edit  d: delete  /: filter  r: report  q: quit"
+	tuiHelpForm   = "Tab/Down: next field  Up: previous field  Enter: save  Esc: cancel"
+	tuiHelpFilter = "Enter: apply filter on description  Esc: cancel"
+)
+
+// tuiFormFields are labels of transaction form fields in order of appearance
+var tuiFormFields = []string{HTDate, HAName, HCName, HTValue, HTDescription}
+
+// tui keeps the state of terminal user interface
+type tui struct {
+	fh       *gsqlitehandler.SqliteDB
+	currency string
+	tree     *CategoryTree
+
+	accounts     []*Account // the first element is nil and stands for all accounts
+	account      int
+	transactions []*Transaction
+	transaction  int
+	filter       string
+	report       int
+
+	filtering  bool
+	deleting   bool
+	edited     *Transaction // transaction shown in the form, nil if the form is closed
+	formValues []string
+	message    string
+}
+
+// CmdTerminalUI runs full screen terminal user interface for browsing and editing transactions
+func CmdTerminalUI(c *cli.Context) error {
+	var err error
+
+	// Get loggers
+	_, printError := GetLoggers()
+
+	// Check obligatory flags
+	f := c.String(OptFile)
+	if f == NotSetStringValue {
+		return printError.Fail(errMissingFileFlag)
+	}
+	currency := c.String(OptCurrency)
+	if currency == NotSetStringValue {
+		return printError.Fail(errMissingCurrencyFlag)
+	}
+
+	// Open data file
+	fh := dataFileHandler(f)
+	if err = openDataFile(fh); err != nil {
+		return printError.Fail(err)
+	}
+	defer closeDataFile(fh)
+	KeepDataFileUnlocked(fh)
+
+	// Load data
+	t := &tui{fh: fh, currency: currency}
+	if err = t.loadAccounts(); err != nil {
+		return printError.Fail(err)
+	}
+	if err = t.loadTransactions(); err != nil {
+		return printError.Fail(err)
+	}
+
+	// Run the interface
+	var g *gocui.Gui
+	if g, err = gocui.NewGui(gocui.OutputNormal); err != nil {
+		return printError.Fail(err)
+	}
+	g.InputEsc = true
+	g.Highlight = true
+	g.SelFgColor = gocui.ColorGreen
+	g.SetManagerFunc(t.layout)
+	if err = t.keybindings(g); err != nil {
+		g.Close()
+		return printError.Fail(err)
+	}
+	err = g.MainLoop()
+	g.Close()
+	if err != nil && err != gocui.ErrQuit {
+		return printError.Fail(err)
+	}
+
+	return nil
+}
+
+// loadAccounts reads all open accounts from data file
+func (t *tui) loadAccounts() error {
+	getNextAccount, err := AccountList(t.fh, NotSetStringValue, NotSetStringValue, NotSetStringValue, NotSetStringValue, ATUnset, ISOpen)
+	if err != nil {
+		return err
+	}
+	t.accounts = []*Account{nil}
+	for a := getNextAccount(); a != nil; a = getNextAccount() {
+		t.accounts = append(t.accounts, a)
+	}
+	if t.account >= len(t.accounts) {
+		t.account = len(t.accounts) - 1
+	}
+
+	return nil
+}
+
+// loadTransactions reads transactions of selected account matching the filter.
+// The latest transaction becomes the selected one.
+func (t *tui) loadTransactions() error {
+	var err error
+
+	if t.tree, err = CategoryTreeGet(t.fh); err != nil {
+		return err
+	}
+	getNextTransaction, err := TransactionList(t.fh, time.Time{}, time.Time{}, t.accounts[t.account], t.filter, nil, nil)
+	if err != nil {
+		return err
+	}
+	t.transactions = nil
+	for tr := getNextTransaction(); tr != nil; tr = getNextTransaction() {
+		t.transactions = append(t.transactions, tr)
+	}
+	t.transaction = len(t.transactions) - 1
+	if t.transaction < 0 {
+		t.transaction = 0
+	}
+
+	return nil
+}
+
+// layout creates (or resizes) all the views
+func (t *tui) layout(g *gocui.Gui) error {
+	maxX, maxY := g.Size()
+	accW := maxX / 5
+	if accW < 20 {
+		accW = 20
+	}
+	reportY := maxY - maxY/3 - 1
+
+	if v, err := g.SetView(tuiViewAccounts, 0, 0, accW-1, maxY-2); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = "Accounts"
+		v.Highlight = true
+		v.SelBgColor = gocui.ColorGreen
+		v.SelFgColor = gocui.ColorBlack
+		t.renderAccounts(v)
+	}
+	if _, err := g.SetView(tuiViewHeader, accW, 0, maxX-1, 2); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+	}
+	if v, err := g.SetView(tuiViewTransactions, accW, 3, maxX-1, reportY-1); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Highlight = true
+		v.SelBgColor = gocui.ColorGreen
+		v.SelFgColor = gocui.ColorBlack
+		if err = t.renderTransactions(g); err != nil {
+			return err
+		}
+		if _, err = g.SetCurrentView(tuiViewTransactions); err != nil {
+			return err
+		}
+	}
+	if v, err := g.SetView(tuiViewReport, accW, reportY, maxX-1, maxY-2); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		t.renderReport(v)
+	}
+	if v, err := g.SetView(tuiViewStatus, -1, maxY-2, maxX, maxY); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Frame = false
+		t.renderStatus(g)
+	}
+
+	// Pop-up windows
+	g.Cursor = t.filtering || t.edited != nil
+	if t.filtering {
+		if v, err := g.SetView(tuiViewFilter, maxX/4, maxY/2-1, maxX-maxX/4, maxY/2+1); err != nil {
+			if err != gocui.ErrUnknownView {
+				return err
+			}
+			v.Title = "Filter"
+			v.Editable = true
+			fmt.Fprint(v, t.filter)
+			v.SetCursor(utf8.RuneCountInString(t.filter), 0)
+			if _, err = g.SetCurrentView(tuiViewFilter); err != nil {
+				return err
+			}
+		}
+	}
+	if t.edited != nil {
+		labelW := 0
+		for _, l := range tuiFormFields {
+			labelW = MaxLen(l, labelW)
+		}
+		x0, y0 := maxX/2-35, maxY/2-len(tuiFormFields)/2-1
+		if x0 < 0 {
+			x0 = 0
+		}
+		x1, y1 := x0+70, y0+len(tuiFormFields)+1
+		if x1 > maxX-1 {
+			x1 = maxX - 1
+		}
+		if v, err := g.SetView(tuiViewForm, x0, y0, x1, y1); err != nil {
+			if err != gocui.ErrUnknownView {
+				return err
+			}
+			v.Title = "New transaction"
+			if t.edited.Id != int64(NotSetIntValue) {
+				v.Title = fmt.Sprintf("Transaction %d", t.edited.Id)
+			}
+			for _, l := range tuiFormFields {
+				fmt.Fprintf(v, "%s\n", l)
+			}
+		}
+		for i := range tuiFormFields {
+			fx, fy := x0+labelW+2, y0+1+i
+			if v, err := g.SetView(tuiFormFieldView(i), fx-1, fy-1, x1, fy+1); err != nil {
+				if err != gocui.ErrUnknownView {
+					return err
+				}
+				v.Frame = false
+				v.Editable = true
+				fmt.Fprint(v, t.formValues[i])
+				v.SetCursor(utf8.RuneCountInString(t.formValues[i]), 0)
+				if i == 0 {
+					if _, err = g.SetCurrentView(v.Name()); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// tuiFormFieldView returns the name of view for i-th field of the form
+func tuiFormFieldView(i int) string {
+	return fmt.Sprintf("%s%d", tuiViewForm, i)
+}
+
+// keybindings sets all the key bindings of the interface
+func (t *tui) keybindings(g *gocui.Gui) error {
+	type binding struct {
+		views   []string
+		keys    []interface{}
+		handler func(*gocui.Gui, *gocui.View) error
+	}
+	lists := []string{tuiViewAccounts, tuiViewTransactions}
+	var fields []string
+	for i := range tuiFormFields {
+		fields = append(fields, tuiFormFieldView(i))
+	}
+
+	bindings := []binding{
+		{[]string{NotSetStringValue}, []interface{}{gocui.KeyCtrlC}, t.quit},
+		{lists, []interface{}{'q'}, t.quit},
+		{lists, []interface{}{gocui.KeyTab}, t.switchPane},
+		{lists, []interface{}{gocui.KeyArrowDown, 'j'}, t.cursorMover(1)},
+		{lists, []interface{}{gocui.KeyArrowUp, 'k'}, t.cursorMover(-1)},
+		{lists, []interface{}{gocui.KeyPgdn}, t.pageMover(1)},
+		{lists, []interface{}{gocui.KeyPgup}, t.pageMover(-1)},
+		{lists, []interface{}{'/'}, t.openFilter},
+		{lists, []interface{}{'r'}, t.switchReport},
+		{lists, []interface{}{'a'}, t.openNewForm},
+		{[]string{tuiViewTransactions}, []interface{}{'e', gocui.KeyEnter}, t.openEditForm},
+		{[]string{tuiViewTransactions}, []interface{}{'d'}, t.askDelete},
+		{[]string{tuiViewTransactions}, []interface{}{'y'}, t.confirmDelete},
+		{[]string{tuiViewTransactions}, []interface{}{'n', gocui.KeyEsc}, t.cancelDelete},
+		{[]string{tuiViewFilter}, []interface{}{gocui.KeyEnter}, t.applyFilter},
+		{[]string{tuiViewFilter}, []interface{}{gocui.KeyEsc}, t.closeFilter},
+		{fields, []interface{}{gocui.KeyTab, gocui.KeyArrowDown}, t.fieldMover(1)},
+		{fields, []interface{}{gocui.KeyArrowUp}, t.fieldMover(-1)},
+		{fields, []interface{}{gocui.KeyEnter}, t.saveForm},
+		{fields, []interface{}{gocui.KeyEsc}, t.closeForm},
+	}
+	for _, b := range bindings {
+		for _, v := range b.views {
+			for _, k := range b.keys {
+				if err := g.SetKeybinding(v, k, gocui.ModNone, b.handler); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// quit leaves the interface
+func (t *tui) quit(g *gocui.Gui, v *gocui.View) error {
+	return gocui.ErrQuit
+}
+
+// switchPane moves focus between accounts and transactions
+func (t *tui) switchPane(g *gocui.Gui, v *gocui.View) error {
+	next := tuiViewAccounts
+	if v.Name() == tuiViewAccounts {
+		next = tuiViewTransactions
+	}
+	_, err := g.SetCurrentView(next)
+
+	return err
+}
+
+// cursorMover returns handler moving cursor in a list by d lines
+func (t *tui) cursorMover(d int) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		return t.moveSelection(g, v, d)
+	}
+}
+
+// pageMover returns handler moving cursor in a list by d pages
+func (t *tui) pageMover(d int) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		_, h := v.Size()
+		return t.moveSelection(g, v, d*h)
+	}
+}
+
+// moveSelection changes selected account or transaction.
+// Changing account reloads the transactions.
+func (t *tui) moveSelection(g *gocui.Gui, v *gocui.View, d int) error {
+	t.message = NotSetStringValue
+	t.deleting = false
+	switch v.Name() {
+	case tuiViewAccounts:
+		i := tuiLimit(t.account+d, len(t.accounts))
+		if i == t.account {
+			return nil
+		}
+		t.account = i
+		tuiSelectLine(v, i)
+		if err := t.loadTransactions(); err != nil {
+			t.message = err.Error()
+		}
+		if err := t.renderTransactions(g); err != nil {
+			return err
+		}
+	case tuiViewTransactions:
+		t.transaction = tuiLimit(t.transaction+d, len(t.transactions))
+		tuiSelectLine(v, t.transaction)
+	}
+	t.renderStatus(g)
+
+	return nil
+}
+
+// tuiLimit returns i limited to the range of indexes of n elements
+func tuiLimit(i, n int) int {
+	if i > n-1 {
+		i = n - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+
+	return i
+}
+
+// tuiSelectLine moves cursor to i-th line of the view scrolling it if necessary
+func tuiSelectLine(v *gocui.View, i int) {
+	_, h := v.Size()
+	_, oy := v.Origin()
+	if i < oy {
+		oy = i
+	} else if h > 0 && i >= oy+h {
+		oy = i - h + 1
+	}
+	v.SetOrigin(0, oy)
+	v.SetCursor(0, i-oy)
+}
+
+// switchReport shows the next report in the report pane
+func (t *tui) switchReport(g *gocui.Gui, v *gocui.View) error {
+	t.report = (t.report + 1) % tuiReportsNo
+	return t.refreshReport(g)
+}
+
+// openFilter shows pop-up window for filtering transactions by description
+func (t *tui) openFilter(g *gocui.Gui, v *gocui.View) error {
+	t.filtering = true
+	t.message = tuiHelpFilter
+	t.renderStatus(g)
+
+	// Create the window at once so that following keys already go to it
+	return t.layout(g)
+}
+
+// applyFilter reloads transactions with the description typed in filter window
+func (t *tui) applyFilter(g *gocui.Gui, v *gocui.View) error {
+	t.filter = strings.TrimSpace(v.Buffer())
+	if err := t.loadTransactions(); err != nil {
+		t.message = err.Error()
+	}
+	if err := t.renderTransactions(g); err != nil {
+		return err
+	}
+
+	return t.closeFilter(g, v)
+}
+
+// closeFilter hides filter window
+func (t *tui) closeFilter(g *gocui.Gui, v *gocui.View) error {
+	t.filtering = false
+	if t.message == tuiHelpFilter {
+		t.message = NotSetStringValue
+	}
+	if err := g.DeleteView(tuiViewFilter); err != nil {
+		return err
+	}
+	if _, err := g.SetCurrentView(tuiViewTransactions); err != nil {
+		return err
+	}
+	t.renderStatus(g)
+
+	return nil
+}
+
+// openNewForm shows form for a new transaction on selected account
+func (t *tui) openNewForm(g *gocui.Gui, v *gocui.View) error {
+	tr := TransactionNew()
+	accountName := NotSetStringValue
+	if a := t.accounts[t.account]; a != nil {
+		tr.Account = a
+		accountName = a.Name
+	}
+	return t.openForm(g, tr, []string{tr.Date.Format(DateFormat), accountName, NotSetStringValue, NotSetStringValue, NotSetStringValue})
+}
+
+// openEditForm shows form for selected transaction
+func (t *tui) openEditForm(g *gocui.Gui, v *gocui.View) error {
+	if len(t.transactions) == 0 {
+		return nil
+	}
+	tr := t.transactions[t.transaction]
+	return t.openForm(g, tr, []string{tr.Date.Format(DateFormat), tr.Account.Name, t.tree.FullPath(tr.Category), strconv.FormatFloat(tr.Value, 'f', 2, 64), tr.Description})
+}
+
+// openForm shows form for transaction tr filled in with given values
+func (t *tui) openForm(g *gocui.Gui, tr *Transaction, values []string) error {
+	t.edited = tr
+	t.formValues = values
+	t.deleting = false
+	t.message = tuiHelpForm
+	t.renderStatus(g)
+
+	return t.layout(g)
+}
+
+// fieldMover returns handler moving focus in the form by d fields
+func (t *tui) fieldMover(d int) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		n := len(tuiFormFields)
+		for i := range tuiFormFields {
+			if v.Name() == tuiFormFieldView(i) {
+				_, err := g.SetCurrentView(tuiFormFieldView((i + d + n) % n))
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// saveForm validates the form and adds or updates the transaction
+func (t *tui) saveForm(g *gocui.Gui, v *gocui.View) error {
+	var err error
+
+	values := make([]string, len(tuiFormFields))
+	for i := range tuiFormFields {
+		var fv *gocui.View
+		if fv, err = g.View(tuiFormFieldView(i)); err != nil {
+			return err
+		}
+		values[i] = strings.TrimSpace(fv.Buffer())
+	}
+
+	// Build transaction the same way the command line does
+	tr := TransactionNew()
+	tr.Id = t.edited.Id
+	if tr.Date, err = DateParse(t.fh, values[0]); err == nil {
+		if tr.Account, err = AccountForName(t.fh, values[1]); err == nil {
+			if tr.Category, err = CategoryForName(t.fh, values[2]); err == nil {
+				if tr.Value, err = strconv.ParseFloat(values[3], 64); err != nil || tr.Value == NotSetFloatValue {
+					err = errors.New(errMissingValueFlag)
+				} else if tr.Description = values[4]; tr.Description == NotSetStringValue {
+					err = errors.New(errMissingDescriptionFlag)
+				}
+			}
+		}
+	}
+	if err == nil {
+		err = BackupOnNextChange(t.fh)
+	}
+	if err == nil {
+		if tr.Id == int64(NotSetIntValue) {
+			err = TransactionAdd(t.fh, tr)
+		} else {
+			err = TransactionEdit(t.fh, tr)
+		}
+	}
+	if err != nil {
+		t.message = err.Error()
+		t.renderStatus(g)
+		return nil
+	}
+
+	// Refresh everything
+	if err = t.closeForm(g, v); err != nil {
+		return err
+	}
+	if err = t.refreshAll(g); err != nil {
+		return err
+	}
+	if tr.Id == int64(NotSetIntValue) {
+		t.message = "added new transaction"
+	} else {
+		t.message = fmt.Sprintf("changed details of transaction with id = %d", tr.Id)
+	}
+	t.renderStatus(g)
+
+	return nil
+}
+
+// closeForm hides the transaction form
+func (t *tui) closeForm(g *gocui.Gui, v *gocui.View) error {
+	t.edited = nil
+	t.message = NotSetStringValue
+	for i := range tuiFormFields {
+		if err := g.DeleteView(tuiFormFieldView(i)); err != nil {
+			return err
+		}
+	}
+	if err := g.DeleteView(tuiViewForm); err != nil {
+		return err
+	}
+	if _, err := g.SetCurrentView(tuiViewTransactions); err != nil {
+		return err
+	}
+	t.renderStatus(g)
+
+	return nil
+}
+
+// askDelete asks the user to confirm removing selected transaction
+func (t *tui) askDelete(g *gocui.Gui, v *gocui.View) error {
+	if len(t.transactions) == 0 {
+		return nil
+	}
+	t.deleting = true
+	t.message = fmt.Sprintf("remove transaction with id = %d? (y/n)", t.transactions[t.transaction].Id)
+	t.renderStatus(g)
+
+	return nil
+}
+
+// confirmDelete removes selected transaction if the user was asked about it
+func (t *tui) confirmDelete(g *gocui.Gui, v *gocui.View) error {
+	if !t.deleting {
+		return nil
+	}
+	t.deleting = false
+	tr := t.transactions[t.transaction]
+	err := BackupOnNextChange(t.fh)
+	if err == nil {
+		err = TransactionRemove(t.fh, tr)
+	}
+	if err != nil {
+		t.message = err.Error()
+		t.renderStatus(g)
+		return nil
+	}
+	if err := t.refreshAll(g); err != nil {
+		return err
+	}
+	t.message = fmt.Sprintf("removed transaction with id = %d", tr.Id)
+	t.renderStatus(g)
+
+	return nil
+}
+
+// cancelDelete cancels removing transaction
+func (t *tui) cancelDelete(g *gocui.Gui, v *gocui.View) error {
+	if t.deleting {
+		t.deleting = false
+		t.message = NotSetStringValue
+		t.renderStatus(g)
+	}
+
+	return nil
+}
+
+// refreshAll reloads transactions and the report keeping the selection where possible
+func (t *tui) refreshAll(g *gocui.Gui) error {
+	selected := t.transaction
+	if err := t.loadTransactions(); err != nil {
+		t.message = err.Error()
+	}
+	if selected < len(t.transactions) {
+		t.transaction = selected
+	}
+	if err := t.renderTransactions(g); err != nil {
+		return err
+	}
+
+	return t.refreshReport(g)
+}
+
+// refreshReport prints the selected report again
+func (t *tui) refreshReport(g *gocui.Gui) error {
+	v, err := g.View(tuiViewReport)
+	if err != nil {
+		return err
+	}
+	t.renderReport(v)
+
+	return nil
+}
+
+// renderAccounts prints list of accounts
+func (t *tui) renderAccounts(v *gocui.View) {
+	v.Clear()
+	for _, a := range t.accounts {
+		if a == nil {
+			fmt.Fprintln(v, "All accounts")
+		} else {
+			fmt.Fprintln(v, a.Name)
+		}
+	}
+	tuiSelectLine(v, t.account)
+}
+
+// renderTransactions prints heading and list of transactions
+func (t *tui) renderTransactions(g *gocui.Gui) error {
+	hv, err := g.View(tuiViewHeader)
+	if err != nil {
+		return err
+	}
+	v, err := g.View(tuiViewTransactions)
+	if err != nil {
+		return err
+	}
+
+	lId := utf8.RuneCountInString(HTId)
+	lDate := utf8.RuneCountInString(HTDate)
+	lAccount := utf8.RuneCountInString(HAName)
+	lMCat := utf8.RuneCountInString(HMCName)
+	lCat := utf8.RuneCountInString(HCName)
+	lValue := utf8.RuneCountInString(HTValue)
+	lCur := utf8.RuneCountInString(HACurrency)
+	for _, tr := range t.transactions {
+		lId = MaxLen(strconv.FormatInt(tr.Id, 10), lId)
+		lDate = MaxLen(tr.Date.Format(DateFormat), lDate)
+		lAccount = MaxLen(tr.Account.Name, lAccount)
+		lMCat = MaxLen(tr.Category.Main.Name, lMCat)
+		lCat = MaxLen(t.tree.Path(tr.Category), lCat)
+		lValue = MaxLen(strconv.FormatFloat(tr.GetSValue(), 'f', 2, 64), lValue)
+		lCur = MaxLen(tr.Account.Currency, lCur)
+	}
+	lineH := LineFor(HFSForNumeric(lId), HFSForText(lDate), HFSForText(lAccount), HFSForText(lMCat), HFSForText(lCat), HFSForNumeric(lValue), HFSForText(lCur), "%s")
+	lineD := LineFor(DFSForID(lId), DFSForText(lDate), DFSForText(lAccount), DFSForText(lMCat), DFSForText(lCat), DFSForValue(lValue), DFSForText(lCur), "%s")
+
+	hv.Clear()
+	hv.Title = "Transactions"
+	if t.filter != NotSetStringValue {
+		hv.Title = fmt.Sprintf("Transactions (description: %s)", t.filter)
+	}
+	fmt.Fprintf(hv, lineH, HTId, HTDate, HAName, HMCName, HCName, HTValue, HACurrency, HTDescription)
+	v.Clear()
+	for _, tr := range t.transactions {
+		fmt.Fprintf(v, lineD, tr.Id, tr.Date.Format(DateFormat), tr.Account.Name, tr.Category.Main.Name, t.tree.Path(tr.Category), tr.GetSValue(), tr.Account.Currency, tr.Description)
+	}
+	tuiSelectLine(v, t.transaction)
+
+	return nil
+}
+
+// renderReport prints selected report using the same functions as command line reports
+func (t *tui) renderReport(v *gocui.View) {
+	var err error
+
+	v.Clear()
+	v.SetOrigin(0, 0)
+	switch t.report {
+	case tuiReportAccountBalance:
+		v.Title = "Accounts balance"
+		err = printAccountBalance(v, t.fh, time.Now())
+	case tuiReportBudgetCategories:
+		v.Title = "Budget categories"
+		var p *BPeriod
+		if p, err = BPeriodCurrent(); err == nil {
+			err = printBudgetCategories(v, t.fh, p, t.currency, NotSetIntValue)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(v, err)
+	}
+}
+
+// renderStatus prints message or help in the status bar
+func (t *tui) renderStatus(g *gocui.Gui) {
+	v, err := g.View(tuiViewStatus)
+	if err != nil {
+		return
+	}
+	v.Clear()
+	if t.message != NotSetStringValue {
+		fmt.Fprint(v, t.message)
+	} else {
+		fmt.Fprint(v, tuiHelpMain)
+	}
+}
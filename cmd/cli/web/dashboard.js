@@ -0,0 +1,279 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+// Dashboard showing reports of financoj HTTP API as charts drawn with SVG,
+// so that it works without any external libraries.
+"use strict";
+
+var svgNS = "http://www.w3.org/2000/svg";
+var colors = ["#2980b9", "#27ae60", "#e67e22", "#8e44ad", "#c0392b", "#16a085", "#f1c40f", "#7f8c8d"];
+var chartWidth = 600, chartHeight = 260, margin = {top: 10, right: 10, bottom: 30, left: 70};
+
+// report gets report with given name and criteria from the API
+function report(name, criteria) {
+    var query = Object.keys(criteria).filter(function (k) {
+        return criteria[k] !== "";
+    }).map(function (k) {
+        return encodeURIComponent(k) + "=" + encodeURIComponent(criteria[k]);
+    }).join("&");
+
+    return fetch("api/report/" + name + "?" + query).then(function (resp) {
+        return resp.json().then(function (body) {
+            if (!resp.ok) {
+                throw new Error(body.error || resp.statusText);
+            }
+            return body;
+        });
+    });
+}
+
+// el creates SVG element with given attributes
+function el(name, attrs, text) {
+    var e = document.createElementNS(svgNS, name);
+    Object.keys(attrs || {}).forEach(function (k) {
+        e.setAttribute(k, attrs[k]);
+    });
+    if (text !== undefined) {
+        e.textContent = text;
+    }
+    return e;
+}
+
+// html creates HTML element with given class and text
+function html(name, cls, text) {
+    var e = document.createElement(name);
+    if (cls) {
+        e.className = cls;
+    }
+    if (text !== undefined) {
+        e.textContent = text;
+    }
+    return e;
+}
+
+function format(v) {
+    return v.toLocaleString(undefined, {minimumFractionDigits: 2, maximumFractionDigits: 2});
+}
+
+// show puts content into the chart box, or message if there is nothing to show
+function show(id, content) {
+    var box = document.getElementById(id);
+    box.textContent = "";
+    box.appendChild(content);
+}
+
+function showMessage(id, cls, msg) {
+    show(id, html("p", cls, msg));
+}
+
+// scale returns function mapping values from domain [d0, d1] to range [r0, r1]
+function scale(d0, d1, r0, r1) {
+    if (d0 === d1) {
+        d1 = d0 + 1;
+    }
+    return function (v) {
+        return r0 + (v - d0) * (r1 - r0) / (d1 - d0);
+    };
+}
+
+// axes draws value axis with a few labels and period labels under the chart
+function axes(svg, y, min, max, periods, x) {
+    var ticks = 4;
+    for (var i = 0; i <= ticks; i++) {
+        var v = min + (max - min) * i / ticks;
+        svg.appendChild(el("line", {x1: margin.left, x2: chartWidth - margin.right, y1: y(v), y2: y(v), "class": "axis", "stroke-dasharray": "2,3"}));
+        svg.appendChild(el("text", {x: margin.left - 5, y: y(v) + 4, "text-anchor": "end"}, format(v)));
+    }
+    var step = Math.ceil(periods.length / 8);
+    periods.forEach(function (p, i) {
+        if (i % step === 0) {
+            svg.appendChild(el("text", {x: x(i), y: chartHeight - margin.bottom + 15, "text-anchor": "middle"}, p));
+        }
+    });
+}
+
+function newChart() {
+    return el("svg", {viewBox: "0 0 " + chartWidth + " " + chartHeight});
+}
+
+// lineChart draws net value in time
+function lineChart(id, entries) {
+    if (entries.length === 0) {
+        return showMessage(id, "empty", "no data");
+    }
+    var values = entries.map(function (e) {
+        return e.value;
+    });
+    var min = Math.min(0, Math.min.apply(null, values)), max = Math.max(0, Math.max.apply(null, values));
+    var x = scale(0, entries.length - 1, margin.left + 10, chartWidth - margin.right - 10);
+    var y = scale(min, max, chartHeight - margin.bottom, margin.top);
+    var svg = newChart();
+
+    axes(svg, y, min, max, entries.map(function (e) {
+        return e.period;
+    }), x);
+    var points = entries.map(function (e, i) {
+        return x(i) + "," + y(e.value);
+    });
+    svg.appendChild(el("polyline", {points: points.join(" "), "class": "line"}));
+    entries.forEach(function (e, i) {
+        var dot = el("circle", {cx: x(i), cy: y(e.value), r: 3, fill: colors[0]});
+        dot.appendChild(el("title", {}, e.period + ": " + format(e.value)));
+        svg.appendChild(dot);
+    });
+    show(id, svg);
+}
+
+// barChart draws income and cost side by side for every period
+function barChart(id, entries) {
+    if (entries.length === 0) {
+        return showMessage(id, "empty", "no data");
+    }
+    var max = 0;
+    entries.forEach(function (e) {
+        max = Math.max(max, Math.abs(e.income), Math.abs(e.cost));
+    });
+    var slot = (chartWidth - margin.left - margin.right) / entries.length;
+    var x = function (i) {
+        return margin.left + slot * (i + 0.5);
+    };
+    var y = scale(0, max, chartHeight - margin.bottom, margin.top);
+    var svg = newChart();
+
+    axes(svg, y, 0, max, entries.map(function (e) {
+        return e.period;
+    }), x);
+    var w = Math.max(1, slot * 0.4);
+    entries.forEach(function (e, i) {
+        [["income", e.income, -w], ["cost", Math.abs(e.cost), 0]].forEach(function (b) {
+            var bar = el("rect", {x: x(i) + b[2], y: y(b[1]), width: w, height: y(0) - y(b[1]), "class": b[0]});
+            bar.appendChild(el("title", {}, e.period + " " + b[0] + ": " + format(b[1]) + ", difference: " + format(e.difference)));
+            svg.appendChild(bar);
+        });
+    });
+
+    var box = html("div");
+    box.appendChild(svg);
+    var legend = html("div", "legend");
+    ["income", "cost"].forEach(function (n) {
+        var s = html("span", null, n);
+        s.insertBefore(html("i", n), s.firstChild);
+        legend.appendChild(s);
+    });
+    box.appendChild(legend);
+    show(id, box);
+}
+
+// budgetBars draws progress bar of actual value against budget limit for every category
+function budgetBars(id, entries) {
+    entries = entries.filter(function (e) {
+        return e.limit !== 0 || e.actual !== 0;
+    });
+    if (entries.length === 0) {
+        return showMessage(id, "empty", "no budget for the period");
+    }
+    var box = html("div");
+    entries.forEach(function (e) {
+        var limit = Math.abs(e.limit), actual = Math.abs(e.actual);
+        var used = limit === 0 ? 1 : actual / limit;
+        var row = html("div", "budget");
+        var name = html("div", "name");
+        name.appendChild(html("span", null, e.category));
+        name.appendChild(html("span", null, format(actual) + " / " + format(limit) + (limit === 0 ? "" : " (" + Math.round(used * 100) + "%)")));
+        var bar = html("div", "bar");
+        var fill = html("div", used > 1 ? "over" : null);
+        fill.style.width = Math.min(100, used * 100) + "%";
+        bar.appendChild(fill);
+        row.appendChild(name);
+        row.appendChild(bar);
+        box.appendChild(row);
+    });
+    show(id, box);
+}
+
+// pieChart draws allocation of assets between account types.
+// Types with negative balance (e.g. loans) are listed in the table only.
+function pieChart(id, entries) {
+    var types = {}, names = [];
+    entries.forEach(function (e) {
+        if (!(e.type in types)) {
+            types[e.type] = 0;
+            names.push(e.type);
+        }
+        types[e.type] += e.balance;
+    });
+    var total = names.reduce(function (t, n) {
+        return types[n] > 0 ? t + types[n] : t;
+    }, 0);
+    if (total === 0) {
+        return showMessage(id, "empty", "no assets");
+    }
+
+    var svg = el("svg", {viewBox: "-1.05 -1.05 2.1 2.1"});
+    var table = html("table");
+    var angle = -Math.PI / 2;
+    names.forEach(function (n, i) {
+        var color = colors[i % colors.length];
+        var row = html("tr");
+        var mark = html("td");
+        mark.appendChild(html("i"));
+        mark.firstChild.style.cssText = "display:inline-block;width:0.8em;height:0.8em;background:" + color;
+        row.appendChild(mark);
+        row.appendChild(html("td", null, n));
+        row.appendChild(html("td", "value", format(types[n])));
+        row.appendChild(html("td", "value", types[n] > 0 ? Math.round(types[n] / total * 100) + "%" : ""));
+        table.appendChild(row);
+        if (types[n] <= 0) {
+            return;
+        }
+
+        var part = types[n] / total, slice;
+        if (part === 1) {
+            slice = el("circle", {cx: 0, cy: 0, r: 1, fill: color});
+        } else {
+            var end = angle + part * 2 * Math.PI;
+            slice = el("path", {
+                d: "M0,0 L" + Math.cos(angle) + "," + Math.sin(angle) +
+                " A1,1 0 " + (part > 0.5 ? 1 : 0) + ",1 " + Math.cos(end) + "," + Math.sin(end) + " Z",
+                fill: color
+            });
+            angle = end;
+        }
+        slice.appendChild(el("title", {}, n + ": " + format(types[n])));
+        svg.appendChild(slice);
+    });
+
+    var box = html("div", "assets");
+    box.appendChild(svg);
+    box.appendChild(table);
+    show(id, box);
+}
+
+// refresh loads all the reports for criteria given in the form
+function refresh() {
+    var form = document.getElementById("criteria");
+    var currency = form.elements["currency"].value.trim();
+    var dates = {"currency": currency, "date-from": form.elements["date-from"].value, "date-to": form.elements["date-to"].value};
+    var charts = [
+        ["net-value", "net-value", dates, lineChart],
+        ["income-cost", "income-cost-monthly", dates, barChart],
+        ["budget", "budget-categories", {"currency": currency, "period": form.elements["period"].value}, budgetBars],
+        ["assets", "assets-summary", {"currency": currency, "date": form.elements["date-to"].value}, pieChart]
+    ];
+
+    charts.forEach(function (c) {
+        showMessage(c[0], "empty", "loading...");
+        report(c[1], c[2]).then(function (entries) {
+            c[3](c[0], entries);
+        }).catch(function (err) {
+            showMessage(c[0], "error", err.message);
+        });
+    });
+}
+
+document.getElementById("criteria").addEventListener("submit", function (ev) {
+    ev.preventDefault();
+    refresh();
+});
+refresh();
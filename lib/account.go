@@ -63,14 +63,22 @@ func AccountAdd(db *gsqlitehandler.SqliteDB, a *Account) error {
 	var err error
 	var stmt *sql.Stmt
 
-	if stmt, err = db.Handler.Prepare("INSERT INTO accounts VALUES (NULL, ?, ?, ?, upper(?), ?, ?);"); err != nil {
+	if err = beforeChange(db); err != nil {
+		return err
+	}
+
+	if stmt, err = dbHandler(db).Prepare("INSERT INTO accounts VALUES (NULL, ?, ?, ?, upper(?), ?, ?);"); err != nil {
 		return errors.New(errWritingToFile)
 	}
 	defer stmt.Close()
 
-	if _, err = stmt.Exec(a.Name, a.Description, a.Institution, a.Currency, a.AType, a.Status); err != nil {
+	var res sql.Result
+	if res, err = stmt.Exec(a.Name, a.Description, a.Institution, a.Currency, a.AType, a.Status); err != nil {
 		return errors.New(errWritingToFile)
 	}
+	if id, err := res.LastInsertId(); err == nil {
+		a.Id = id
+	}
 
 	return nil
 	//TODO: add test
@@ -112,7 +120,7 @@ func AccountList(db *gsqlitehandler.SqliteDB, n string, d string, i string, c st
 		"AND (type=? OR ?=?) " +
 		"AND (status=? OR ?=?) " +
 		"ORDER BY type ASC, name ASC;"
-	if stmt, err = db.Handler.Prepare(sqlQuery); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 	if rows, err = stmt.Query(n, n, noStringParamForSQL, d, d, noStringParamForSQL, i, i, noStringParamForSQL, c, c, noStringParamForSQL, t, t, ATUnset, s, s, ISUnset); err != nil {
@@ -140,7 +148,7 @@ func AccountForID(db *gsqlitehandler.SqliteDB, i int) (a *Account, err error) {
 	var stmt *sql.Stmt
 
 	sqlQuery := "SELECT id, name, description, institution, currency, type, status FROM accounts WHERE id=? AND status=?;"
-	if stmt, err = db.Handler.Prepare(sqlQuery); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 	defer stmt.Close()
@@ -161,7 +169,7 @@ func AccountForName(db *gsqlitehandler.SqliteDB, n string) (a *Account, err erro
 
 	n = "%" + n + "%"
 	sqlQuery := "SELECT id, name, description, institution, currency, type, status FROM accounts WHERE name LIKE ? AND status=?;"
-	if stmt, err = db.Handler.Prepare(sqlQuery); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 	defer stmt.Close()
@@ -196,6 +204,10 @@ func AccountEdit(db *gsqlitehandler.SqliteDB, a *Account) error {
 	var err error
 	var stmt *sql.Stmt
 
+	if err = beforeChange(db); err != nil {
+		return err
+	}
+
 	sqlQuery := "UPDATE accounts SET " +
 		"name=? " +
 		",description=? " +
@@ -204,7 +216,7 @@ func AccountEdit(db *gsqlitehandler.SqliteDB, a *Account) error {
 		",type=? " +
 		",status=? " +
 		"WHERE id=?;"
-	if stmt, err = db.Handler.Prepare(sqlQuery); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
 		return errors.New(errWritingToFile)
 	}
 	defer stmt.Close()
@@ -222,9 +234,13 @@ func AccountRemove(db *gsqlitehandler.SqliteDB, a *Account) error {
 	var err error
 	var stmt *sql.Stmt
 
+	if err = beforeChange(db); err != nil {
+		return err
+	}
+
 	// Set correct status (ISClose)
 	sqlQuery := "UPDATE accounts SET status=? WHERE id=?;"
-	if stmt, err = db.Handler.Prepare(sqlQuery); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
 		return errors.New(errWritingToFile)
 	}
 	defer stmt.Close()
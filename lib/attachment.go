@@ -0,0 +1,190 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"github.com/zbroju/gsqlitehandler"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Objects which files can be attached to
+const (
+	AttachmentTransaction = "transaction"
+	AttachmentAccount     = "account"
+)
+
+// Table of files attached to transactions and accounts, kept in data file together with their SHA-256 hashes
+const sqlCreateAttachments = "CREATE TABLE IF NOT EXISTS attachments (id INTEGER PRIMARY KEY, object TEXT, object_id INTEGER, " +
+	"name TEXT, mime_type TEXT, hash TEXT, size INTEGER, added TEXT, data BLOB);"
+
+// Attachment is a file (e.g. receipt or invoice) attached to transaction or account.
+// Its contents is read separately with AttachmentData.
+type Attachment struct {
+	Id       int64
+	Object   string
+	ObjectId int64
+	Name     string
+	MIMEType string
+	Hash     string
+	Size     int64
+	Added    time.Time
+}
+
+// AttachmentAdd attaches file with given name and contents to object (AttachmentTransaction or AttachmentAccount) with given id.
+// The same file cannot be attached twice to the same object.
+func AttachmentAdd(db *gsqlitehandler.SqliteDB, object string, objectId int64, name string, data []byte) (a *Attachment, err error) {
+	var stmt *sql.Stmt
+
+	if object != AttachmentTransaction && object != AttachmentAccount {
+		return nil, errors.New(errAttachmentObject + object)
+	}
+	sum := sha256.Sum256(data)
+	a = &Attachment{Object: object, ObjectId: objectId, Name: filepath.Base(name), MIMEType: attachmentMIMEType(name, data),
+		Hash: hex.EncodeToString(sum[:]), Size: int64(len(data)), Added: time.Now()}
+
+	var n int
+	if err = dbHandler(db).QueryRow("SELECT COUNT(*) FROM attachments WHERE object=? AND object_id=? AND hash=?;", a.Object, a.ObjectId, a.Hash).Scan(&n); err != nil {
+		return nil, errors.New(errReadingFromFile)
+	}
+	if n > 0 {
+		return nil, errors.New(errAttachmentExists)
+	}
+
+	if err = beforeChange(db); err != nil {
+		return nil, err
+	}
+
+	sqlQuery := "INSERT INTO attachments (object, object_id, name, mime_type, hash, size, added, data) VALUES (?, ?, ?, ?, ?, ?, ?, ?);"
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
+		return nil, errors.New(errWritingToFile)
+	}
+	defer stmt.Close()
+
+	var res sql.Result
+	if res, err = stmt.Exec(a.Object, a.ObjectId, a.Name, a.MIMEType, a.Hash, a.Size, a.Added.Format(DateFormat), data); err != nil {
+		return nil, errors.New(errWritingToFile)
+	}
+	if a.Id, err = res.LastInsertId(); err != nil {
+		return nil, errors.New(errWritingToFile)
+	}
+
+	return a, nil
+	//TODO: add test
+}
+
+// attachmentMIMEType returns MIME type of file given by its extension or, if it is unknown, guessed from its contents
+func attachmentMIMEType(name string, data []byte) string {
+	if t := mime.TypeByExtension(strings.ToLower(filepath.Ext(name))); t != NotSetStringValue {
+		return t
+	}
+
+	return http.DetectContentType(data)
+}
+
+// AttachmentForID returns attachment with given id (without its contents)
+func AttachmentForID(db *gsqlitehandler.SqliteDB, i int) (a *Attachment, err error) {
+	var stmt *sql.Stmt
+
+	sqlQuery := "SELECT id, object, object_id, name, mime_type, hash, size, added FROM attachments WHERE id=?;"
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
+		return nil, errors.New(errReadingFromFile)
+	}
+	defer stmt.Close()
+
+	a = new(Attachment)
+	var tmpDate string
+	if err = stmt.QueryRow(i).Scan(&a.Id, &a.Object, &a.ObjectId, &a.Name, &a.MIMEType, &a.Hash, &a.Size, &tmpDate); err != nil {
+		return nil, errors.New(errAttachmentWithIDNone)
+	}
+	if a.Added, err = time.Parse(DateFormat, tmpDate); err != nil {
+		a.Added = time.Time{}
+	}
+
+	return a, nil
+	//TODO: add test
+}
+
+// AttachmentList returns attachments (without their contents) as closure: the ones of given object
+// and object id, all the ones of given object if objectId is not set, or all of them if object is not set
+func AttachmentList(db *gsqlitehandler.SqliteDB, object string, objectId int64) (f func() *Attachment, err error) {
+	var stmt *sql.Stmt
+	var rows *sql.Rows
+
+	if object == NotSetStringValue {
+		object = noStringParamForSQL
+	}
+
+	sqlQuery := "SELECT id, object, object_id, name, mime_type, hash, size, added FROM attachments " +
+		"WHERE (object=? OR ?=?) AND (object_id=? OR ?=?) ORDER BY object, object_id, id;"
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
+		return nil, errors.New(errReadingFromFile)
+	}
+	if rows, err = stmt.Query(object, object, noStringParamForSQL, objectId, objectId, NotSetIntValue); err != nil {
+		stmt.Close()
+		return nil, errors.New(errReadingFromFile)
+	}
+
+	f = func() *Attachment {
+		if rows.Next() {
+			a := new(Attachment)
+			var tmpDate string
+			rows.Scan(&a.Id, &a.Object, &a.ObjectId, &a.Name, &a.MIMEType, &a.Hash, &a.Size, &tmpDate)
+			if a.Added, err = time.Parse(DateFormat, tmpDate); err != nil {
+				a.Added = time.Time{}
+			}
+			return a
+		}
+		rows.Close()
+		stmt.Close()
+
+		return nil
+	}
+
+	return f, nil
+	//TODO: add test
+}
+
+// AttachmentData returns contents of attachment, checking it against its hash
+func AttachmentData(db *gsqlitehandler.SqliteDB, a *Attachment) (data []byte, err error) {
+	if err = dbHandler(db).QueryRow("SELECT data FROM attachments WHERE id=?;", a.Id).Scan(&data); err != nil {
+		return nil, errors.New(errAttachmentWithIDNone)
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != a.Hash {
+		return nil, errors.New(errAttachmentHash)
+	}
+
+	return data, nil
+	//TODO: add test
+}
+
+// AttachmentRemove removes attachment from data file
+func AttachmentRemove(db *gsqlitehandler.SqliteDB, a *Attachment) error {
+	var err error
+	var stmt *sql.Stmt
+
+	if err = beforeChange(db); err != nil {
+		return err
+	}
+
+	if stmt, err = dbHandler(db).Prepare("DELETE FROM attachments WHERE id=?;"); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.Exec(a.Id); err != nil {
+		return errors.New(errWritingToFile)
+	}
+
+	return nil
+	//TODO: add test
+}
@@ -0,0 +1,335 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/mattn/go-sqlite3"
+	"github.com/zbroju/gprops"
+	"github.com/zbroju/gsqlitehandler"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config file settings of backups
+const (
+	confBackupDir         = "BACKUP_DIR"
+	confBackupKeepLast    = "BACKUP_KEEP_LAST"
+	confBackupKeepDaily   = "BACKUP_KEEP_DAILY"
+	confBackupKeepMonthly = "BACKUP_KEEP_MONTHLY"
+)
+
+// Backups are named after data file and time they were made at, e.g. finance.db-20160131-153000.123.bak
+const (
+	backupTimeFormat = "20060102-150405.000"
+	backupExtension  = ".bak"
+)
+
+// BackupSettings tells where backups of data file are kept and how many of them: the last ones
+// and the last ones of each day and month. Backups are not made if all the numbers are zero.
+type BackupSettings struct {
+	Dir         string
+	KeepLast    int
+	KeepDaily   int
+	KeepMonthly int
+}
+
+// Backup is a copy of data file
+type Backup struct {
+	Name string
+	Time time.Time
+	Size int64
+}
+
+// GetBackupSettings returns settings of backups from config file. By default backups are kept
+// in directory backups next to data file: 10 last ones and the last ones of 7 days and 12 months.
+func GetBackupSettings(dataFile string) (s *BackupSettings, err error) {
+	var configSettings *gprops.Props
+	if configSettings, err = readConfigFile(); err != nil {
+		return nil, err
+	}
+
+	s = &BackupSettings{Dir: configSettings.GetOrDefault(confBackupDir, filepath.Join(filepath.Dir(dataFile), "backups"))}
+	for _, n := range []struct {
+		key string
+		v   *int
+		def string
+	}{
+		{confBackupKeepLast, &s.KeepLast, "10"},
+		{confBackupKeepDaily, &s.KeepDaily, "7"},
+		{confBackupKeepMonthly, &s.KeepMonthly, "12"},
+	} {
+		if *n.v, err = strconv.Atoi(configSettings.GetOrDefault(n.key, n.def)); err != nil || *n.v < 0 {
+			return nil, errors.New(errBackupSettings + n.key)
+		}
+	}
+
+	return s, nil
+	//TODO: add test
+}
+
+// Enabled checks if backups are to be made
+func (s *BackupSettings) Enabled() bool {
+	return s.KeepLast > 0 || s.KeepDaily > 0 || s.KeepMonthly > 0
+}
+
+// Data files to be backed up right before their next change, with settings of backups
+var dataFileBackups = make(map[*gsqlitehandler.SqliteDB]*BackupSettings)
+
+// BackupOnNextChange makes data file opened with db backed up (unless backups are turned off in config file)
+// right before it is changed next time, so that commands which fail or change nothing make no backups.
+// Changes grouped in transaction (see BeginDataFileTransaction) are backed up only once, before the first of them.
+func BackupOnNextChange(db *gsqlitehandler.SqliteDB) error {
+	if c, ok := dataFileTransactions[db]; ok && c.backedUp {
+		return nil
+	}
+	s, err := GetBackupSettings(dataFilePaths[db])
+	if err != nil {
+		return err
+	}
+	if s.Enabled() {
+		dataFileBackups[db] = s
+	}
+
+	return nil
+	//TODO: add test
+}
+
+// beforeChange backs up data file opened with db if it is to be backed up before its change (see BackupOnNextChange)
+func beforeChange(db *gsqlitehandler.SqliteDB) error {
+	s, ok := dataFileBackups[db]
+	if !ok {
+		return nil
+	}
+	if _, err := BackupCreate(dataFilePaths[db], s); err != nil {
+		return err
+	}
+	delete(dataFileBackups, db)
+	if c, ok := dataFileTransactions[db]; ok {
+		c.backedUp = true
+	}
+
+	return nil
+}
+
+// BackupCreate copies data file to new backup with SQLite online backup API (so that the copy is consistent
+// even if the file is used at the same time) and removes backups which are not to be kept any longer.
+// Nothing is done for data file which does not exist.
+func BackupCreate(dataFile string, s *BackupSettings) (b *Backup, err error) {
+	if b, err = backupMake(dataFile, s); err != nil || b == nil {
+		return nil, err
+	}
+	if err = backupRotate(dataFile, s); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+	//TODO: add test
+}
+
+// backupMake copies data file to new backup
+func backupMake(dataFile string, s *BackupSettings) (b *Backup, err error) {
+	if _, err = os.Stat(dataFile); err != nil {
+		return nil, nil
+	}
+	if err = os.MkdirAll(s.Dir, 0700); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	b = &Backup{Name: filepath.Base(dataFile) + "-" + now.Format(backupTimeFormat) + backupExtension, Time: now}
+	if err = backupCopyFile(dataFile, filepath.Join(s.Dir, b.Name)); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// BackupList returns backups of data file as closure, from the newest one
+func BackupList(dataFile string, s *BackupSettings) (f func() *Backup, err error) {
+	var l []*Backup
+	if l, err = backupFiles(dataFile, s); err != nil {
+		return nil, err
+	}
+
+	f = func() *Backup {
+		if len(l) == 0 {
+			return nil
+		}
+		b := l[0]
+		l = l[1:]
+		return b
+	}
+
+	return f, nil
+	//TODO: add test
+}
+
+// BackupRestore replaces contents of data file with backup of given name. The current contents is backed up before.
+func BackupRestore(dataFile string, s *BackupSettings, name string) error {
+	var err error
+	var l []*Backup
+	if l, err = backupFiles(dataFile, s); err != nil {
+		return err
+	}
+	var b *Backup
+	for _, c := range l {
+		if c.Name == name {
+			b = c
+		}
+	}
+	if b == nil {
+		return errors.New(errBackupNone + name)
+	}
+
+	// Old backups are removed after restoring, so that the restored one is not removed before
+	err = withDataFileLock(dataFile, func() error {
+		if _, err := backupMake(dataFile, s); err != nil {
+			return err
+		}
+		return backupCopyFile(filepath.Join(s.Dir, b.Name), dataFile)
+	})
+	if err != nil {
+		return err
+	}
+
+	return backupRotate(dataFile, s)
+	//TODO: add test
+}
+
+// backupCopyFile copies data file from src to dst. Encrypted data files are copied as they are,
+// as they are written at once (see writeFileAtomic), while SQLite databases with backup API.
+func backupCopyFile(src, dst string) error {
+	if !isEncryptedFile(src) && !isEncryptedFile(dst) {
+		return backupCopy(src, dst)
+	}
+
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return errors.New(errBackupCopy + err.Error())
+	}
+
+	return writeFileAtomic(dst, data)
+}
+
+// backupCopy copies SQLite database from file src to file dst with online backup API
+func backupCopy(src, dst string) (err error) {
+	ctx := context.Background()
+	var srcDB, dstDB *sql.DB
+	var srcConn, dstConn *sql.Conn
+
+	if srcDB, err = sql.Open("sqlite3", src); err != nil {
+		return errors.New(errReadingFromFile)
+	}
+	defer srcDB.Close()
+	if dstDB, err = sql.Open("sqlite3", dst); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	defer dstDB.Close()
+	if srcConn, err = srcDB.Conn(ctx); err != nil {
+		return errors.New(errReadingFromFile)
+	}
+	defer srcConn.Close()
+	if dstConn, err = dstDB.Conn(ctx); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	defer dstConn.Close()
+
+	err = dstConn.Raw(func(d interface{}) error {
+		return srcConn.Raw(func(s interface{}) error {
+			b, err := d.(*sqlite3.SQLiteConn).Backup("main", s.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+			// Copy all pages at once, the file is locked only while doing it
+			if _, err = b.Step(-1); err != nil {
+				b.Finish()
+				return err
+			}
+			return b.Finish()
+		})
+	})
+	if err != nil {
+		return errors.New(errBackupCopy + err.Error())
+	}
+
+	return nil
+}
+
+// backupFiles returns backups of data file found in backup directory, from the newest one
+func backupFiles(dataFile string, s *BackupSettings) (l []*Backup, err error) {
+	var entries []os.DirEntry
+	if entries, err = os.ReadDir(s.Dir); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	prefix := filepath.Base(dataFile) + "-"
+	for _, e := range entries {
+		n := e.Name()
+		if e.IsDir() || !strings.HasPrefix(n, prefix) || !strings.HasSuffix(n, backupExtension) {
+			continue
+		}
+		t, err := time.ParseInLocation(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(n, prefix), backupExtension), time.Local)
+		if err != nil {
+			continue
+		}
+		b := &Backup{Name: n, Time: t}
+		if fi, err := e.Info(); err == nil {
+			b.Size = fi.Size()
+		}
+		l = append(l, b)
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i].Time.After(l[j].Time) })
+
+	return l, nil
+}
+
+// backupRotate removes backups of data file except for the last ones and the last ones of each day and month
+func backupRotate(dataFile string, s *BackupSettings) error {
+	l, err := backupFiles(dataFile, s)
+	if err != nil {
+		return err
+	}
+
+	keep := make(map[string]bool)
+	for i := 0; i < len(l) && i < s.KeepLast; i++ {
+		keep[l[i].Name] = true
+	}
+	// Backups are sorted from the newest one, so the first backup of each period is the last one made in it
+	for _, p := range []struct {
+		format string
+		n      int
+	}{
+		{"2006-01-02", s.KeepDaily},
+		{"2006-01", s.KeepMonthly},
+	} {
+		periods := make(map[string]bool)
+		for _, b := range l {
+			if k := b.Time.Format(p.format); !periods[k] && len(periods) < p.n {
+				periods[k] = true
+				keep[b.Name] = true
+			}
+		}
+	}
+
+	for _, b := range l {
+		if !keep[b.Name] {
+			if err = os.Remove(filepath.Join(s.Dir, b.Name)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -7,6 +7,7 @@ package lib
 import (
 	"errors"
 	"fmt"
+	"github.com/zbroju/gsqlitehandler"
 	"strconv"
 	"strings"
 	"time"
@@ -24,6 +25,18 @@ const (
 type BPeriod struct {
 	Year  int64
 	Month int64
+
+	// fiscalYearStart is the first month of fiscal year of yearly period (January if it is not set)
+	fiscalYearStart time.Month
+}
+
+// yearStart returns the first month of fiscal year of yearly period
+func (p *BPeriod) yearStart() time.Month {
+	if p.fiscalYearStart == 0 {
+		return time.January
+	}
+
+	return p.fiscalYearStart
 }
 
 // incorrectYear returns error if y (year) is out of range.
@@ -49,7 +62,7 @@ func incorrectMonth(m int64) error {
 // String satisfies fmt.Stringer interface in order to get human readable names.
 func (p *BPeriod) String() string {
 	if p.Month == int64(NotSetIntValue) {
-		return fmt.Sprintf("%04d", p.Year)
+		return fiscalYearString(int(p.Year), p.yearStart())
 	} else {
 		return fmt.Sprintf("%04d%s%02d", p.Year, DateSeparator, p.Month)
 	}
@@ -66,6 +79,17 @@ func (p *BPeriod) GetStrings() (y, m string) {
 	return y, m
 }
 
+// Dates returns the first and the last day of budget period. Year is the fiscal year starting in it.
+func (p *BPeriod) Dates() (from, to time.Time) {
+	if p.Month == int64(NotSetIntValue) {
+		from = time.Date(int(p.Year), p.yearStart(), 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(1, 0, -1)
+	}
+	from = time.Date(int(p.Year), time.Month(p.Month), 1, 0, 0, 0, 0, time.UTC)
+
+	return from, from.AddDate(0, 1, -1)
+}
+
 // Set verifies if year y and month m are within their ranges and assigns it to the budgeting period fields.
 func (p *BPeriod) Set(y, m int64) error {
 	if err := incorrectYear(y); err != nil {
@@ -84,6 +108,9 @@ func (p *BPeriod) Set(y, m int64) error {
 // their ranges assign them to the budget period fields.
 func BPeriodParseYM(s string) (b *BPeriod, err error) {
 	sarr := strings.SplitN(s, DateSeparator, 2)
+	if len(sarr) < 2 {
+		return nil, errors.New(errPeriodIncorrect)
+	}
 
 	var y, m int64
 	if y, err = strconv.ParseInt(sarr[0], 10, 64); err != nil {
@@ -106,9 +133,12 @@ func BPeriodParseYM(s string) (b *BPeriod, err error) {
 	return b, nil
 }
 
-// BPeriodParseYOrYM converts string (expected format: yyyy-mm or yyyy) to year and month or to year only and after verification if they are within
-// their ranges assign them to the budget period fields.
-func BPeriodParseYOrYM(s string) (b *BPeriod, err error) {
+// BPeriodParseYOrYM converts string (expected format: yyyy-mm, yyyy or yyyy/yy for fiscal year) to year and month or to year only and after verification if they are within
+// their ranges assign them to the budget period fields. Years are fiscal years of data file opened with db.
+func BPeriodParseYOrYM(db *gsqlitehandler.SqliteDB, s string) (b *BPeriod, err error) {
+	if y, ok := fiscalYearParse(s); ok {
+		s = strconv.Itoa(y)
+	}
 	switch utf8.RuneCountInString(s) {
 	case 4:
 		var y int64
@@ -116,6 +146,7 @@ func BPeriodParseYOrYM(s string) (b *BPeriod, err error) {
 			if err = incorrectYear(y); err == nil {
 				b = new(BPeriod)
 				b.Year = y
+				b.fiscalYearStart = fiscalYearStartOf(db)
 			}
 		}
 	case 6, 7:
@@ -124,7 +155,7 @@ func BPeriodParseYOrYM(s string) (b *BPeriod, err error) {
 		err = errors.New(errPeriodIncorrect)
 	}
 
-	return b, nil
+	return b, err
 }
 
 func BPeriodCurrent() (b *BPeriod, err error) {
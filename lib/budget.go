@@ -32,8 +32,12 @@ func BudgetAdd(db *gsqlitehandler.SqliteDB, b *Budget) error {
 	var err error
 	var stmt *sql.Stmt
 
+	if err = beforeChange(db); err != nil {
+		return err
+	}
+
 	sqlQuery := "INSERT INTO budgets VALUES (?, ?, ?, round(?,2), upper(?));"
-	if stmt, err = db.Handler.Prepare(sqlQuery); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
 		return errors.New(errWritingToFile)
 	}
 	defer stmt.Close()
@@ -53,12 +57,13 @@ func BudgetGet(db *gsqlitehandler.SqliteDB, p *BPeriod, c *Category) (b *Budget,
 	sqlQuery := "SELECT b.year, b.month, b.value, b.currency, c.id, c.name, c.status, m.id, m.name, m.status, t.id, t.name, t.factor " +
 		"FROM budgets b INNER JOIN categories c ON b.category_id=c.id INNER JOIN main_categories m ON c.main_category_id=m.id INNER JOIN main_categories_types t ON m.type_id=t.id " +
 		"WHERE b.year=? AND b.month=? AND b.category_id=?;"
-	if stmt, err = db.Handler.Prepare(sqlQuery); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 	defer stmt.Close()
 
 	b = BudgetNew()
+	b.Period.fiscalYearStart = fiscalYearStartOf(db)
 	if err = stmt.QueryRow(p.Year, p.Month, c.Id).Scan(&b.Period.Year, &b.Period.Month, &b.Value, &b.Currency, &b.Category.Id, &b.Category.Name, &b.Category.Status, &b.Category.Main.Id, &b.Category.Main.Name, &b.Category.Main.Status, &b.Category.Main.MType.Id, &b.Category.Main.MType.Name, &b.Category.Main.MType.Factor); err != nil {
 		return nil, errors.New(errBudgetNone)
 	}
@@ -72,9 +77,13 @@ func BudgetRemove(db *gsqlitehandler.SqliteDB, b *Budget) error {
 	var err error
 	var stmt *sql.Stmt
 
+	if err = beforeChange(db); err != nil {
+		return err
+	}
+
 	// Remove budget
 	sqlQuery := "DELETE FROM budgets WHERE year=? AND month=? AND category_id=?;"
-	if stmt, err = db.Handler.Prepare(sqlQuery); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
 		return errors.New(errWritingToFile)
 	}
 	defer stmt.Close()
@@ -93,8 +102,12 @@ func BudgetEdit(db *gsqlitehandler.SqliteDB, b *Budget) error {
 	var err error
 	var stmt *sql.Stmt
 
+	if err = beforeChange(db); err != nil {
+		return err
+	}
+
 	sqlQuery := "UPDATE budgets SET value=?, currency=upper(?) WHERE year=? AND month=? AND category_id=?;"
-	if stmt, err = db.Handler.Prepare(sqlQuery); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
 		return errors.New(errWritingToFile)
 	}
 	defer stmt.Close()
@@ -133,7 +146,7 @@ func BudgetList(db *gsqlitehandler.SqliteDB, p *BPeriod, c *Category) (f func()
 		"FROM budgets b INNER JOIN categories c ON b.category_id=c.id INNER JOIN main_categories m ON c.main_category_id=m.id INNER JOIN main_categories_types t ON m.type_id=t.id " +
 		"WHERE (b.year=? OR ?=?) AND (b.month=? OR ?=?) AND (c.id=? OR ?=?) " +
 		"ORDER BY b.year, b.month, t.name, m.name, c.name;"
-	if stmt, err = db.Handler.Prepare(sqlQuery); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 
@@ -144,6 +157,7 @@ func BudgetList(db *gsqlitehandler.SqliteDB, p *BPeriod, c *Category) (f func()
 	f = func() *Budget {
 		if rows.Next() {
 			b := BudgetNew()
+			b.Period.fiscalYearStart = fiscalYearStartOf(db)
 			rows.Scan(&b.Period.Year, &b.Period.Month, &b.Value, &b.Currency, &b.Category.Id, &b.Category.Name, &b.Category.Status, &b.Category.Main.Id, &b.Category.Main.Name, &b.Category.Main.Status, &b.Category.Main.MType.Id, &b.Category.Main.MType.Name, &b.Category.Main.MType.Factor)
 			return b
 		}
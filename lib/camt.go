@@ -0,0 +1,225 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Elements of ISO 20022 camt.053 (bank to customer statement) used for import.
+// Names of elements do not depend on version of the message, except for parties which are nested in Pty element since version 8.
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtStatement struct {
+	Currency string        `xml:"Acct>Ccy"`
+	Balances []camtBalance `xml:"Bal"`
+	Entries  []camtEntry   `xml:"Ntry"`
+}
+
+type camtBalance struct {
+	Type   string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount camtAmount `xml:"Amt"`
+	Sign   string     `xml:"CdtDbtInd"`
+	Date   camtDate   `xml:"Dt"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+// camtStatus is status of entry given as text or, since version 8, as code
+type camtStatus struct {
+	Text string `xml:",chardata"`
+	Code string `xml:"Cd"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+type camtEntry struct {
+	Reference     string          `xml:"NtryRef"`
+	Amount        camtAmount      `xml:"Amt"`
+	Sign          string          `xml:"CdtDbtInd"`
+	Reversal      bool            `xml:"RvslInd"`
+	Status        camtStatus      `xml:"Sts"`
+	BookingDate   camtDate        `xml:"BookgDt"`
+	ValueDate     camtDate        `xml:"ValDt"`
+	BankReference string          `xml:"AcctSvcrRef"`
+	Details       []camtTxDetails `xml:"NtryDtls>TxDtls"`
+	AdditionalInf string          `xml:"AddtlNtryInf"`
+}
+
+type camtTxDetails struct {
+	BankReference   string    `xml:"Refs>AcctSvcrRef"`
+	EndToEndID      string    `xml:"Refs>EndToEndId"`
+	Debtor          camtParty `xml:"RltdPties>Dbtr"`
+	DebtorAccount   string    `xml:"RltdPties>DbtrAcct>Id>IBAN"`
+	Creditor        camtParty `xml:"RltdPties>Cdtr"`
+	CreditorAccount string    `xml:"RltdPties>CdtrAcct>Id>IBAN"`
+	Unstructured    []string  `xml:"RmtInf>Ustrd"`
+	AdditionalTxInf string    `xml:"AddtlTxInf"`
+}
+
+type camtParty struct {
+	Name      string `xml:"Nm"`
+	PartyName string `xml:"Pty>Nm"`
+}
+
+// name returns name of the party given directly or in Pty element
+func (p camtParty) name() string {
+	if p.Name != NotSetStringValue {
+		return p.Name
+	}
+	return p.PartyName
+}
+
+// StatementReadCAMT053 reads bank statement from ISO 20022 camt.053 file. Only booked entries are imported.
+// For files with many statements the opening balance is taken from the first and the closing balance from the last one.
+func StatementReadCAMT053(r io.Reader) (s *Statement, err error) {
+	var doc camtDocument
+	if err = xml.NewDecoder(r).Decode(&doc); err != nil || len(doc.Statements) == 0 {
+		return nil, errors.New(errStatementFormat)
+	}
+
+	s = new(Statement)
+	ids := make(map[string]int)
+	var openingDate time.Time
+	for i, cs := range doc.Statements {
+		if s.Currency == NotSetStringValue {
+			s.Currency = cs.Currency
+		}
+
+		for _, b := range cs.Balances {
+			var d time.Time
+			var v float64
+			if d, err = b.Date.parse(); err != nil {
+				return nil, err
+			}
+			if v, err = camtValue(b.Amount, b.Sign, false); err != nil {
+				return nil, err
+			}
+			switch b.Type {
+			case "OPBD", "PRCD":
+				if i == 0 {
+					s.OpeningBalance, openingDate = v, d
+				}
+			case "CLBD":
+				s.Balance, s.BalanceDate = v, d
+			}
+		}
+
+		for _, e := range cs.Entries {
+			if status := strings.TrimSpace(e.Status.Text + e.Status.Code); status != NotSetStringValue && status != "BOOK" {
+				continue
+			}
+			var st *StatementTransaction
+			if st, err = e.statementTransaction(ids); err != nil {
+				return nil, err
+			}
+			s.Transactions = append(s.Transactions, st)
+		}
+	}
+	s.OpeningBalanceDate = s.openingBalanceDate(openingDate)
+
+	return s, nil
+	//TODO: add test
+}
+
+// statementTransaction returns transaction of statement for the entry.
+// Details of the first transaction of entry are used (entries with many transactions are imported as one).
+func (e camtEntry) statementTransaction(ids map[string]int) (st *StatementTransaction, err error) {
+	st = new(StatementTransaction)
+	if st.Date, err = e.BookingDate.parse(); err != nil {
+		return nil, err
+	}
+	if st.ValueDate, err = e.ValueDate.parse(); err != nil {
+		return nil, err
+	}
+	if st.Value, err = camtValue(e.Amount, e.Sign, e.Reversal); err != nil {
+		return nil, err
+	}
+
+	var remittance []string
+	st.Reference = e.BankReference
+	if len(e.Details) > 0 {
+		d := e.Details[0]
+		if st.Reference == NotSetStringValue {
+			st.Reference = d.BankReference
+		}
+		if st.Reference == NotSetStringValue && d.EndToEndID != "NOTPROVIDED" {
+			st.Reference = d.EndToEndID
+		}
+		// The other party is creditor of debits and debtor of credits
+		if st.Value < 0 {
+			st.Counterparty, st.CounterpartyIBAN = d.Creditor.name(), d.CreditorAccount
+		} else {
+			st.Counterparty, st.CounterpartyIBAN = d.Debtor.name(), d.DebtorAccount
+		}
+		remittance = append(remittance, d.Unstructured...)
+		if len(remittance) == 0 && d.AdditionalTxInf != NotSetStringValue {
+			remittance = append(remittance, d.AdditionalTxInf)
+		}
+	}
+	if len(remittance) == 0 && e.AdditionalInf != NotSetStringValue {
+		remittance = append(remittance, e.AdditionalInf)
+	}
+	st.Description = statementDescription(strings.TrimSpace(st.Counterparty), strings.TrimSpace(strings.Join(remittance, " ")))
+
+	if st.Reference != NotSetStringValue {
+		st.ImportID = "camt:" + st.Reference
+	} else {
+		st.ImportID = fmt.Sprintf("camt:%s:%.2f:%s", st.Date.Format(DateFormat), st.Value, e.Reference+st.Description)
+	}
+	ids[st.ImportID]++
+	if n := ids[st.ImportID]; n > 1 {
+		st.ImportID = fmt.Sprintf("%s#%d", st.ImportID, n)
+	}
+
+	return st, nil
+}
+
+// parse returns date given as date or date and time (zero date if none is given)
+func (d camtDate) parse() (time.Time, error) {
+	s := d.Date
+	if s == NotSetStringValue {
+		s = d.DateTime
+	}
+	if s == NotSetStringValue {
+		return time.Time{}, nil
+	}
+	if len(s) > len(DateFormat) {
+		s = s[:len(DateFormat)]
+	}
+	t, err := time.Parse(DateFormat, s)
+	if err != nil {
+		return time.Time{}, errors.New(errStatementDate + s)
+	}
+
+	return t, nil
+}
+
+// camtValue returns value with sign given by credit/debit indicator (reversals change the sign)
+func camtValue(a camtAmount, sign string, reversal bool) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(a.Value), 64)
+	if err != nil {
+		return NotSetFloatValue, errors.New(errStatementAmount + a.Value)
+	}
+	if (sign == "DBIT") != reversal {
+		v = -v
+	}
+
+	return v, nil
+}
@@ -0,0 +1,109 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"strings"
+	"testing"
+)
+
+// camtFile returns camt.053 document with one statement holding given balances and entries
+func camtFile(balances, entries string) string {
+	return "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<Document xmlns=\"urn:iso:std:iso:20022:tech:xsd:camt.053.001.08\">" +
+		"<BkToCstmrStmt><Stmt><Id>1</Id><Acct><Id><IBAN>SE123</IBAN></Id><Ccy>SEK</Ccy></Acct>" +
+		balances + entries + "</Stmt></BkToCstmrStmt></Document>"
+}
+
+// camtBalances are opening and closing balances of statement of 2016-03-03
+const camtBalances = "<Bal><Tp><CdOrPrtry><Cd>OPBD</Cd></CdOrPrtry></Tp><Amt Ccy=\"SEK\">100.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2016-03-03</Dt></Dt></Bal>" +
+	"<Bal><Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp><Amt Ccy=\"SEK\">25.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2016-03-03</Dt></Dt></Bal>"
+
+func TestStatementReadCAMT053(t *testing.T) {
+	type transaction struct {
+		importID, date, description, counterparty string
+		value                                     float64
+	}
+	tests := []struct {
+		name         string
+		file         string
+		openingDate  string
+		transactions []transaction
+	}{
+		{
+			name: "batched entry with many transactions",
+			file: camtFile(camtBalances,
+				"<Ntry><Amt Ccy=\"SEK\">75.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts><Cd>BOOK</Cd></Sts><BookgDt><Dt>2016-03-03</Dt></BookgDt>"+
+					"<ValDt><Dt>2016-03-03</Dt></ValDt><AcctSvcrRef>BATCH1</AcctSvcrRef><NtryDtls>"+
+					"<TxDtls><Refs><EndToEndId>E1</EndToEndId></Refs><RltdPties><Cdtr><Nm>ICA</Nm></Cdtr></RltdPties><RmtInf><Ustrd>Invoice 12</Ustrd></RmtInf></TxDtls>"+
+					"<TxDtls><Refs><EndToEndId>E2</EndToEndId></Refs><RltdPties><Cdtr><Nm>Coop</Nm></Cdtr></RltdPties><RmtInf><Ustrd>Invoice 13</Ustrd></RmtInf></TxDtls>"+
+					"</NtryDtls><AddtlNtryInf>Payments batch</AddtlNtryInf></Ntry>"),
+			openingDate: "2016-03-02",
+			transactions: []transaction{
+				{importID: "camt:BATCH1", date: "2016-03-03", description: "ICA - Invoice 12", counterparty: "ICA", value: -75},
+			},
+		},
+		{
+			name: "repeated references and entries not booked",
+			file: camtFile(camtBalances,
+				"<Ntry><Amt Ccy=\"SEK\">50.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts>BOOK</Sts><BookgDt><Dt>2016-03-03</Dt></BookgDt><AcctSvcrRef>R1</AcctSvcrRef>"+
+					"<AddtlNtryInf>Card</AddtlNtryInf></Ntry>"+
+					"<Ntry><Amt Ccy=\"SEK\">25.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts>BOOK</Sts><BookgDt><Dt>2016-03-03</Dt></BookgDt><AcctSvcrRef>R1</AcctSvcrRef>"+
+					"<AddtlNtryInf>Card</AddtlNtryInf></Ntry>"+
+					"<Ntry><Amt Ccy=\"SEK\">5.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts>PDNG</Sts><BookgDt><Dt>2016-03-04</Dt></BookgDt></Ntry>"),
+			openingDate: "2016-03-02",
+			transactions: []transaction{
+				{importID: "camt:R1", date: "2016-03-03", description: "Card", value: -50},
+				{importID: "camt:R1#2", date: "2016-03-03", description: "Card", value: -25},
+			},
+		},
+		{
+			name:        "statement without entries",
+			file:        camtFile(camtBalances, ""),
+			openingDate: "2016-03-03",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := StatementReadCAMT053(strings.NewReader(tt.file))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if d := s.OpeningBalanceDate.Format(DateFormat); d != tt.openingDate {
+				t.Errorf("opening balance date = %s, want %s", d, tt.openingDate)
+			}
+			if len(s.Transactions) != len(tt.transactions) {
+				t.Fatalf("got %d transactions, want %d", len(s.Transactions), len(tt.transactions))
+			}
+			for i, want := range tt.transactions {
+				st := s.Transactions[i]
+				got := transaction{importID: st.ImportID, date: st.Date.Format(DateFormat), description: st.Description, counterparty: st.Counterparty, value: st.Value}
+				if got != want {
+					t.Errorf("transaction %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+// The same statement gives the same opening balance date in MT940 and camt.053
+func TestStatementOpeningBalanceDate(t *testing.T) {
+	mt940 := ":20:STMT1\n:25:SE123\n:28C:1/1\n:60F:C160302SEK100,00\n:61:1603030303DR75,00NTRFNONREF//R1\n:86:Card\n:62F:C160303SEK25,00\n-"
+	camt := camtFile(camtBalances,
+		"<Ntry><Amt Ccy=\"SEK\">75.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts>BOOK</Sts><BookgDt><Dt>2016-03-03</Dt></BookgDt><AcctSvcrRef>R1</AcctSvcrRef></Ntry>")
+
+	sm, err := StatementReadMT940(strings.NewReader(mt940))
+	if err != nil {
+		t.Fatalf("unexpected error of MT940: %v", err)
+	}
+	sc, err := StatementReadCAMT053(strings.NewReader(camt))
+	if err != nil {
+		t.Fatalf("unexpected error of camt.053: %v", err)
+	}
+	if !sm.OpeningBalanceDate.Equal(sc.OpeningBalanceDate) || sm.OpeningBalance != sc.OpeningBalance {
+		t.Errorf("MT940 opening balance %.2f on %s, camt.053 %.2f on %s", sm.OpeningBalance, sm.OpeningBalanceDate.Format(DateFormat),
+			sc.OpeningBalance, sc.OpeningBalanceDate.Format(DateFormat))
+	}
+}
@@ -8,14 +8,19 @@ import (
 	"database/sql"
 	"errors"
 	"github.com/zbroju/gsqlitehandler"
+	"sort"
+	"strings"
 )
 
-// Category represents the basic object for category
+// Category represents the basic object for category.
+// Categories build a tree: main categories are the top level nodes, categories with ParentId
+// equal to NotSetIntValue are placed directly under their main category and all the others under their parent category.
 type Category struct {
-	Id     int64
-	Main   *MainCategory
-	Name   string
-	Status ItemStatus
+	Id       int64
+	Main     *MainCategory
+	ParentId int64
+	Name     string
+	Status   ItemStatus
 }
 
 func CategoryNew() *Category {
@@ -30,14 +35,31 @@ func CategoryAdd(db *gsqlitehandler.SqliteDB, c *Category) error {
 	var err error
 	var stmt *sql.Stmt
 
-	if stmt, err = db.Handler.Prepare("INSERT INTO categories VALUES (NULL, ?, ?, ?);"); err != nil {
+	// Subcategory always belongs to the same main category as its parent
+	if c.ParentId != int64(NotSetIntValue) {
+		var p *Category
+		if p, err = CategoryForID(db, int(c.ParentId)); err != nil {
+			return err
+		}
+		c.Main = p.Main
+	}
+
+	if err = beforeChange(db); err != nil {
+		return err
+	}
+
+	if stmt, err = dbHandler(db).Prepare("INSERT INTO categories VALUES (NULL, ?, ?, ?, ?);"); err != nil {
 		return errors.New(errWritingToFile)
 	}
 	defer stmt.Close()
 
-	if _, err = stmt.Exec(c.Main.Id, c.Name, c.Status); err != nil {
+	var res sql.Result
+	if res, err = stmt.Exec(c.Main.Id, c.Name, c.Status, c.ParentId); err != nil {
 		return errors.New(errWritingToFile)
 	}
+	if id, err := res.LastInsertId(); err == nil {
+		c.Id = id
+	}
 
 	return nil
 
@@ -48,53 +70,71 @@ func CategoryAdd(db *gsqlitehandler.SqliteDB, c *Category) error {
 func CategoryForID(db *gsqlitehandler.SqliteDB, i int) (c *Category, err error) {
 	var stmt *sql.Stmt
 
-	sqlQuery := "SELECT c.id, c.name, c.status, m.id, m.name, m.status, t.id, t.name, t.factor " +
+	sqlQuery := "SELECT c.id, c.name, c.status, c.parent_id, m.id, m.name, m.status, t.id, t.name, t.factor " +
 		"FROM categories c INNER JOIN main_categories m ON c.main_category_id=m.id INNER JOIN main_categories_types t ON m.type_id=t.id " +
 		"WHERE c.id=? AND c.status<>?;"
-	if stmt, err = db.Handler.Prepare(sqlQuery); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 	defer stmt.Close()
 
 	c = CategoryNew()
-	if err = stmt.QueryRow(i, ISClose).Scan(&c.Id, &c.Name, &c.Status, &c.Main.Id, &c.Main.Name, &c.Main.Status, &c.Main.MType.Id, &c.Main.MType.Name, &c.Main.MType.Factor); err != nil {
+	if err = stmt.QueryRow(i, ISClose).Scan(&c.Id, &c.Name, &c.Status, &c.ParentId, &c.Main.Id, &c.Main.Name, &c.Main.Status, &c.Main.MType.Id, &c.Main.MType.Name, &c.Main.MType.Factor); err != nil {
 		return nil, errors.New(errCategoryWithIDNone)
 	}
 	return c, nil
 	//TODO: add test
 }
 
-// CategoryForName returns pointer to Category for given (part of) name
+// CategoryForName returns pointer to Category for given (part of) name.
+// Name can be given as a path (e.g. 'Housing:Utilities') in order to distinguish categories
+// by their main category and parents. Every part of the path can be given partially.
 func CategoryForName(db *gsqlitehandler.SqliteDB, n string) (c *Category, err error) {
 	var stmt *sql.Stmt
 	var rows *sql.Rows
 
-	n = "%" + n + "%"
-	sqlQuery := "SELECT c.id, c.name, c.status, m.id, m.name, m.status, t.id, t.name, t.factor " +
+	path := strings.Split(n, CategoryPathSeparator)
+	n = "%" + path[len(path)-1] + "%"
+	sqlQuery := "SELECT c.id, c.name, c.status, c.parent_id, m.id, m.name, m.status, t.id, t.name, t.factor " +
 		"FROM categories c INNER JOIN main_categories m ON c.main_category_id=m.id INNER JOIN main_categories_types t ON m.type_id=t.id " +
 		"WHERE c.name LIKE ? AND c.status<>?;"
-	if stmt, err = db.Handler.Prepare(sqlQuery); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 	defer stmt.Close()
 
-	c = CategoryNew()
 	if rows, err = stmt.Query(n, ISClose); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 	defer rows.Close()
 
-	var noOfCategories int
+	var candidates []*Category
 	for rows.Next() {
-		noOfCategories++
-		rows.Scan(&c.Id, &c.Name, &c.Status, &c.Main.Id, &c.Main.Name, &c.Main.Status, &c.Main.MType.Id, &c.Main.MType.Name, &c.Main.MType.Factor)
+		cc := CategoryNew()
+		rows.Scan(&cc.Id, &cc.Name, &cc.Status, &cc.ParentId, &cc.Main.Id, &cc.Main.Name, &cc.Main.Status, &cc.Main.MType.Id, &cc.Main.MType.Name, &cc.Main.MType.Factor)
+		candidates = append(candidates, cc)
 	}
 
-	switch noOfCategories {
+	// Filter out categories which ancestors do not match the rest of the path
+	if len(path) > 1 && len(candidates) > 0 {
+		var t *CategoryTree
+		if t, err = CategoryTreeGet(db); err != nil {
+			return nil, err
+		}
+		var matching []*Category
+		for _, cc := range candidates {
+			if t.matchesPath(cc, path) {
+				matching = append(matching, cc)
+			}
+		}
+		candidates = matching
+	}
+
+	switch len(candidates) {
 	case 0:
 		return nil, errors.New(errCategoryWithNameNone)
 	case 1:
-		return c, nil
+		return candidates[0], nil
 	default:
 		return nil, errors.New(errCategoryWithNameAmbiguous)
 	}
@@ -102,10 +142,12 @@ func CategoryForName(db *gsqlitehandler.SqliteDB, n string) (c *Category, err er
 	//TODO: add test
 }
 
-// CategoryEdit updates category with new values for name, main category and status
-// All three fields are updated, so make sure you pass old values in argument 'c'
+// CategoryEdit updates category with new values for name, main category, parent and status
+// All the fields are updated, so make sure you pass old values in argument 'c'.
+// Subcategories of c are moved together with it.
 func CategoryEdit(db *gsqlitehandler.SqliteDB, c *Category) error {
 	var err error
+	var tx *change
 	var stmt *sql.Stmt
 
 	// Check if it is not a system object
@@ -113,12 +155,47 @@ func CategoryEdit(db *gsqlitehandler.SqliteDB, c *Category) error {
 		return errors.New(errSystemObject)
 	}
 
-	if stmt, err = db.Handler.Prepare("UPDATE categories SET main_category_id=?, name=?, status=? WHERE id=?;"); err != nil {
+	// Check if the new parent is correct
+	if c.ParentId != int64(NotSetIntValue) {
+		var t *CategoryTree
+		if t, err = CategoryTreeGet(db); err != nil {
+			return err
+		}
+		if c.ParentId == c.Id || t.IsDescendant(c.ParentId, c.Id) {
+			return errors.New(errCategoryParentIncorrect)
+		}
+		var p *Category
+		if p, err = CategoryForID(db, int(c.ParentId)); err != nil {
+			return err
+		}
+		c.Main = p.Main
+	}
+
+	if tx, err = beginChange(db); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	defer tx.Rollback()
+
+	if stmt, err = tx.Prepare("UPDATE categories SET main_category_id=?, name=?, status=?, parent_id=? WHERE id=?;"); err != nil {
 		return errors.New(errWritingToFile)
 	}
 	defer stmt.Close()
 
-	if _, err = stmt.Exec(c.Main.Id, c.Name, c.Status, c.Id); err != nil {
+	if _, err = stmt.Exec(c.Main.Id, c.Name, c.Status, c.ParentId, c.Id); err != nil {
+		return errors.New(errWritingToFile)
+	}
+
+	// Move all subcategories to the same main category
+	if stmt, err = tx.Prepare(sqlCategoryDescendantsMainCategoryUpdate); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.Exec(c.Main.Id, c.Id); err != nil {
+		return errors.New(errWritingToFile)
+	}
+
+	if err = tx.Commit(); err != nil {
 		return errors.New(errWritingToFile)
 	}
 
@@ -126,9 +203,11 @@ func CategoryEdit(db *gsqlitehandler.SqliteDB, c *Category) error {
 	//TODO: add test
 }
 
-// CategoryRemove updates given category status with ISClose
+// CategoryRemove updates given category status with ISClose.
+// Its open subcategories are closed together with it.
 func CategoryRemove(db *gsqlitehandler.SqliteDB, c *Category) error {
 	var err error
+	var tx *change
 	var stmt *sql.Stmt
 
 	// Check if it is not a system object
@@ -136,8 +215,13 @@ func CategoryRemove(db *gsqlitehandler.SqliteDB, c *Category) error {
 		return errors.New(errSystemObject)
 	}
 
+	if tx, err = beginChange(db); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	defer tx.Rollback()
+
 	// Set correct status (ISClose)
-	if stmt, err = db.Handler.Prepare("UPDATE categories SET status=? WHERE id=?;"); err != nil {
+	if stmt, err = tx.Prepare("UPDATE categories SET status=? WHERE id=?;"); err != nil {
 		return errors.New(errWritingToFile)
 	}
 	defer stmt.Close()
@@ -146,11 +230,26 @@ func CategoryRemove(db *gsqlitehandler.SqliteDB, c *Category) error {
 		return errors.New(errWritingToFile)
 	}
 
+	// Close all its open subcategories
+	if stmt, err = tx.Prepare(sqlCategoryDescendantsClose); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.Exec(ISClose, c.Id, ISOpen); err != nil {
+		return errors.New(errWritingToFile)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.New(errWritingToFile)
+	}
+
 	return nil
 	//TODO: add test
 }
 
-// CategoryList returns all categories from file as closure
+// CategoryList returns all categories from file as closure.
+// Categories are ordered by main category and then by their path in the tree.
 func CategoryList(db *gsqlitehandler.SqliteDB, m *MainCategory, c string, s ItemStatus) (f func() *Category, err error) {
 	var stmt *sql.Stmt
 	var rows *sql.Rows
@@ -167,21 +266,18 @@ func CategoryList(db *gsqlitehandler.SqliteDB, m *MainCategory, c string, s Item
 		c = "%" + c + "%"
 	}
 
-	sqlQuery := "SELECT c.id, c.name, c.status, m.id, m.name, m.status, t.id, t.name, t.factor " +
-		"FROM categories c INNER JOIN main_categories m ON c.main_category_id=m.id INNER JOIN main_categories_types t ON m.type_id=t.id " +
-		"WHERE (m.id=? OR ?=?) AND (c.name LIKE ? OR ?=?) AND (c.status=? or ?=?) ORDER BY m.type_id, m.name, c.name;"
-	if stmt, err = db.Handler.Prepare(sqlQuery); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlCategoryList); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 
-	if rows, err = stmt.Query(mId, mId, noIntParamForSQL, c, c, noStringParamForSQL, s, s, ISUnset); err != nil {
+	if rows, err = stmt.Query(NotSetIntValue, CategoryPathSeparator, mId, mId, noIntParamForSQL, c, c, noStringParamForSQL, s, s, ISUnset); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 
 	f = func() *Category {
 		if rows.Next() {
 			c := CategoryNew()
-			rows.Scan(&c.Id, &c.Name, &c.Status, &c.Main.Id, &c.Main.Name, &c.Main.Status, &c.Main.MType.Id, &c.Main.MType.Name, &c.Main.MType.Factor)
+			rows.Scan(&c.Id, &c.Name, &c.Status, &c.ParentId, &c.Main.Id, &c.Main.Name, &c.Main.Status, &c.Main.MType.Id, &c.Main.MType.Name, &c.Main.MType.Factor)
 			return c
 		}
 		rows.Close()
@@ -194,4 +290,168 @@ func CategoryList(db *gsqlitehandler.SqliteDB, m *MainCategory, c string, s Item
 	//TODO: add test
 }
 
+// CategoryTree keeps all categories (including removed ones) in memory in order to walk the hierarchy.
+type CategoryTree struct {
+	categories map[int64]*Category
+}
+
+// CategoryTreeGet returns the tree of all categories from data file
+func CategoryTreeGet(db *gsqlitehandler.SqliteDB) (t *CategoryTree, err error) {
+	var getNextCategory func() *Category
+	if getNextCategory, err = CategoryList(db, nil, NotSetStringValue, ISUnset); err != nil {
+		return nil, err
+	}
+
+	t = new(CategoryTree)
+	t.categories = make(map[int64]*Category)
+	for c := getNextCategory(); c != nil; c = getNextCategory() {
+		t.categories[c.Id] = c
+	}
+
+	return t, nil
+	//TODO: add test
+}
+
+// ancestors returns category c followed by all its ancestors, up to the top level category
+func (t *CategoryTree) ancestors(c *Category) []*Category {
+	l := []*Category{c}
+	visited := map[int64]bool{c.Id: true}
+	for p, ok := t.categories[c.ParentId]; ok && !visited[p.Id]; p, ok = t.categories[p.ParentId] {
+		visited[p.Id] = true
+		l = append(l, p)
+	}
+
+	return l
+}
+
+// Path returns names of category c and all its ancestors (without main category) separated with CategoryPathSeparator
+func (t *CategoryTree) Path(c *Category) string {
+	if tc, ok := t.categories[c.Id]; ok {
+		c = tc
+	}
+	a := t.ancestors(c)
+	names := make([]string, len(a))
+	for i, p := range a {
+		names[len(a)-1-i] = p.Name
+	}
+
+	return strings.Join(names, CategoryPathSeparator)
+}
+
+// FullPath returns the same as Path, but starting with the name of main category
+func (t *CategoryTree) FullPath(c *Category) string {
+	return c.Main.Name + CategoryPathSeparator + t.Path(c)
+}
+
+// Depth returns the level of category c in the tree (1 for categories placed directly under main category)
+func (t *CategoryTree) Depth(c *Category) int {
+	if tc, ok := t.categories[c.Id]; ok {
+		c = tc
+	}
+
+	return len(t.ancestors(c))
+}
+
+// Ancestor returns the ancestor of category c placed on given level of the tree,
+// or c itself if it is placed on this level or higher.
+func (t *CategoryTree) Ancestor(c *Category, depth int) *Category {
+	if tc, ok := t.categories[c.Id]; ok {
+		c = tc
+	}
+	a := t.ancestors(c)
+	if depth < 1 || depth >= len(a) {
+		return c
+	}
+
+	return a[len(a)-depth]
+}
+
+// IsDescendant checks if category with id i is placed (directly or not) under category with id p
+func (t *CategoryTree) IsDescendant(i, p int64) bool {
+	c, ok := t.categories[i]
+	if !ok {
+		return false
+	}
+	for _, a := range t.ancestors(c)[1:] {
+		if a.Id == p {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesPath checks if the closest ancestors of category c (and finally its main category) contain
+// subsequent parts of the path p.
+func (t *CategoryTree) matchesPath(c *Category, p []string) bool {
+	names := []string{}
+	for _, a := range t.ancestors(c) {
+		names = append(names, a.Name)
+	}
+	names = append(names, c.Main.Name)
+	if len(p) > len(names) {
+		return false
+	}
+	for i := range p {
+		n, part := names[i], p[len(p)-1-i]
+		if !strings.Contains(strings.ToLower(n), strings.ToLower(part)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// rollUp maps categories cs to their ancestors on given level of the tree.
+// It returns the list of distinct categories representing the groups (ordered by main category as in cs and then by path)
+// and for each element of cs the index of the group it belongs to. Depth equal to NotSetIntValue means no roll up.
+func (t *CategoryTree) rollUp(cs []*Category, depth int) (groups []*Category, idx []int) {
+	mainOrder := make(map[int64]int)
+	groupIdx := make(map[int64]int)
+	idx = make([]int, len(cs))
+	for i, c := range cs {
+		if _, ok := mainOrder[c.Main.Id]; !ok {
+			mainOrder[c.Main.Id] = len(mainOrder)
+		}
+		a := c
+		if depth != NotSetIntValue {
+			a = t.Ancestor(c, depth)
+		}
+		gi, ok := groupIdx[a.Id]
+		if !ok {
+			g := new(Category)
+			*g = *a
+			g.Main = c.Main
+			gi = len(groups)
+			groupIdx[a.Id] = gi
+			groups = append(groups, g)
+		}
+		idx[i] = gi
+	}
+
+	// Order the groups
+	order := make([]int, len(groups))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		gi, gj := groups[order[i]], groups[order[j]]
+		if mainOrder[gi.Main.Id] != mainOrder[gj.Main.Id] {
+			return mainOrder[gi.Main.Id] < mainOrder[gj.Main.Id]
+		}
+		return t.Path(gi) < t.Path(gj)
+	})
+	newPosition := make([]int, len(groups))
+	sorted := make([]*Category, len(groups))
+	for pos, gi := range order {
+		newPosition[gi] = pos
+		sorted[pos] = groups[gi]
+	}
+	for i := range idx {
+		idx[i] = newPosition[idx[i]]
+	}
+
+	return sorted, idx
+}
+
 //FIXME: make sure all 'list' functions are consistent with 'LIKE' or '=' for other objects, e.g. LIKE name vs name=?
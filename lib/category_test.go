@@ -0,0 +1,50 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"testing"
+)
+
+// Subcategories are moved and closed together with their category
+func TestCategoryEditRemoveSubtree(t *testing.T) {
+	db := newTestDataFile(t)
+	food := newTestCategory(t, db, "Food", nil)
+	fruit := newTestCategory(t, db, "Fruit", food)
+	apples := newTestCategory(t, db, "Apples", fruit)
+	other := newTestCategory(t, db, "Other", nil)
+
+	category := func(c *Category) (mId int64, status ItemStatus) {
+		if err := dbHandler(db).QueryRow("SELECT main_category_id, status FROM categories WHERE id=?;", c.Id).Scan(&mId, &status); err != nil {
+			t.Fatalf("reading category: %v", err)
+		}
+		return mId, status
+	}
+
+	fruit.ParentId = other.Id
+	if err := CategoryEdit(db, fruit); err != nil {
+		t.Fatalf("editing category: %v", err)
+	}
+	for _, c := range []*Category{fruit, apples} {
+		if mId, _ := category(c); mId != other.Main.Id {
+			t.Errorf("main category of %s = %d, want %d", c.Name, mId, other.Main.Id)
+		}
+	}
+	if mId, _ := category(food); mId != food.Main.Id {
+		t.Errorf("main category of Food = %d, want %d", mId, food.Main.Id)
+	}
+
+	if err := CategoryRemove(db, other); err != nil {
+		t.Fatalf("removing category: %v", err)
+	}
+	for _, c := range []*Category{other, fruit, apples} {
+		if _, s := category(c); s != ISClose {
+			t.Errorf("status of %s = %d, want %d", c.Name, s, ISClose)
+		}
+	}
+	if _, s := category(food); s != ISOpen {
+		t.Errorf("status of Food = %d, want %d", s, ISOpen)
+	}
+}
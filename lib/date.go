@@ -0,0 +1,176 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"errors"
+	"github.com/zbroju/gsqlitehandler"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Relative dates: number of days, weeks, months or years from today (e.g. -3d, +2w, -1m)
+var dateRelative = regexp.MustCompile(`^([+-]?\d+)([dwmy])$`)
+
+// DateParse returns date given by expression s (see DateRangeParse). Expressions of periods give their first day.
+func DateParse(db *gsqlitehandler.SqliteDB, s string) (time.Time, error) {
+	d, _, err := DateRangeParse(db, s)
+
+	return d, err
+	//TODO: add test
+}
+
+// DateRangeParse returns the first and the last day of period given by expression s, relative to today:
+// dates (yyyy-mm-dd), months (yyyy-mm), years (yyyy), today, yesterday, tomorrow, numbers of days, weeks,
+// months or years from today (-3d, +2w, -1m, -1y), weekdays (last-monday, this-friday, next-sunday),
+// periods (this-, last- or next- week, month, quarter or year) and periods to date (wtd, mtd, qtd, ytd).
+// Words may be separated with space, hyphen or underscore and are not case sensitive.
+// For single days both dates are the same. Weeks start on Monday, years and quarters
+// with the first month of fiscal year of data file opened with db (yyyy/yy is also accepted for fiscal years);
+// with db nil they are calendar years and quarters.
+func DateRangeParse(db *gsqlitehandler.SqliteDB, s string) (from, to time.Time, err error) {
+	return dateRangeParse(s, time.Now(), fiscalYearStartOf(db))
+}
+
+// dateRangeParse returns period given by expression s relative to now, with fiscal years starting in month fy
+func dateRangeParse(s string, now time.Time, fy time.Month) (from, to time.Time, err error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	day := func(d time.Time) (time.Time, time.Time, error) {
+		return d, d, nil
+	}
+
+	s = strings.TrimSpace(s)
+	e := strings.ToLower(strings.Join(strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == '-' || r == '_' }), "-"))
+
+	// Calendar dates
+	if d, err := time.Parse(DateFormat, s); err == nil {
+		return day(d)
+	}
+	if d, err := time.Parse("2006"+DateSeparator+"01", s); err == nil {
+		return d, d.AddDate(0, 1, -1), nil
+	}
+	if d, err := time.Parse("2006", s); err == nil {
+		from = time.Date(d.Year(), fy, 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(1, 0, -1), nil
+	}
+	if y, ok := fiscalYearParse(s); ok {
+		from = time.Date(y, fy, 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(1, 0, -1), nil
+	}
+
+	// Days relative to today
+	switch e {
+	case "today":
+		return day(today)
+	case "yesterday":
+		return day(today.AddDate(0, 0, -1))
+	case "tomorrow":
+		return day(today.AddDate(0, 0, 1))
+	}
+	// Days missing in shorter months are replaced by their last days (e.g. -1m on March 31st gives February 28th or 29th)
+	if m := dateRelative.FindStringSubmatch(strings.ToLower(s)); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		switch m[2] {
+		case "d":
+			return day(dateAdd(today, GranularityDay, n))
+		case "w":
+			return day(dateAdd(today, GranularityWeek, n))
+		case "m":
+			return day(dateAdd(today, GranularityMonth, n))
+		default:
+			return day(dateAdd(today, GranularityYear, n))
+		}
+	}
+
+	// Periods to date
+	switch e {
+	case "wtd":
+		return dateWeekStart(today), today, nil
+	case "mtd":
+		return dateMonthStart(today), today, nil
+	case "qtd":
+		return dateQuarterStart(today, fy), today, nil
+	case "ytd":
+		return dateYearStart(today, fy), today, nil
+	}
+
+	// Weekdays and periods: this, last or next one
+	words := strings.SplitN(e, "-", 2)
+	if len(words) != 2 {
+		return from, to, errors.New(errDateIncorrect + s)
+	}
+	var n int
+	switch words[0] {
+	case "this":
+		n = 0
+	case "last":
+		n = -1
+	case "next":
+		n = 1
+	default:
+		return from, to, errors.New(errDateIncorrect + s)
+	}
+	switch words[1] {
+	case "week":
+		from = dateWeekStart(today).AddDate(0, 0, 7*n)
+		return from, from.AddDate(0, 0, 6), nil
+	case "month":
+		from = dateMonthStart(today).AddDate(0, n, 0)
+		return from, from.AddDate(0, 1, -1), nil
+	case "quarter":
+		from = dateQuarterStart(today, fy).AddDate(0, 3*n, 0)
+		return from, from.AddDate(0, 3, -1), nil
+	case "year":
+		from = dateYearStart(today, fy).AddDate(n, 0, 0)
+		return from, from.AddDate(1, 0, -1), nil
+	}
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		if words[1] != strings.ToLower(wd.String()) {
+			continue
+		}
+		// The last and the next weekday are the closest ones before and after today, this one is in the current week
+		offset := (int(wd) - int(today.Weekday()) + 7) % 7
+		switch n {
+		case -1:
+			offset -= 7
+		case 0:
+			offset = (int(wd)+6)%7 - (int(today.Weekday())+6)%7
+		case 1:
+			if offset == 0 {
+				offset = 7
+			}
+		}
+		return day(today.AddDate(0, 0, offset))
+	}
+
+	return from, to, errors.New(errDateIncorrect + s)
+}
+
+// dateWeekStart returns Monday of the week of day d
+func dateWeekStart(d time.Time) time.Time {
+	return d.AddDate(0, 0, -((int(d.Weekday()) + 6) % 7))
+}
+
+// dateMonthStart returns the first day of the month of day d
+func dateMonthStart(d time.Time) time.Time {
+	return time.Date(d.Year(), d.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// dateQuarterStart returns the first day of the quarter (of fiscal year starting in month fy) of day d
+func dateQuarterStart(d time.Time, fy time.Month) time.Time {
+	return time.Date(d.Year(), d.Month()-dateFiscalMonth(d, fy)%3, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// dateYearStart returns the first day of the fiscal year (starting in month fy) of day d
+func dateYearStart(d time.Time, fy time.Month) time.Time {
+	return time.Date(d.Year(), d.Month()-dateFiscalMonth(d, fy), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// dateFiscalMonth returns number of months from the beginning of fiscal year (starting in month fy) to the month of day d (0-11)
+func dateFiscalMonth(d time.Time, fy time.Month) time.Month {
+	return (d.Month() - fy + 12) % 12
+}
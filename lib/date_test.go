@@ -0,0 +1,107 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDateRangeParse(t *testing.T) {
+	// Thursday
+	now := time.Date(2016, 3, 31, 15, 4, 5, 0, time.Local)
+
+	tests := []struct {
+		s        string
+		now      time.Time
+		from, to string
+		err      bool
+	}{
+		{s: "2016-03-05", from: "2016-03-05", to: "2016-03-05"},
+		{s: "2016-02", from: "2016-02-01", to: "2016-02-29"},
+		{s: "2015", from: "2015-01-01", to: "2015-12-31"},
+		{s: "today", from: "2016-03-31", to: "2016-03-31"},
+		{s: "Yesterday", from: "2016-03-30", to: "2016-03-30"},
+		{s: "tomorrow", from: "2016-04-01", to: "2016-04-01"},
+		{s: "-3d", from: "2016-03-28", to: "2016-03-28"},
+		{s: "+2w", from: "2016-04-14", to: "2016-04-14"},
+		{s: "-1m", from: "2016-02-29", to: "2016-02-29"},
+		{s: "1m", from: "2016-04-30", to: "2016-04-30"},
+		{s: "+1m", now: time.Date(2016, 1, 31, 0, 0, 0, 0, time.UTC), from: "2016-02-29", to: "2016-02-29"},
+		{s: "-1y", now: time.Date(2016, 2, 29, 0, 0, 0, 0, time.UTC), from: "2015-02-28", to: "2015-02-28"},
+		{s: "+4y", now: time.Date(2016, 2, 29, 0, 0, 0, 0, time.UTC), from: "2020-02-29", to: "2020-02-29"},
+		{s: "wtd", from: "2016-03-28", to: "2016-03-31"},
+		{s: "mtd", from: "2016-03-01", to: "2016-03-31"},
+		{s: "qtd", from: "2016-01-01", to: "2016-03-31"},
+		{s: "ytd", from: "2016-01-01", to: "2016-03-31"},
+		{s: "last-week", from: "2016-03-21", to: "2016-03-27"},
+		{s: "Last Month", from: "2016-02-01", to: "2016-02-29"},
+		{s: "this_month", from: "2016-03-01", to: "2016-03-31"},
+		{s: "next-quarter", from: "2016-04-01", to: "2016-06-30"},
+		{s: "last-year", from: "2015-01-01", to: "2015-12-31"},
+		{s: "last-monday", from: "2016-03-28", to: "2016-03-28"},
+		{s: "last-thursday", from: "2016-03-24", to: "2016-03-24"},
+		{s: "this-friday", from: "2016-04-01", to: "2016-04-01"},
+		{s: "this-sunday", from: "2016-04-03", to: "2016-04-03"},
+		{s: "next-thursday", from: "2016-04-07", to: "2016-04-07"},
+		{s: "soon", err: true},
+		{s: "last-fortnight", err: true},
+		{s: "+3x", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			n := now
+			if !tt.now.IsZero() {
+				n = tt.now
+			}
+			from, to, err := dateRangeParse(tt.s, n, time.January)
+			if tt.err {
+				if err == nil {
+					t.Errorf("got %s - %s, want error", from.Format(DateFormat), to.Format(DateFormat))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if f, l := from.Format(DateFormat), to.Format(DateFormat); f != tt.from || l != tt.to {
+				t.Errorf("got %s - %s, want %s - %s", f, l, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestDateRangeParseFiscalYear(t *testing.T) {
+	// the second quarter of fiscal year 2016/17
+	now := time.Date(2016, 11, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		s        string
+		from, to string
+	}{
+		{s: "2016/17", from: "2016-07-01", to: "2017-06-30"},
+		{s: "2016", from: "2016-07-01", to: "2017-06-30"},
+		{s: "qtd", from: "2016-10-01", to: "2016-11-15"},
+		{s: "ytd", from: "2016-07-01", to: "2016-11-15"},
+		{s: "this-quarter", from: "2016-10-01", to: "2016-12-31"},
+		{s: "last-quarter", from: "2016-07-01", to: "2016-09-30"},
+		{s: "next-quarter", from: "2017-01-01", to: "2017-03-31"},
+		{s: "last-year", from: "2015-07-01", to: "2016-06-30"},
+		{s: "2016-11", from: "2016-11-01", to: "2016-11-30"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			from, to, err := dateRangeParse(tt.s, now, time.July)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if f, l := from.Format(DateFormat), to.Format(DateFormat); f != tt.from || l != tt.to {
+				t.Errorf("got %s - %s, want %s - %s", f, l, tt.from, tt.to)
+			}
+		})
+	}
+}
@@ -0,0 +1,643 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/zbroju/gsqlitehandler"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dump is the whole contents of data file, table by table, with rows ordered by their keys
+// so that dumps of the same data are identical. Properties give version of data file structure.
+type Dump struct {
+	Properties            map[string]string          `json:"properties"`
+	MainCategoryTypes     []DumpMainCategoryType     `json:"main_category_types"`
+	MainCategories        []DumpMainCategory         `json:"main_categories"`
+	Categories            []DumpCategory             `json:"categories"`
+	Accounts              []DumpAccount              `json:"accounts"`
+	Currencies            []DumpCurrency             `json:"currencies"`
+	Transactions          []DumpTransaction          `json:"transactions"`
+	Budgets               []DumpBudget               `json:"budgets"`
+	ImportedTransactions  []DumpImportedTransaction  `json:"imported_transactions"`
+	DismissedDuplicates   []DumpDismissedDuplicate   `json:"dismissed_duplicates"`
+	Attachments           []DumpAttachment           `json:"attachments"`
+	Settings              []DumpSetting              `json:"settings"`
+	RecurringTransactions []DumpRecurringTransaction `json:"recurring_transactions"`
+	Goals                 []DumpGoal                 `json:"goals"`
+	GoalsAccounts         []DumpGoalAccount          `json:"goals_accounts"`
+}
+
+// Rows of tables, with the same fields as columns of data file
+type DumpMainCategoryType struct {
+	Id           int64  `json:"id"`
+	Name         string `json:"name"`
+	Factor       int64  `json:"factor"`
+	InIncomeCost int64  `json:"in_income_cost"`
+	InBudget     int64  `json:"in_budget"`
+	InNetValue   int64  `json:"in_net_value"`
+	Status       int64  `json:"status"`
+}
+
+type DumpMainCategory struct {
+	Id     int64  `json:"id"`
+	TypeId int64  `json:"type_id"`
+	Name   string `json:"name"`
+	Status int64  `json:"status"`
+}
+
+type DumpCategory struct {
+	Id             int64  `json:"id"`
+	MainCategoryId int64  `json:"main_category_id"`
+	ParentId       int64  `json:"parent_id"`
+	Name           string `json:"name"`
+	Status         int64  `json:"status"`
+}
+
+type DumpAccount struct {
+	Id          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Institution string `json:"institution"`
+	Currency    string `json:"currency"`
+	Type        int64  `json:"type"`
+	Status      int64  `json:"status"`
+}
+
+type DumpCurrency struct {
+	CurrencyFrom string  `json:"currency_from"`
+	CurrencyTo   string  `json:"currency_to"`
+	ExchangeRate float64 `json:"exchange_rate"`
+}
+
+type DumpTransaction struct {
+	Id          int64   `json:"id"`
+	Date        string  `json:"date"`
+	AccountId   int64   `json:"account_id"`
+	CategoryId  int64   `json:"category_id"`
+	Description string  `json:"description"`
+	Value       float64 `json:"value"`
+}
+
+type DumpBudget struct {
+	Year       int64   `json:"year"`
+	Month      int64   `json:"month"`
+	CategoryId int64   `json:"category_id"`
+	Value      float64 `json:"value"`
+	Currency   string  `json:"currency"`
+}
+
+type DumpImportedTransaction struct {
+	AccountId        int64  `json:"account_id"`
+	ImportId         string `json:"import_id"`
+	TransactionId    int64  `json:"transaction_id"`
+	Reference        string `json:"reference"`
+	ValueDate        string `json:"value_date"`
+	Counterparty     string `json:"counterparty"`
+	CounterpartyIBAN string `json:"counterparty_iban"`
+}
+
+type DumpDismissedDuplicate struct {
+	TransactionId int64 `json:"transaction_id"`
+	OtherId       int64 `json:"other_id"`
+}
+
+// Contents of attachments is written in base64
+type DumpAttachment struct {
+	Id       int64  `json:"id"`
+	Object   string `json:"object"`
+	ObjectId int64  `json:"object_id"`
+	Name     string `json:"name"`
+	MIMEType string `json:"mime_type"`
+	Hash     string `json:"hash"`
+	Size     int64  `json:"size"`
+	Added    string `json:"added"`
+	Data     []byte `json:"data"`
+}
+
+type DumpSetting struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type DumpRecurringTransaction struct {
+	Id          int64   `json:"id"`
+	AccountId   int64   `json:"account_id"`
+	CategoryId  int64   `json:"category_id"`
+	Description string  `json:"description"`
+	Value       float64 `json:"value"`
+	DateFrom    string  `json:"date_from"`
+	DateTo      string  `json:"date_to"`
+	Granularity string  `json:"granularity"`
+	Every       int64   `json:"every"`
+}
+
+type DumpGoal struct {
+	Id         int64   `json:"id"`
+	Name       string  `json:"name"`
+	Value      float64 `json:"value"`
+	Currency   string  `json:"currency"`
+	DateFrom   string  `json:"date_from"`
+	DateTo     string  `json:"date_to"`
+	CategoryId int64   `json:"category_id"`
+}
+
+type DumpGoalAccount struct {
+	GoalId    int64 `json:"goal_id"`
+	AccountId int64 `json:"account_id"`
+}
+
+// DumpGet reads the whole contents of data file
+func DumpGet(db *gsqlitehandler.SqliteDB) (d *Dump, err error) {
+	// Empty tables are given as empty lists rather than null
+	d = &Dump{
+		Properties:            make(map[string]string),
+		MainCategoryTypes:     []DumpMainCategoryType{},
+		MainCategories:        []DumpMainCategory{},
+		Categories:            []DumpCategory{},
+		Accounts:              []DumpAccount{},
+		Currencies:            []DumpCurrency{},
+		Transactions:          []DumpTransaction{},
+		Budgets:               []DumpBudget{},
+		ImportedTransactions:  []DumpImportedTransaction{},
+		DismissedDuplicates:   []DumpDismissedDuplicate{},
+		Attachments:           []DumpAttachment{},
+		Settings:              []DumpSetting{},
+		RecurringTransactions: []DumpRecurringTransaction{},
+		Goals:                 []DumpGoal{},
+		GoalsAccounts:         []DumpGoalAccount{},
+	}
+	for k, v := range dataFileProperties {
+		d.Properties[k] = v
+	}
+
+	queries := []struct {
+		query string
+		scan  func(rows *sql.Rows) error
+	}{
+		{"SELECT id, name, factor, in_income_cost, in_budget, in_net_value, status FROM main_categories_types ORDER BY id;", func(rows *sql.Rows) error {
+			var r DumpMainCategoryType
+			err := rows.Scan(&r.Id, &r.Name, &r.Factor, &r.InIncomeCost, &r.InBudget, &r.InNetValue, &r.Status)
+			d.MainCategoryTypes = append(d.MainCategoryTypes, r)
+			return err
+		}},
+		{"SELECT id, type_id, name, status FROM main_categories ORDER BY id;", func(rows *sql.Rows) error {
+			var r DumpMainCategory
+			err := rows.Scan(&r.Id, &r.TypeId, &r.Name, &r.Status)
+			d.MainCategories = append(d.MainCategories, r)
+			return err
+		}},
+		{"SELECT id, main_category_id, parent_id, name, status FROM categories ORDER BY id;", func(rows *sql.Rows) error {
+			var r DumpCategory
+			err := rows.Scan(&r.Id, &r.MainCategoryId, &r.ParentId, &r.Name, &r.Status)
+			d.Categories = append(d.Categories, r)
+			return err
+		}},
+		{"SELECT id, name, description, institution, currency, type, status FROM accounts ORDER BY id;", func(rows *sql.Rows) error {
+			var r DumpAccount
+			err := rows.Scan(&r.Id, &r.Name, &r.Description, &r.Institution, &r.Currency, &r.Type, &r.Status)
+			d.Accounts = append(d.Accounts, r)
+			return err
+		}},
+		{"SELECT currency_from, currency_to, exchange_rate FROM currencies ORDER BY currency_from, currency_to;", func(rows *sql.Rows) error {
+			var r DumpCurrency
+			err := rows.Scan(&r.CurrencyFrom, &r.CurrencyTo, &r.ExchangeRate)
+			d.Currencies = append(d.Currencies, r)
+			return err
+		}},
+		{"SELECT id, date, account_id, category_id, description, value FROM transactions ORDER BY id;", func(rows *sql.Rows) error {
+			var r DumpTransaction
+			err := rows.Scan(&r.Id, &r.Date, &r.AccountId, &r.CategoryId, &r.Description, &r.Value)
+			d.Transactions = append(d.Transactions, r)
+			return err
+		}},
+		{"SELECT year, month, category_id, value, currency FROM budgets ORDER BY year, month, category_id;", func(rows *sql.Rows) error {
+			var r DumpBudget
+			err := rows.Scan(&r.Year, &r.Month, &r.CategoryId, &r.Value, &r.Currency)
+			d.Budgets = append(d.Budgets, r)
+			return err
+		}},
+		{"SELECT account_id, import_id, transaction_id, reference, value_date, counterparty, counterparty_iban FROM imported_transactions ORDER BY account_id, import_id;", func(rows *sql.Rows) error {
+			var r DumpImportedTransaction
+			err := rows.Scan(&r.AccountId, &r.ImportId, &r.TransactionId, &r.Reference, &r.ValueDate, &r.Counterparty, &r.CounterpartyIBAN)
+			d.ImportedTransactions = append(d.ImportedTransactions, r)
+			return err
+		}},
+		{"SELECT transaction_id, other_id FROM dismissed_duplicates ORDER BY transaction_id, other_id;", func(rows *sql.Rows) error {
+			var r DumpDismissedDuplicate
+			err := rows.Scan(&r.TransactionId, &r.OtherId)
+			d.DismissedDuplicates = append(d.DismissedDuplicates, r)
+			return err
+		}},
+		{"SELECT id, object, object_id, name, mime_type, hash, size, added, data FROM attachments ORDER BY id;", func(rows *sql.Rows) error {
+			var r DumpAttachment
+			err := rows.Scan(&r.Id, &r.Object, &r.ObjectId, &r.Name, &r.MIMEType, &r.Hash, &r.Size, &r.Added, &r.Data)
+			d.Attachments = append(d.Attachments, r)
+			return err
+		}},
+		{"SELECT key, value FROM settings ORDER BY key;", func(rows *sql.Rows) error {
+			var r DumpSetting
+			err := rows.Scan(&r.Key, &r.Value)
+			d.Settings = append(d.Settings, r)
+			return err
+		}},
+		{"SELECT id, account_id, category_id, description, value, date_from, date_to, granularity, every FROM recurring_transactions ORDER BY id;", func(rows *sql.Rows) error {
+			var r DumpRecurringTransaction
+			err := rows.Scan(&r.Id, &r.AccountId, &r.CategoryId, &r.Description, &r.Value, &r.DateFrom, &r.DateTo, &r.Granularity, &r.Every)
+			d.RecurringTransactions = append(d.RecurringTransactions, r)
+			return err
+		}},
+		{"SELECT id, name, value, currency, date_from, date_to, category_id FROM goals ORDER BY id;", func(rows *sql.Rows) error {
+			var r DumpGoal
+			err := rows.Scan(&r.Id, &r.Name, &r.Value, &r.Currency, &r.DateFrom, &r.DateTo, &r.CategoryId)
+			d.Goals = append(d.Goals, r)
+			return err
+		}},
+		{"SELECT goal_id, account_id FROM goals_accounts ORDER BY goal_id, account_id;", func(rows *sql.Rows) error {
+			var r DumpGoalAccount
+			err := rows.Scan(&r.GoalId, &r.AccountId)
+			d.GoalsAccounts = append(d.GoalsAccounts, r)
+			return err
+		}},
+	}
+	for _, q := range queries {
+		if err = dumpRows(db, q.query, q.scan); err != nil {
+			return nil, err
+		}
+	}
+
+	return d, nil
+	//TODO: add test
+}
+
+// dumpRows calls scan for each row returned by query
+func dumpRows(db *gsqlitehandler.SqliteDB, query string, scan func(rows *sql.Rows) error) error {
+	rows, err := dbHandler(db).Query(query)
+	if err != nil {
+		return errors.New(errReadingFromFile)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err = scan(rows); err != nil {
+			return errors.New(errReadingFromFile)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return errors.New(errReadingFromFile)
+	}
+
+	return nil
+}
+
+// DumpWrite writes dump as indented JSON
+func DumpWrite(d *Dump, w io.Writer) error {
+	b, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	_, err = w.Write(b)
+
+	return err
+	//TODO: add test
+}
+
+// DumpRead reads dump written by DumpWrite and checks that it can be restored:
+// it must come from data file of this or older version and all the references between rows must be correct
+func DumpRead(r io.Reader) (d *Dump, err error) {
+	d = new(Dump)
+	if err = json.NewDecoder(r).Decode(d); err != nil {
+		return nil, errors.New(errDumpFormat)
+	}
+	if d.Properties["applicationName"] != dataFileProperties["applicationName"] {
+		return nil, errors.New(errDumpFormat)
+	}
+	if dumpVersionNewer(d.Properties["databaseVersion"], dataFileProperties["databaseVersion"]) {
+		return nil, errors.New(errDumpVersion + d.Properties["databaseVersion"])
+	}
+	if err = d.check(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+	//TODO: add test
+}
+
+// dumpVersionNewer checks if version v (e.g. 2.1) is newer than version current
+func dumpVersionNewer(v, current string) bool {
+	vp, cp := strings.Split(v, "."), strings.Split(current, ".")
+	for i := 0; i < len(vp) || i < len(cp); i++ {
+		var vn, cn int
+		if i < len(vp) {
+			vn, _ = strconv.Atoi(vp[i])
+		}
+		if i < len(cp) {
+			cn, _ = strconv.Atoi(cp[i])
+		}
+		if vn != cn {
+			return vn > cn
+		}
+	}
+
+	return false
+}
+
+// check returns error describing the first incorrect reference between rows of dump (or repeated key)
+func (d *Dump) check() error {
+	bad := func(format string, a ...interface{}) error {
+		return errors.New(errDumpReference + fmt.Sprintf(format, a...))
+	}
+
+	types := make(map[int64]bool)
+	for _, r := range d.MainCategoryTypes {
+		if types[r.Id] {
+			return bad("main category type %d repeated", r.Id)
+		}
+		types[r.Id] = true
+	}
+	mainCategories := make(map[int64]bool)
+	for _, r := range d.MainCategories {
+		if mainCategories[r.Id] {
+			return bad("main category %d repeated", r.Id)
+		}
+		if !types[r.TypeId] {
+			return bad("main category %d has unknown type %d", r.Id, r.TypeId)
+		}
+		mainCategories[r.Id] = true
+	}
+	parents := make(map[int64]int64)
+	for _, r := range d.Categories {
+		if _, ok := parents[r.Id]; ok {
+			return bad("category %d repeated", r.Id)
+		}
+		if !mainCategories[r.MainCategoryId] {
+			return bad("category %d has unknown main category %d", r.Id, r.MainCategoryId)
+		}
+		parents[r.Id] = r.ParentId
+	}
+	for _, r := range d.Categories {
+		// Each category must lead to the top of the tree through existing parents
+		p := r.ParentId
+		for i := 0; p != int64(NotSetIntValue); i++ {
+			next, ok := parents[p]
+			if !ok {
+				return bad("category %d has unknown parent %d", r.Id, p)
+			}
+			if i > len(parents) {
+				return bad("category %d is its own parent", r.Id)
+			}
+			p = next
+		}
+	}
+	accounts := make(map[int64]bool)
+	for _, r := range d.Accounts {
+		if accounts[r.Id] {
+			return bad("account %d repeated", r.Id)
+		}
+		accounts[r.Id] = true
+	}
+	currencies := make(map[[2]string]bool)
+	for _, r := range d.Currencies {
+		if currencies[[2]string{r.CurrencyFrom, r.CurrencyTo}] {
+			return bad("exchange rate %s/%s repeated", r.CurrencyFrom, r.CurrencyTo)
+		}
+		currencies[[2]string{r.CurrencyFrom, r.CurrencyTo}] = true
+	}
+	transactions := make(map[int64]bool)
+	for _, r := range d.Transactions {
+		if transactions[r.Id] {
+			return bad("transaction %d repeated", r.Id)
+		}
+		if _, err := time.Parse(DateFormat, r.Date); err != nil {
+			return bad("transaction %d has incorrect date %s", r.Id, r.Date)
+		}
+		if !accounts[r.AccountId] {
+			return bad("transaction %d has unknown account %d", r.Id, r.AccountId)
+		}
+		if _, ok := parents[r.CategoryId]; !ok {
+			return bad("transaction %d has unknown category %d", r.Id, r.CategoryId)
+		}
+		transactions[r.Id] = true
+	}
+	budgets := make(map[[3]int64]bool)
+	for _, r := range d.Budgets {
+		if budgets[[3]int64{r.Year, r.Month, r.CategoryId}] {
+			return bad("budget %d-%d for category %d repeated", r.Year, r.Month, r.CategoryId)
+		}
+		if _, ok := parents[r.CategoryId]; !ok {
+			return bad("budget %d-%d has unknown category %d", r.Year, r.Month, r.CategoryId)
+		}
+		budgets[[3]int64{r.Year, r.Month, r.CategoryId}] = true
+	}
+	imported := make(map[int64]map[string]bool)
+	for _, r := range d.ImportedTransactions {
+		if imported[r.AccountId] == nil {
+			imported[r.AccountId] = make(map[string]bool)
+		}
+		if imported[r.AccountId][r.ImportId] {
+			return bad("imported transaction %s repeated", r.ImportId)
+		}
+		if !accounts[r.AccountId] || !transactions[r.TransactionId] {
+			return bad("imported transaction %s has unknown account %d or transaction %d", r.ImportId, r.AccountId, r.TransactionId)
+		}
+		imported[r.AccountId][r.ImportId] = true
+	}
+	for _, r := range d.DismissedDuplicates {
+		if !transactions[r.TransactionId] || !transactions[r.OtherId] {
+			return bad("dismissed duplicate has unknown transaction %d or %d", r.TransactionId, r.OtherId)
+		}
+	}
+	attachments := make(map[int64]bool)
+	for _, r := range d.Attachments {
+		if attachments[r.Id] {
+			return bad("attachment %d repeated", r.Id)
+		}
+		switch {
+		case r.Object == AttachmentTransaction && transactions[r.ObjectId]:
+		case r.Object == AttachmentAccount && accounts[r.ObjectId]:
+		default:
+			return bad("attachment %d has unknown %s %d", r.Id, r.Object, r.ObjectId)
+		}
+		if sum := sha256.Sum256(r.Data); hex.EncodeToString(sum[:]) != r.Hash {
+			return bad("attachment %d does not match its hash", r.Id)
+		}
+		attachments[r.Id] = true
+	}
+	settings := make(map[string]bool)
+	for _, r := range d.Settings {
+		if settings[r.Key] {
+			return bad("setting %s repeated", r.Key)
+		}
+		if n, err := strconv.Atoi(r.Value); r.Key == settingFiscalYearStart && (err != nil || n < 1 || n > 12) {
+			return bad("setting %s has incorrect value %s", r.Key, r.Value)
+		}
+		settings[r.Key] = true
+	}
+	recurring := make(map[int64]bool)
+	for _, r := range d.RecurringTransactions {
+		if recurring[r.Id] {
+			return bad("recurring transaction %d repeated", r.Id)
+		}
+		if !accounts[r.AccountId] {
+			return bad("recurring transaction %d has unknown account %d", r.Id, r.AccountId)
+		}
+		if _, ok := parents[r.CategoryId]; !ok {
+			return bad("recurring transaction %d has unknown category %d", r.Id, r.CategoryId)
+		}
+		if _, err := time.Parse(DateFormat, r.DateFrom); err != nil {
+			return bad("recurring transaction %d has incorrect date %s", r.Id, r.DateFrom)
+		}
+		if _, err := time.Parse(DateFormat, r.DateTo); r.DateTo != NotSetStringValue && err != nil {
+			return bad("recurring transaction %d has incorrect date %s", r.Id, r.DateTo)
+		}
+		if _, err := GranularityParse(r.Granularity); err != nil || r.Every < 1 {
+			return bad("recurring transaction %d has incorrect period %d %s", r.Id, r.Every, r.Granularity)
+		}
+		recurring[r.Id] = true
+	}
+	goals := make(map[int64]bool)
+	for _, r := range d.Goals {
+		if goals[r.Id] {
+			return bad("goal %d repeated", r.Id)
+		}
+		if _, ok := parents[r.CategoryId]; r.CategoryId != int64(NotSetIntValue) && !ok {
+			return bad("goal %d has unknown category %d", r.Id, r.CategoryId)
+		}
+		for _, s := range []string{r.DateFrom, r.DateTo} {
+			if _, err := time.Parse(DateFormat, s); err != nil {
+				return bad("goal %d has incorrect date %s", r.Id, s)
+			}
+		}
+		goals[r.Id] = true
+	}
+	goalsAccounts := make(map[[2]int64]bool)
+	for _, r := range d.GoalsAccounts {
+		if goalsAccounts[[2]int64{r.GoalId, r.AccountId}] {
+			return bad("account %d of goal %d repeated", r.AccountId, r.GoalId)
+		}
+		if !goals[r.GoalId] || !accounts[r.AccountId] {
+			return bad("goal account has unknown goal %d or account %d", r.GoalId, r.AccountId)
+		}
+		goalsAccounts[[2]int64{r.GoalId, r.AccountId}] = true
+	}
+
+	return nil
+}
+
+// DumpRestore writes the contents of dump to new data file, replacing the system objects created with it
+func DumpRestore(db *gsqlitehandler.SqliteDB, d *Dump) error {
+	var err error
+	var tx *change
+
+	if tx, err = beginChange(db); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	defer tx.Rollback()
+
+	exec := func(query string, args ...interface{}) error {
+		stmt, err := tx.Prepare(query)
+		if err != nil {
+			return errors.New(errWritingToFile)
+		}
+		defer stmt.Close()
+		if _, err = stmt.Exec(args...); err != nil {
+			return errors.New(errWritingToFile)
+		}
+		return nil
+	}
+
+	for _, t := range []string{"main_categories_types", "main_categories", "categories", "accounts", "currencies", "transactions", "budgets", "imported_transactions", "dismissed_duplicates", "attachments", "settings", "recurring_transactions", "goals", "goals_accounts"} {
+		if err = exec("DELETE FROM " + t + ";"); err != nil {
+			return err
+		}
+	}
+	for _, r := range d.MainCategoryTypes {
+		if err = exec("INSERT INTO main_categories_types (id, name, factor, in_income_cost, in_budget, in_net_value, status) VALUES (?, ?, ?, ?, ?, ?, ?);", r.Id, r.Name, r.Factor, r.InIncomeCost, r.InBudget, r.InNetValue, r.Status); err != nil {
+			return err
+		}
+	}
+	for _, r := range d.MainCategories {
+		if err = exec("INSERT INTO main_categories (id, type_id, name, status) VALUES (?, ?, ?, ?);", r.Id, r.TypeId, r.Name, r.Status); err != nil {
+			return err
+		}
+	}
+	for _, r := range d.Categories {
+		if err = exec("INSERT INTO categories (id, main_category_id, parent_id, name, status) VALUES (?, ?, ?, ?, ?);", r.Id, r.MainCategoryId, r.ParentId, r.Name, r.Status); err != nil {
+			return err
+		}
+	}
+	for _, r := range d.Accounts {
+		if err = exec("INSERT INTO accounts (id, name, description, institution, currency, type, status) VALUES (?, ?, ?, ?, ?, ?, ?);", r.Id, r.Name, r.Description, r.Institution, r.Currency, r.Type, r.Status); err != nil {
+			return err
+		}
+	}
+	for _, r := range d.Currencies {
+		if err = exec("INSERT INTO currencies (currency_from, currency_to, exchange_rate) VALUES (?, ?, ?);", r.CurrencyFrom, r.CurrencyTo, r.ExchangeRate); err != nil {
+			return err
+		}
+	}
+	for _, r := range d.Transactions {
+		if err = exec("INSERT INTO transactions (id, date, account_id, category_id, description, value) VALUES (?, ?, ?, ?, ?, ?);", r.Id, r.Date, r.AccountId, r.CategoryId, r.Description, r.Value); err != nil {
+			return err
+		}
+	}
+	for _, r := range d.Budgets {
+		if err = exec("INSERT INTO budgets (year, month, category_id, value, currency) VALUES (?, ?, ?, ?, ?);", r.Year, r.Month, r.CategoryId, r.Value, r.Currency); err != nil {
+			return err
+		}
+	}
+	for _, r := range d.ImportedTransactions {
+		if err = exec("INSERT INTO imported_transactions (account_id, import_id, transaction_id, reference, value_date, counterparty, counterparty_iban) VALUES (?, ?, ?, ?, ?, ?, ?);", r.AccountId, r.ImportId, r.TransactionId, r.Reference, r.ValueDate, r.Counterparty, r.CounterpartyIBAN); err != nil {
+			return err
+		}
+	}
+	for _, r := range d.DismissedDuplicates {
+		if err = exec("INSERT INTO dismissed_duplicates (transaction_id, other_id) VALUES (?, ?);", r.TransactionId, r.OtherId); err != nil {
+			return err
+		}
+	}
+	for _, r := range d.Attachments {
+		if err = exec("INSERT INTO attachments (id, object, object_id, name, mime_type, hash, size, added, data) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);", r.Id, r.Object, r.ObjectId, r.Name, r.MIMEType, r.Hash, r.Size, r.Added, r.Data); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range d.Settings {
+		if err = exec("INSERT INTO settings (key, value) VALUES (?, ?);", r.Key, r.Value); err != nil {
+			return err
+		}
+	}
+	for _, r := range d.RecurringTransactions {
+		if err = exec("INSERT INTO recurring_transactions (id, account_id, category_id, description, value, date_from, date_to, granularity, every) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);", r.Id, r.AccountId, r.CategoryId, r.Description, r.Value, r.DateFrom, r.DateTo, r.Granularity, r.Every); err != nil {
+			return err
+		}
+	}
+	for _, r := range d.Goals {
+		if err = exec("INSERT INTO goals (id, name, value, currency, date_from, date_to, category_id) VALUES (?, ?, ?, ?, ?, ?, ?);", r.Id, r.Name, r.Value, r.Currency, r.DateFrom, r.DateTo, r.CategoryId); err != nil {
+			return err
+		}
+	}
+	for _, r := range d.GoalsAccounts {
+		if err = exec("INSERT INTO goals_accounts (goal_id, account_id) VALUES (?, ?);", r.GoalId, r.AccountId); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.New(errWritingToFile)
+	}
+
+	return fiscalYearLoad(db)
+	//TODO: add test
+}
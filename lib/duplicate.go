@@ -0,0 +1,311 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/zbroju/gsqlitehandler"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Default criteria of duplicates: the greatest number of days between transactions and the least similarity of their descriptions
+const (
+	DuplicateDays       = 3
+	DuplicateSimilarity = 0.5
+)
+
+// Duplicate is a pair of transactions which may be double entries of the same transaction
+// (the same account and value, close dates and similar descriptions). First is the one with lower ID.
+type Duplicate struct {
+	First      *Transaction
+	Second     *Transaction
+	Similarity float64
+}
+
+// DuplicateList returns candidate duplicates as closure: pairs of transactions of account a (all accounts if nil)
+// with the same value, dates differing by at most days and descriptions with similarity of at least similarity (0-1).
+// Only pairs with at least one transaction between dates df and dt (zero dates mean no limit) are returned.
+// Pairs dismissed before and pairs of transactions both imported from bank statements are skipped.
+func DuplicateList(db *gsqlitehandler.SqliteDB, a *Account, df, dt time.Time, days int, similarity float64) (f func() *Duplicate, err error) {
+	window := time.Duration(days) * 24 * time.Hour
+
+	// Transactions close to the dates are needed to find their duplicates
+	lf, lt := df, dt
+	if !lf.IsZero() {
+		lf = lf.Add(-window)
+	}
+	if !lt.IsZero() {
+		lt = lt.Add(window)
+	}
+	var getNextTransaction func() *Transaction
+	if getNextTransaction, err = TransactionList(db, lf, lt, a, NotSetStringValue, nil, nil); err != nil {
+		return nil, err
+	}
+	byAccount := make(map[int64][]*Transaction)
+	for t := getNextTransaction(); t != nil; t = getNextTransaction() {
+		byAccount[t.Account.Id] = append(byAccount[t.Account.Id], t)
+	}
+
+	var imported, dismissed map[[2]int64]bool
+	if imported, err = duplicateIDs(db, "SELECT transaction_id, 0 FROM imported_transactions;"); err != nil {
+		return nil, err
+	}
+	if dismissed, err = duplicateIDs(db, "SELECT transaction_id, other_id FROM dismissed_duplicates;"); err != nil {
+		return nil, err
+	}
+
+	inRange := func(t *Transaction) bool {
+		return (df.IsZero() || !t.Date.Before(df)) && (dt.IsZero() || !t.Date.After(dt))
+	}
+
+	// Transactions are sorted by date, so the other ones are checked until they are too late
+	var l []*Duplicate
+	for _, ts := range byAccount {
+		for i, t := range ts {
+			for _, o := range ts[i+1:] {
+				if o.Date.Sub(t.Date) > window {
+					break
+				}
+				if math.Abs(o.GetSValue()-t.GetSValue()) >= 0.005 || !(inRange(t) || inRange(o)) {
+					continue
+				}
+				d := &Duplicate{First: t, Second: o}
+				if o.Id < t.Id {
+					d.First, d.Second = o, t
+				}
+				if dismissed[[2]int64{d.First.Id, d.Second.Id}] || (imported[[2]int64{t.Id, 0}] && imported[[2]int64{o.Id, 0}]) {
+					continue
+				}
+				if d.Similarity = DescriptionSimilarity(t.Description, o.Description); d.Similarity >= similarity {
+					l = append(l, d)
+				}
+			}
+		}
+	}
+	sortDuplicates(l)
+
+	f = func() *Duplicate {
+		if len(l) == 0 {
+			return nil
+		}
+		d := l[0]
+		l = l[1:]
+		return d
+	}
+
+	return f, nil
+	//TODO: add test
+}
+
+// sortDuplicates sorts duplicates by date and IDs of their transactions
+func sortDuplicates(l []*Duplicate) {
+	sort.Slice(l, func(i, j int) bool {
+		a, b := l[i], l[j]
+		switch {
+		case !a.First.Date.Equal(b.First.Date):
+			return a.First.Date.Before(b.First.Date)
+		case a.First.Id != b.First.Id:
+			return a.First.Id < b.First.Id
+		}
+		return a.Second.Id < b.Second.Id
+	})
+}
+
+// duplicateIDs returns set of pairs of IDs read with query
+func duplicateIDs(db *gsqlitehandler.SqliteDB, query string) (ids map[[2]int64]bool, err error) {
+	var rows *sql.Rows
+	if rows, err = dbHandler(db).Query(query); err != nil {
+		return nil, errors.New(errReadingFromFile)
+	}
+	defer rows.Close()
+
+	ids = make(map[[2]int64]bool)
+	for rows.Next() {
+		var id [2]int64
+		if err = rows.Scan(&id[0], &id[1]); err != nil {
+			return nil, errors.New(errReadingFromFile)
+		}
+		ids[id] = true
+	}
+
+	return ids, nil
+}
+
+// DuplicateForIDs returns pair of transactions with given IDs
+func DuplicateForIDs(db *gsqlitehandler.SqliteDB, i1, i2 int) (d *Duplicate, err error) {
+	if i1 == i2 {
+		return nil, errors.New(errDuplicateSameTransaction)
+	}
+	if i1 > i2 {
+		i1, i2 = i2, i1
+	}
+
+	d = new(Duplicate)
+	if d.First, err = TransactionForID(db, i1); err != nil {
+		return nil, err
+	}
+	if d.Second, err = TransactionForID(db, i2); err != nil {
+		return nil, err
+	}
+	d.Similarity = DescriptionSimilarity(d.First.Description, d.Second.Description)
+
+	return d, nil
+	//TODO: add test
+}
+
+// DuplicateDismiss remembers that transactions of d are not duplicates, so that they are not listed again
+func DuplicateDismiss(db *gsqlitehandler.SqliteDB, d *Duplicate) error {
+	var err error
+	var stmt *sql.Stmt
+
+	if err = beforeChange(db); err != nil {
+		return err
+	}
+
+	if stmt, err = dbHandler(db).Prepare("INSERT OR IGNORE INTO dismissed_duplicates VALUES (?, ?);"); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.Exec(d.First.Id, d.Second.Id); err != nil {
+		return errors.New(errWritingToFile)
+	}
+
+	return nil
+	//TODO: add test
+}
+
+// DuplicateMerge keeps the first transaction of d and removes the second one. Links of the second one with bank statements
+// and its attachments are moved to the first one (so it is not imported again) and its description is kept if the first one has none.
+// Only transactions of the same account and value can be merged (see duplicateMergeable).
+func DuplicateMerge(db *gsqlitehandler.SqliteDB, d *Duplicate) error {
+	var err error
+	var tx *change
+
+	if err = duplicateMergeable(d); err != nil {
+		return err
+	}
+
+	if tx, err = beginChange(db); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	defer tx.Rollback()
+
+	queries := []struct {
+		query string
+		args  []interface{}
+	}{
+		{"UPDATE transactions SET description=? WHERE id=? AND description='';", []interface{}{d.Second.Description, d.First.Id}},
+		{"UPDATE imported_transactions SET transaction_id=? WHERE transaction_id=?;", []interface{}{d.First.Id, d.Second.Id}},
+		{"DELETE FROM dismissed_duplicates WHERE transaction_id=? OR other_id=?;", []interface{}{d.Second.Id, d.Second.Id}},
+		{"UPDATE attachments SET object_id=? WHERE object=? AND object_id=?;", []interface{}{d.First.Id, AttachmentTransaction, d.Second.Id}},
+		{"DELETE FROM transactions WHERE id=?;", []interface{}{d.Second.Id}},
+	}
+	for _, q := range queries {
+		var stmt *sql.Stmt
+		if stmt, err = tx.Prepare(q.query); err != nil {
+			return errors.New(errWritingToFile)
+		}
+		_, err = stmt.Exec(q.args...)
+		stmt.Close()
+		if err != nil {
+			return errors.New(errWritingToFile)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.New(errWritingToFile)
+	}
+
+	return nil
+	//TODO: add test
+}
+
+// duplicateMergeable checks if the second transaction of d can be removed as duplicate of the first one:
+// they have the same account and value (so they are not two sides of a transfer), and the second one is not
+// a transfer unless the first one is (otherwise the other side of the transfer would be left alone)
+func duplicateMergeable(d *Duplicate) error {
+	switch {
+	case d.First.Account.Id != d.Second.Account.Id:
+		return errors.New(errDuplicateAccount)
+	case math.Abs(d.First.GetSValue()-d.Second.GetSValue()) >= 0.005:
+		return errors.New(errDuplicateValue)
+	case d.Second.Category.Id == SOCategoryTransferID && d.First.Category.Id != SOCategoryTransferID:
+		return errors.New(errDuplicateTransfer)
+	}
+
+	return nil
+}
+
+// DescriptionSimilarity returns similarity of descriptions from 0 (different) to 1 (the same),
+// ignoring case and characters other than letters and digits. Description contained in the other one
+// is the same. Empty description gives 0.5, as it may describe anything.
+func DescriptionSimilarity(s1, s2 string) float64 {
+	n1, n2 := normalizeDescription(s1), normalizeDescription(s2)
+	switch {
+	case n1 == NotSetStringValue && n2 == NotSetStringValue:
+		return 1
+	case n1 == NotSetStringValue || n2 == NotSetStringValue:
+		return 0.5
+	case strings.Contains(n1, n2) || strings.Contains(n2, n1):
+		return 1
+	}
+
+	r1, r2 := []rune(n1), []rune(n2)
+	l := len(r1)
+	if len(r2) > l {
+		l = len(r2)
+	}
+
+	return 1 - float64(levenshtein(r1, r2))/float64(l)
+}
+
+// normalizeDescription returns description in lower case with letters and digits only
+func normalizeDescription(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
+
+// levenshtein returns edit distance between r1 and r2 (number of inserted, removed or replaced characters)
+func levenshtein(r1, r2 []rune) int {
+	prev := make([]int, len(r2)+1)
+	cur := make([]int, len(r2)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(r1); i++ {
+		cur[0] = i
+		for j := 1; j <= len(r2); j++ {
+			cost := 1
+			if r1[i-1] == r2[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(r2)]
+}
+
+// minInt returns the least of given integers
+func minInt(n int, l ...int) int {
+	for _, v := range l {
+		if v < n {
+			n = v
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,67 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"testing"
+	"time"
+)
+
+// duplicateTransaction returns transaction of cost category (or transfer) with given ID, account, value and day of March 2016
+func duplicateTransaction(id, account int64, value float64, day int, transfer bool) *Transaction {
+	t := TransactionNew()
+	t.Id, t.Account.Id, t.Value = id, account, value
+	t.Date = time.Date(2016, 3, day, 0, 0, 0, 0, time.UTC)
+	t.Category.Id, t.Category.Main.MType.Factor = 20, -1
+	if transfer {
+		t.Category.Id, t.Category.Main.MType.Factor = SOCategoryTransferID, 1
+		t.Value = -value
+	}
+
+	return t
+}
+
+func TestDuplicateMergeable(t *testing.T) {
+	tests := []struct {
+		name          string
+		first, second *Transaction
+		want          string
+	}{
+		{name: "the same account and value", first: duplicateTransaction(1, 1, 10, 1, false), second: duplicateTransaction(2, 1, 10, 2, false)},
+		{name: "different accounts", first: duplicateTransaction(1, 1, 10, 1, false), second: duplicateTransaction(2, 2, 10, 1, false), want: errDuplicateAccount},
+		{name: "different values", first: duplicateTransaction(1, 1, 10, 1, false), second: duplicateTransaction(2, 1, 10.5, 1, false), want: errDuplicateValue},
+		{name: "two sides of transfer", first: duplicateTransaction(1, 1, 10, 1, true), second: duplicateTransaction(2, 2, -10, 1, true), want: errDuplicateAccount},
+		{name: "transfer into cost", first: duplicateTransaction(1, 1, 10, 1, false), second: duplicateTransaction(2, 1, 10, 1, true), want: errDuplicateTransfer},
+		{name: "cost into transfer", first: duplicateTransaction(1, 1, 10, 1, true), second: duplicateTransaction(2, 1, 10, 1, false)},
+		{name: "transfer into transfer", first: duplicateTransaction(1, 1, 10, 1, true), second: duplicateTransaction(2, 1, 10, 2, true)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := duplicateMergeable(&Duplicate{First: tt.first, Second: tt.second})
+			switch {
+			case tt.want == NotSetStringValue && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.want != NotSetStringValue && (err == nil || err.Error() != tt.want):
+				t.Errorf("error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortDuplicates(t *testing.T) {
+	d := func(id1 int64, day1 int, id2 int64) *Duplicate {
+		return &Duplicate{First: duplicateTransaction(id1, 1, 10, day1, false), Second: duplicateTransaction(id2, 1, 10, day1, false)}
+	}
+	l := []*Duplicate{d(5, 3, 9), d(4, 1, 8), d(5, 3, 6), d(2, 3, 7), d(1, 2, 3)}
+	sortDuplicates(l)
+
+	want := [][2]int64{{4, 8}, {1, 3}, {2, 7}, {5, 6}, {5, 9}}
+	for i, w := range want {
+		if got := [2]int64{l[i].First.Id, l[i].Second.Id}; got != w {
+			t.Errorf("duplicate %d = %v, want %v", i, got, w)
+		}
+	}
+}
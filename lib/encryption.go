@@ -0,0 +1,369 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/zbroju/gprops"
+	"github.com/zbroju/gsqlitehandler"
+	"golang.org/x/crypto/scrypt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Encrypted data file starts with header, followed by salt of the key, nonce and the data file encrypted with AES-256-GCM
+const (
+	encryptedHeader    = "financoj encrypted data file v1\n"
+	encryptedSaltSize  = 16
+	encryptedNonceSize = 12
+	sqliteHeader       = "SQLite format 3\x00"
+)
+
+// Sources of passphrase of encrypted data files (before asking the user)
+const (
+	envPassphrase = "FINANCOJ_PASSPHRASE"
+	envKeyFile    = "FINANCOJ_KEYFILE"
+	confKeyFile   = "KEY_FILE"
+)
+
+// PassphrasePrompt asks the user for passphrase of encrypted data file (twice for new passphrase, if confirm is set).
+// It is set by user interface and used when the passphrase is given neither in environment variable nor in key file.
+var PassphrasePrompt func(file string, confirm bool) ([]byte, error)
+
+// encryptedDataFile is encrypted data file used through decrypted copy. The copy is kept in memory
+// based file system where possible, readable for the user only, and removed when data file is closed.
+type encryptedDataFile struct {
+	path      string
+	plainPath string
+	key       []byte
+	salt      []byte
+	sum       [sha256.Size]byte
+}
+
+// Encrypted data files used through data file handlers
+var encryptedDataFiles = make(map[*gsqlitehandler.SqliteDB]*encryptedDataFile)
+
+// newDataFileHandler returns handler for data file, working on decrypted copy of encrypted data file
+func newDataFileHandler(filePath string) *gsqlitehandler.SqliteDB {
+	if !isEncryptedFile(filePath) {
+		db := gsqlitehandler.New(filePath, dataFileProperties)
+		dataFilePaths[db] = filePath
+		return db
+	}
+
+	dir := os.TempDir()
+	if fi, err := os.Stat("/dev/shm"); err == nil && fi.IsDir() {
+		dir = "/dev/shm"
+	}
+	random := make([]byte, 8)
+	rand.Read(random)
+	ef := &encryptedDataFile{path: filePath, plainPath: filepath.Join(dir, AppName+"-"+hex.EncodeToString(random)+".db")}
+	db := gsqlitehandler.New(ef.plainPath, dataFileProperties)
+	encryptedDataFiles[db] = ef
+	dataFilePaths[db] = filePath
+
+	return db
+}
+
+// decrypt writes decrypted copy of encrypted data file
+func (ef *encryptedDataFile) decrypt() error {
+	data, err := os.ReadFile(ef.path)
+	if err != nil {
+		return err
+	}
+	passphrase, err := dataFilePassphrase(ef.path, false)
+	if err != nil {
+		return err
+	}
+	var plain []byte
+	if plain, ef.key, ef.salt, err = decryptData(data, passphrase); err != nil {
+		return err
+	}
+	ef.sum = sha256.Sum256(plain)
+
+	f, err := os.OpenFile(ef.plainPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(plain); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// close encrypts decrypted copy back to data file if it was changed and removes the copy
+func (ef *encryptedDataFile) close() error {
+	defer os.Remove(ef.plainPath + "-journal")
+	defer os.Remove(ef.plainPath + "-wal")
+	defer os.Remove(ef.plainPath + "-shm")
+	defer os.Remove(ef.plainPath)
+
+	plain, err := os.ReadFile(ef.plainPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if sha256.Sum256(plain) == ef.sum {
+		return nil
+	}
+	data, err := encryptData(plain, ef.key, ef.salt)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(ef.path, data)
+}
+
+// CloseDataFile closes data file and removes its lock. Changes of encrypted data file are encrypted and saved.
+func CloseDataFile(db *gsqlitehandler.SqliteDB) error {
+	err := db.Close()
+	if cerr := closeDecryptedCopy(db); err == nil {
+		err = cerr
+	}
+	unlockDataFile(db)
+	delete(dataFileBackups, db)
+	delete(fiscalYearStarts, db)
+
+	return err
+	//TODO: add test
+}
+
+// closeDecryptedCopy saves and removes decrypted copy of encrypted data file (if it is one)
+func closeDecryptedCopy(db *gsqlitehandler.SqliteDB) error {
+	ef, ok := encryptedDataFiles[db]
+	if !ok {
+		return nil
+	}
+	delete(encryptedDataFiles, db)
+
+	return ef.close()
+}
+
+// CloseDataFiles closes all the opened data files, so that no decrypted copies nor locks are left
+// when the application ends without closing them (e.g. because of error)
+func CloseDataFiles() {
+	for db := range dataFileLocks {
+		CloseDataFile(db)
+	}
+	for db := range encryptedDataFiles {
+		closeDecryptedCopy(db)
+	}
+}
+
+// DataFileEncrypt encrypts data file with new passphrase
+func DataFileEncrypt(filePath string) error {
+	return withDataFileLock(filePath, func() error { return dataFileEncrypt(filePath) })
+	//TODO: add test
+}
+
+// dataFileEncrypt encrypts data file, which is locked
+func dataFileEncrypt(filePath string) error {
+	// Changes left in write-ahead log (see useConcurrentAccess) are moved to the data file before
+	if err := checkpointDataFile(filePath); err != nil {
+		return err
+	}
+	plain, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	if isEncryptedFile(filePath) {
+		return errors.New(errDataFileEncrypted)
+	}
+	if !bytes.HasPrefix(plain, []byte(sqliteHeader)) {
+		return errors.New(errDataFileNotSQLite)
+	}
+	passphrase, err := dataFilePassphrase(filePath, true)
+	if err != nil {
+		return err
+	}
+	salt := make([]byte, encryptedSaltSize)
+	if _, err = rand.Read(salt); err != nil {
+		return err
+	}
+	key, err := encryptionKey(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	data, err := encryptData(plain, key, salt)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filePath, data)
+}
+
+// DataFileDecrypt decrypts data file, so that it can be used without passphrase
+func DataFileDecrypt(filePath string) error {
+	return withDataFileLock(filePath, func() error { return dataFileDecrypt(filePath) })
+	//TODO: add test
+}
+
+// dataFileDecrypt decrypts data file, which is locked
+func dataFileDecrypt(filePath string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	if !isEncryptedFile(filePath) {
+		return errors.New(errDataFileNotEncrypted)
+	}
+	passphrase, err := dataFilePassphrase(filePath, false)
+	if err != nil {
+		return err
+	}
+	plain, _, _, err := decryptData(data, passphrase)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filePath, plain)
+}
+
+// isEncryptedFile checks if file starts with header of encrypted data file
+func isEncryptedFile(filePath string) bool {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	header := make([]byte, len(encryptedHeader))
+	if _, err = io.ReadFull(f, header); err != nil {
+		return false
+	}
+
+	return string(header) == encryptedHeader
+}
+
+// dataFilePassphrase returns passphrase of data file from environment variable, key file (given in environment
+// variable or in config file) or, if there is none, asks the user for it
+func dataFilePassphrase(filePath string, confirm bool) ([]byte, error) {
+	if p := os.Getenv(envPassphrase); p != NotSetStringValue {
+		return []byte(p), nil
+	}
+
+	keyFile := os.Getenv(envKeyFile)
+	if keyFile == NotSetStringValue {
+		var configSettings *gprops.Props
+		var err error
+		if configSettings, err = readConfigFile(); err != nil {
+			return nil, err
+		}
+		keyFile = configSettings.GetOrDefault(confKeyFile, NotSetStringValue)
+	}
+	if keyFile != NotSetStringValue {
+		p, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		// Key files written by text editors end with new line, which is not part of the passphrase
+		return []byte(strings.TrimRight(string(p), "\r\n")), nil
+	}
+
+	if PassphrasePrompt == nil {
+		return nil, errors.New(errPassphraseMissing)
+	}
+	p, err := PassphrasePrompt(filePath, confirm)
+	if err != nil {
+		return nil, err
+	}
+	if len(p) == 0 {
+		return nil, errors.New(errPassphraseMissing)
+	}
+
+	return p, nil
+}
+
+// encryptionKey derives AES-256 key from passphrase with scrypt
+func encryptionKey(passphrase, salt []byte) ([]byte, error) {
+	return scrypt.Key(passphrase, salt, 1<<15, 8, 1, 32)
+}
+
+// encryptData returns header, salt, new nonce and data encrypted with key
+func encryptData(plain, key, salt []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, encryptedNonceSize)
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	data := append([]byte(encryptedHeader), salt...)
+	data = append(data, nonce...)
+
+	// Header and salt are authenticated together with the data
+	return gcm.Seal(data, nonce, plain, data[:len(encryptedHeader)+encryptedSaltSize]), nil
+}
+
+// decryptData returns data decrypted with key derived from passphrase, and the key and its salt
+func decryptData(data, passphrase []byte) (plain, key, salt []byte, err error) {
+	prefix := len(encryptedHeader) + encryptedSaltSize
+	if len(data) < prefix+encryptedNonceSize || string(data[:len(encryptedHeader)]) != encryptedHeader {
+		return nil, nil, nil, errors.New(errDataFileNotEncrypted)
+	}
+	salt = data[len(encryptedHeader):prefix]
+	nonce := data[prefix : prefix+encryptedNonceSize]
+
+	if key, err = encryptionKey(passphrase, salt); err != nil {
+		return nil, nil, nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if plain, err = gcm.Open(nil, nonce, data[prefix+encryptedNonceSize:], data[:prefix]); err != nil {
+		return nil, nil, nil, errors.New(errPassphraseWrong)
+	}
+
+	return plain, key, salt, nil
+}
+
+// writeFileAtomic replaces contents of file, so that it is either old or new one even if writing fails
+func writeFileAtomic(filePath string, data []byte) error {
+	mode := os.FileMode(0600)
+	if fi, err := os.Stat(filePath); err == nil {
+		mode = fi.Mode().Perm()
+	}
+
+	tmp := filePath + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, filePath)
+}
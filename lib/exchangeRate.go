@@ -28,8 +28,12 @@ func ExchangeRateAdd(db *gsqlitehandler.SqliteDB, e *ExchangeRate) error {
 		return errors.New(errExchangeRateAlreadyExists)
 	}
 
+	if err = beforeChange(db); err != nil {
+		return err
+	}
+
 	// Add new currency exchange rate
-	if stmt, err = db.Handler.Prepare("INSERT into currencies VALUES (upper(?), upper(?), round(?,4));"); err != nil {
+	if stmt, err = dbHandler(db).Prepare("INSERT into currencies VALUES (upper(?), upper(?), round(?,4));"); err != nil {
 		return errors.New(errWritingToFile)
 	}
 	defer stmt.Close()
@@ -47,7 +51,11 @@ func ExchangeRateEdit(db *gsqlitehandler.SqliteDB, e *ExchangeRate) error {
 	var err error
 	var stmt *sql.Stmt
 
-	if stmt, err = db.Handler.Prepare("UPDATE currencies SET exchange_rate=round(?,4) WHERE currency_from=upper(?) AND currency_to=upper(?);"); err != nil {
+	if err = beforeChange(db); err != nil {
+		return err
+	}
+
+	if stmt, err = dbHandler(db).Prepare("UPDATE currencies SET exchange_rate=round(?,4) WHERE currency_from=upper(?) AND currency_to=upper(?);"); err != nil {
 		return errors.New(errWritingToFile)
 	}
 	defer stmt.Close()
@@ -73,7 +81,7 @@ func ExchangeRateForCurrencies(db *gsqlitehandler.SqliteDB, cf string, ct string
 		return e, nil
 	}
 
-	if stmt, err = db.Handler.Prepare("SELECT exchange_rate FROM currencies WHERE currency_from=upper(?) AND currency_to=upper(?);"); err != nil {
+	if stmt, err = dbHandler(db).Prepare("SELECT exchange_rate FROM currencies WHERE currency_from=upper(?) AND currency_to=upper(?);"); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 	defer stmt.Close()
@@ -92,7 +100,7 @@ func ExchangeRateList(db *gsqlitehandler.SqliteDB) (f func() *ExchangeRate, err
 	var stmt *sql.Stmt
 	var rows *sql.Rows
 
-	if stmt, err = db.Handler.Prepare("SELECT currency_from, currency_to, exchange_rate FROM currencies ORDER BY currency_from, currency_to;"); err != nil {
+	if stmt, err = dbHandler(db).Prepare("SELECT currency_from, currency_to, exchange_rate FROM currencies ORDER BY currency_from, currency_to;"); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 
@@ -122,7 +130,11 @@ func ExchangeRateRemove(db *gsqlitehandler.SqliteDB, e *ExchangeRate) error {
 	var err error
 	var stmt *sql.Stmt
 
-	if stmt, err = db.Handler.Prepare("DELETE FROM currencies WHERE currency_from=upper(?) AND currency_to=upper(?);"); err != nil {
+	if err = beforeChange(db); err != nil {
+		return err
+	}
+
+	if stmt, err = dbHandler(db).Prepare("DELETE FROM currencies WHERE currency_from=upper(?) AND currency_to=upper(?);"); err != nil {
 		return errors.New(errWritingToFile)
 	}
 	defer stmt.Close()
@@ -5,6 +5,9 @@
 package lib
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/zbroju/gprops"
 	"github.com/zbroju/gsqlitehandler"
@@ -19,6 +22,14 @@ const (
 	confCurrency = "DEFAULT_CURRENCY"
 )
 
+// Table linking transactions with their IDs in imported bank statements
+// (with details given by bank: reference, value date and the other party of transaction)
+const sqlCreateImportedTransactions = "CREATE TABLE IF NOT EXISTS imported_transactions (account_id INTEGER, import_id TEXT, transaction_id INTEGER, " +
+	"reference TEXT DEFAULT '', value_date TEXT DEFAULT '', counterparty TEXT DEFAULT '', counterparty_iban TEXT DEFAULT '', PRIMARY KEY (account_id, import_id));"
+
+// Table of pairs of transactions which the user marked as not being duplicates (transaction_id is the lower ID)
+const sqlCreateDismissedDuplicates = "CREATE TABLE IF NOT EXISTS dismissed_duplicates (transaction_id INTEGER, other_id INTEGER, PRIMARY KEY (transaction_id, other_id));"
+
 // DB Properties
 var dataFileProperties = map[string]string{
 	"applicationName": "financoj",
@@ -28,28 +39,344 @@ var dataFileProperties = map[string]string{
 // GetConfigSettings returns contents of settings file
 func GetConfigSettings() (dataFile string, currency string, err error) {
 	// Read config file
+	var configSettings *gprops.Props
+	if configSettings, err = readConfigFile(); err != nil {
+		return NotSetStringValue, NotSetStringValue, err
+	}
+	dataFile = configSettings.GetOrDefault(confDataFile, NotSetStringValue)
+	currency = configSettings.GetOrDefault(confCurrency, NotSetStringValue)
+
+	return dataFile, currency, nil
+	//TODO: add test
+}
+
+// readConfigFile returns settings from config file in home directory (none if there is no such file)
+func readConfigFile() (*gprops.Props, error) {
 	configSettings := gprops.New()
 	configFile, err := os.Open(path.Join(os.Getenv("HOME"), configFile))
 	if err == nil {
 		err = configSettings.Load(configFile)
+		configFile.Close()
 		if err != nil {
-			return NotSetStringValue, NotSetStringValue, err
+			return nil, err
 		}
 	}
-	configFile.Close()
-	dataFile = configSettings.GetOrDefault(confDataFile, NotSetStringValue)
-	currency = configSettings.GetOrDefault(confCurrency, NotSetStringValue)
 
-	return dataFile, currency, nil
-	//TODO: add test
+	return configSettings, nil
 }
 
 // GetDataFileHandler returns new file handler for given path
+// (encrypted data file is used through its decrypted copy, written when the file is opened)
 func GetDataFileHandler(filePath string) *gsqlitehandler.SqliteDB {
-	return gsqlitehandler.New(filePath, dataFileProperties)
+	return newDataFileHandler(filePath)
 	//TODO: add test
 }
 
+// OpenDataFile opens data file for given data file handler and upgrades its structure
+// if it was created by an older version of the application
+// The data file is locked until it is closed with CloseDataFile (see KeepDataFileUnlocked), so that other applications using it wait for it.
+func OpenDataFile(db *gsqlitehandler.SqliteDB) error {
+	path := dataFilePaths[db]
+	lockPath, err := lockDataFile(path)
+	if err != nil {
+		return err
+	}
+	dataFileLocks[db] = lockPath
+
+	if ef, ok := encryptedDataFiles[db]; ok {
+		if err = ef.decrypt(); err != nil {
+			unlockDataFile(db)
+			return err
+		}
+		path = ef.plainPath
+	}
+	if err = db.Open(); err != nil {
+		closeDecryptedCopy(db)
+		unlockDataFile(db)
+		return err
+	}
+	if err = useConcurrentAccess(db, path); err != nil {
+		CloseDataFile(db)
+		return err
+	}
+	if err = upgradeDataFile(db); err != nil {
+		CloseDataFile(db)
+		return err
+	}
+	if err = fiscalYearLoad(db); err != nil {
+		CloseDataFile(db)
+		return err
+	}
+
+	return nil
+	//TODO: add test
+}
+
+// IsDataFileError checks if err is a failure of reading from or writing to data file,
+// as opposed to errors caused by incorrect data (e.g. unknown names or system objects)
+func IsDataFileError(err error) bool {
+	return err != nil && (err.Error() == errReadingFromFile || err.Error() == errWritingToFile)
+	//TODO: add test
+}
+
+// sqlHandler queries and changes data file, either through its pool of connections
+// or through the connection of transaction started with BeginDataFileTransaction
+type sqlHandler interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Prepare(query string) (*sql.Stmt, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// dataFileConn is the connection keeping transaction which groups changes of data file
+// (backedUp is set once the data file has been backed up before them)
+type dataFileConn struct {
+	conn     *sql.Conn
+	backedUp bool
+}
+
+func (c *dataFileConn) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.conn.ExecContext(context.Background(), query, args...)
+}
+
+func (c *dataFileConn) Prepare(query string) (*sql.Stmt, error) {
+	return c.conn.PrepareContext(context.Background(), query)
+}
+
+func (c *dataFileConn) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.conn.QueryContext(context.Background(), query, args...)
+}
+
+func (c *dataFileConn) QueryRow(query string, args ...interface{}) *sql.Row {
+	return c.conn.QueryRowContext(context.Background(), query, args...)
+}
+
+// Data files with changes grouped in transaction (see BeginDataFileTransaction) and connections keeping them
+var dataFileTransactions = make(map[*gsqlitehandler.SqliteDB]*dataFileConn)
+
+// dbHandler returns handler used to query and change data file db.
+// Within transaction it is the connection of the transaction, so that the queries see the changes not committed yet.
+// Other connections of the pool are still available, so queries can be run while rows of another one are read.
+func dbHandler(db *gsqlitehandler.SqliteDB) sqlHandler {
+	if c, ok := dataFileTransactions[db]; ok {
+		return c
+	}
+	return db.Handler
+}
+
+// BeginDataFileTransaction starts transaction grouping all the following changes of data file,
+// until CommitDataFileTransaction or RollbackDataFileTransaction is called.
+// Meanwhile the data file is queried through the connection of the transaction, so that the changes are visible for all the queries.
+func BeginDataFileTransaction(db *gsqlitehandler.SqliteDB) error {
+	if DataFileInTransaction(db) {
+		return errors.New(errDataFileTransactionOpen)
+	}
+
+	conn, err := db.Handler.Conn(context.Background())
+	if err != nil {
+		return errors.New(errWritingToFile)
+	}
+	if _, err = conn.ExecContext(context.Background(), "BEGIN IMMEDIATE;"); err != nil {
+		conn.Close()
+		return errors.New(errWritingToFile)
+	}
+	dataFileTransactions[db] = &dataFileConn{conn: conn}
+
+	return nil
+	//TODO: add test
+}
+
+// CommitDataFileTransaction saves all the changes made since BeginDataFileTransaction
+func CommitDataFileTransaction(db *gsqlitehandler.SqliteDB) error {
+	return dataFileTransactionEnd(db, "COMMIT;")
+	//TODO: add test
+}
+
+// RollbackDataFileTransaction cancels all the changes made since BeginDataFileTransaction
+func RollbackDataFileTransaction(db *gsqlitehandler.SqliteDB) error {
+	return dataFileTransactionEnd(db, "ROLLBACK;")
+	//TODO: add test
+}
+
+// DataFileInTransaction checks if changes of data file are grouped in transaction
+func DataFileInTransaction(db *gsqlitehandler.SqliteDB) bool {
+	_, ok := dataFileTransactions[db]
+	return ok
+}
+
+// dataFileTransactionEnd ends transaction started with BeginDataFileTransaction using given sql statement
+// and returns its connection to the pool
+func dataFileTransactionEnd(db *gsqlitehandler.SqliteDB, sqlEnd string) error {
+	c, ok := dataFileTransactions[db]
+	if !ok {
+		return errors.New(errDataFileTransactionNone)
+	}
+
+	if _, err := c.Exec(sqlEnd); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	delete(dataFileTransactions, db)
+	c.conn.Close()
+
+	return nil
+}
+
+// change groups statements so that either all or none of them are saved in data file.
+// Within transaction started with BeginDataFileTransaction a savepoint is used, because SQLite transactions cannot be nested.
+type change struct {
+	db   *gsqlitehandler.SqliteDB
+	tx   *sql.Tx
+	done bool
+}
+
+// beginChange starts new change of data file
+func beginChange(db *gsqlitehandler.SqliteDB) (ch *change, err error) {
+	if err = beforeChange(db); err != nil {
+		return nil, err
+	}
+	ch = &change{db: db}
+	if DataFileInTransaction(db) {
+		_, err = dbHandler(db).Exec("SAVEPOINT change;")
+	} else {
+		ch.tx, err = db.Handler.Begin()
+	}
+
+	return ch, err
+}
+
+// Prepare creates prepared statement being part of the change
+func (ch *change) Prepare(query string) (*sql.Stmt, error) {
+	if ch.tx != nil {
+		return ch.tx.Prepare(query)
+	}
+	return dbHandler(ch.db).Prepare(query)
+}
+
+// Commit saves the change
+func (ch *change) Commit() error {
+	ch.done = true
+	if ch.tx != nil {
+		return ch.tx.Commit()
+	}
+	_, err := dbHandler(ch.db).Exec("RELEASE change;")
+	return err
+}
+
+// Rollback cancels the change. It does nothing once the change is committed or cancelled,
+// so it can be deferred right after beginChange.
+func (ch *change) Rollback() error {
+	if ch.done {
+		return nil
+	}
+	ch.done = true
+	if ch.tx != nil {
+		return ch.tx.Rollback()
+	}
+	_, err := dbHandler(ch.db).Exec("ROLLBACK TO change; RELEASE change;")
+	return err
+}
+
+// upgradeDataFile adds to the data file all the structures missing in older versions of the application.
+// Existing categories are kept directly under their main categories, which become the top level of categories tree.
+func upgradeDataFile(db *gsqlitehandler.SqliteDB) error {
+	var err error
+	var exists bool
+
+	if exists, err = columnExists(db, "categories", "parent_id"); err != nil {
+		return err
+	}
+	if !exists {
+		if _, err = db.Handler.Exec(fmt.Sprintf("ALTER TABLE categories ADD COLUMN parent_id INTEGER DEFAULT %d;", NotSetIntValue)); err != nil {
+			return errors.New(errWritingToFile)
+		}
+	}
+
+	// Main category types got flags deciding about reports they are taken into.
+	// Transfers were excluded from all reports but net value before.
+	if exists, err = columnExists(db, "main_categories_types", "in_income_cost"); err != nil {
+		return err
+	}
+	if !exists {
+		sqlUpgrade := "ALTER TABLE main_categories_types ADD COLUMN in_income_cost INTEGER DEFAULT 1;" +
+			"ALTER TABLE main_categories_types ADD COLUMN in_budget INTEGER DEFAULT 1;" +
+			"ALTER TABLE main_categories_types ADD COLUMN in_net_value INTEGER DEFAULT 1;" +
+			fmt.Sprintf("ALTER TABLE main_categories_types ADD COLUMN status INTEGER DEFAULT %d;", ISSystem) +
+			fmt.Sprintf("UPDATE main_categories_types SET in_income_cost=0, in_budget=0 WHERE id=%d;", MCTTransfer) +
+			fmt.Sprintf("UPDATE main_categories_types SET in_income_cost=0, in_budget=0, in_net_value=0 WHERE id IN (%d, %d);", MCTUnknown, MCTUnset)
+		if _, err = db.Handler.Exec(sqlUpgrade); err != nil {
+			return errors.New(errWritingToFile)
+		}
+	}
+
+	// Transactions imported from bank statements are remembered to skip them when imported again
+	if _, err = db.Handler.Exec(sqlCreateImportedTransactions); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	if exists, err = columnExists(db, "imported_transactions", "reference"); err != nil {
+		return err
+	}
+	if !exists {
+		sqlUpgrade := "ALTER TABLE imported_transactions ADD COLUMN reference TEXT DEFAULT '';" +
+			"ALTER TABLE imported_transactions ADD COLUMN value_date TEXT DEFAULT '';" +
+			"ALTER TABLE imported_transactions ADD COLUMN counterparty TEXT DEFAULT '';" +
+			"ALTER TABLE imported_transactions ADD COLUMN counterparty_iban TEXT DEFAULT '';"
+		if _, err = db.Handler.Exec(sqlUpgrade); err != nil {
+			return errors.New(errWritingToFile)
+		}
+	}
+
+	// Pairs of transactions dismissed as duplicates are not shown again
+	if _, err = db.Handler.Exec(sqlCreateDismissedDuplicates); err != nil {
+		return errors.New(errWritingToFile)
+	}
+
+	// Files may be attached to transactions and accounts
+	if _, err = db.Handler.Exec(sqlCreateAttachments); err != nil {
+		return errors.New(errWritingToFile)
+	}
+
+	// Settings of data file (fiscal year) are kept in it
+	if _, err = db.Handler.Exec(sqlCreateSettings); err != nil {
+		return errors.New(errWritingToFile)
+	}
+
+	// Recurring transactions are used for forecast of balances
+	if _, err = db.Handler.Exec(sqlCreateRecurringTransactions); err != nil {
+		return errors.New(errWritingToFile)
+	}
+
+	// Savings goals with accounts they are saved in
+	if _, err = db.Handler.Exec(sqlCreateGoals); err != nil {
+		return errors.New(errWritingToFile)
+	}
+
+	return nil
+}
+
+// columnExists checks if table t in data file contains column c
+func columnExists(db *gsqlitehandler.SqliteDB, t, c string) (exists bool, err error) {
+	var rows *sql.Rows
+
+	if rows, err = db.Handler.Query(fmt.Sprintf("PRAGMA table_info(%s);", t)); err != nil {
+		return false, errors.New(errReadingFromFile)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, cType string
+		var dflt sql.NullString
+		if err = rows.Scan(&cid, &name, &cType, &notNull, &dflt, &pk); err != nil {
+			return false, errors.New(errReadingFromFile)
+		}
+		if name == c {
+			exists = true
+		}
+	}
+
+	return exists, nil
+}
+
 // CreateNewDataFile creates new data file for given data file handler
 func CreateNewDataFile(db *gsqlitehandler.SqliteDB) error {
 	// Create new file
@@ -57,19 +384,25 @@ func CreateNewDataFile(db *gsqlitehandler.SqliteDB) error {
 		"CREATE TABLE accounts (id INTEGER PRIMARY KEY, name TEXT, description TEXT, institution TEXT, currency TEXT, type INTEGER, status INTEGER);" +
 		"CREATE TABLE transactions (id INTEGER PRIMARY KEY, date TEXT, account_id INTEGER, description TEXT, value REAL, category_id INTEGER);" +
 		"CREATE TABLE budgets (year INTEGER, month INTEGER, category_id INTEGER, value REAL, currency TEXT, PRIMARY KEY (YEAR, MONTH, CATEGORY_ID));" +
-		"CREATE TABLE categories (id INTEGER PRIMARY KEY, main_category_id INTEGER, name TEXT, status INTEGER);" +
+		"CREATE TABLE categories (id INTEGER PRIMARY KEY, main_category_id INTEGER, name TEXT, status INTEGER, parent_id INTEGER DEFAULT 0);" +
 		"CREATE TABLE main_categories (id INTEGER PRIMARY KEY, type_id INTEGER, name TEXT, status INTEGER);" +
-		"CREATE TABLE main_categories_types (id INTEGER PRIMARY KEY, name TEXT, factor INTEGER);"
+		"CREATE TABLE main_categories_types (id INTEGER PRIMARY KEY, name TEXT, factor INTEGER, in_income_cost INTEGER, in_budget INTEGER, in_net_value INTEGER, status INTEGER);" +
+		sqlCreateImportedTransactions +
+		sqlCreateDismissedDuplicates +
+		sqlCreateAttachments +
+		sqlCreateSettings +
+		sqlCreateRecurringTransactions +
+		sqlCreateGoals
 
-	sqlInsertMainCategoryTypes := fmt.Sprintf("INSERT INTO main_categories_types VALUES (%d, 'Unknown', 0);", MCTUnknown)
-	sqlInsertMainCategoryTypes += fmt.Sprintf("INSERT INTO main_categories_types VALUES (%d, 'Not set', 0);", MCTUnset)
-	sqlInsertMainCategoryTypes += fmt.Sprintf("INSERT INTO main_categories_types VALUES (%d, 'Cost', -1);", MCTCost)
-	sqlInsertMainCategoryTypes += fmt.Sprintf("INSERT INTO main_categories_types VALUES (%d, 'Transfer', 1);", MCTTransfer)
-	sqlInsertMainCategoryTypes += fmt.Sprintf("INSERT INTO main_categories_types VALUES (%d, 'Income', 1);", MCTIncome)
+	sqlInsertMainCategoryTypes := fmt.Sprintf("INSERT INTO main_categories_types VALUES (%d, 'Unknown', 0, 0, 0, 0, %d);", MCTUnknown, ISSystem)
+	sqlInsertMainCategoryTypes += fmt.Sprintf("INSERT INTO main_categories_types VALUES (%d, 'Not set', 0, 0, 0, 0, %d);", MCTUnset, ISSystem)
+	sqlInsertMainCategoryTypes += fmt.Sprintf("INSERT INTO main_categories_types VALUES (%d, 'Cost', -1, 1, 1, 1, %d);", MCTCost, ISSystem)
+	sqlInsertMainCategoryTypes += fmt.Sprintf("INSERT INTO main_categories_types VALUES (%d, 'Transfer', 1, 0, 0, 1, %d);", MCTTransfer, ISSystem)
+	sqlInsertMainCategoryTypes += fmt.Sprintf("INSERT INTO main_categories_types VALUES (%d, 'Income', 1, 1, 1, 1, %d);", MCTIncome, ISSystem)
 
 	sqlInsertMainCategories := fmt.Sprintf("INSERT INTO main_categories VALUES (%d, %d, '%s',%d);", SOMCNonBudgetaryID, MCTTransfer, "NonBudgetary", ISSystem)
 
-	sqlInsertCategories := fmt.Sprintf("INSERT INTO categories VALUES(%d, %d, '%s', %d);", SOCategoryTransferID, SOMCNonBudgetaryID, "Transfer", ISSystem)
+	sqlInsertCategories := fmt.Sprintf("INSERT INTO categories VALUES(%d, %d, '%s', %d, %d);", SOCategoryTransferID, SOMCNonBudgetaryID, "Transfer", ISSystem, NotSetIntValue)
 
 	err := db.CreateNew(sqlCreateTables + sqlInsertMainCategoryTypes + sqlInsertMainCategories + sqlInsertCategories)
 
@@ -0,0 +1,141 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/zbroju/gsqlitehandler"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Table of settings of data file, kept as pairs of key and value
+const sqlCreateSettings = "CREATE TABLE IF NOT EXISTS settings (key TEXT PRIMARY KEY, value TEXT);"
+
+// Key of setting with the first month of fiscal year (1-12)
+const settingFiscalYearStart = "fiscal_year_start"
+
+// First months of fiscal years of opened data files.
+// Years and quarters of periods, budgets and date expressions of data file begin with it.
+var fiscalYearStarts = make(map[*gsqlitehandler.SqliteDB]time.Month)
+
+// fiscalYearStartOf returns the first month of fiscal year of data file opened with db
+// (January if db is nil or the data file is not opened)
+func fiscalYearStartOf(db *gsqlitehandler.SqliteDB) time.Month {
+	if m, ok := fiscalYearStarts[db]; ok {
+		return m
+	}
+
+	return time.January
+}
+
+// FiscalYearStart returns the first month of fiscal year set for data file (January if it is not set)
+func FiscalYearStart(db *gsqlitehandler.SqliteDB) (m time.Month, err error) {
+	var s string
+	err = dbHandler(db).QueryRow("SELECT value FROM settings WHERE key=?;", settingFiscalYearStart).Scan(&s)
+	switch {
+	case err == sql.ErrNoRows:
+		return time.January, nil
+	case err != nil:
+		return time.January, errors.New(errReadingFromFile)
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < int(time.January) || n > int(time.December) {
+		return time.January, errors.New(errFiscalYearStartIncorrect + s)
+	}
+
+	return time.Month(n), nil
+	//TODO: add test
+}
+
+// FiscalYearStartSet sets the first month of fiscal year for data file
+func FiscalYearStartSet(db *gsqlitehandler.SqliteDB, m time.Month) error {
+	var err error
+	var tx *change
+	var stmt *sql.Stmt
+
+	if m < time.January || m > time.December {
+		return errors.New(errFiscalYearStartIncorrect + strconv.Itoa(int(m)))
+	}
+
+	if tx, err = beginChange(db); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	defer tx.Rollback()
+	if stmt, err = tx.Prepare("INSERT OR REPLACE INTO settings (key, value) VALUES (?, ?);"); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	defer stmt.Close()
+	if _, err = stmt.Exec(settingFiscalYearStart, strconv.Itoa(int(m))); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	if err = tx.Commit(); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	fiscalYearStarts[db] = m
+
+	return nil
+	//TODO: add test
+}
+
+// MonthParse returns month given by its number (1-12) or English name (full or three first letters)
+func MonthParse(s string) (time.Month, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < int(time.January) || n > int(time.December) {
+			return time.January, errors.New(errFiscalYearStartIncorrect + s)
+		}
+		return time.Month(n), nil
+	}
+	for m := time.January; m <= time.December; m++ {
+		if n := strings.ToLower(m.String()); strings.ToLower(s) == n || strings.ToLower(s) == n[:3] {
+			return m, nil
+		}
+	}
+
+	return time.January, errors.New(errFiscalYearStartIncorrect + s)
+	//TODO: add test
+}
+
+// fiscalYearLoad makes periods of data file follow its fiscal year
+func fiscalYearLoad(db *gsqlitehandler.SqliteDB) error {
+	m, err := FiscalYearStart(db)
+	if err != nil {
+		return err
+	}
+	fiscalYearStarts[db] = m
+
+	return nil
+}
+
+// fiscalYearString returns name of fiscal year starting in month start of year y:
+// yyyy for calendar years, yyyy/yy for the other ones (e.g. 2016/17 from July 2016 to June 2017)
+func fiscalYearString(y int, start time.Month) string {
+	if start == time.January {
+		return fmt.Sprintf("%04d", y)
+	}
+
+	return fmt.Sprintf("%04d/%02d", y, (y+1)%100)
+}
+
+// fiscalYearParse returns year which fiscal year given as yyyy/yy (see fiscalYearString) starts in
+func fiscalYearParse(s string) (y int, ok bool) {
+	ys := strings.SplitN(s, "/", 2)
+	if len(ys) != 2 || len(ys[0]) != 4 || len(ys[1]) != 2 {
+		return 0, false
+	}
+	y, err := strconv.Atoi(ys[0])
+	if err != nil {
+		return 0, false
+	}
+	if n, err := strconv.Atoi(ys[1]); err != nil || n != (y+1)%100 {
+		return 0, false
+	}
+
+	return y, true
+}
@@ -0,0 +1,212 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/zbroju/gsqlitehandler"
+	"strings"
+	"time"
+)
+
+// Tables of savings goals and accounts they are saved in.
+// Category_id is 0 for goals saved in accounts rather than in transactions of a category.
+const sqlCreateGoals = "CREATE TABLE IF NOT EXISTS goals (id INTEGER PRIMARY KEY, name TEXT, value REAL, currency TEXT, date_from TEXT, date_to TEXT, category_id INTEGER);" +
+	"CREATE TABLE IF NOT EXISTS goals_accounts (goal_id INTEGER, account_id INTEGER, PRIMARY KEY (goal_id, account_id));"
+
+// Goal is an amount to be saved from the day it was set until given day, either on balance of accounts
+// or as transactions of category (including its subcategories). Category is nil for goals saved in accounts.
+type Goal struct {
+	Id       int64
+	Name     string
+	Value    float64
+	Currency string
+	DateFrom time.Time
+	DateTo   time.Time
+	Category *Category
+	Accounts []*Account
+}
+
+// GoalNew returns pointer to new instance of Goal set today
+func GoalNew() *Goal {
+	g := new(Goal)
+	g.DateFrom = time.Now()
+
+	return g
+}
+
+// GoalAdd adds new goal g with links to its accounts
+func GoalAdd(db *gsqlitehandler.SqliteDB, g *Goal) error {
+	var err error
+	var tx *change
+
+	if (g.Category == nil) == (len(g.Accounts) == 0) {
+		return errors.New(errGoalLink)
+	}
+	if g.Value <= 0 {
+		return errors.New(errGoalValue)
+	}
+	if !g.DateTo.After(g.DateFrom) {
+		return errors.New(errGoalDate)
+	}
+
+	if tx, err = beginChange(db); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	defer tx.Rollback()
+
+	var stmt *sql.Stmt
+	if stmt, err = tx.Prepare("INSERT INTO goals (name, value, currency, date_from, date_to, category_id) VALUES (?, round(?,2), upper(?), ?, ?, ?);"); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	defer stmt.Close()
+	var cId int64
+	if g.Category != nil {
+		cId = g.Category.Id
+	}
+	var res sql.Result
+	if res, err = stmt.Exec(g.Name, g.Value, g.Currency, g.DateFrom.Format(DateFormat), g.DateTo.Format(DateFormat), cId); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	if g.Id, err = res.LastInsertId(); err != nil {
+		return errors.New(errWritingToFile)
+	}
+
+	var stmtA *sql.Stmt
+	if stmtA, err = tx.Prepare("INSERT OR IGNORE INTO goals_accounts (goal_id, account_id) VALUES (?, ?);"); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	defer stmtA.Close()
+	for _, a := range g.Accounts {
+		if _, err = stmtA.Exec(g.Id, a.Id); err != nil {
+			return errors.New(errWritingToFile)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	g.Currency = strings.ToUpper(g.Currency)
+
+	return nil
+	//TODO: add test
+}
+
+// GoalForID returns pointer to goal with given id
+func GoalForID(db *gsqlitehandler.SqliteDB, i int) (g *Goal, err error) {
+	var l []*Goal
+	if l, err = goalsGet(db, i); err != nil {
+		return nil, err
+	}
+	if len(l) == 0 {
+		return nil, errors.New(errGoalWithIDNone)
+	}
+
+	return l[0], nil
+	//TODO: add test
+}
+
+// GoalList returns all goals, ordered by their last days, as closure
+func GoalList(db *gsqlitehandler.SqliteDB) (f func() *Goal, err error) {
+	var l []*Goal
+	if l, err = goalsGet(db, NotSetIntValue); err != nil {
+		return nil, err
+	}
+
+	i := 0
+	f = func() *Goal {
+		if i == len(l) {
+			return nil
+		}
+		g := l[i]
+		i++
+		return g
+	}
+
+	return f, nil
+	//TODO: add test
+}
+
+// GoalRemove removes goal g (accounts and transactions it was saved in are kept)
+func GoalRemove(db *gsqlitehandler.SqliteDB, g *Goal) error {
+	var err error
+	var tx *change
+
+	if tx, err = beginChange(db); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	defer tx.Rollback()
+	for _, q := range []string{"DELETE FROM goals_accounts WHERE goal_id=?;", "DELETE FROM goals WHERE id=?;"} {
+		var stmt *sql.Stmt
+		if stmt, err = tx.Prepare(q); err != nil {
+			return errors.New(errWritingToFile)
+		}
+		_, err = stmt.Exec(g.Id)
+		stmt.Close()
+		if err != nil {
+			return errors.New(errWritingToFile)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return errors.New(errWritingToFile)
+	}
+
+	return nil
+	//TODO: add test
+}
+
+// goalsGet reads goal with id i (or all of them if i is not set) together with their categories and accounts
+func goalsGet(db *gsqlitehandler.SqliteDB, i int) (l []*Goal, err error) {
+	var rows *sql.Rows
+
+	sqlQuery := "SELECT g.id, g.name, g.value, g.currency, g.date_from, g.date_to, " +
+		"ifnull(c.id, 0), ifnull(c.name, ''), ifnull(c.status, 0), ifnull(m.id, 0), ifnull(m.name, ''), ifnull(m.status, 0), ifnull(mt.id, 0), ifnull(mt.name, ''), ifnull(mt.factor, 0) " +
+		"FROM goals g LEFT JOIN categories c ON g.category_id=c.id LEFT JOIN main_categories m ON c.main_category_id=m.id LEFT JOIN main_categories_types mt on m.type_id=mt.id " +
+		"WHERE (g.id=? OR ?=?) ORDER BY g.date_to, g.name, g.id;"
+	if rows, err = dbHandler(db).Query(sqlQuery, i, i, NotSetIntValue); err != nil {
+		return nil, errors.New(errReadingFromFile)
+	}
+	index := make(map[int64]*Goal)
+	for rows.Next() {
+		g := GoalNew()
+		g.Category = CategoryNew()
+		var tmpDateFrom, tmpDateTo string
+		if err = rows.Scan(&g.Id, &g.Name, &g.Value, &g.Currency, &tmpDateFrom, &tmpDateTo,
+			&g.Category.Id, &g.Category.Name, &g.Category.Status, &g.Category.Main.Id, &g.Category.Main.Name, &g.Category.Main.Status,
+			&g.Category.Main.MType.Id, &g.Category.Main.MType.Name, &g.Category.Main.MType.Factor); err != nil {
+			rows.Close()
+			return nil, errors.New(errReadingFromFile)
+		}
+		if g.Category.Id == int64(NotSetIntValue) {
+			g.Category = nil
+		}
+		g.DateFrom, _ = time.Parse(DateFormat, tmpDateFrom)
+		g.DateTo, _ = time.Parse(DateFormat, tmpDateTo)
+		index[g.Id] = g
+		l = append(l, g)
+	}
+	rows.Close()
+
+	// Accounts of goals
+	sqlQuery = "SELECT ga.goal_id, a.id, a.name, a.description, a.institution, a.currency, a.type, a.status " +
+		"FROM goals_accounts ga INNER JOIN accounts a ON ga.account_id=a.id WHERE (ga.goal_id=? OR ?=?) ORDER BY a.name;"
+	if rows, err = dbHandler(db).Query(sqlQuery, i, i, NotSetIntValue); err != nil {
+		return nil, errors.New(errReadingFromFile)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var gId int64
+		a := new(Account)
+		if err = rows.Scan(&gId, &a.Id, &a.Name, &a.Description, &a.Institution, &a.Currency, &a.AType, &a.Status); err != nil {
+			return nil, errors.New(errReadingFromFile)
+		}
+		if g, ok := index[gId]; ok {
+			g.Accounts = append(g.Accounts, a)
+		}
+	}
+
+	return l, nil
+}
@@ -0,0 +1,342 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/zbroju/gsqlitehandler"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Top level journal accounts for accounts of given type (accounts of unknown type are assets)
+var journalAccountTypes = map[AccountType]string{
+	ATTransactional: "Assets:Current",
+	ATSaving:        "Assets:Savings",
+	ATProperty:      "Assets:Property",
+	ATInvestment:    "Assets:Investments",
+	ATLoan:          "Liabilities:Loans",
+}
+
+// Top level journal accounts for categories
+const (
+	journalExpenses    = "Expenses"
+	journalIncome      = "Income"
+	journalEquity      = "Equity"
+	journalOtherAssets = "Assets:Other"
+
+	// Account balancing budgets in periodic transactions
+	journalBudgetBalance = "Assets"
+)
+
+// journalWriter keeps objects of data file used while writing plain text accounting journal
+type journalWriter struct {
+	w         *bufio.Writer
+	beancount bool
+	tree      *CategoryTree
+	types     map[int]*MainCategoryType
+	start     time.Time
+}
+
+// JournalWriteLedger writes the whole data file as ledger (or hledger) journal: accounts, exchange rates
+// as prices, transactions (transfers as entries with two accounts) and budgets as periodic transactions.
+func JournalWriteLedger(db *gsqlitehandler.SqliteDB, w io.Writer) error {
+	return journalWrite(db, w, false)
+	//TODO: add test
+}
+
+// JournalWriteBeancount writes the whole data file as beancount journal, the same way as JournalWriteLedger,
+// except for budgets which are written as custom budget directives (used by fava).
+func JournalWriteBeancount(db *gsqlitehandler.SqliteDB, w io.Writer) error {
+	return journalWrite(db, w, true)
+	//TODO: add test
+}
+
+// journalWrite writes journal in ledger or beancount syntax
+func journalWrite(db *gsqlitehandler.SqliteDB, w io.Writer, beancount bool) (err error) {
+	jw := &journalWriter{w: bufio.NewWriter(w), beancount: beancount, types: make(map[int]*MainCategoryType)}
+	if jw.tree, err = CategoryTreeGet(db); err != nil {
+		return err
+	}
+	var getNextType func() *MainCategoryType
+	if getNextType, err = MainCategoryTypeList(db, ISUnset); err != nil {
+		return err
+	}
+	for t := getNextType(); t != nil; t = getNextType() {
+		jw.types[t.Id] = t
+	}
+
+	// Read everything first, as accounts must be opened before the first transaction
+	var accounts []*Account
+	var getNextAccount func() *Account
+	if getNextAccount, err = AccountList(db, NotSetStringValue, NotSetStringValue, NotSetStringValue, NotSetStringValue, ATUnset, ISUnset); err != nil {
+		return err
+	}
+	for a := getNextAccount(); a != nil; a = getNextAccount() {
+		accounts = append(accounts, a)
+	}
+	var transactions []*Transaction
+	var getNextTransaction func() *Transaction
+	if getNextTransaction, err = TransactionList(db, time.Time{}, time.Time{}, nil, NotSetStringValue, nil, nil); err != nil {
+		return err
+	}
+	for t := getNextTransaction(); t != nil; t = getNextTransaction() {
+		transactions = append(transactions, t)
+	}
+	var budgets []*Budget
+	var getNextBudget func() *Budget
+	if getNextBudget, err = BudgetList(db, nil, nil); err != nil {
+		return err
+	}
+	for b := getNextBudget(); b != nil; b = getNextBudget() {
+		budgets = append(budgets, b)
+	}
+	var rates []*ExchangeRate
+	var getNextRate func() *ExchangeRate
+	if getNextRate, err = ExchangeRateList(db); err != nil {
+		return err
+	}
+	for e := getNextRate(); e != nil; e = getNextRate() {
+		rates = append(rates, e)
+	}
+
+	// Accounts and prices get the date of the first transaction (or budget)
+	jw.start = time.Now()
+	if len(transactions) > 0 {
+		jw.start = transactions[0].Date
+	}
+	if len(budgets) > 0 {
+		if d, _, _ := journalBudgetPeriod(budgets[0].Period); d.Before(jw.start) {
+			jw.start = d
+		}
+	}
+
+	fmt.Fprintf(jw.w, "; %s data file\n\n", AppName)
+	jw.writeAccounts(accounts)
+	jw.writePrices(rates)
+	jw.writeTransactions(transactions)
+	jw.writeBudgets(budgets)
+
+	return jw.w.Flush()
+}
+
+// writeAccounts opens (beancount) or declares (ledger) accounts and categories
+func (jw *journalWriter) writeAccounts(accounts []*Account) {
+	var names []string
+	for _, a := range accounts {
+		if jw.beancount {
+			names = append(names, jw.account(a)+" "+a.Currency)
+		} else {
+			names = append(names, jw.account(a))
+		}
+	}
+	var categories []string
+	seen := make(map[string]bool)
+	for _, c := range jw.tree.categories {
+		if n := jw.category(c); !seen[n] {
+			seen[n] = true
+			categories = append(categories, n)
+		}
+	}
+	sort.Strings(categories)
+	names = append(names, categories...)
+
+	for _, n := range names {
+		if jw.beancount {
+			fmt.Fprintf(jw.w, "%s open %s\n", jw.start.Format(DateFormat), n)
+		} else {
+			fmt.Fprintf(jw.w, "account %s\n", n)
+		}
+	}
+	fmt.Fprintln(jw.w)
+}
+
+// writePrices writes exchange rates as prices of currencies
+func (jw *journalWriter) writePrices(rates []*ExchangeRate) {
+	if len(rates) == 0 {
+		return
+	}
+	for _, e := range rates {
+		rate := strconv.FormatFloat(e.Rate, 'f', -1, 64)
+		if jw.beancount {
+			fmt.Fprintf(jw.w, "%s price %s %s %s\n", jw.start.Format(DateFormat), e.CurrencyFrom, rate, e.CurrencyTo)
+		} else {
+			fmt.Fprintf(jw.w, "P %s %s %s %s\n", jw.date(jw.start), e.CurrencyFrom, rate, e.CurrencyTo)
+		}
+	}
+	fmt.Fprintln(jw.w)
+}
+
+// writeTransactions writes transactions as entries with the account and category.
+// Transfers are written as one entry with both accounts if the other side can be found
+// (the same date and description, opposite value), otherwise with the transfer category.
+func (jw *journalWriter) writeTransactions(transactions []*Transaction) {
+	done := make([]bool, len(transactions))
+	for i, t := range transactions {
+		if done[i] {
+			continue
+		}
+		jw.header(t.Date, t.Description)
+
+		var other *Transaction
+		if t.Category.Id == SOCategoryTransferID {
+			for j := i + 1; j < len(transactions) && transactions[j].Date.Equal(t.Date); j++ {
+				o := transactions[j]
+				if !done[j] && o.Category.Id == SOCategoryTransferID && o.Account.Id != t.Account.Id && o.Description == t.Description && o.Value*t.Value < 0 {
+					other, done[j] = o, true
+					break
+				}
+			}
+		}
+
+		if other == nil {
+			jw.posting(jw.account(t.Account), t.GetSValue(), t.Account.Currency, NotSetStringValue)
+			jw.posting(jw.category(t.Category), -t.GetSValue(), t.Account.Currency, NotSetStringValue)
+		} else {
+			price := NotSetStringValue
+			if other.Account.Currency != t.Account.Currency {
+				price = fmt.Sprintf(" @@ %s %s", journalAmount(math.Abs(other.GetSValue())), other.Account.Currency)
+			}
+			jw.posting(jw.account(t.Account), t.GetSValue(), t.Account.Currency, price)
+			jw.posting(jw.account(other.Account), other.GetSValue(), other.Account.Currency, NotSetStringValue)
+		}
+		fmt.Fprintln(jw.w)
+	}
+}
+
+// writeBudgets writes budgets of each period as one periodic transaction (ledger)
+// or as custom budget directives (beancount)
+func (jw *journalWriter) writeBudgets(budgets []*Budget) {
+	for i, b := range budgets {
+		from, to, period := journalBudgetPeriod(b.Period)
+		if jw.beancount {
+			fmt.Fprintf(jw.w, "%s custom \"budget\" %s \"%s\" %s %s\n", from.Format(DateFormat), jw.category(b.Category), period, journalAmount(-b.Value), b.Currency)
+			if i == len(budgets)-1 {
+				fmt.Fprintln(jw.w)
+			}
+			continue
+		}
+
+		if i == 0 || budgets[i-1].Period.String() != b.Period.String() {
+			fmt.Fprintf(jw.w, "~ %s from %s to %s\n", period, jw.date(from), jw.date(to))
+		}
+		jw.posting(jw.category(b.Category), -b.Value, b.Currency, NotSetStringValue)
+		if i == len(budgets)-1 || budgets[i+1].Period.String() != b.Period.String() {
+			fmt.Fprintf(jw.w, "    %s\n\n", journalBudgetBalance)
+		}
+	}
+}
+
+// header writes the first line of entry
+func (jw *journalWriter) header(d time.Time, description string) {
+	if jw.beancount {
+		fmt.Fprintf(jw.w, "%s * \"%s\"\n", d.Format(DateFormat), strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(description))
+	} else {
+		fmt.Fprintf(jw.w, "%s %s\n", jw.date(d), description)
+	}
+}
+
+// posting writes posting of entry, with amounts aligned (and separated from account with at least two spaces)
+func (jw *journalWriter) posting(account string, v float64, currency string, price string) {
+	amount := journalAmount(v)
+	pad := 60 - len(account) - len(amount)
+	if pad < 2 {
+		pad = 2
+	}
+	fmt.Fprintf(jw.w, "    %s%s%s %s%s\n", account, strings.Repeat(" ", pad), amount, currency, price)
+}
+
+// date returns date in ledger syntax
+func (jw *journalWriter) date(d time.Time) string {
+	return d.Format("2006/01/02")
+}
+
+// account returns journal account for account a, placed according to account type
+func (jw *journalWriter) account(a *Account) string {
+	root, ok := journalAccountTypes[a.AType]
+	if !ok {
+		root = journalOtherAssets
+	}
+
+	return root + CategoryPathSeparator + jw.name(a.Name)
+}
+
+// category returns journal account for category c: expenses for main categories decreasing balance,
+// income for the others used in income and cost reports and equity for the rest (e.g. transfers)
+func (jw *journalWriter) category(c *Category) string {
+	root := journalEquity
+	if t, ok := jw.types[c.Main.MType.Id]; ok {
+		switch {
+		case t.Factor < 0:
+			root = journalExpenses
+		case t.InIncomeCost:
+			root = journalIncome
+		}
+	}
+
+	names := []string{root}
+	for _, n := range strings.Split(jw.tree.FullPath(c), CategoryPathSeparator) {
+		names = append(names, jw.name(n))
+	}
+
+	return strings.Join(names, CategoryPathSeparator)
+}
+
+// name returns name usable as part of journal account. Beancount requires it to start
+// with capital letter or digit and to contain letters, digits and dashes only,
+// ledger ends account names with two spaces or tab.
+func (jw *journalWriter) name(n string) string {
+	if !jw.beancount {
+		return strings.Join(strings.Fields(n), " ")
+	}
+
+	var b strings.Builder
+	dash := false
+	for _, r := range n {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if b.Len() == 0 {
+				r = unicode.ToUpper(r)
+			}
+			b.WriteRune(r)
+			dash = false
+		case b.Len() > 0 && !dash:
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+	s := strings.TrimRight(b.String(), "-")
+	if s == NotSetStringValue {
+		return "X"
+	}
+
+	return s
+}
+
+// journalBudgetPeriod returns the first day of budget period, the first day after it and its length
+func journalBudgetPeriod(p *BPeriod) (from, to time.Time, period string) {
+	from, to = p.Dates()
+	if p.Month == int64(NotSetIntValue) {
+		return from, to.AddDate(0, 0, 1), "yearly"
+	}
+
+	return from, to.AddDate(0, 0, 1), "monthly"
+}
+
+// journalAmount returns amount with two decimal places (without sign for zero)
+func journalAmount(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	if s == "-0.00" {
+		return "0.00"
+	}
+
+	return s
+}
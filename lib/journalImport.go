@@ -0,0 +1,911 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/zbroju/gsqlitehandler"
+	"io"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Kinds of journal accounts, given by the first part of their names
+const (
+	journalKindUnknown = iota
+	journalKindAsset
+	journalKindLiability
+	journalKindExpense
+	journalKindIncome
+	journalKindEquity
+)
+
+// Kinds of journal accounts for the first parts of their names (in lower case, as hledger uses them)
+var journalKinds = map[string]int{
+	"assets":      journalKindAsset,
+	"liabilities": journalKindLiability,
+	"expenses":    journalKindExpense,
+	"income":      journalKindIncome,
+	"revenue":     journalKindIncome,
+	"revenues":    journalKindIncome,
+	"equity":      journalKindEquity,
+}
+
+// Currencies for commodity symbols used in ledger journals
+var journalSymbols = map[string]string{"$": "USD", "€": "EUR", "£": "GBP", "¥": "JPY", "zł": "PLN"}
+
+// Journal keeps the parts of plain text accounting journal (beancount or ledger) which can be imported to data file
+type Journal struct {
+	accounts map[string]*journalAccount
+	names    []string
+	entries  []*journalEntry
+	prices   []*ExchangeRate
+	budgets  []*journalBudget
+
+	// Warnings describe the parts of journal which cannot be represented in data file (and are skipped)
+	Warnings []string
+}
+
+// journalAccount keeps account of journal as opened, declared or used in postings
+type journalAccount struct {
+	name     string
+	currency string
+	closed   bool
+}
+
+// journalEntry is a transaction of journal
+type journalEntry struct {
+	line        int
+	date        time.Time
+	description string
+	postings    []*journalPosting
+
+	// invalid is set if any posting could not be read (and has been reported)
+	invalid bool
+}
+
+// journalPosting is a posting of transaction. Postings without amount are balancing the others.
+// Price is the total price (in price currency) of posting amount given in other currency.
+type journalPosting struct {
+	account       string
+	value         float64
+	currency      string
+	missing       bool
+	price         float64
+	priceCurrency string
+}
+
+// journalBudget is a budget of journal account for the months (or years) between dates from and to
+type journalBudget struct {
+	line     int
+	from, to time.Time
+	yearly   bool
+	account  string
+	value    float64
+	currency string
+}
+
+// newJournal returns empty journal
+func newJournal() *Journal {
+	return &Journal{accounts: make(map[string]*journalAccount)}
+}
+
+// warn adds warning about line of journal
+func (j *Journal) warn(line int, format string, a ...interface{}) {
+	j.Warnings = append(j.Warnings, fmt.Sprintf("line %d: ", line)+fmt.Sprintf(format, a...))
+}
+
+// account returns account of journal with given name, adding it if it is used for the first time
+func (j *Journal) account(name string) *journalAccount {
+	ja := j.accounts[name]
+	if ja == nil {
+		ja = &journalAccount{name: name}
+		j.accounts[name] = ja
+		j.names = append(j.names, name)
+	}
+
+	return ja
+}
+
+// JournalReadBeancount reads accounts (open and close directives), transactions, prices and budgets
+// (custom budget directives) from beancount journal. Other directives are reported in warnings.
+func JournalReadBeancount(r io.Reader) (j *Journal, err error) {
+	j = newJournal()
+	var e *journalEntry
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		tokens := journalTokens(line)
+		if len(tokens) == 0 {
+			continue
+		}
+
+		// Postings and metadata of transaction
+		if line[0] == ' ' || line[0] == '\t' {
+			if e == nil || strings.HasSuffix(tokens[0], ":") {
+				continue
+			}
+			if tokens[0] == "*" || tokens[0] == "!" {
+				tokens = tokens[1:]
+			}
+			if p := j.posting(lineNo, tokens[0], strings.Join(tokens[1:], " ")); p != nil {
+				e.postings = append(e.postings, p)
+				j.account(p.account)
+			} else {
+				e.invalid = true
+			}
+			continue
+		}
+
+		e = nil
+		var d time.Time
+		if d, err = time.Parse(DateFormat, tokens[0]); err != nil || len(tokens) < 2 {
+			switch tokens[0] {
+			case "include", "plugin":
+				j.warn(lineNo, "%s skipped", tokens[0])
+			}
+			err = nil
+			continue
+		}
+
+		switch directive := tokens[1]; directive {
+		case "open", "close":
+			if len(tokens) < 3 {
+				return nil, fmt.Errorf("%s (line %d)", errJournalFormat, lineNo)
+			}
+			ja := j.account(tokens[2])
+			if directive == "close" {
+				ja.closed = true
+			} else if len(tokens) > 3 && !strings.HasPrefix(tokens[3], "\"") {
+				ja.currency = strings.Split(tokens[3], ",")[0]
+			}
+		case "price":
+			if len(tokens) < 5 {
+				return nil, fmt.Errorf("%s (line %d)", errJournalFormat, lineNo)
+			}
+			var rate float64
+			if rate, err = journalNumber(tokens[3]); err != nil {
+				return nil, fmt.Errorf("%s (line %d)", err, lineNo)
+			}
+			j.prices = append(j.prices, &ExchangeRate{CurrencyFrom: tokens[2], CurrencyTo: tokens[4], Rate: rate})
+		case "custom":
+			if len(tokens) < 7 || tokens[2] != "\"budget\"" {
+				j.warn(lineNo, "custom directive skipped")
+				continue
+			}
+			b := &journalBudget{line: lineNo, from: d, account: tokens[3], currency: tokens[6]}
+			switch strings.Trim(tokens[4], "\"") {
+			case "monthly":
+				b.to = d.AddDate(0, 1, 0)
+			case "yearly":
+				b.to, b.yearly = d.AddDate(1, 0, 0), true
+			default:
+				j.warn(lineNo, "budget for period %s skipped", tokens[4])
+				continue
+			}
+			if b.value, err = journalNumber(tokens[5]); err != nil {
+				return nil, fmt.Errorf("%s (line %d)", err, lineNo)
+			}
+			j.account(b.account)
+			j.budgets = append(j.budgets, b)
+		case "*", "!", "txn":
+			e = &journalEntry{line: lineNo, date: d}
+			var texts []string
+			for _, t := range tokens[2:] {
+				if strings.HasPrefix(t, "\"") {
+					texts = append(texts, journalUnquote(t))
+				}
+			}
+			switch len(texts) {
+			case 1:
+				e.description = texts[0]
+			case 2:
+				e.description = statementDescription(texts[0], texts[1])
+			}
+			j.entries = append(j.entries, e)
+		case "commodity", "event", "query":
+		default:
+			j.warn(lineNo, "%s directive skipped", directive)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return j, nil
+	//TODO: add test
+}
+
+// JournalReadLedger reads accounts (account directives and accounts used in postings), transactions, prices
+// and budgets (periodic transactions with dates) from ledger or hledger journal. Other directives are reported in warnings.
+func JournalReadLedger(r io.Reader) (j *Journal, err error) {
+	j = newJournal()
+	var e *journalEntry
+	var periodic []*journalBudget
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == NotSetStringValue || strings.ContainsRune(";#%|*", rune(line[0])) {
+			continue
+		}
+
+		// Postings of transaction or periodic transaction (budgets)
+		if line[0] == ' ' || line[0] == '\t' {
+			text := strings.TrimSpace(line)
+			if i := strings.Index(text, ";"); i >= 0 {
+				text = strings.TrimSpace(text[:i])
+			}
+			if text == NotSetStringValue || (e == nil && periodic == nil) {
+				continue
+			}
+			if strings.HasPrefix(text, "* ") || strings.HasPrefix(text, "! ") {
+				text = strings.TrimSpace(text[2:])
+			}
+			account, amount := text, NotSetStringValue
+			if i := strings.IndexAny(text, "\t"); i >= 0 {
+				account, amount = text[:i], text[i+1:]
+			}
+			if i := strings.Index(account, "  "); i >= 0 {
+				account, amount = account[:i], account[i+2:]+amount
+			}
+			if strings.HasPrefix(account, "(") || strings.HasPrefix(account, "[") {
+				j.warn(lineNo, "virtual posting to %s skipped", account)
+				continue
+			}
+			p := j.posting(lineNo, account, amount)
+			if p == nil {
+				if e != nil {
+					e.invalid = true
+				}
+				continue
+			}
+			if e != nil {
+				e.postings = append(e.postings, p)
+				j.account(p.account)
+			} else if !p.missing {
+				b := *periodic[0]
+				b.account, b.value, b.currency = p.account, p.value, p.currency
+				periodic = append(periodic, &b)
+			}
+			continue
+		}
+
+		if periodic != nil {
+			j.budgets = append(j.budgets, periodic[1:]...)
+		}
+		e, periodic = nil, nil
+		fields := strings.Fields(line)
+		switch {
+		case line[0] >= '0' && line[0] <= '9':
+			e = &journalEntry{line: lineNo}
+			if e.date, err = journalDate(strings.Split(fields[0], "=")[0]); err != nil {
+				return nil, fmt.Errorf("%s (line %d)", err, lineNo)
+			}
+			description := strings.TrimSpace(line[len(fields[0]):])
+			if strings.HasPrefix(description, "*") || strings.HasPrefix(description, "!") {
+				description = strings.TrimSpace(description[1:])
+			}
+			if strings.HasPrefix(description, "(") {
+				if i := strings.Index(description, ")"); i >= 0 {
+					description = strings.TrimSpace(description[i+1:])
+				}
+			}
+			if i := strings.Index(description, "  ;"); i >= 0 {
+				description = strings.TrimSpace(description[:i])
+			}
+			e.description = description
+			j.entries = append(j.entries, e)
+		case fields[0] == "P" && len(fields) >= 4:
+			// Date may be followed by time
+			rest := fields[2:]
+			if strings.Contains(rest[0], ":") {
+				rest = rest[1:]
+			}
+			var rate float64
+			var to string
+			if len(rest) < 2 {
+				return nil, fmt.Errorf("%s (line %d)", errJournalFormat, lineNo)
+			}
+			if rate, to, err = journalAmountParse(strings.Join(rest[1:], " ")); err != nil {
+				return nil, fmt.Errorf("%s (line %d)", err, lineNo)
+			}
+			j.prices = append(j.prices, &ExchangeRate{CurrencyFrom: journalCurrency(rest[0]), CurrencyTo: to, Rate: rate})
+		case fields[0] == "account" && len(fields) > 1:
+			j.account(strings.TrimSpace(line[len("account"):]))
+		case fields[0] == "~":
+			b, ok := journalPeriod(strings.TrimSpace(line[1:]))
+			if !ok {
+				j.warn(lineNo, "periodic transaction '%s' skipped (only monthly or yearly ones with dates are budgets)", strings.TrimSpace(line[1:]))
+				continue
+			}
+			b.line = lineNo
+			periodic = []*journalBudget{b}
+		case fields[0] == "commodity", fields[0] == "payee", fields[0] == "tag", fields[0] == "N", fields[0] == "D":
+		default:
+			j.warn(lineNo, "%s directive skipped", fields[0])
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	if periodic != nil {
+		j.budgets = append(j.budgets, periodic[1:]...)
+	}
+	for _, b := range j.budgets {
+		j.account(b.account)
+	}
+
+	return j, nil
+	//TODO: add test
+}
+
+// posting parses posting of account with given amount (empty for balancing posting).
+// Postings with costs of lots or amounts which cannot be parsed are reported in warnings and skipped.
+func (j *Journal) posting(line int, account string, amount string) *journalPosting {
+	p := &journalPosting{account: account}
+
+	// Balance assertions are skipped
+	if i := strings.Index(amount, "="); i >= 0 {
+		amount = amount[:i]
+	}
+	amount = strings.TrimSpace(amount)
+	if amount == NotSetStringValue {
+		p.missing = true
+		return p
+	}
+	if strings.ContainsAny(amount, "{}") {
+		j.warn(line, "posting to %s with cost (lot) skipped", account)
+		return nil
+	}
+
+	var err error
+	price := NotSetStringValue
+	total := false
+	if i := strings.Index(amount, "@"); i >= 0 {
+		price, total = amount[i+1:], strings.HasPrefix(amount[i+1:], "@")
+		price, amount = strings.TrimLeft(price, "@"), amount[:i]
+	}
+	if p.value, p.currency, err = journalAmountParse(amount); err != nil {
+		j.warn(line, "posting to %s skipped: %s", account, err)
+		return nil
+	}
+	if price != NotSetStringValue {
+		if p.price, p.priceCurrency, err = journalAmountParse(price); err != nil {
+			j.warn(line, "posting to %s skipped: %s", account, err)
+			return nil
+		}
+		p.price = math.Abs(p.price)
+		if !total {
+			p.price *= math.Abs(p.value)
+		}
+	}
+
+	return p
+}
+
+// weight returns value of posting in currency in which it balances transaction (price currency if given)
+func (p *journalPosting) weight() (float64, string) {
+	if p.priceCurrency != NotSetStringValue {
+		return math.Copysign(p.price, p.value), p.priceCurrency
+	}
+
+	return p.value, p.currency
+}
+
+// journalTokens splits line of beancount journal into tokens separated with white space,
+// keeping quoted strings as one token and skipping comments
+func journalTokens(line string) (tokens []string) {
+	var b strings.Builder
+	quoted, escaped := false, false
+	for _, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case !quoted && r == ';':
+			if b.Len() > 0 {
+				tokens = append(tokens, b.String())
+			}
+			return tokens
+		case !quoted && unicode.IsSpace(r):
+			if b.Len() > 0 {
+				tokens = append(tokens, b.String())
+				b.Reset()
+			}
+			continue
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() > 0 {
+		tokens = append(tokens, b.String())
+	}
+
+	return tokens
+}
+
+// journalUnquote returns text of quoted string
+func journalUnquote(s string) string {
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "\""), "\"")
+
+	return strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(s)
+}
+
+// journalNumber parses number of journal (with optional thousands separators)
+func journalNumber(s string) (float64, error) {
+	v, err := qifAmount(s)
+	if err != nil {
+		return NotSetFloatValue, errors.New(errJournalAmount + s)
+	}
+
+	return v, nil
+}
+
+// journalAmountParse parses amount of journal given with commodity before or after number (e.g. -10.50 EUR, $-10.50, -$10.50).
+// Commodity symbols are changed to currency codes.
+func journalAmountParse(s string) (v float64, currency string, err error) {
+	s = strings.TrimSpace(s)
+	var number, commodity strings.Builder
+	quoted := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case !quoted && (unicode.IsDigit(r) || r == '-' || r == '+' || r == '.' || r == ','):
+			number.WriteRune(r)
+		case !quoted && unicode.IsSpace(r):
+		default:
+			commodity.WriteRune(r)
+		}
+	}
+	if number.Len() == 0 {
+		return NotSetFloatValue, NotSetStringValue, errors.New(errJournalAmount + s)
+	}
+	if v, err = journalNumber(number.String()); err != nil {
+		return NotSetFloatValue, NotSetStringValue, err
+	}
+
+	return v, journalCurrency(commodity.String()), nil
+}
+
+// journalCurrency returns currency code for commodity (symbols are changed to codes)
+func journalCurrency(commodity string) string {
+	if c, ok := journalSymbols[commodity]; ok {
+		return c
+	}
+
+	return commodity
+}
+
+// journalDate parses date of ledger journal (e.g. 2016/01/31, 2016-01-31 or 2016.01.31)
+func journalDate(s string) (time.Time, error) {
+	d, err := time.Parse(DateFormat, strings.NewReplacer("/", DateSeparator, ".", DateSeparator).Replace(s))
+	if err != nil {
+		return time.Time{}, errors.New(errStatementDate + s)
+	}
+
+	return d, nil
+}
+
+// journalPeriod parses period of periodic transaction which can be budget, e.g. 'monthly from 2016/01/01 to 2016/07/01'
+func journalPeriod(s string) (b *journalBudget, ok bool) {
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) != 5 || fields[1] != "from" || (fields[3] != "to" && fields[3] != "until") {
+		return nil, false
+	}
+	b = new(journalBudget)
+	switch fields[0] {
+	case "monthly":
+	case "yearly":
+		b.yearly = true
+	default:
+		return nil, false
+	}
+	var err error
+	if b.from, err = journalPeriodDate(fields[2]); err != nil {
+		return nil, false
+	}
+	if b.to, err = journalPeriodDate(fields[4]); err != nil {
+		return nil, false
+	}
+
+	return b, b.from.Before(b.to)
+}
+
+// journalPeriodDate parses date of period, which may be given without day or month (e.g. 2016/01, 2016)
+func journalPeriodDate(s string) (time.Time, error) {
+	switch strings.Count(strings.NewReplacer("/", DateSeparator, ".", DateSeparator).Replace(s), DateSeparator) {
+	case 0:
+		s += "/01/01"
+	case 1:
+		s += "/01"
+	}
+
+	return journalDate(s)
+}
+
+// journalKind returns kind of journal account
+func journalKind(name string) int {
+	return journalKinds[strings.ToLower(strings.SplitN(name, CategoryPathSeparator, 2)[0])]
+}
+
+// journalAccountName returns name and type of account for asset or liability account of journal,
+// e.g. Bank (Operational) for Assets:Current:Bank, as written by JournalWriteLedger and JournalWriteBeancount
+func journalAccountName(name string) (string, AccountType) {
+	for t, prefix := range journalAccountTypes {
+		if len(name) > len(prefix) && strings.EqualFold(name[:len(prefix)+1], prefix+CategoryPathSeparator) {
+			return name[len(prefix)+1:], t
+		}
+	}
+	parts := strings.SplitN(name, CategoryPathSeparator, 2)
+	t := AccountType(ATTransactional)
+	if journalKind(name) == journalKindLiability {
+		t = ATLoan
+	}
+	if len(parts) == 1 {
+		return name, t
+	}
+
+	return parts[1], t
+}
+
+// journalCategoryPath returns path of category (main category first) for expense, income or equity account of journal
+func journalCategoryPath(name string) string {
+	parts := strings.SplitN(name, CategoryPathSeparator, 2)
+	if len(parts) == 1 {
+		return name
+	}
+
+	return parts[1]
+}
+
+// journalImport keeps objects of data file used while importing journal
+type journalImport struct {
+	j        *Journal
+	accounts map[string]*Account
+	ids      map[string]int
+}
+
+// JournalImport adds the contents of journal j to data file: accounts, main categories (of cost type for expenses,
+// income type for income and transfer type for equity), exchange rates, transactions and budgets.
+// Transactions with one account become transactions of its categories (split if there are many ones),
+// transactions with two accounts and no categories become transfers and the others are reported in warnings.
+// Transactions imported before are skipped, so the same journal can be imported again.
+func JournalImport(db *gsqlitehandler.SqliteDB, j *Journal) (added, skipped int, err error) {
+	ji := &journalImport{j: j, accounts: make(map[string]*Account), ids: make(map[string]int)}
+
+	if err = ji.addAccounts(db); err != nil {
+		return 0, 0, err
+	}
+	if err = ji.addMainCategories(db); err != nil {
+		return 0, 0, err
+	}
+	if err = ji.addExchangeRates(db); err != nil {
+		return 0, 0, err
+	}
+
+	s := new(Statement)
+	for _, e := range j.entries {
+		if st := ji.transaction(e); st != nil {
+			s.Transactions = append(s.Transactions, st)
+		}
+	}
+
+	var si *statementImport
+	if si, err = statementImportNew(db, s, nil, nil); err != nil {
+		return 0, 0, err
+	}
+	if si.tx, err = beginChange(db); err != nil {
+		return 0, 0, errors.New(errWritingToFile)
+	}
+	defer si.tx.Rollback()
+	if err = si.prepare(); err != nil {
+		return 0, 0, err
+	}
+	defer si.close()
+
+	if added, skipped, err = si.importTransactions(s, nil, nil); err != nil {
+		return 0, 0, err
+	}
+	if err = ji.addBudgets(si); err != nil {
+		return 0, 0, err
+	}
+	if err = si.tx.Commit(); err != nil {
+		return 0, 0, errors.New(errWritingToFile)
+	}
+
+	// Accounts are closed after their transactions are saved
+	for _, n := range j.names {
+		if a := ji.accounts[n]; a != nil && j.accounts[n].closed && a.Status != ISClose {
+			a.Status = ISClose
+			if err = AccountEdit(db, a); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+
+	return added, skipped, nil
+	//TODO: add test
+}
+
+// addAccounts finds or creates accounts of data file for asset and liability accounts of journal.
+// Currency of account is the one given when opening it or the one of its first posting.
+func (ji *journalImport) addAccounts(db *gsqlitehandler.SqliteDB) error {
+	currencies := make(map[string]string)
+	for _, e := range ji.j.entries {
+		for _, p := range e.postings {
+			if _, ok := currencies[p.account]; !ok && p.currency != NotSetStringValue {
+				currencies[p.account] = p.currency
+			}
+		}
+	}
+
+	existing := make(map[string]*Account)
+	getNextAccount, err := AccountList(db, NotSetStringValue, NotSetStringValue, NotSetStringValue, NotSetStringValue, ATUnset, ISUnset)
+	if err != nil {
+		return err
+	}
+	for a := getNextAccount(); a != nil; a = getNextAccount() {
+		existing[strings.ToLower(a.Name)] = a
+	}
+
+	used := make(map[string]bool)
+	for _, n := range ji.j.names {
+		if k := journalKind(n); k != journalKindAsset && k != journalKindLiability {
+			continue
+		}
+		name, t := journalAccountName(n)
+		if used[strings.ToLower(name)] {
+			name = n
+		}
+		used[strings.ToLower(name)] = true
+
+		if a := existing[strings.ToLower(name)]; a != nil {
+			if a.Status != ISOpen {
+				ji.j.Warnings = append(ji.j.Warnings, fmt.Sprintf("account %s skipped: account %s is closed", n, a.Name))
+				continue
+			}
+			ji.accounts[n] = a
+			continue
+		}
+		currency := ji.j.accounts[n].currency
+		if currency == NotSetStringValue {
+			currency = currencies[n]
+		}
+		if currency == NotSetStringValue {
+			ji.j.Warnings = append(ji.j.Warnings, fmt.Sprintf("account %s skipped: no currency", n))
+			continue
+		}
+		a := &Account{Name: name, Currency: strings.ToUpper(currency), AType: t, Status: ISOpen}
+		if err = AccountAdd(db, a); err != nil {
+			return err
+		}
+		ji.accounts[n] = a
+	}
+
+	return nil
+}
+
+// addMainCategories creates missing main categories for expense, income and equity accounts of journal
+func (ji *journalImport) addMainCategories(db *gsqlitehandler.SqliteDB) error {
+	existing := make(map[string]bool)
+	getNextMainCategory, err := MainCategoryList(db, nil, NotSetStringValue, ISUnset)
+	if err != nil {
+		return err
+	}
+	for m := getNextMainCategory(); m != nil; m = getNextMainCategory() {
+		existing[strings.ToLower(m.Name)] = true
+	}
+
+	types := map[int]int{journalKindExpense: MCTCost, journalKindIncome: MCTIncome, journalKindEquity: MCTTransfer}
+	for _, n := range ji.j.names {
+		t, ok := types[journalKind(n)]
+		if !ok {
+			continue
+		}
+		name := strings.Split(journalCategoryPath(n), CategoryPathSeparator)[0]
+		if existing[strings.ToLower(name)] {
+			continue
+		}
+		m := MainCategoryNew()
+		m.Name, m.Status, m.MType.Id = name, ISOpen, t
+		if err = MainCategoryAdd(db, m); err != nil {
+			return err
+		}
+		existing[strings.ToLower(name)] = true
+	}
+
+	return nil
+}
+
+// addExchangeRates adds or updates exchange rates with prices of journal (the last price of currency is used)
+func (ji *journalImport) addExchangeRates(db *gsqlitehandler.SqliteDB) error {
+	last := make(map[string]*ExchangeRate)
+	var keys []string
+	for _, e := range ji.j.prices {
+		k := strings.ToUpper(e.CurrencyFrom + " " + e.CurrencyTo)
+		if _, ok := last[k]; !ok {
+			keys = append(keys, k)
+		}
+		last[k] = e
+	}
+
+	for _, k := range keys {
+		e := last[k]
+		if strings.EqualFold(e.CurrencyFrom, e.CurrencyTo) {
+			continue
+		}
+		var err error
+		if old, _ := ExchangeRateForCurrencies(db, e.CurrencyFrom, e.CurrencyTo); old != nil {
+			err = ExchangeRateEdit(db, e)
+		} else {
+			err = ExchangeRateAdd(db, e)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// transaction returns transaction of statement for journal entry e, or nil (with warning) if it cannot be represented
+func (ji *journalImport) transaction(e *journalEntry) *StatementTransaction {
+	if e.invalid {
+		return nil
+	}
+
+	// Amount of balancing posting
+	var missing *journalPosting
+	sums := make(map[string]float64)
+	for _, p := range e.postings {
+		if p.missing {
+			if missing != nil {
+				ji.j.warn(e.line, "transaction with many postings without amount skipped")
+				return nil
+			}
+			missing = p
+			continue
+		}
+		v, c := p.weight()
+		sums[c] += v
+	}
+	if missing != nil {
+		if len(sums) != 1 {
+			ji.j.warn(e.line, "transaction in many currencies with posting without amount skipped")
+			return nil
+		}
+		for c, v := range sums {
+			missing.value, missing.currency, missing.missing = -v, c, false
+		}
+	}
+
+	var assets, categories []*journalPosting
+	for _, p := range e.postings {
+		switch journalKind(p.account) {
+		case journalKindAsset, journalKindLiability:
+			if ji.accounts[p.account] == nil {
+				ji.j.warn(e.line, "transaction of skipped account %s skipped", p.account)
+				return nil
+			}
+			assets = append(assets, p)
+		case journalKindExpense, journalKindIncome, journalKindEquity:
+			categories = append(categories, p)
+		default:
+			ji.j.warn(e.line, "transaction skipped: account %s is not under Assets, Liabilities, Expenses, Income or Equity", p.account)
+			return nil
+		}
+	}
+
+	switch {
+	case len(assets) == 1 && len(categories) > 0:
+		a := ji.accounts[assets[0].account]
+		v, ok := journalValue(assets[0], a.Currency)
+		if !ok {
+			ji.j.warn(e.line, "transaction skipped: amount of %s not in its currency %s", assets[0].account, a.Currency)
+			return nil
+		}
+		st := &StatementTransaction{Date: e.date, Description: e.description, Value: v, Account: a.Name}
+		st.ImportID = ji.importID(st)
+		if len(categories) == 1 {
+			st.Category = journalCategoryPath(categories[0].account)
+			return st
+		}
+		for i, p := range categories {
+			cv, ok := journalValue(p, a.Currency)
+			if !ok {
+				ji.j.warn(e.line, "transaction skipped: amount of %s not in currency %s of account", p.account, a.Currency)
+				return nil
+			}
+			st.Splits = append(st.Splits, &StatementTransaction{ImportID: fmt.Sprintf("%s/%d", st.ImportID, i+1), Date: e.date,
+				Description: e.description, Value: -cv, Account: a.Name, Category: journalCategoryPath(p.account)})
+		}
+		return st
+	case len(assets) == 2 && len(categories) == 0:
+		from, to := ji.accounts[assets[0].account], ji.accounts[assets[1].account]
+		if from.Id == to.Id {
+			ji.j.warn(e.line, "transfer to the same account skipped")
+			return nil
+		}
+		vFrom, okFrom := journalValue(assets[0], from.Currency)
+		vTo, okTo := journalValue(assets[1], to.Currency)
+		if !okFrom || !okTo {
+			ji.j.warn(e.line, "transfer skipped: amounts not in currencies of accounts")
+			return nil
+		}
+		return &StatementTransaction{Date: e.date, Description: e.description, Value: vFrom, Account: from.Name,
+			TransferAccount: to.Name, TransferValue: vTo}
+	}
+
+	ji.j.warn(e.line, "transaction with %d account(s) and %d category(ies) skipped", len(assets), len(categories))
+	return nil
+}
+
+// importID returns import ID of transaction: its fields and the number of identical transactions before
+func (ji *journalImport) importID(st *StatementTransaction) string {
+	id := fmt.Sprintf("journal:%s:%s:%.2f:%s", st.Account, st.Date.Format(DateFormat), st.Value, st.Description)
+	ji.ids[id]++
+
+	return fmt.Sprintf("%s#%d", id, ji.ids[id])
+}
+
+// journalValue returns value of posting in given currency: its amount or price (postings without currency are in any)
+func journalValue(p *journalPosting, currency string) (float64, bool) {
+	switch {
+	case p.currency == NotSetStringValue || strings.EqualFold(p.currency, currency):
+		return p.value, true
+	case strings.EqualFold(p.priceCurrency, currency):
+		return math.Copysign(p.price, p.value), true
+	}
+
+	return NotSetFloatValue, false
+}
+
+// addBudgets saves budgets of journal for every month (or year) of their periods
+func (ji *journalImport) addBudgets(si *statementImport) error {
+	stmt, err := si.tx.Prepare("INSERT OR REPLACE INTO budgets VALUES (?, ?, ?, round(?,2), upper(?));")
+	if err != nil {
+		return errors.New(errWritingToFile)
+	}
+	defer stmt.Close()
+
+	for _, b := range ji.j.budgets {
+		if k := journalKind(b.account); k != journalKindExpense && k != journalKindIncome && k != journalKindEquity {
+			ji.j.warn(b.line, "budget of %s skipped: it is not a category", b.account)
+			continue
+		}
+		if b.currency == NotSetStringValue {
+			ji.j.warn(b.line, "budget of %s skipped: no currency", b.account)
+			continue
+		}
+		var c *Category
+		if c, err = si.category(journalCategoryPath(b.account), -b.value); err != nil {
+			return err
+		}
+
+		// Budgets are kept with values as typed by user, which are signed by main category type factor
+		v := -b.value * float64(c.Main.MType.Factor)
+		for d := b.from; d.Before(b.to); {
+			month, next := d.Month(), d.AddDate(0, 1, 0)
+			if b.yearly {
+				month, next = time.Month(NotSetIntValue), d.AddDate(1, 0, 0)
+			}
+			if _, err = stmt.Exec(d.Year(), month, c.Id, v, b.currency); err != nil {
+				return errors.New(errWritingToFile)
+			}
+			d = next
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/zbroju/gsqlitehandler"
+	"net/url"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Concurrent access to data file: other applications wait for the lock of data file up to dataFileLockWait,
+// and SQLite waits for locks of database up to dataFileBusyTimeout (in milliseconds) retrying meanwhile.
+const (
+	dataFileLockExtension = ".lock"
+	dataFileLockWait      = 10 * time.Second
+	dataFileLockRetry     = 200 * time.Millisecond
+	dataFileBusyTimeout   = 10000
+)
+
+// Paths of data files given to GetDataFileHandler and lock files of the opened ones
+var (
+	dataFilePaths = make(map[*gsqlitehandler.SqliteDB]string)
+	dataFileLocks = make(map[*gsqlitehandler.SqliteDB]string)
+)
+
+// lockDataFile creates lock file of data file with ID of the process and name of the host.
+// Lock files left by processes which ended are removed, for other ones it waits up to dataFileLockWait.
+func lockDataFile(path string) (lockPath string, err error) {
+	lockPath = path + dataFileLockExtension
+	host, _ := os.Hostname()
+
+	deadline := time.Now().Add(dataFileLockWait)
+	for {
+		var f *os.File
+		if f, err = os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600); err == nil {
+			_, err = fmt.Fprintf(f, "%d %s\n", os.Getpid(), host)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				os.Remove(lockPath)
+				return NotSetStringValue, err
+			}
+			return lockPath, nil
+		}
+		if !os.IsExist(err) {
+			return NotSetStringValue, err
+		}
+
+		// Lock file may be removed meanwhile, and it is empty until its owner writes to it
+		var pid int
+		var owner string
+		if l, err := os.ReadFile(lockPath); err == nil {
+			fmt.Sscan(string(l), &pid, &owner)
+		}
+		if pid > 0 && owner == host && !processRunning(pid) {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return NotSetStringValue, errors.New(errDataFileInUse + fmt.Sprintf("%d (%s)", pid, owner))
+		}
+		time.Sleep(dataFileLockRetry)
+	}
+}
+
+// unlockDataFile removes lock file of data file opened with db
+func unlockDataFile(db *gsqlitehandler.SqliteDB) {
+	if lockPath, ok := dataFileLocks[db]; ok {
+		delete(dataFileLocks, db)
+		os.Remove(lockPath)
+	}
+}
+
+// KeepDataFileUnlocked removes lock of data file opened with db by application keeping it open for long (e.g. server),
+// so that other applications do not wait for it until it ends; SQLite locks of database keep their changes from colliding then.
+// Encrypted data files stay locked, as their decrypted copies are saved when they are closed.
+func KeepDataFileUnlocked(db *gsqlitehandler.SqliteDB) {
+	if _, ok := encryptedDataFiles[db]; !ok {
+		unlockDataFile(db)
+	}
+	//TODO: add test
+}
+
+// withDataFileLock calls f with data file locked, unless it is already opened (and locked) by the application
+func withDataFileLock(path string, f func() error) error {
+	for _, lockPath := range dataFileLocks {
+		if lockPath == path+dataFileLockExtension {
+			return f()
+		}
+	}
+	lockPath, err := lockDataFile(path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(lockPath)
+
+	return f()
+}
+
+// processRunning checks if process with given ID is running (on the current host)
+func processRunning(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = p.Signal(syscall.Signal(0))
+
+	// Other errors (e.g. no permission to signal the process) mean that the process exists
+	return !errors.Is(err, os.ErrProcessDone) && !errors.Is(err, syscall.ESRCH)
+}
+
+// useConcurrentAccess reopens database of data file (at path) in WAL mode, which lets reading it while it is written,
+// with busy timeout and transactions taking write lock at once, so that they wait for each other instead of failing
+func useConcurrentAccess(db *gsqlitehandler.SqliteDB, path string) error {
+	dsn := fmt.Sprintf("file:%s?_busy_timeout=%d&_journal_mode=WAL&_txlock=immediate", (&url.URL{Path: path}).EscapedPath(), dataFileBusyTimeout)
+	h, err := sql.Open("sqlite3", dsn)
+	if err == nil {
+		err = h.Ping()
+	}
+	if err != nil {
+		return errors.New(errReadingFromFile)
+	}
+	db.Handler.Close()
+	db.Handler = h
+
+	return nil
+}
+
+// checkpointDataFile moves changes left in write-ahead log of data file (e.g. by application which was killed) to the file
+func checkpointDataFile(path string) error {
+	if fi, err := os.Stat(path + "-wal"); err != nil || fi.Size() == 0 {
+		return nil
+	}
+	h, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return errors.New(errReadingFromFile)
+	}
+	defer h.Close()
+	if _, err = h.Exec("PRAGMA wal_checkpoint(TRUNCATE);"); err != nil {
+		return errors.New(errWritingToFile)
+	}
+
+	return nil
+}
@@ -36,14 +36,22 @@ func MainCategoryAdd(db *gsqlitehandler.SqliteDB, m *MainCategory) error {
 		t.Id = MCTCost
 	}
 
-	if stmt, err = db.Handler.Prepare("INSERT INTO main_categories VALUES (NULL, ?, ?, ?);"); err != nil {
+	if err = beforeChange(db); err != nil {
+		return err
+	}
+
+	if stmt, err = dbHandler(db).Prepare("INSERT INTO main_categories VALUES (NULL, ?, ?, ?);"); err != nil {
 		return errors.New(errWritingToFile)
 	}
 	defer stmt.Close()
 
-	if _, err = stmt.Exec(m.MType.Id, m.Name, m.Status); err != nil {
+	var res sql.Result
+	if res, err = stmt.Exec(m.MType.Id, m.Name, m.Status); err != nil {
 		return errors.New(errWritingToFile)
 	}
+	if id, err := res.LastInsertId(); err == nil {
+		m.Id = id
+	}
 
 	return nil
 	//TODO: add test
@@ -53,16 +61,16 @@ func MainCategoryAdd(db *gsqlitehandler.SqliteDB, m *MainCategory) error {
 func MainCategoryForID(db *gsqlitehandler.SqliteDB, i int) (m *MainCategory, err error) {
 	var stmt *sql.Stmt
 
-	sqlQuery := "SELECT m.id, m.name, m.status, t.id, t.name, t.factor " +
+	sqlQuery := "SELECT m.id, m.name, m.status, t.id, t.name, t.factor, t.in_income_cost, t.in_budget, t.in_net_value, t.status " +
 		"FROM main_categories m INNER JOIN main_categories_types t ON m.type_id=t.id " +
 		"WHERE m.id=? AND m.status<>?;"
-	if stmt, err = db.Handler.Prepare(sqlQuery); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 	defer stmt.Close()
 
 	m = MainCategoryNew()
-	if err = stmt.QueryRow(i, ISClose).Scan(&m.Id, &m.Name, &m.Status, &m.MType.Id, &m.MType.Name, &m.MType.Factor); err != nil {
+	if err = stmt.QueryRow(i, ISClose).Scan(&m.Id, &m.Name, &m.Status, &m.MType.Id, &m.MType.Name, &m.MType.Factor, &m.MType.InIncomeCost, &m.MType.InBudget, &m.MType.InNetValue, &m.MType.Status); err != nil {
 		return nil, errors.New(errMainCategoryWithIDNone)
 	}
 
@@ -79,10 +87,10 @@ func MainCategoryForName(db *gsqlitehandler.SqliteDB, n string) (m *MainCategory
 
 	n = "%" + n + "%"
 
-	sqlQuery := "SELECT m.id, m.name, m.status, t.id, t.name, t.factor " +
+	sqlQuery := "SELECT m.id, m.name, m.status, t.id, t.name, t.factor, t.in_income_cost, t.in_budget, t.in_net_value, t.status " +
 		"FROM main_categories m INNER JOIN main_categories_types t ON m.type_id=t.id " +
 		"WHERE m.name LIKE ? AND m.status<>?;"
-	if stmt, err = db.Handler.Prepare(sqlQuery); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 	defer stmt.Close()
@@ -96,7 +104,7 @@ func MainCategoryForName(db *gsqlitehandler.SqliteDB, n string) (m *MainCategory
 	var noOfMainCategories int
 	for rows.Next() {
 		noOfMainCategories++
-		rows.Scan(&m.Id, &m.Name, &m.Status, &m.MType.Id, &m.MType.Name, &m.MType.Factor)
+		rows.Scan(&m.Id, &m.Name, &m.Status, &m.MType.Id, &m.MType.Name, &m.MType.Factor, &m.MType.InIncomeCost, &m.MType.InBudget, &m.MType.InNetValue, &m.MType.Status)
 	}
 
 	switch noOfMainCategories {
@@ -122,8 +130,12 @@ func MainCategoryEdit(db *gsqlitehandler.SqliteDB, m *MainCategory) error {
 		return errors.New(errSystemObject)
 	}
 
+	if err = beforeChange(db); err != nil {
+		return err
+	}
+
 	sqlQuery := "UPDATE main_categories SET type_id=?, name=?, status=? WHERE id=?;"
-	if stmt, err = db.Handler.Prepare(sqlQuery); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
 		return errors.New(errWritingToFile)
 	}
 	defer stmt.Close()
@@ -146,8 +158,12 @@ func MainCategoryRemove(db *gsqlitehandler.SqliteDB, m *MainCategory) error {
 		return errors.New(errSystemObject)
 	}
 
+	if err = beforeChange(db); err != nil {
+		return err
+	}
+
 	// Set correct status (ISClose)
-	if stmt, err = db.Handler.Prepare("UPDATE main_categories SET status=? WHERE id=?;"); err != nil {
+	if stmt, err = dbHandler(db).Prepare("UPDATE main_categories SET status=? WHERE id=?;"); err != nil {
 		return errors.New(errWritingToFile)
 	}
 	defer stmt.Close()
@@ -177,10 +193,10 @@ func MainCategoryList(db *gsqlitehandler.SqliteDB, t *MainCategoryType, n string
 		tId = t.Id
 	}
 
-	sqlQuery := "SELECT m.id, m.name, m.status, t.id, t.name, t.factor " +
+	sqlQuery := "SELECT m.id, m.name, m.status, t.id, t.name, t.factor, t.in_income_cost, t.in_budget, t.in_net_value, t.status " +
 		"FROM main_categories m INNER JOIN main_categories_types t ON m.type_id=t.id " +
 		"WHERE (m.type_id=? OR ?=?) AND (m.name LIKE ? OR ?=?) AND (m.status=? or ?=?) ORDER BY t.id, m.name;"
-	if stmt, err = db.Handler.Prepare(sqlQuery); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 	if rows, err = stmt.Query(tId, tId, noIntParamForSQL, n, n, noStringParamForSQL, s, s, ISUnset); err != nil {
@@ -190,7 +206,7 @@ func MainCategoryList(db *gsqlitehandler.SqliteDB, t *MainCategoryType, n string
 	f = func() *MainCategory {
 		if rows.Next() {
 			m := MainCategoryNew()
-			rows.Scan(&m.Id, &m.Name, &m.Status, &m.MType.Id, &m.MType.Name, &m.MType.Factor)
+			rows.Scan(&m.Id, &m.Name, &m.Status, &m.MType.Id, &m.MType.Name, &m.MType.Factor, &m.MType.InIncomeCost, &m.MType.InBudget, &m.MType.InNetValue, &m.MType.Status)
 			return m
 		}
 		rows.Close()
@@ -12,18 +12,29 @@ import (
 
 // Errors
 const (
-	errMainCategoriesTypeWithNameNone = "there is no main category type with such name"
-	errMainCategoryTypeNameAmbiguous  = "main category type name is ambiguous"
+	errMainCategoriesTypeWithNameNone   = "there is no main category type with such name"
+	errMainCategoryTypeNameAmbiguous    = "main category type name is ambiguous"
+	errMainCategoryTypeWithIDNone       = "no main category type with given ID"
+	errMainCategoryTypeFactorIncorrect  = "main category type factor must be 1 or -1"
+	errMainCategoryTypeInUse            = "main category type is used by open main categories"
+	errMainCategoryTypeNameAlreadyExist = "main category type with given name already exists"
 )
 
-// MainCategoryStatusT describes the behaviour of categories and its descendants (transactions)
+// MainCategoryType describes the behaviour of categories and its descendants (transactions).
+// Factor gives the sign of transactions values, and the In* flags decide
+// which reports take the transactions into account.
 type MainCategoryType struct {
-	Id     int
-	Name   string
-	Factor int
+	Id           int
+	Name         string
+	Factor       int
+	InIncomeCost bool
+	InBudget     bool
+	InNetValue   bool
+	Status       ItemStatus
 }
 
 // MCT* constants identify particular type in database (used as id)
+// for the types created together with the data file.
 const (
 	MCTUnknown = iota
 	MCTUnset
@@ -32,20 +43,63 @@ const (
 	MCTIncome
 )
 
+// MainCategoryTypeNew returns pointer to new main category type with all flags set
+func MainCategoryTypeNew() *MainCategoryType {
+	return &MainCategoryType{Factor: -1, InIncomeCost: true, InBudget: true, InNetValue: true, Status: ISOpen}
+}
+
+// MainCategoryTypeAdd adds new main category type
+func MainCategoryTypeAdd(db *gsqlitehandler.SqliteDB, t *MainCategoryType) error {
+	var err error
+	var stmt *sql.Stmt
+
+	if t.Factor != 1 && t.Factor != -1 {
+		return errors.New(errMainCategoryTypeFactorIncorrect)
+	}
+	if ot, err := mainCategoryTypeForExactName(db, t.Name); err != nil {
+		return err
+	} else if ot != nil {
+		return errors.New(errMainCategoryTypeNameAlreadyExist)
+	}
+
+	if err = beforeChange(db); err != nil {
+		return err
+	}
+
+	if stmt, err = dbHandler(db).Prepare("INSERT INTO main_categories_types VALUES (NULL, ?, ?, ?, ?, ?, ?);"); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	defer stmt.Close()
+
+	var res sql.Result
+	if res, err = stmt.Exec(t.Name, t.Factor, t.InIncomeCost, t.InBudget, t.InNetValue, t.Status); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		t.Id = int(id)
+	}
+
+	return nil
+	//TODO: add test
+}
+
 // MainCategoryTypeForID returns pointer to the Main Category Type for given ID
 func MainCategoryTypeForID(db *gsqlitehandler.SqliteDB, i int) (mt *MainCategoryType, err error) {
 	var stmt *sql.Stmt
 
-	sqlQuery := "SELECT id, name, factor " +
+	sqlQuery := "SELECT id, name, factor, in_income_cost, in_budget, in_net_value, status " +
 		"FROM main_categories_types " +
 		"WHERE id=?;"
-	if stmt, err = db.Handler.Prepare(sqlQuery); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 	defer stmt.Close()
 
 	mt = new(MainCategoryType)
-	if err = stmt.QueryRow(i).Scan(&mt.Id, &mt.Name, &mt.Factor); err != nil {
+	if err = stmt.QueryRow(i).Scan(&mt.Id, &mt.Name, &mt.Factor, &mt.InIncomeCost, &mt.InBudget, &mt.InNetValue, &mt.Status); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New(errMainCategoryTypeWithIDNone)
+		}
 		return nil, errors.New(errReadingFromFile)
 	}
 
@@ -58,17 +112,22 @@ func MainCategoryTypeForName(db *gsqlitehandler.SqliteDB, n string) (mt *MainCat
 	var stmt *sql.Stmt
 	var rows *sql.Rows
 
+	// Exact name wins over partial matches, so that e.g. 'Cost' and 'Cost of living' can be told apart
+	if mt, err = mainCategoryTypeForExactName(db, n); err != nil || mt != nil {
+		return mt, err
+	}
+
 	n = "%" + n + "%"
-	sqlQuery := "SELECT id, name, factor " +
+	sqlQuery := "SELECT id, name, factor, in_income_cost, in_budget, in_net_value, status " +
 		"FROM main_categories_types " +
-		"WHERE name LIKE ?;"
-	if stmt, err = db.Handler.Prepare(sqlQuery); err != nil {
+		"WHERE name LIKE ? AND status<>?;"
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 	defer stmt.Close()
 
 	mt = new(MainCategoryType)
-	if rows, err = stmt.Query(n); err != nil {
+	if rows, err = stmt.Query(n, ISClose); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 	defer rows.Close()
@@ -76,7 +135,7 @@ func MainCategoryTypeForName(db *gsqlitehandler.SqliteDB, n string) (mt *MainCat
 	var noOfTypes int
 	for rows.Next() {
 		noOfTypes++
-		rows.Scan(&mt.Id, &mt.Name, &mt.Factor)
+		rows.Scan(&mt.Id, &mt.Name, &mt.Factor, &mt.InIncomeCost, &mt.InBudget, &mt.InNetValue, &mt.Status)
 	}
 
 	switch noOfTypes {
@@ -90,3 +149,134 @@ func MainCategoryTypeForName(db *gsqlitehandler.SqliteDB, n string) (mt *MainCat
 
 	//TODO: add test
 }
+
+// mainCategoryTypeForExactName returns pointer to Main Category Type with exactly given name (case insensitive)
+// or nil if there is no such type
+func mainCategoryTypeForExactName(db *gsqlitehandler.SqliteDB, n string) (mt *MainCategoryType, err error) {
+	var stmt *sql.Stmt
+
+	sqlQuery := "SELECT id, name, factor, in_income_cost, in_budget, in_net_value, status " +
+		"FROM main_categories_types " +
+		"WHERE lower(name)=lower(?) AND status<>?;"
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
+		return nil, errors.New(errReadingFromFile)
+	}
+	defer stmt.Close()
+
+	mt = new(MainCategoryType)
+	switch err = stmt.QueryRow(n, ISClose).Scan(&mt.Id, &mt.Name, &mt.Factor, &mt.InIncomeCost, &mt.InBudget, &mt.InNetValue, &mt.Status); err {
+	case nil:
+		return mt, nil
+	case sql.ErrNoRows:
+		return nil, nil
+	default:
+		return nil, errors.New(errReadingFromFile)
+	}
+}
+
+// MainCategoryTypeEdit updates main category type with new values for name, factor and flags.
+// All the values are updated, so make sure you pass old values in argument 't'
+func MainCategoryTypeEdit(db *gsqlitehandler.SqliteDB, t *MainCategoryType) error {
+	var err error
+	var stmt *sql.Stmt
+
+	// Check if it is not a system object
+	if t.Status == ISSystem {
+		return errors.New(errSystemObject)
+	}
+	if t.Factor != 1 && t.Factor != -1 {
+		return errors.New(errMainCategoryTypeFactorIncorrect)
+	}
+	if ot, err := mainCategoryTypeForExactName(db, t.Name); err != nil {
+		return err
+	} else if ot != nil && ot.Id != t.Id {
+		return errors.New(errMainCategoryTypeNameAlreadyExist)
+	}
+
+	if err = beforeChange(db); err != nil {
+		return err
+	}
+
+	sqlQuery := "UPDATE main_categories_types SET name=?, factor=?, in_income_cost=?, in_budget=?, in_net_value=? WHERE id=?;"
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.Exec(t.Name, t.Factor, t.InIncomeCost, t.InBudget, t.InNetValue, t.Id); err != nil {
+		return errors.New(errWritingToFile)
+	}
+
+	return nil
+	//TODO: add test
+}
+
+// MainCategoryTypeRemove updates main category type status with ISClose.
+// Types still used by open main categories cannot be removed.
+func MainCategoryTypeRemove(db *gsqlitehandler.SqliteDB, t *MainCategoryType) error {
+	var err error
+	var stmt *sql.Stmt
+
+	// Check if it is not a system object
+	if t.Status == ISSystem {
+		return errors.New(errSystemObject)
+	}
+
+	// Check if it is not used
+	var noOfMainCategories int
+	if err = dbHandler(db).QueryRow("SELECT count(*) FROM main_categories WHERE type_id=? AND status<>?;", t.Id, ISClose).Scan(&noOfMainCategories); err != nil {
+		return errors.New(errReadingFromFile)
+	}
+	if noOfMainCategories > 0 {
+		return errors.New(errMainCategoryTypeInUse)
+	}
+
+	if err = beforeChange(db); err != nil {
+		return err
+	}
+
+	// Set correct status (ISClose)
+	if stmt, err = dbHandler(db).Prepare("UPDATE main_categories_types SET status=? WHERE id=?;"); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.Exec(ISClose, t.Id); err != nil {
+		return errors.New(errWritingToFile)
+	}
+
+	return nil
+	//TODO: add test
+}
+
+// MainCategoryTypeList returns closure which generates a sequence of Main Category Type objects.
+// Technical types (unknown and not set) are skipped.
+func MainCategoryTypeList(db *gsqlitehandler.SqliteDB, s ItemStatus) (f func() *MainCategoryType, err error) {
+	var stmt *sql.Stmt
+	var rows *sql.Rows
+
+	sqlQuery := "SELECT id, name, factor, in_income_cost, in_budget, in_net_value, status " +
+		"FROM main_categories_types " +
+		"WHERE id>? AND (status=? OR status=? OR ?=?) ORDER BY id;"
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
+		return nil, errors.New(errReadingFromFile)
+	}
+	if rows, err = stmt.Query(MCTUnset, s, ISSystem, s, ISUnset); err != nil {
+		return nil, errors.New(errReadingFromFile)
+	}
+
+	f = func() *MainCategoryType {
+		if rows.Next() {
+			t := new(MainCategoryType)
+			rows.Scan(&t.Id, &t.Name, &t.Factor, &t.InIncomeCost, &t.InBudget, &t.InNetValue, &t.Status)
+			return t
+		}
+		rows.Close()
+		stmt.Close()
+
+		return nil
+	}
+
+	return f, nil
+	//TODO: add test
+}
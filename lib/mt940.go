@@ -0,0 +1,303 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MT940 date format (value dates and dates of balances)
+const mt940DateFormat = "060102"
+
+// StatementReadMT940 reads bank statement from SWIFT MT940 file. Files with many statements are accepted -
+// the opening balance is then taken from the first and the closing balance from the last one.
+// Information for the account owner (field 86) is read in German (?20, ?32...) and Dutch (/NAME/, /REMI/...)
+// structured forms, otherwise it is used as free text.
+func StatementReadMT940(r io.Reader) (s *Statement, err error) {
+	s = new(Statement)
+	ids := make(map[string]int)
+	var opened bool
+	var openingDate time.Time
+	var st *StatementTransaction
+	var info string
+
+	// finish completes the last read transaction with information for the account owner
+	finish := func() {
+		if st == nil {
+			return
+		}
+		mt940Information(st, info)
+		st.ImportID = mt940ImportID(st, ids)
+		s.Transactions = append(s.Transactions, st)
+		st, info = nil, NotSetStringValue
+	}
+
+	fields, err := mt940Fields(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, errors.New(errStatementFormat)
+	}
+	for _, f := range fields {
+		switch f.tag {
+		case "20":
+			finish()
+		case "60F", "60M":
+			finish()
+			if !opened {
+				if s.OpeningBalance, openingDate, s.Currency, err = mt940Balance(f.value); err != nil {
+					return nil, err
+				}
+				opened = true
+			}
+		case "61":
+			finish()
+			if st, err = mt940Transaction(f.value); err != nil {
+				return nil, err
+			}
+		case "86":
+			if st != nil {
+				info = f.value
+			}
+		case "62F", "62M":
+			finish()
+			if s.Balance, s.BalanceDate, _, err = mt940Balance(f.value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	finish()
+	s.OpeningBalanceDate = s.openingBalanceDate(openingDate)
+
+	return s, nil
+	//TODO: add test
+}
+
+// mt940Field is a field of MT940 file with its tag (e.g. 61) and value (possibly of many lines)
+type mt940Field struct {
+	tag   string
+	value string
+}
+
+// mt940Fields splits MT940 file into fields. Lines which do not start with tag continue the previous field.
+// Headers and trailers of SWIFT messages ({1:...}, -}) are skipped.
+func mt940Fields(r io.Reader) (fields []mt940Field, err error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if !utf8.ValidString(line) {
+			line = latin1ToString([]byte(line))
+		}
+		if i := strings.Index(line, "{4:"); i >= 0 {
+			line = line[i+len("{4:"):]
+		}
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == NotSetStringValue || trimmed == "-" || strings.HasPrefix(trimmed, "-}") || strings.HasPrefix(trimmed, "{"):
+			continue
+		case len(line) > 3 && line[0] == ':':
+			if end := strings.Index(line[1:], ":"); end > 0 && end <= 3 {
+				fields = append(fields, mt940Field{tag: line[1 : end+1], value: line[end+2:]})
+				continue
+			}
+		}
+		if n := len(fields); n > 0 {
+			fields[n-1].value += "\n" + line
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// mt940Balance parses balance field, e.g. C160131EUR1234,56
+func mt940Balance(s string) (v float64, d time.Time, currency string, err error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 11 || (s[0] != 'C' && s[0] != 'D') {
+		return NotSetFloatValue, time.Time{}, NotSetStringValue, errors.New(errStatementFormat)
+	}
+	if d, err = mt940Date(s[1:7]); err != nil {
+		return NotSetFloatValue, time.Time{}, NotSetStringValue, err
+	}
+	currency = s[7:10]
+	if v, err = mt940Amount(s[10:]); err != nil {
+		return NotSetFloatValue, time.Time{}, NotSetStringValue, err
+	}
+	if s[0] == 'D' {
+		v = -v
+	}
+
+	return v, d, currency, nil
+}
+
+// mt940Transaction parses statement line (field 61), e.g. 1601310131DR12,50NTRFNONREF//B6A31XYZ
+// (value date, booking date, debit/credit mark, amount, type, reference for the account owner, bank reference)
+func mt940Transaction(s string) (st *StatementTransaction, err error) {
+	st = new(StatementTransaction)
+	line := s
+	if i := strings.Index(s, "\n"); i >= 0 {
+		line = s[:i]
+	}
+	if len(line) < 6 {
+		return nil, errors.New(errStatementFormat)
+	}
+	if st.ValueDate, err = mt940Date(line[:6]); err != nil {
+		return nil, err
+	}
+	line = line[6:]
+
+	// Booking date is given without year, which is that of value date unless they are on the other sides of new year
+	st.Date = st.ValueDate
+	if len(line) >= 4 && line[0] >= '0' && line[0] <= '9' {
+		var m, d int
+		if m, err = strconv.Atoi(line[:2]); err == nil {
+			d, err = strconv.Atoi(line[2:4])
+		}
+		if err != nil {
+			return nil, errors.New(errStatementDate + line[:4])
+		}
+		st.Date = time.Date(st.ValueDate.Year(), time.Month(m), d, 0, 0, 0, 0, time.UTC)
+		switch diff := st.Date.Sub(st.ValueDate); {
+		case diff > 180*24*time.Hour:
+			st.Date = st.Date.AddDate(-1, 0, 0)
+		case diff < -180*24*time.Hour:
+			st.Date = st.Date.AddDate(1, 0, 0)
+		}
+		line = line[4:]
+	}
+
+	// Debit/credit mark, with R for reversals
+	var debit bool
+	switch {
+	case strings.HasPrefix(line, "RD"):
+		line = line[2:]
+	case strings.HasPrefix(line, "RC"):
+		debit, line = true, line[2:]
+	case strings.HasPrefix(line, "D"):
+		debit, line = true, line[1:]
+	case strings.HasPrefix(line, "C"):
+		line = line[1:]
+	default:
+		return nil, errors.New(errStatementFormat)
+	}
+	// Third character of currency code (funds code) may precede the amount
+	if len(line) > 0 && line[0] >= 'A' && line[0] <= 'Z' {
+		line = line[1:]
+	}
+	end := strings.IndexFunc(line, func(r rune) bool { return (r < '0' || r > '9') && r != ',' })
+	if end < 0 {
+		end = len(line)
+	}
+	if st.Value, err = mt940Amount(line[:end]); err != nil {
+		return nil, err
+	}
+	if debit {
+		st.Value = -st.Value
+	}
+
+	// Transaction type (N and three characters) and references
+	line = line[end:]
+	if len(line) >= 4 {
+		line = line[4:]
+	}
+	if i := strings.Index(line, "//"); i >= 0 {
+		st.Reference = strings.TrimSpace(line[i+2:])
+	}
+
+	return st, nil
+}
+
+// mt940Information sets counterparty and description of transaction from information for the account owner (field 86)
+func mt940Information(st *StatementTransaction, info string) {
+	info = strings.TrimSpace(info)
+	var remittance string
+	switch {
+	case strings.HasPrefix(info, "?") || len(info) > 3 && info[3] == '?':
+		// German structured form (usually after transaction code): ?20-?29 and ?60-?63 remittance,
+		// ?31 IBAN of counterparty, ?32-?33 its name
+		parts := strings.Split(strings.Replace(info, "\n", NotSetStringValue, -1), "?")
+		var text, name []string
+		for _, p := range parts[1:] {
+			if len(p) < 2 {
+				continue
+			}
+			code, value := p[:2], p[2:]
+			switch {
+			case code >= "20" && code <= "29", code >= "60" && code <= "63":
+				text = append(text, value)
+			case code == "31":
+				st.CounterpartyIBAN = strings.TrimSpace(value)
+			case code == "32", code == "33":
+				name = append(name, value)
+			}
+		}
+		st.Counterparty = strings.TrimSpace(strings.Join(name, NotSetStringValue))
+		remittance = strings.Join(text, NotSetStringValue)
+	case strings.HasPrefix(info, "/"):
+		// Dutch structured form: /CODE/value/CODE/value...
+		parts := strings.Split(strings.Replace(info, "\n", NotSetStringValue, -1), "/")
+		for i := 1; i+1 < len(parts); i += 2 {
+			switch parts[i] {
+			case "NAME":
+				st.Counterparty = strings.TrimSpace(parts[i+1])
+			case "IBAN":
+				st.CounterpartyIBAN = strings.TrimSpace(parts[i+1])
+			case "REMI":
+				remittance = parts[i+1]
+			}
+		}
+	default:
+		remittance = strings.Replace(info, "\n", " ", -1)
+	}
+	st.Description = statementDescription(st.Counterparty, strings.Join(strings.Fields(remittance), " "))
+}
+
+// mt940ImportID returns import ID of transaction: its bank reference (numbered if it is repeated in the statement)
+// or, if there is none, its fields and the number of identical transactions read before
+func mt940ImportID(st *StatementTransaction, ids map[string]int) string {
+	if st.Reference != NotSetStringValue && !strings.EqualFold(st.Reference, "NONREF") {
+		id := "mt940:" + st.Reference
+		ids[id]++
+		if n := ids[id]; n > 1 {
+			return fmt.Sprintf("%s#%d", id, n)
+		}
+		return id
+	}
+
+	id := fmt.Sprintf("mt940:%s:%.2f:%s", st.Date.Format(DateFormat), st.Value, st.Description)
+	ids[id]++
+
+	return fmt.Sprintf("%s#%d", id, ids[id])
+}
+
+// mt940Date parses date of MT940 file (YYMMDD)
+func mt940Date(s string) (time.Time, error) {
+	d, err := time.Parse(mt940DateFormat, s)
+	if err != nil {
+		return time.Time{}, errors.New(errStatementDate + s)
+	}
+
+	return d, nil
+}
+
+// mt940Amount parses amount of MT940 file (with comma as decimal separator)
+func mt940Amount(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(s), ",", ".", 1), 64)
+	if err != nil {
+		return NotSetFloatValue, errors.New(errStatementAmount + s)
+	}
+
+	return v, nil
+}
@@ -0,0 +1,79 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStatementReadMT940(t *testing.T) {
+	type transaction struct {
+		importID, date, description, counterparty, iban string
+		value                                           float64
+	}
+	tests := []struct {
+		name         string
+		file         string
+		openingDate  string
+		transactions []transaction
+	}{
+		{
+			name: "free text in many lines of field 86",
+			file: ":20:STMT1\n:25:SE123\n:28C:1/1\n:60F:C160302SEK100,00\n" +
+				":61:1603030303DR24,50NTRFNONREF//B1\n:86:Invoice 12\nfor March\n  2016\n" +
+				":62F:C160303SEK75,50\n-",
+			openingDate: "2016-03-02",
+			transactions: []transaction{
+				{importID: "mt940:B1", date: "2016-03-03", description: "Invoice 12 for March 2016", value: -24.5},
+			},
+		},
+		{
+			name: "German structured form split over lines",
+			file: ":20:STMT1\n:25:SE123\n:28C:1/1\n:60F:C160303SEK100,00\n" +
+				":61:1603030303DR24,50NTRFNONREF//B1\n:86:166?00SEPA?20Invoice ?2112?31SE999\n?32ICA Super?33market\n" +
+				":62F:C160303SEK75,50\n-",
+			openingDate: "2016-03-02",
+			transactions: []transaction{
+				{importID: "mt940:B1", date: "2016-03-03", description: "ICA Supermarket - Invoice 12", counterparty: "ICA Supermarket", iban: "SE999", value: -24.5},
+			},
+		},
+		{
+			name: "Dutch structured form and repeated bank reference",
+			file: ":20:STMT1\n:25:SE123\n:28C:1/1\n:60F:C160302SEK100,00\n" +
+				":61:160310C10,NTRFNONREF//B7\n:86:/NAME/Mom/IBAN/SE1/\nREMI/Gift/\n" +
+				":61:160310C10,NTRFNONREF//B7\n:86:/NAME/Mom/IBAN/SE1/REMI/Gift/\n" +
+				":62F:C160310SEK120,00\n-",
+			openingDate: "2016-03-09",
+			transactions: []transaction{
+				{importID: "mt940:B7", date: "2016-03-10", description: "Mom - Gift", counterparty: "Mom", iban: "SE1", value: 10},
+				{importID: "mt940:B7#2", date: "2016-03-10", description: "Mom - Gift", counterparty: "Mom", iban: "SE1", value: 10},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := StatementReadMT940(strings.NewReader(tt.file))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if d := s.OpeningBalanceDate.Format(DateFormat); d != tt.openingDate {
+				t.Errorf("opening balance date = %s, want %s", d, tt.openingDate)
+			}
+			if len(s.Transactions) != len(tt.transactions) {
+				t.Fatalf("got %d transactions, want %d", len(s.Transactions), len(tt.transactions))
+			}
+			for i, want := range tt.transactions {
+				st := s.Transactions[i]
+				got := transaction{importID: st.ImportID, date: st.Date.Format(DateFormat), description: st.Description,
+					counterparty: st.Counterparty, iban: st.CounterpartyIBAN, value: st.Value}
+				if got != want {
+					t.Errorf("transaction %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"errors"
+	"html"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// OFX date format (the time part and time zone which may follow are skipped)
+const ofxDateFormat = "20060102"
+
+// StatementReadOFX reads bank or credit card statement from OFX (or QFX) file.
+// Both OFX 1.x (SGML, where elements with values need not be closed) and OFX 2.x (XML) are accepted.
+func StatementReadOFX(r io.Reader) (s *Statement, err error) {
+	var data []byte
+	if data, err = ioutil.ReadAll(r); err != nil {
+		return nil, err
+	}
+	content := string(data)
+	if !utf8.Valid(data) {
+		// OFX 1.x files are usually encoded in single byte code pages
+		content = latin1ToString(data)
+	}
+
+	start := strings.Index(strings.ToUpper(content), "<OFX>")
+	if start < 0 {
+		return nil, errors.New(errStatementFormat)
+	}
+
+	s = new(Statement)
+	var t *StatementTransaction
+	var name, memo string
+	var inBalance bool
+
+	for _, el := range ofxElements(content[start:]) {
+		switch {
+		case el.closing:
+			switch el.name {
+			case "STMTTRN":
+				if t != nil {
+					t.Description = statementDescription(name, memo)
+					s.Transactions = append(s.Transactions, t)
+					t, name, memo = nil, NotSetStringValue, NotSetStringValue
+				}
+			case "LEDGERBAL":
+				inBalance = false
+			}
+		case !el.leaf:
+			switch el.name {
+			case "STMTTRN":
+				t = new(StatementTransaction)
+			case "LEDGERBAL":
+				inBalance = true
+			}
+		case t != nil:
+			switch el.name {
+			case "FITID":
+				t.ImportID = el.value
+			case "DTPOSTED":
+				if t.Date, err = ofxDate(el.value); err != nil {
+					return nil, err
+				}
+			case "TRNAMT":
+				if t.Value, err = ofxAmount(el.value); err != nil {
+					return nil, err
+				}
+			case "NAME":
+				name = el.value
+			case "MEMO":
+				memo = el.value
+			}
+		case inBalance:
+			switch el.name {
+			case "BALAMT":
+				if s.Balance, err = ofxAmount(el.value); err != nil {
+					return nil, err
+				}
+			case "DTASOF":
+				if s.BalanceDate, err = ofxDate(el.value); err != nil {
+					return nil, err
+				}
+			}
+		case el.name == "CURDEF":
+			s.Currency = el.value
+		}
+	}
+
+	return s, nil
+	//TODO: add test
+}
+
+// ofxElement is a tag of OFX file: opening of aggregate, its closing or element with value (leaf)
+type ofxElement struct {
+	name    string
+	value   string
+	leaf    bool
+	closing bool
+}
+
+// ofxElements splits OFX contents into elements. Elements with value are treated the same way
+// whether they are closed (XML) or not (SGML); their closing tags are skipped.
+func ofxElements(content string) (elements []ofxElement) {
+	for {
+		open := strings.Index(content, "<")
+		if open < 0 {
+			return elements
+		}
+		end := strings.Index(content[open:], ">")
+		if end < 0 {
+			return elements
+		}
+		tag := strings.TrimSpace(content[open+1 : open+end])
+		content = content[open+end+1:]
+		if tag == NotSetStringValue || tag[0] == '?' || tag[0] == '!' {
+			continue
+		}
+
+		next := strings.Index(content, "<")
+		if next < 0 {
+			next = len(content)
+		}
+		value := strings.TrimSpace(content[:next])
+
+		switch {
+		case tag[0] == '/':
+			name := strings.ToUpper(tag[1:])
+			if n := len(elements); n > 0 && elements[n-1].leaf && elements[n-1].name == name {
+				continue
+			}
+			elements = append(elements, ofxElement{name: name, closing: true})
+		case value != NotSetStringValue:
+			elements = append(elements, ofxElement{name: strings.ToUpper(tag), value: html.UnescapeString(value), leaf: true})
+		default:
+			elements = append(elements, ofxElement{name: strings.ToUpper(tag)})
+		}
+	}
+}
+
+// ofxDate parses date of OFX file, e.g. 20160131 or 20160131120000.000[-5:EST]
+func ofxDate(s string) (time.Time, error) {
+	if len(s) < len(ofxDateFormat) {
+		return time.Time{}, errors.New(errStatementDate + s)
+	}
+	d, err := time.Parse(ofxDateFormat, s[:len(ofxDateFormat)])
+	if err != nil {
+		return time.Time{}, errors.New(errStatementDate + s)
+	}
+
+	return d, nil
+}
+
+// ofxAmount parses amount of OFX file. Some banks use comma as decimal separator.
+func ofxAmount(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+	if err != nil {
+		return NotSetFloatValue, errors.New(errStatementAmount + s)
+	}
+
+	return v, nil
+}
+
+// latin1ToString converts text encoded in ISO-8859-1 to string
+func latin1ToString(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+
+	return string(runes)
+}
@@ -0,0 +1,69 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStatementReadOFX(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+	}{
+		{
+			name: "OFX 1.x (SGML)",
+			file: "OFXHEADER:100\nDATA:OFXSGML\nVERSION:102\n\n" +
+				"<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><CURDEF>SEK\n<BANKTRANLIST>\n" +
+				"<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20160305120000[-5:EST]<TRNAMT>-24.50<FITID>T1<NAME>ICA<MEMO>Invoice 12</STMTTRN>\n" +
+				"<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20160310<TRNAMT>10.00<FITID>T2<NAME>Mom &amp; Dad</STMTTRN>\n" +
+				"</BANKTRANLIST><LEDGERBAL><BALAMT>85.50<DTASOF>20160331</LEDGERBAL></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>",
+		},
+		{
+			name: "OFX 2.x (XML)",
+			file: "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<?OFX OFXHEADER=\"200\" VERSION=\"220\"?>\n" +
+				"<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><CURDEF>SEK</CURDEF><BANKTRANLIST>\n" +
+				"<STMTTRN><TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20160305</DTPOSTED><TRNAMT>-24.50</TRNAMT><FITID>T1</FITID><NAME>ICA</NAME><MEMO>Invoice 12</MEMO></STMTTRN>\n" +
+				"<STMTTRN><TRNTYPE>CREDIT</TRNTYPE><DTPOSTED>20160310</DTPOSTED><TRNAMT>10.00</TRNAMT><FITID>T2</FITID><NAME>Mom &amp; Dad</NAME></STMTTRN>\n" +
+				"</BANKTRANLIST><LEDGERBAL><BALAMT>85.50</BALAMT><DTASOF>20160331</DTASOF></LEDGERBAL></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>",
+		},
+	}
+
+	// Both versions give the same statement
+	type transaction struct {
+		importID, date, description string
+		value                       float64
+	}
+	want := []transaction{
+		{importID: "T1", date: "2016-03-05", description: "ICA - Invoice 12", value: -24.5},
+		{importID: "T2", date: "2016-03-10", description: "Mom & Dad", value: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := StatementReadOFX(strings.NewReader(tt.file))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if s.Currency != "SEK" {
+				t.Errorf("currency = %q, want SEK", s.Currency)
+			}
+			if s.Balance != 85.5 || s.BalanceDate.Format(DateFormat) != "2016-03-31" {
+				t.Errorf("balance = %.2f on %s, want 85.50 on 2016-03-31", s.Balance, s.BalanceDate.Format(DateFormat))
+			}
+			if len(s.Transactions) != len(want) {
+				t.Fatalf("got %d transactions, want %d", len(s.Transactions), len(want))
+			}
+			for i, w := range want {
+				st := s.Transactions[i]
+				got := transaction{importID: st.ImportID, date: st.Date.Format(DateFormat), description: st.Description, value: st.Value}
+				if got != w {
+					t.Errorf("transaction %d = %+v, want %+v", i, got, w)
+				}
+			}
+		})
+	}
+}
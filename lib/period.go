@@ -0,0 +1,153 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"github.com/zbroju/gsqlitehandler"
+	"strings"
+	"time"
+)
+
+// Granularity is the length of periods which time-series reports are summed up in
+type Granularity int
+
+const (
+	GranularityDay Granularity = iota + 1
+	GranularityWeek
+	GranularityMonth
+	GranularityQuarter
+	GranularityYear
+)
+
+var granularityNames = map[Granularity]string{
+	GranularityDay:     "day",
+	GranularityWeek:    "week",
+	GranularityMonth:   "month",
+	GranularityQuarter: "quarter",
+	GranularityYear:    "year",
+}
+
+// GranularityParse returns granularity given by its name (day, week, month, quarter, year) or its first letter
+func GranularityParse(s string) (Granularity, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for g, n := range granularityNames {
+		if s == n || s == n[:1] {
+			return g, nil
+		}
+	}
+
+	return GranularityMonth, errors.New(errGranularityIncorrect + s)
+	//TODO: add test
+}
+
+// String returns name of granularity
+func (g Granularity) String() string {
+	return granularityNames[g]
+}
+
+// Period is a day, week (starting on Monday), month, quarter or year (quarters and years of fiscal year).
+// Start and End are its first and last day.
+type Period struct {
+	Granularity Granularity
+	Start       time.Time
+	End         time.Time
+
+	// fiscalYearStart is the first month of fiscal year which quarters and years begin with
+	fiscalYearStart time.Month
+}
+
+// PeriodFor returns period of granularity g containing day d, with quarters and years of fiscal year
+// of data file opened with db (calendar ones if db is nil)
+func PeriodFor(db *gsqlitehandler.SqliteDB, g Granularity, d time.Time) *Period {
+	return periodFor(g, d, fiscalYearStartOf(db))
+	//TODO: add test
+}
+
+// periodFor returns period of granularity g containing day d, with fiscal years starting in month fy
+func periodFor(g Granularity, d time.Time, fy time.Month) *Period {
+	d = time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC)
+	p := &Period{Granularity: g, fiscalYearStart: fy}
+	switch g {
+	case GranularityDay:
+		p.Start = d
+	case GranularityWeek:
+		p.Start = dateWeekStart(d)
+	case GranularityQuarter:
+		p.Start = dateQuarterStart(d, fy)
+	case GranularityYear:
+		p.Start = dateYearStart(d, fy)
+	default:
+		p.Granularity, p.Start = GranularityMonth, dateMonthStart(d)
+	}
+	p.End = p.after().AddDate(0, 0, -1)
+
+	return p
+}
+
+// after returns the first day after period p
+func (p *Period) after() time.Time {
+	switch p.Granularity {
+	case GranularityDay:
+		return p.Start.AddDate(0, 0, 1)
+	case GranularityWeek:
+		return p.Start.AddDate(0, 0, 7)
+	case GranularityQuarter:
+		return p.Start.AddDate(0, 3, 0)
+	case GranularityYear:
+		return p.Start.AddDate(1, 0, 0)
+	default:
+		return p.Start.AddDate(0, 1, 0)
+	}
+}
+
+// Next returns period following p
+func (p *Period) Next() *Period {
+	return periodFor(p.Granularity, p.after(), p.fiscalYearStart)
+	//TODO: add test
+}
+
+// Contains checks if day d is within period p
+func (p *Period) Contains(d time.Time) bool {
+	d = time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC)
+	return !d.Before(p.Start) && !d.After(p.End)
+	//TODO: add test
+}
+
+// String returns period as yyyy-mm-dd (day), yyyy-Www (ISO week), yyyy-mm (month), yyyy-Qq (quarter) or yyyy (year),
+// with yyyy/yy for fiscal years different from calendar ones
+func (p *Period) String() string {
+	switch p.Granularity {
+	case GranularityDay:
+		return p.Start.Format(DateFormat)
+	case GranularityWeek:
+		y, w := p.Start.ISOWeek()
+		return fmt.Sprintf("%d%sW%02d", y, DateSeparator, w)
+	case GranularityQuarter:
+		fy := p.fiscalYearStart
+		return fmt.Sprintf("%s%sQ%d", fiscalYearString(dateYearStart(p.Start, fy).Year(), fy), DateSeparator, dateFiscalMonth(p.Start, fy)/3+1)
+	case GranularityYear:
+		return fiscalYearString(p.Start.Year(), p.fiscalYearStart)
+	default:
+		return p.Start.Format("2006" + DateSeparator + "01")
+	}
+}
+
+// PeriodList returns periods of granularity g between days from and to (both included) as closure
+// (see PeriodFor)
+func PeriodList(db *gsqlitehandler.SqliteDB, g Granularity, from, to time.Time) func() *Period {
+	p := PeriodFor(db, g, from)
+
+	return func() *Period {
+		if p.Start.After(to) {
+			return nil
+		}
+		c := p
+		p = p.Next()
+		return c
+	}
+	//TODO: add test
+}
@@ -0,0 +1,125 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"testing"
+	"time"
+)
+
+// testDate returns day given as yyyy-mm-dd
+func testDate(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse(DateFormat, s)
+	if err != nil {
+		t.Fatalf("incorrect date %s: %v", s, err)
+	}
+
+	return d
+}
+
+func TestPeriodFor(t *testing.T) {
+	tests := []struct {
+		g                 Granularity
+		fy                time.Month
+		d                 string
+		start, end, label string
+	}{
+		{g: GranularityDay, d: "2016-03-05", start: "2016-03-05", end: "2016-03-05", label: "2016-03-05"},
+		{g: GranularityWeek, d: "2016-03-05", start: "2016-02-29", end: "2016-03-06", label: "2016-W09"},
+		{g: GranularityWeek, d: "2016-01-01", start: "2015-12-28", end: "2016-01-03", label: "2015-W53"},
+		{g: GranularityMonth, d: "2016-02-10", start: "2016-02-01", end: "2016-02-29", label: "2016-02"},
+		{g: GranularityQuarter, d: "2016-05-31", start: "2016-04-01", end: "2016-06-30", label: "2016-Q2"},
+		{g: GranularityYear, d: "2016-12-31", start: "2016-01-01", end: "2016-12-31", label: "2016"},
+		{g: GranularityQuarter, fy: time.July, d: "2016-07-01", start: "2016-07-01", end: "2016-09-30", label: "2016/17-Q1"},
+		{g: GranularityQuarter, fy: time.July, d: "2017-03-31", start: "2017-01-01", end: "2017-03-31", label: "2016/17-Q3"},
+		{g: GranularityQuarter, fy: time.February, d: "2016-01-15", start: "2015-11-01", end: "2016-01-31", label: "2015/16-Q4"},
+		{g: GranularityQuarter, fy: time.February, d: "2016-02-29", start: "2016-02-01", end: "2016-04-30", label: "2016/17-Q1"},
+		{g: GranularityYear, fy: time.July, d: "2016-06-30", start: "2015-07-01", end: "2016-06-30", label: "2015/16"},
+		{g: GranularityYear, fy: time.December, d: "2016-12-01", start: "2016-12-01", end: "2017-11-30", label: "2016/17"},
+		{g: GranularityMonth, fy: time.July, d: "2016-07-10", start: "2016-07-01", end: "2016-07-31", label: "2016-07"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.g.String()+" "+tt.fy.String()+" "+tt.d, func(t *testing.T) {
+			fy := tt.fy
+			if fy == 0 {
+				fy = time.January
+			}
+			p := periodFor(tt.g, testDate(t, tt.d), fy)
+			if s, e := p.Start.Format(DateFormat), p.End.Format(DateFormat); s != tt.start || e != tt.end {
+				t.Errorf("period = %s - %s, want %s - %s", s, e, tt.start, tt.end)
+			}
+			if l := p.String(); l != tt.label {
+				t.Errorf("label = %s, want %s", l, tt.label)
+			}
+		})
+	}
+}
+
+func TestPeriodList(t *testing.T) {
+	tests := []struct {
+		g        Granularity
+		from, to string
+		want     []string
+	}{
+		{g: GranularityDay, from: "2016-02-28", to: "2016-03-01", want: []string{"2016-02-28", "2016-02-29", "2016-03-01"}},
+		{g: GranularityWeek, from: "2016-02-25", to: "2016-03-07", want: []string{"2016-W08", "2016-W09", "2016-W10"}},
+		{g: GranularityMonth, from: "2016-01-31", to: "2016-03-01", want: []string{"2016-01", "2016-02", "2016-03"}},
+		{g: GranularityQuarter, from: "2015-12-31", to: "2016-01-01", want: []string{"2015-Q4", "2016-Q1"}},
+		{g: GranularityYear, from: "2016-03-05", to: "2016-03-05", want: []string{"2016"}},
+		{g: GranularityMonth, from: "2016-03-05", to: "2016-02-05"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.g.String()+" "+tt.from, func(t *testing.T) {
+			var got []string
+			getNextPeriod := PeriodList(nil, tt.g, testDate(t, tt.from), testDate(t, tt.to))
+			for p := getNextPeriod(); p != nil; p = getNextPeriod() {
+				got = append(got, p.String())
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("periods = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("periods = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+// Every data file keeps its own fiscal year
+func TestPeriodForDataFiles(t *testing.T) {
+	calendar, fiscal := newTestDataFile(t), newTestDataFile(t)
+	if err := FiscalYearStartSet(fiscal, time.July); err != nil {
+		t.Fatalf("setting fiscal year: %v", err)
+	}
+
+	d := testDate(t, "2016-08-15")
+	tests := []struct {
+		name  string
+		l     string
+		label string
+	}{
+		{name: "calendar year", l: PeriodFor(calendar, GranularityQuarter, d).String(), label: "2016-Q3"},
+		{name: "fiscal year", l: PeriodFor(fiscal, GranularityQuarter, d).String(), label: "2016/17-Q1"},
+		{name: "no data file", l: PeriodFor(nil, GranularityQuarter, d).String(), label: "2016-Q3"},
+	}
+	for _, tt := range tests {
+		if tt.l != tt.label {
+			t.Errorf("%s: label = %s, want %s", tt.name, tt.l, tt.label)
+		}
+	}
+
+	if from, _, err := DateRangeParse(fiscal, "2016/17"); err != nil || from.Format(DateFormat) != "2016-07-01" {
+		t.Errorf("fiscal year 2016/17 starts %s (%v), want 2016-07-01", from.Format(DateFormat), err)
+	}
+	if from, _, err := DateRangeParse(calendar, "2016"); err != nil || from.Format(DateFormat) != "2016-01-01" {
+		t.Errorf("year 2016 starts %s (%v), want 2016-01-01", from.Format(DateFormat), err)
+	}
+}
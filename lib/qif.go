@@ -0,0 +1,269 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Date format of exported QIF files (the most common one, month first)
+const qifDateFormat = "01/02/2006"
+
+// Sections of QIF file
+const (
+	qifSectionOther = iota
+	qifSectionAccount
+	qifSectionTransactions
+)
+
+// QIF types of accounts (sections with transactions)
+var qifTransactionTypes = map[string]bool{"bank": true, "cash": true, "ccard": true, "oth a": true, "oth l": true}
+
+// StatementReadQIF reads transactions from QIF file. Files with many accounts (exported with !Account headers)
+// are accepted - the transactions are then given with the names of their accounts.
+// Categories are given as paths (Main category:Category), transfers as account names and splits as separate parts.
+// Sections other than bank, cash, credit card and asset/liability accounts (e.g. investments, lists of categories) are skipped.
+func StatementReadQIF(r io.Reader) (s *Statement, err error) {
+	s = new(Statement)
+	section := qifSectionOther
+	account := NotSetStringValue
+	var t *qifTransaction
+	ids := make(map[string]int)
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if !utf8.ValidString(line) {
+			line = latin1ToString([]byte(line))
+		}
+		if strings.TrimSpace(line) == NotSetStringValue {
+			continue
+		}
+
+		if line[0] == '!' {
+			h := strings.ToLower(strings.TrimSpace(line[1:]))
+			switch {
+			case h == "account":
+				section = qifSectionAccount
+			case strings.HasPrefix(h, "type:") && qifTransactionTypes[strings.TrimSpace(h[len("type:"):])]:
+				section = qifSectionTransactions
+				t = new(qifTransaction)
+			case strings.HasPrefix(h, "option:") || strings.HasPrefix(h, "clear:"):
+			default:
+				section = qifSectionOther
+			}
+			continue
+		}
+
+		code, value := line[0], strings.TrimSpace(line[1:])
+		switch section {
+		case qifSectionAccount:
+			if code == 'N' {
+				account = value
+			}
+		case qifSectionTransactions:
+			if code == '^' {
+				var st *StatementTransaction
+				if st, err = t.statementTransaction(account, ids); err != nil {
+					return nil, fmt.Errorf("%s (line %d)", err, lineNo)
+				}
+				s.Transactions = append(s.Transactions, st)
+				t = new(qifTransaction)
+				continue
+			}
+			if err = t.set(code, value); err != nil {
+				return nil, fmt.Errorf("%s (line %d)", err, lineNo)
+			}
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+	//TODO: add test
+}
+
+// qifTransaction keeps fields of transaction read from QIF file
+type qifTransaction struct {
+	date     time.Time
+	amount   float64
+	payee    string
+	memo     string
+	number   string
+	category string
+	splits   []*qifSplit
+}
+
+// qifSplit keeps fields of one part of split transaction
+type qifSplit struct {
+	category string
+	memo     string
+	amount   float64
+}
+
+// set sets field of transaction with given code
+func (t *qifTransaction) set(code byte, value string) (err error) {
+	switch code {
+	case 'D':
+		t.date, err = qifDate(value)
+	case 'T', 'U':
+		t.amount, err = qifAmount(value)
+	case 'P':
+		t.payee = value
+	case 'M':
+		t.memo = value
+	case 'N':
+		t.number = value
+	case 'L':
+		t.category = value
+	case 'S':
+		t.splits = append(t.splits, &qifSplit{category: value})
+	case 'E':
+		if len(t.splits) > 0 {
+			t.splits[len(t.splits)-1].memo = value
+		}
+	case '$':
+		if len(t.splits) > 0 {
+			t.splits[len(t.splits)-1].amount, err = qifAmount(value)
+		}
+	}
+
+	return err
+}
+
+// statementTransaction returns transaction of statement. As QIF has no identifiers of transactions,
+// import ID is made of its fields and the number of identical transactions read before.
+func (t *qifTransaction) statementTransaction(account string, ids map[string]int) (*StatementTransaction, error) {
+	if t.date.IsZero() {
+		return nil, errors.New(errStatementDateMissing)
+	}
+
+	id := fmt.Sprintf("qif:%s:%s:%.2f:%s:%s", account, t.date.Format(DateFormat), t.amount, t.number, t.payee)
+	ids[id]++
+	id = fmt.Sprintf("%s#%d", id, ids[id])
+
+	st := &StatementTransaction{ImportID: id, Date: t.date, Description: statementDescription(t.payee, t.memo), Value: t.amount, Account: account}
+	st.Category, st.TransferAccount = qifCategory(t.category)
+	for i, sp := range t.splits {
+		p := &StatementTransaction{ImportID: fmt.Sprintf("%s/%d", id, i+1), Date: t.date, Value: sp.amount, Account: account}
+		p.Description = statementDescription(st.Description, sp.memo)
+		p.Category, p.TransferAccount = qifCategory(sp.category)
+		st.Splits = append(st.Splits, p)
+	}
+
+	return st, nil
+}
+
+// qifCategory returns category path or name of transfer account (given in square brackets) for QIF category field.
+// Class of transaction (given after slash) is skipped.
+func qifCategory(s string) (category, transferAccount string) {
+	if i := strings.Index(s, "/"); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		return NotSetStringValue, strings.TrimSpace(s[1 : len(s)-1])
+	}
+
+	return s, NotSetStringValue
+}
+
+// qifDate parses date of QIF file. Dates with slashes are taken as month first (e.g. 12/31/2016, 1/ 5'16),
+// dates with dots as day first (31.12.2016). Years given with two digits after apostrophe are from 2000 on.
+func qifDate(s string) (time.Time, error) {
+	if d, err := time.Parse(DateFormat, s); err == nil {
+		return d, nil
+	}
+
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == '/' || r == '.' || r == '\'' || r == '-' || r == ' ' })
+	if len(fields) != 3 {
+		return time.Time{}, errors.New(errStatementDate + s)
+	}
+	var n [3]int
+	for i, f := range fields {
+		var err error
+		if n[i], err = strconv.Atoi(f); err != nil {
+			return time.Time{}, errors.New(errStatementDate + s)
+		}
+	}
+	m, d, y := n[0], n[1], n[2]
+	if strings.Contains(s, ".") {
+		d, m = m, d
+	}
+	if y < 100 {
+		if strings.Contains(s, "'") || y < 70 {
+			y += 2000
+		} else {
+			y += 1900
+		}
+	}
+	date := time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC)
+	if date.Month() != time.Month(m) || date.Day() != d {
+		return time.Time{}, errors.New(errStatementDate + s)
+	}
+
+	return date, nil
+}
+
+// qifAmount parses amount of QIF file, with optional thousands separators.
+// Comma is taken as decimal separator if it follows dots (e.g. 1.234,56) or is not followed by exactly three digits.
+func qifAmount(s string) (float64, error) {
+	a := strings.Replace(s, " ", "", -1)
+	dot, comma := strings.LastIndex(a, "."), strings.LastIndex(a, ",")
+	switch {
+	case comma > dot && (dot >= 0 || len(a)-comma-1 != 3):
+		a = strings.Replace(strings.Replace(a, ".", "", -1), ",", ".", 1)
+	default:
+		a = strings.Replace(a, ",", "", -1)
+	}
+	v, err := strconv.ParseFloat(a, 64)
+	if err != nil {
+		return NotSetFloatValue, errors.New(errStatementAmount + s)
+	}
+
+	return v, nil
+}
+
+// StatementWriteQIF writes transactions of statement s of account a to QIF file
+// (with account header, so that it is imported to the account of the same name)
+func StatementWriteQIF(w io.Writer, a *Account, s *Statement) error {
+	qifType := "Bank"
+	switch a.AType {
+	case ATProperty, ATInvestment:
+		qifType = "Oth A"
+	case ATLoan:
+		qifType = "Oth L"
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "!Account\nN%s\nT%s\n", a.Name, qifType)
+	if a.Description != NotSetStringValue {
+		fmt.Fprintf(bw, "D%s\n", a.Description)
+	}
+	fmt.Fprintf(bw, "^\n!Type:%s\n", qifType)
+	for _, t := range s.Transactions {
+		fmt.Fprintf(bw, "D%s\nT%.2f\n", t.Date.Format(qifDateFormat), t.Value)
+		if t.Description != NotSetStringValue {
+			fmt.Fprintf(bw, "P%s\n", t.Description)
+		}
+		if t.TransferAccount != NotSetStringValue {
+			fmt.Fprintf(bw, "L[%s]\n", t.TransferAccount)
+		} else if t.Category != NotSetStringValue {
+			fmt.Fprintf(bw, "L%s\n", t.Category)
+		}
+		fmt.Fprintf(bw, "^\n")
+	}
+
+	return bw.Flush()
+	//TODO: add test
+}
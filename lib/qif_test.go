@@ -0,0 +1,72 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStatementReadQIFSplits(t *testing.T) {
+	type part struct {
+		importID, description, category, transfer string
+		value                                     float64
+	}
+	tests := []struct {
+		name  string
+		file  string
+		parts []part
+	}{
+		{
+			name: "no splits",
+			file: "!Type:Bank\nD03/05/2016\nT-24.50\nPICA\nLFood:Groceries\n^\n",
+		},
+		{
+			name: "splits with categories, memos and transfer",
+			file: "!Type:Bank\nD03/05/2016\nT-124.50\nPICA\nLFood:Groceries\n" +
+				"SFood:Groceries\nEmilk\n$-24.50\nSHousehold/Work\n$-50.00\nS[Savings]\nEput aside\n$-50.00\n^\n",
+			parts: []part{
+				{importID: "/1", description: "ICA - milk", category: "Food:Groceries", value: -24.5},
+				{importID: "/2", description: "ICA", category: "Household", value: -50},
+				{importID: "/3", description: "ICA - put aside", transfer: "Savings", value: -50},
+			},
+		},
+		{
+			name: "split amounts with decimal comma",
+			file: "!Type:Bank\nD05.03.2016\nT-1.024,50\nPICA\nSFood\n$-1.000,00\nSFood:Groceries\n$-24,50\n^\n",
+			parts: []part{
+				{importID: "/1", description: "ICA", category: "Food", value: -1000},
+				{importID: "/2", description: "ICA", category: "Food:Groceries", value: -24.5},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := StatementReadQIF(strings.NewReader(tt.file))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(s.Transactions) != 1 {
+				t.Fatalf("got %d transactions, want 1", len(s.Transactions))
+			}
+			st := s.Transactions[0]
+			if d := st.Date.Format(DateFormat); d != "2016-03-05" {
+				t.Errorf("date = %s, want 2016-03-05", d)
+			}
+			if len(st.Splits) != len(tt.parts) {
+				t.Fatalf("got %d splits, want %d", len(st.Splits), len(tt.parts))
+			}
+			for i, want := range tt.parts {
+				p := st.Splits[i]
+				want.importID = st.ImportID + want.importID
+				got := part{importID: p.ImportID, description: p.Description, category: p.Category, transfer: p.TransferAccount, value: p.Value}
+				if got != want {
+					t.Errorf("split %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/zbroju/gsqlitehandler"
+	"time"
+)
+
+// Table of transactions scheduled to repeat (e.g. salary, rent), taken into forecast.
+// Date_to is empty for the ones repeated with no end.
+const sqlCreateRecurringTransactions = "CREATE TABLE IF NOT EXISTS recurring_transactions (id INTEGER PRIMARY KEY, account_id INTEGER, category_id INTEGER, " +
+	"description TEXT, value REAL, date_from TEXT, date_to TEXT, granularity TEXT, every INTEGER);"
+
+// Recurring is a transaction repeated every given number of days, weeks, months, quarters or years
+// from its first date until its last one (if it is set). Its value has no sign, like the one of Transaction.
+type Recurring struct {
+	Id          int64
+	Account     *Account
+	Category    *Category
+	Description string
+	Value       float64
+	DateFrom    time.Time
+	DateTo      time.Time
+	Granularity Granularity
+	Every       int
+}
+
+// RecurringNew returns pointer to new instance of Recurring repeated every month from today
+func RecurringNew() *Recurring {
+	r := new(Recurring)
+	r.Account = new(Account)
+	r.Category = CategoryNew()
+	r.DateFrom = time.Now()
+	r.Granularity = GranularityMonth
+	r.Every = 1
+
+	return r
+}
+
+// GetSValue returns value of recurring transaction with sign of its category type
+func (r *Recurring) GetSValue() float64 {
+	return r.Value * float64(r.Category.Main.MType.Factor)
+}
+
+// Dates returns dates of the recurring transaction between days from and to (both included)
+func (r *Recurring) Dates(from, to time.Time) (l []time.Time) {
+	if !r.DateTo.IsZero() && r.DateTo.Before(to) {
+		to = r.DateTo
+	}
+	for i := 0; ; i++ {
+		d := dateAdd(r.DateFrom, r.Granularity, i*r.Every)
+		if d.After(to) {
+			return l
+		}
+		if !d.Before(from) {
+			l = append(l, d)
+		}
+	}
+	//TODO: add test
+}
+
+// dateAdd returns day d moved by n periods of granularity g.
+// Days missing in shorter months are replaced by their last days (e.g. 31st by 30th).
+func dateAdd(d time.Time, g Granularity, n int) time.Time {
+	d = time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC)
+	var months int
+	switch g {
+	case GranularityDay:
+		return d.AddDate(0, 0, n)
+	case GranularityWeek:
+		return d.AddDate(0, 0, 7*n)
+	case GranularityQuarter:
+		months = 3 * n
+	case GranularityYear:
+		months = 12 * n
+	default:
+		months = n
+	}
+	m := dateMonthStart(d).AddDate(0, months, 0)
+	if last := m.AddDate(0, 1, -1); d.Day() > last.Day() {
+		return last
+	}
+
+	return m.AddDate(0, 0, d.Day()-1)
+}
+
+// RecurringAdd adds new recurring transaction r
+func RecurringAdd(db *gsqlitehandler.SqliteDB, r *Recurring) error {
+	var err error
+	var stmt *sql.Stmt
+
+	if r.Every < 1 {
+		return errors.New(errRecurringEvery)
+	}
+	if !r.DateTo.IsZero() && r.DateTo.Before(r.DateFrom) {
+		return errors.New(errRecurringDates)
+	}
+
+	if err = beforeChange(db); err != nil {
+		return err
+	}
+
+	sqlQuery := "INSERT INTO recurring_transactions (account_id, category_id, description, value, date_from, date_to, granularity, every) VALUES (?, ?, ?, round(?,2), ?, ?, ?, ?);"
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	defer stmt.Close()
+
+	dt := NotSetStringValue
+	if !r.DateTo.IsZero() {
+		dt = r.DateTo.Format(DateFormat)
+	}
+	var res sql.Result
+	if res, err = stmt.Exec(r.Account.Id, r.Category.Id, r.Description, r.Value, r.DateFrom.Format(DateFormat), dt, r.Granularity.String(), r.Every); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		r.Id = id
+	}
+
+	return nil
+	//TODO: add test
+}
+
+// Query of recurring transactions with details of their accounts and categories
+const sqlRecurringSelect = "SELECT r.id, r.description, r.value, r.date_from, r.date_to, r.granularity, r.every, " +
+	"a.id, a.name, a.description, a.institution, a.currency, a.type, a.status, c.id, c.name, c.status, m.id, m.name, m.status, mt.id, mt.name, mt.factor " +
+	"FROM recurring_transactions r INNER JOIN accounts a ON r.account_id=a.id INNER JOIN categories c ON r.category_id=c.id " +
+	"INNER JOIN main_categories m ON c.main_category_id=m.id INNER JOIN main_categories_types mt on m.type_id=mt.id "
+
+// recurringScan reads recurring transaction from row returned by sqlRecurringSelect
+func recurringScan(scan func(dest ...interface{}) error) (r *Recurring, err error) {
+	r = RecurringNew()
+	var tmpDateFrom, tmpDateTo, tmpGranularity string
+	if err = scan(&r.Id, &r.Description, &r.Value, &tmpDateFrom, &tmpDateTo, &tmpGranularity, &r.Every,
+		&r.Account.Id, &r.Account.Name, &r.Account.Description, &r.Account.Institution, &r.Account.Currency, &r.Account.AType, &r.Account.Status,
+		&r.Category.Id, &r.Category.Name, &r.Category.Status, &r.Category.Main.Id, &r.Category.Main.Name, &r.Category.Main.Status,
+		&r.Category.Main.MType.Id, &r.Category.Main.MType.Name, &r.Category.Main.MType.Factor); err != nil {
+		return nil, err
+	}
+	if r.DateFrom, err = time.Parse(DateFormat, tmpDateFrom); err != nil {
+		return nil, err
+	}
+	r.DateTo = time.Time{}
+	if tmpDateTo != NotSetStringValue {
+		if r.DateTo, err = time.Parse(DateFormat, tmpDateTo); err != nil {
+			return nil, err
+		}
+	}
+	if r.Granularity, err = GranularityParse(tmpGranularity); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// RecurringForID returns pointer to recurring transaction with given id
+func RecurringForID(db *gsqlitehandler.SqliteDB, i int) (r *Recurring, err error) {
+	var stmt *sql.Stmt
+
+	if stmt, err = dbHandler(db).Prepare(sqlRecurringSelect + "WHERE r.id=?;"); err != nil {
+		return nil, errors.New(errReadingFromFile)
+	}
+	defer stmt.Close()
+
+	if r, err = recurringScan(stmt.QueryRow(i).Scan); err != nil {
+		return nil, errors.New(errRecurringWithIDNone)
+	}
+
+	return r, nil
+	//TODO: add test
+}
+
+// RecurringList returns recurring transactions (of account a, or all of them if it is nil) as closure
+func RecurringList(db *gsqlitehandler.SqliteDB, a *Account) (f func() *Recurring, err error) {
+	var stmt *sql.Stmt
+	var rows *sql.Rows
+
+	var aId int64
+	if a == nil {
+		aId = noIntParamForSQL
+	} else {
+		aId = a.Id
+	}
+
+	if stmt, err = dbHandler(db).Prepare(sqlRecurringSelect + "WHERE (a.id=? OR ?=?) ORDER BY a.name, r.date_from, r.id;"); err != nil {
+		return nil, errors.New(errReadingFromFile)
+	}
+	if rows, err = stmt.Query(aId, aId, NotSetIntValue); err != nil {
+		stmt.Close()
+		return nil, errors.New(errReadingFromFile)
+	}
+
+	f = func() *Recurring {
+		for rows.Next() {
+			if r, err := recurringScan(rows.Scan); err == nil {
+				return r
+			}
+		}
+		rows.Close()
+		stmt.Close()
+
+		return nil
+	}
+
+	return f, nil
+	//TODO: add test
+}
+
+// RecurringRemove removes recurring transaction r (transactions added before are kept)
+func RecurringRemove(db *gsqlitehandler.SqliteDB, r *Recurring) error {
+	var err error
+	var stmt *sql.Stmt
+
+	if err = beforeChange(db); err != nil {
+		return err
+	}
+
+	if stmt, err = dbHandler(db).Prepare("DELETE FROM recurring_transactions WHERE id=?;"); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.Exec(r.Id); err != nil {
+		return errors.New(errWritingToFile)
+	}
+
+	return nil
+	//TODO: add test
+}
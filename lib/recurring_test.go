@@ -0,0 +1,85 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDateAdd(t *testing.T) {
+	tests := []struct {
+		d    string
+		g    Granularity
+		n    int
+		want string
+	}{
+		{d: "2016-02-28", g: GranularityDay, n: 2, want: "2016-03-01"},
+		{d: "2016-03-01", g: GranularityDay, n: -1, want: "2016-02-29"},
+		{d: "2016-12-29", g: GranularityWeek, n: 1, want: "2017-01-05"},
+		{d: "2016-01-31", g: GranularityMonth, n: 1, want: "2016-02-29"},
+		{d: "2015-01-31", g: GranularityMonth, n: 1, want: "2015-02-28"},
+		{d: "2016-03-31", g: GranularityMonth, n: -1, want: "2016-02-29"},
+		{d: "2016-01-31", g: GranularityMonth, n: 3, want: "2016-04-30"},
+		{d: "2016-10-31", g: GranularityMonth, n: 14, want: "2017-12-31"},
+		{d: "2016-11-30", g: GranularityQuarter, n: 1, want: "2017-02-28"},
+		{d: "2016-05-31", g: GranularityQuarter, n: -2, want: "2015-11-30"},
+		{d: "2016-02-29", g: GranularityYear, n: 1, want: "2017-02-28"},
+		{d: "2016-02-29", g: GranularityYear, n: 4, want: "2020-02-29"},
+		{d: "2016-03-15", g: GranularityMonth, n: 0, want: "2016-03-15"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %+d %s", tt.d, tt.n, tt.g), func(t *testing.T) {
+			if got := dateAdd(testDate(t, tt.d), tt.g, tt.n).Format(DateFormat); got != tt.want {
+				t.Errorf("date = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecurringDates(t *testing.T) {
+	tests := []struct {
+		name     string
+		dateFrom string
+		dateTo   string
+		g        Granularity
+		every    int
+		from, to string
+		want     []string
+	}{
+		{name: "monthly from the last day", dateFrom: "2016-01-31", g: GranularityMonth, every: 1, from: "2016-01-01", to: "2016-04-30",
+			want: []string{"2016-01-31", "2016-02-29", "2016-03-31", "2016-04-30"}},
+		{name: "before the range", dateFrom: "2015-11-15", g: GranularityMonth, every: 2, from: "2016-01-01", to: "2016-05-31",
+			want: []string{"2016-01-15", "2016-03-15", "2016-05-15"}},
+		{name: "every second week", dateFrom: "2016-03-01", g: GranularityWeek, every: 2, from: "2016-03-01", to: "2016-03-29",
+			want: []string{"2016-03-01", "2016-03-15", "2016-03-29"}},
+		{name: "until last date", dateFrom: "2016-03-01", dateTo: "2016-03-03", g: GranularityDay, every: 1, from: "2016-02-01", to: "2016-03-31",
+			want: []string{"2016-03-01", "2016-03-02", "2016-03-03"}},
+		{name: "quarterly", dateFrom: "2015-08-31", g: GranularityQuarter, every: 1, from: "2016-01-01", to: "2016-12-31",
+			want: []string{"2016-02-29", "2016-05-31", "2016-08-31", "2016-11-30"}},
+		{name: "yearly on leap day", dateFrom: "2016-02-29", g: GranularityYear, every: 1, from: "2016-01-01", to: "2018-12-31",
+			want: []string{"2016-02-29", "2017-02-28", "2018-02-28"}},
+		{name: "after last date", dateFrom: "2016-01-10", dateTo: "2016-02-10", g: GranularityMonth, every: 1, from: "2016-03-01", to: "2016-12-31"},
+		{name: "after the range", dateFrom: "2016-06-01", g: GranularityMonth, every: 1, from: "2016-01-01", to: "2016-05-31"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := RecurringNew()
+			r.DateFrom, r.Granularity, r.Every = testDate(t, tt.dateFrom), tt.g, tt.every
+			if tt.dateTo != NotSetStringValue {
+				r.DateTo = testDate(t, tt.dateTo)
+			}
+			var got []string
+			for _, d := range r.Dates(testDate(t, tt.from), testDate(t, tt.to)) {
+				got = append(got, d.Format(DateFormat))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("dates = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -62,7 +62,7 @@ ORDER BY
 	, a.name
 ;
 `
-	if stmt, err = db.Handler.Prepare(sqlQuery); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 
@@ -138,7 +138,7 @@ func ReportTransactionBalance(db *gsqlitehandler.SqliteDB, currency string, date
 	}
 
 	// Execute main query
-	if stmt, err = db.Handler.Prepare(sqlReportTransactionsBalance); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlReportTransactionsBalance); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 	if rows, err = stmt.Query(currency, currency, df, df, noStringParamForSQL, dt, dt, noStringParamForSQL, aId, aId, noIntParamForSQL, cId, cId, noIntParamForSQL, mId, mId, noIntParamForSQL, description, description, noStringParamForSQL); err != nil {
@@ -178,7 +178,9 @@ func CategoryBalanceReportBalanceNew() *CategoryBalanceReportEntry {
 	return e
 }
 
-func ReportCategoryBalance(db *gsqlitehandler.SqliteDB, currency string, dateFrom, dateTo time.Time, a *Account, c *Category, m *MainCategory) (f func() *CategoryBalanceReportEntry, err error) {
+// ReportCategoryBalance returns categories balance for given criteria. Filtering by category c includes its subcategories.
+// If depth is set, the balances are rolled up to categories on given level of categories tree.
+func ReportCategoryBalance(db *gsqlitehandler.SqliteDB, currency string, dateFrom, dateTo time.Time, a *Account, c *Category, m *MainCategory, depth int) (f func() *CategoryBalanceReportEntry, err error) {
 	var stmt *sql.Stmt
 	var rows *sql.Rows
 
@@ -210,24 +212,46 @@ func ReportCategoryBalance(db *gsqlitehandler.SqliteDB, currency string, dateFro
 	if m != nil {
 		mId = m.Id
 	}
+	var t *CategoryTree
+	if t, err = CategoryTreeGet(db); err != nil {
+		return nil, err
+	}
 
 	// Execute main query
-	if stmt, err = db.Handler.Prepare(sqlReportCategoriesBalance); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlReportCategoriesBalance); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
-	if rows, err = stmt.Query(MCTTransfer, currency, currency, df, df, noStringParamForSQL, dt, dt, noStringParamForSQL, aId, aId, noIntParamForSQL, cId, cId, noIntParamForSQL, mId, mId, noIntParamForSQL); err != nil {
+	if rows, err = stmt.Query(currency, currency, df, df, noStringParamForSQL, dt, dt, noStringParamForSQL, aId, aId, noIntParamForSQL, cId, cId, noIntParamForSQL, mId, mId, noIntParamForSQL); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
+	defer stmt.Close()
+	defer rows.Close()
+
+	// Roll up the balances
+	var cats []*Category
+	var balances []float64
+	for rows.Next() {
+		e := CategoryBalanceReportBalanceNew()
+		rows.Scan(&e.Category.Main.Id, &e.Category.Main.Name, &e.Category.Main.Status, &e.Category.Main.MType.Id, &e.Category.Main.MType.Name, &e.Category.Main.MType.Factor, &e.Category.Id, &e.Category.Name, &e.Category.Status, &e.Balance)
+		cats = append(cats, e.Category)
+		balances = append(balances, e.Balance)
+	}
+	groups, idx := t.rollUp(cats, depth)
+	entries := make([]*CategoryBalanceReportEntry, len(groups))
+	for i, g := range groups {
+		entries[i] = &CategoryBalanceReportEntry{Category: g}
+	}
+	for i, b := range balances {
+		entries[idx[i]].Balance += b
+	}
 
 	// Create closure
+	i := 0
 	f = func() *CategoryBalanceReportEntry {
-		if rows.Next() {
-			e := CategoryBalanceReportBalanceNew()
-			rows.Scan(&e.Category.Main.Id, &e.Category.Main.Name, &e.Category.Main.Status, &e.Category.Main.MType.Id, &e.Category.Main.MType.Name, &e.Category.Main.MType.Factor, &e.Category.Id, &e.Category.Name, &e.Category.Status, &e.Balance)
-			return e
+		if i < len(entries) {
+			i++
+			return entries[i-1]
 		}
-		rows.Close()
-		stmt.Close()
 
 		return nil
 	}
@@ -278,10 +302,10 @@ func ReportMainCategoryBalance(db *gsqlitehandler.SqliteDB, currency string, dat
 	}
 
 	// Execute main query
-	if stmt, err = db.Handler.Prepare(sqlReportMainCategoriesBalance); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlReportMainCategoriesBalance); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
-	if rows, err = stmt.Query(MCTTransfer, currency, currency, df, df, noStringParamForSQL, dt, dt, noStringParamForSQL, aId, aId, noIntParamForSQL, mId, mId, noIntParamForSQL); err != nil {
+	if rows, err = stmt.Query(currency, currency, df, df, noStringParamForSQL, dt, dt, noStringParamForSQL, aId, aId, noIntParamForSQL, mId, mId, noIntParamForSQL); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 
@@ -334,7 +358,7 @@ func ReportAssetsSummary(db *gsqlitehandler.SqliteDB, currency string, onDate ti
 	}
 
 	// Execute main query
-	if stmt, err = db.Handler.Prepare(sqlReportAssetsSummary); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlReportAssetsSummary); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 	if rows, err = stmt.Query(currency, currency, dt, ISClose); err != nil {
@@ -373,28 +397,38 @@ func BudgetCategoriesReportEntryNew() *BudgetCategoriesReportEntry {
 	return e
 }
 
-func ReportBudgetCategories(db *gsqlitehandler.SqliteDB, p *BPeriod, currency string) (f func() *BudgetCategoriesReportEntry, err error) {
+// ReportBudgetCategories returns budget limits and actual values of categories for given period.
+// If depth is set, the values are rolled up to categories on given level of categories tree.
+func ReportBudgetCategories(db *gsqlitehandler.SqliteDB, p *BPeriod, currency string, depth int) (f func() *BudgetCategoriesReportEntry, err error) {
 	var stmt *sql.Stmt
 	var rows *sql.Rows
 
+	var t *CategoryTree
+	if t, err = CategoryTreeGet(db); err != nil {
+		return nil, err
+	}
+
 	y := int(p.Year)
 	m := int(p.Month)
 	ys, ms := p.GetStrings()
 	if m == NotSetIntValue {
-		if stmt, err = db.Handler.Prepare(sqlReportBudgetCategoriesYearly); err != nil {
+		if stmt, err = dbHandler(db).Prepare(sqlReportBudgetCategoriesYearly); err != nil {
 			return nil, errors.New(errReadingFromFile)
 		}
-		if rows, err = stmt.Query(ys, y, MCTTransfer, currency, currency, y, currency, currency, ys); err != nil {
+		df, dt, mf, mt := budgetYearRange(p)
+		if rows, err = stmt.Query(df, dt, mf, mt, currency, currency, mf, mt, currency, currency, df, dt); err != nil {
 			return nil, errors.New(errReadingFromFile)
 		}
 	} else {
-		if stmt, err = db.Handler.Prepare(sqlReportBudgetCategoriesMonthly); err != nil {
+		if stmt, err = dbHandler(db).Prepare(sqlReportBudgetCategoriesMonthly); err != nil {
 			return nil, errors.New(errReadingFromFile)
 		}
-		if rows, err = stmt.Query(ys, ms, y, m, MCTTransfer, currency, currency, y, m, currency, currency, ys, ms); err != nil {
+		if rows, err = stmt.Query(ys, ms, y, m, currency, currency, y, m, currency, currency, ys, ms); err != nil {
 			return nil, errors.New(errReadingFromFile)
 		}
 	}
+	defer stmt.Close()
+	defer rows.Close()
 
 	// Check if we have all necessary currency exchange rates
 	if s, err := missingCurrenciesForTransactions(db, currency); err == nil {
@@ -412,15 +446,34 @@ func ReportBudgetCategories(db *gsqlitehandler.SqliteDB, p *BPeriod, currency st
 		return nil, err
 	}
 
+	// Roll up the values
+	var lines []*BudgetCategoriesReportEntry
+	var cats []*Category
+	for rows.Next() {
+		e := BudgetCategoriesReportEntryNew()
+		rows.Scan(&e.Category.Main.Id, &e.Category.Main.Name, &e.Category.Main.Status, &e.Category.Main.MType.Id, &e.Category.Main.MType.Name, &e.Category.Main.MType.Factor, &e.Category.Id, &e.Category.Name, &e.Category.Status, &e.Limit, &e.Actual, &e.Difference)
+		lines = append(lines, e)
+		cats = append(cats, e.Category)
+	}
+	groups, idx := t.rollUp(cats, depth)
+	entries := make([]*BudgetCategoriesReportEntry, len(groups))
+	for i, g := range groups {
+		entries[i] = &BudgetCategoriesReportEntry{Category: g}
+	}
+	for i, l := range lines {
+		e := entries[idx[i]]
+		e.Limit += l.Limit
+		e.Actual += l.Actual
+		e.Difference += l.Difference
+	}
+
 	// Create closure
+	i := 0
 	f = func() *BudgetCategoriesReportEntry {
-		if rows.Next() {
-			e := BudgetCategoriesReportEntryNew()
-			rows.Scan(&e.Category.Main.Id, &e.Category.Main.Name, &e.Category.Main.Status, &e.Category.Main.MType.Id, &e.Category.Main.MType.Name, &e.Category.Main.MType.Factor, &e.Category.Id, &e.Category.Name, &e.Category.Status, &e.Limit, &e.Actual, &e.Difference)
-			return e
+		if i < len(entries) {
+			i++
+			return entries[i-1]
 		}
-		rows.Close()
-		stmt.Close()
 
 		return nil
 	}
@@ -429,6 +482,14 @@ func ReportBudgetCategories(db *gsqlitehandler.SqliteDB, p *BPeriod, currency st
 	//TODO: add test
 }
 
+// budgetYearRange returns the first and the last day of fiscal year of budget period p
+// and its first and last month as year*100+month (the way budgets are compared with them)
+func budgetYearRange(p *BPeriod) (df, dt string, mf, mt int) {
+	from, to := p.Dates()
+
+	return from.Format(DateFormat), to.Format(DateFormat), from.Year()*100 + int(from.Month()), to.Year()*100 + int(to.Month())
+}
+
 // BudgetMainCategoryEntry represents one line of the report
 type BudgetMainCategoryReportEntry struct {
 	MainCategory *MainCategory
@@ -452,17 +513,18 @@ func ReportBudgetMainCategories(db *gsqlitehandler.SqliteDB, p *BPeriod, currenc
 	m := int(p.Month)
 	ys, ms := p.GetStrings()
 	if m == NotSetIntValue {
-		if stmt, err = db.Handler.Prepare(sqlReportBudgetMainCategoriesYearly); err != nil {
+		if stmt, err = dbHandler(db).Prepare(sqlReportBudgetMainCategoriesYearly); err != nil {
 			return nil, errors.New(errReadingFromFile)
 		}
-		if rows, err = stmt.Query(ys, y, MCTTransfer, currency, currency, y, ys, currency, currency); err != nil {
+		df, dt, mf, mt := budgetYearRange(p)
+		if rows, err = stmt.Query(df, dt, mf, mt, currency, currency, mf, mt, df, dt, currency, currency); err != nil {
 			return nil, errors.New(errReadingFromFile)
 		}
 	} else {
-		if stmt, err = db.Handler.Prepare(sqlReportBudgetMainCategoriesMonthly); err != nil {
+		if stmt, err = dbHandler(db).Prepare(sqlReportBudgetMainCategoriesMonthly); err != nil {
 			return nil, errors.New(errReadingFromFile)
 		}
-		if rows, err = stmt.Query(ys, ms, y, m, MCTTransfer, currency, currency, y, m, ys, ms, currency, currency); err != nil {
+		if rows, err = stmt.Query(ys, ms, y, m, currency, currency, y, m, ys, ms, currency, currency); err != nil {
 			return nil, errors.New(errReadingFromFile)
 		}
 	}
@@ -500,19 +562,14 @@ func ReportBudgetMainCategories(db *gsqlitehandler.SqliteDB, p *BPeriod, currenc
 	//TODO: add test
 }
 
-type NetValueMonthlyReportEntry struct {
-	Period *BPeriod
+type NetValueReportEntry struct {
+	Period *Period
 	Value  float64
 }
 
-func NetValueMonthlyReportEntryNew() *NetValueMonthlyReportEntry {
-	e := new(NetValueMonthlyReportEntry)
-	e.Period = new(BPeriod)
-
-	return e
-}
-
-func ReportNetValueMonthly(db *gsqlitehandler.SqliteDB, currency string, dateFrom, dateTo time.Time) (f func() *NetValueMonthlyReportEntry, err error) {
+// ReportNetValue returns balance of transactions taken into net value in periods of granularity g
+// (until today if dateTo is not set)
+func ReportNetValue(db *gsqlitehandler.SqliteDB, currency string, g Granularity, dateFrom, dateTo time.Time) (f func() *NetValueReportEntry, err error) {
 	var stmt *sql.Stmt
 	var rows *sql.Rows
 
@@ -528,30 +585,34 @@ func ReportNetValueMonthly(db *gsqlitehandler.SqliteDB, currency string, dateFro
 	if !dateFrom.IsZero() {
 		df = dateFrom.Format(DateFormat)
 	}
-	dt := time.Now().Format(DateFormat)
-	if !dateTo.IsZero() {
-		dt = dateTo.Format(DateFormat)
+	if dateTo.IsZero() {
+		dateTo = time.Now()
 	}
+	dt := dateTo.Format(DateFormat)
 
 	// Execute main query
-	if stmt, err = db.Handler.Prepare(sqlReportNetValueMonthly); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlReportNetValue); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
+	defer stmt.Close()
 	if rows, err = stmt.Query(currency, currency, df, df, noStringParamForSQL, dt, dt, noStringParamForSQL); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
+	var periods []*Period
+	var values [][]float64
+	if periods, values, err = reportPeriodValues(db, rows, g, 1, dateFrom, dateTo); err != nil {
+		return nil, err
+	}
 
 	// Create closure
-	f = func() *NetValueMonthlyReportEntry {
-		if rows.Next() {
-			e := NetValueMonthlyReportEntryNew()
-			rows.Scan(&e.Period.Year, &e.Period.Month, &e.Value)
-			return e
+	i := 0
+	f = func() *NetValueReportEntry {
+		if i == len(periods) {
+			return nil
 		}
-		rows.Close()
-		stmt.Close()
-
-		return nil
+		e := &NetValueReportEntry{Period: periods[i], Value: values[i][0]}
+		i++
+		return e
 	}
 
 	return f, nil
@@ -559,67 +620,32 @@ func ReportNetValueMonthly(db *gsqlitehandler.SqliteDB, currency string, dateFro
 }
 
 type BalanceTimeReportEntry struct {
-	Period *BPeriod
+	Period *Period
 	Value  float64
 }
 
-func BalanceTimeReportEntryNew() *BalanceTimeReportEntry {
-	e := new(BalanceTimeReportEntry)
-	e.Period = new(BPeriod)
-
-	return e
-}
-
-func ReportCategoriesBalanceMonthly(db *gsqlitehandler.SqliteDB, currency string, c *Category, dateFrom, dateTo time.Time) (f func() *BalanceTimeReportEntry, err error) {
-	var stmt *sql.Stmt
-	var rows *sql.Rows
-
-	// Check input parameters
-	if s, err := missingCurrenciesForTransactions(db, currency); err == nil {
-		if s != nil {
-			return nil, errors.New(errReportMissingCurrencies + strings.Join(s, ", "))
-		}
-	} else {
-		return nil, err
-	}
+// ReportCategoriesBalanceTime returns balance of category c (with its subcategories) in periods of granularity g
+func ReportCategoriesBalanceTime(db *gsqlitehandler.SqliteDB, currency string, c *Category, g Granularity, dateFrom, dateTo time.Time) (f func() *BalanceTimeReportEntry, err error) {
 	if c == nil {
 		return nil, errors.New(errCategoryMissing)
 	}
-	df := noStringParamForSQL
-	if !dateFrom.IsZero() {
-		df = dateFrom.Format(DateFormat)
-	}
-	dt := noStringParamForSQL
-	if !dateTo.IsZero() {
-		dt = dateTo.Format(DateFormat)
-	}
 
-	// Execute main query
-	if stmt, err = db.Handler.Prepare(sqlReportCategoriesBalanceMonthly); err != nil {
-		return nil, errors.New(errReadingFromFile)
-	}
-	if rows, err = stmt.Query(currency, currency, c.Id, df, df, noStringParamForSQL, dt, dt, noStringParamForSQL); err != nil {
-		return nil, errors.New(errReadingFromFile)
-	}
-
-	// Create closure
-	f = func() *BalanceTimeReportEntry {
-		if rows.Next() {
-			e := BalanceTimeReportEntryNew()
-			rows.Scan(&e.Period.Year, &e.Period.Month, &e.Value)
-			return e
-		}
-		rows.Close()
-		stmt.Close()
+	return reportBalanceTime(db, sqlReportCategoriesBalanceTime, currency, c.Id, g, dateFrom, dateTo)
+	//TODO: add test
+}
 
-		return nil
+// ReportMainCategoriesBalanceTime returns balance of main category m in periods of granularity g
+func ReportMainCategoriesBalanceTime(db *gsqlitehandler.SqliteDB, currency string, m *MainCategory, g Granularity, dateFrom, dateTo time.Time) (f func() *BalanceTimeReportEntry, err error) {
+	if m == nil {
+		return nil, errors.New(errMainCategoryMissing)
 	}
 
-	return f, nil
+	return reportBalanceTime(db, sqlReportMainCategoriesBalanceTime, currency, m.Id, g, dateFrom, dateTo)
 	//TODO: add test
 }
 
-func ReportCategoriesBalanceYearly(db *gsqlitehandler.SqliteDB, currency string, c *Category, dateFrom, dateTo time.Time) (f func() *BalanceTimeReportEntry, err error) {
+// reportBalanceTime returns balance of category or main category (given by query q and its id) in periods of granularity g
+func reportBalanceTime(db *gsqlitehandler.SqliteDB, q string, currency string, id int64, g Granularity, dateFrom, dateTo time.Time) (f func() *BalanceTimeReportEntry, err error) {
 	var stmt *sql.Stmt
 	var rows *sql.Rows
 
@@ -631,9 +657,6 @@ func ReportCategoriesBalanceYearly(db *gsqlitehandler.SqliteDB, currency string,
 	} else {
 		return nil, err
 	}
-	if c == nil {
-		return nil, errors.New(errCategoryMissing)
-	}
 	df := noStringParamForSQL
 	if !dateFrom.IsZero() {
 		df = dateFrom.Format(DateFormat)
@@ -644,31 +667,41 @@ func ReportCategoriesBalanceYearly(db *gsqlitehandler.SqliteDB, currency string,
 	}
 
 	// Execute main query
-	if stmt, err = db.Handler.Prepare(sqlReportCategoriesBalanceYearly); err != nil {
+	if stmt, err = dbHandler(db).Prepare(q); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
-	if rows, err = stmt.Query(currency, currency, c.Id, df, df, noStringParamForSQL, dt, dt, noStringParamForSQL); err != nil {
+	defer stmt.Close()
+	if rows, err = stmt.Query(currency, currency, id, df, df, noStringParamForSQL, dt, dt, noStringParamForSQL); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
+	var periods []*Period
+	var values [][]float64
+	if periods, values, err = reportPeriodValues(db, rows, g, 1, dateFrom, dateTo); err != nil {
+		return nil, err
+	}
 
 	// Create closure
+	i := 0
 	f = func() *BalanceTimeReportEntry {
-		if rows.Next() {
-			e := BalanceTimeReportEntryNew()
-			rows.Scan(&e.Period.Year, &e.Value)
-			return e
+		if i == len(periods) {
+			return nil
 		}
-		rows.Close()
-		stmt.Close()
-
-		return nil
+		e := &BalanceTimeReportEntry{Period: periods[i], Value: values[i][0]}
+		i++
+		return e
 	}
 
 	return f, nil
-	//TODO: add test
 }
 
-func ReportMainCategoriesBalanceMonthly(db *gsqlitehandler.SqliteDB, currency string, m *MainCategory, dateFrom, dateTo time.Time) (f func() *BalanceTimeReportEntry, err error) {
+type IncomeVsCostReportEntry struct {
+	Period *Period
+	Income float64
+	Cost   float64
+}
+
+// ReportIncomeVsCost returns income and cost in periods of granularity g
+func ReportIncomeVsCost(db *gsqlitehandler.SqliteDB, currency string, g Granularity, dateFrom, dateTo time.Time) (f func() *IncomeVsCostReportEntry, err error) {
 	var stmt *sql.Stmt
 	var rows *sql.Rows
 
@@ -680,9 +713,6 @@ func ReportMainCategoriesBalanceMonthly(db *gsqlitehandler.SqliteDB, currency st
 	} else {
 		return nil, err
 	}
-	if m == nil {
-		return nil, errors.New(errMainCategoryMissing)
-	}
 	df := noStringParamForSQL
 	if !dateFrom.IsZero() {
 		df = dateFrom.Format(DateFormat)
@@ -693,96 +723,198 @@ func ReportMainCategoriesBalanceMonthly(db *gsqlitehandler.SqliteDB, currency st
 	}
 
 	// Execute main query
-	if stmt, err = db.Handler.Prepare(sqlReportMainCategoriesBalanceMonthly); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlReportIncomeAndCost); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
-	if rows, err = stmt.Query(currency, currency, m.Id, df, df, noStringParamForSQL, dt, dt, noStringParamForSQL); err != nil {
+	defer stmt.Close()
+	if rows, err = stmt.Query(currency, currency, df, df, noStringParamForSQL, dt, dt, noStringParamForSQL); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
+	var periods []*Period
+	var values [][]float64
+	if periods, values, err = reportPeriodValues(db, rows, g, 2, dateFrom, dateTo); err != nil {
+		return nil, err
+	}
 
 	// Create closure
-	f = func() *BalanceTimeReportEntry {
-		if rows.Next() {
-			e := BalanceTimeReportEntryNew()
-			rows.Scan(&e.Period.Year, &e.Period.Month, &e.Value)
-			return e
+	i := 0
+	f = func() *IncomeVsCostReportEntry {
+		if i == len(periods) {
+			return nil
 		}
-		rows.Close()
-		stmt.Close()
-
-		return nil
+		e := &IncomeVsCostReportEntry{Period: periods[i], Income: values[i][0], Cost: values[i][1]}
+		i++
+		return e
 	}
 
 	return f, nil
 	//TODO: add test
 }
 
-func ReportMainCategoriesBalanceYearly(db *gsqlitehandler.SqliteDB, currency string, m *MainCategory, dateFrom, dateTo time.Time) (f func() *BalanceTimeReportEntry, err error) {
-	var stmt *sql.Stmt
-	var rows *sql.Rows
+type ForecastReportEntry struct {
+	Date     time.Time
+	Balances []float64
+	Negative bool
+}
 
-	// Check input parameters
-	if s, err := missingCurrenciesForTransactions(db, currency); err == nil {
-		if s != nil {
-			return nil, errors.New(errReportMissingCurrencies + strings.Join(s, ", "))
+// ReportForecast returns balances of open accounts projected day by day from tomorrow until given day.
+// Current balances are changed by transactions dated in the future, recurring transactions
+// and budget limits of each month left to spend (after the recurring transactions of their categories),
+// spread evenly over the rest of the month on account a (the first operational one if a is nil).
+// Accounts are given in the same order as balances of entries. Entries are negative if balance
+// of any operational account is below zero.
+func ReportForecast(db *gsqlitehandler.SqliteDB, a *Account, until time.Time) (accounts []*Account, f func() *ForecastReportEntry, err error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	until = time.Date(until.Year(), until.Month(), until.Day(), 0, 0, 0, 0, time.UTC)
+	if !until.After(today) {
+		return nil, nil, errors.New(errForecastUntil)
+	}
+
+	// Accounts with their current balances
+	index := make(map[int64]int)
+	var balances []float64
+	addAccount := func(acc *Account) {
+		if _, ok := index[acc.Id]; ok || acc.Status != ISOpen {
+			return
+		}
+		index[acc.Id] = len(accounts)
+		accounts = append(accounts, acc)
+		balances = append(balances, 0)
+	}
+	var fb func() *AccountBalanceReportEntry
+	if fb, err = ReportAccountBalance(db, today); err != nil {
+		return nil, nil, err
+	}
+	for e := fb(); e != nil; e = fb() {
+		addAccount(e.Account)
+		balances[index[e.Account.Id]] = e.Value
+	}
+	var recurring []*Recurring
+	var fr func() *Recurring
+	if fr, err = RecurringList(db, nil); err != nil {
+		return nil, nil, err
+	}
+	for r := fr(); r != nil; r = fr() {
+		addAccount(r.Account)
+		recurring = append(recurring, r)
+	}
+	if a == nil {
+		for _, acc := range accounts {
+			if acc.AType == ATTransactional {
+				a = acc
+				break
+			}
 		}
 	} else {
-		return nil, err
+		addAccount(a)
 	}
-	if m == nil {
-		return nil, errors.New(errMainCategoryMissing)
+
+	// Changes of balances on days of forecast
+	days := int(until.Sub(today).Hours()/24 + 0.5)
+	changes := make([][]float64, days+1)
+	for i := range changes {
+		changes[i] = make([]float64, len(accounts))
 	}
-	df := noStringParamForSQL
-	if !dateFrom.IsZero() {
-		df = dateFrom.Format(DateFormat)
+	change := func(d time.Time, acc *Account, v float64) {
+		if i, ok := index[acc.Id]; ok {
+			changes[int(d.Sub(today).Hours()/24+0.5)][i] += v
+		}
 	}
-	dt := noStringParamForSQL
-	if !dateTo.IsZero() {
-		dt = dateTo.Format(DateFormat)
+	var ft func() *Transaction
+	if ft, err = TransactionList(db, today.AddDate(0, 0, 1), until, nil, NotSetStringValue, nil, nil); err != nil {
+		return nil, nil, err
 	}
-
-	// Execute main query
-	if stmt, err = db.Handler.Prepare(sqlReportMainCategoriesBalanceYearly); err != nil {
-		return nil, errors.New(errReadingFromFile)
+	for t := ft(); t != nil; t = ft() {
+		change(t.Date, t.Account, t.GetSValue())
 	}
-	if rows, err = stmt.Query(currency, currency, m.Id, df, df, noStringParamForSQL, dt, dt, noStringParamForSQL); err != nil {
-		return nil, errors.New(errReadingFromFile)
+	for _, r := range recurring {
+		for _, d := range r.Dates(today.AddDate(0, 0, 1), until) {
+			change(d, r.Account, r.GetSValue())
+		}
 	}
 
-	// Create closure
-	f = func() *BalanceTimeReportEntry {
-		if rows.Next() {
-			e := BalanceTimeReportEntryNew()
-			rows.Scan(&e.Period.Year, &e.Value)
-			return e
+	// Budget left to spend in each month
+	if a != nil {
+		rates := make(map[string]float64)
+		fp := PeriodList(db, GranularityMonth, today, until)
+		for p := fp(); p != nil; p = fp() {
+			// Recurring transactions of the month are already counted in their categories
+			scheduled := make(map[int64]float64)
+			for _, r := range recurring {
+				n := len(r.Dates(p.Start, p.End))
+				if n == 0 {
+					continue
+				}
+				rate, ok := rates[r.Account.Currency]
+				if !ok {
+					var e *ExchangeRate
+					if e, err = ExchangeRateForCurrencies(db, r.Account.Currency, a.Currency); err != nil {
+						return nil, nil, err
+					}
+					rate = e.Rate
+					rates[r.Account.Currency] = rate
+				}
+				scheduled[r.Category.Id] += float64(n) * r.GetSValue() * rate
+			}
+
+			var fbc func() *BudgetCategoriesReportEntry
+			if fbc, err = ReportBudgetCategories(db, &BPeriod{Year: int64(p.Start.Year()), Month: int64(p.Start.Month())}, a.Currency, NotSetIntValue); err != nil {
+				return nil, nil, err
+			}
+			var left float64
+			for e := fbc(); e != nil; e = fbc() {
+				if l := e.Limit - e.Actual - scheduled[e.Category.Id]; l*e.Limit > 0 {
+					left += l
+				}
+			}
+
+			from := p.Start
+			if !from.After(today) {
+				from = today.AddDate(0, 0, 1)
+			}
+			n := int(p.End.Sub(from).Hours()/24+0.5) + 1
+			for d := from; !d.After(p.End) && !d.After(until); d = d.AddDate(0, 0, 1) {
+				change(d, a, left/float64(n))
+			}
 		}
-		rows.Close()
-		stmt.Close()
+	}
 
-		return nil
+	// Create closure
+	i := 0
+	f = func() *ForecastReportEntry {
+		i++
+		if i > days {
+			return nil
+		}
+		e := &ForecastReportEntry{Date: today.AddDate(0, 0, i), Balances: make([]float64, len(accounts))}
+		for j := range balances {
+			balances[j] += changes[i][j]
+			e.Balances[j] = balances[j]
+			if accounts[j].AType == ATTransactional && balances[j] < -0.005 {
+				e.Negative = true
+			}
+		}
+		return e
 	}
 
-	return f, nil
+	return accounts, f, nil
 	//TODO: add test
 }
 
-type IncomeVsCostReportEntry struct {
-	Period *BPeriod
-	Income float64
-	Cost   float64
-}
-
-func IncomeVsCostReportEntryNew() *IncomeVsCostReportEntry {
-	e := new(IncomeVsCostReportEntry)
-	e.Period = new(BPeriod)
-
-	return e
+type GoalsReportEntry struct {
+	Goal    *Goal
+	Target  float64
+	Current float64
+	Percent float64
+	Monthly float64
+	OnTrack bool
 }
 
-func ReportIncomeVsCostMonthly(db *gsqlitehandler.SqliteDB, currency string, dateFrom, dateTo time.Time) (f func() *IncomeVsCostReportEntry, err error) {
-	var stmt *sql.Stmt
-	var rows *sql.Rows
-
+// ReportGoals returns progress of savings goals in given currency: amount saved until today,
+// percentage of the target, contribution required in each month left to reach it and
+// whether the amount saved is not less than it should be if saving evenly since the goal was set.
+func ReportGoals(db *gsqlitehandler.SqliteDB, currency string) (f func() *GoalsReportEntry, err error) {
 	// Check input parameters
 	if s, err := missingCurrenciesForTransactions(db, currency); err == nil {
 		if s != nil {
@@ -791,84 +923,130 @@ func ReportIncomeVsCostMonthly(db *gsqlitehandler.SqliteDB, currency string, dat
 	} else {
 		return nil, err
 	}
-	df := noStringParamForSQL
-	if !dateFrom.IsZero() {
-		df = dateFrom.Format(DateFormat)
-	}
-	dt := noStringParamForSQL
-	if !dateTo.IsZero() {
-		dt = dateTo.Format(DateFormat)
-	}
 
-	// Execute main query
-	if stmt, err = db.Handler.Prepare(sqlReportIncomeAndCostMonthly); err != nil {
-		return nil, errors.New(errReadingFromFile)
+	var l []*Goal
+	if l, err = goalsGet(db, NotSetIntValue); err != nil {
+		return nil, err
 	}
-	if rows, err = stmt.Query(df, df, noStringParamForSQL, dt, dt, noStringParamForSQL, currency, currency, MCTIncome, currency, currency, MCTCost); err != nil {
-		return nil, errors.New(errReadingFromFile)
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	// Amounts saved on balances of accounts or as transactions of category
+	const sqlGoalAccounts = "SELECT ifnull(sum(mt.factor * t.value * cur.exchange_rate), 0) FROM transactions t " +
+		"INNER JOIN categories c ON t.category_id=c.id INNER JOIN main_categories m ON c.main_category_id=m.id INNER JOIN main_categories_types mt ON m.type_id=mt.id " +
+		"INNER JOIN accounts a ON t.account_id=a.id " +
+		"INNER JOIN (SELECT currency_from, exchange_rate FROM currencies WHERE currency_to=upper(?) UNION SELECT upper(?), 1) cur ON a.currency=cur.currency_from " +
+		"INNER JOIN goals_accounts ga ON ga.account_id=a.id WHERE ga.goal_id=? AND t.date<=?;"
+	const sqlGoalCategory = "SELECT ifnull(sum(t.value * cur.exchange_rate), 0) FROM transactions t " +
+		"INNER JOIN accounts a ON t.account_id=a.id " +
+		"INNER JOIN (SELECT currency_from, exchange_rate FROM currencies WHERE currency_to=upper(?) UNION SELECT upper(?), 1) cur ON a.currency=cur.currency_from " +
+		"WHERE t.category_id IN (WITH RECURSIVE sub(id) AS (SELECT ? UNION SELECT cs.id FROM categories cs INNER JOIN sub ON cs.parent_id=sub.id) SELECT id FROM sub) AND t.date<=?;"
+	var entries []*GoalsReportEntry
+	for _, g := range l {
+		e := &GoalsReportEntry{Goal: g}
+		var r *ExchangeRate
+		if r, err = ExchangeRateForCurrencies(db, g.Currency, currency); err != nil {
+			return nil, errors.New(errReportMissingCurrencies + g.Currency)
+		}
+		e.Target = g.Value * r.Rate
+		if g.Category == nil {
+			err = dbHandler(db).QueryRow(sqlGoalAccounts, currency, currency, g.Id, today.Format(DateFormat)).Scan(&e.Current)
+		} else {
+			err = dbHandler(db).QueryRow(sqlGoalCategory, currency, currency, g.Category.Id, today.Format(DateFormat)).Scan(&e.Current)
+		}
+		if err != nil {
+			return nil, errors.New(errReadingFromFile)
+		}
+		e.Percent = 100 * e.Current / e.Target
+
+		// Months left include the current one, goals which are due need the whole rest at once
+		months := (g.DateTo.Year()-today.Year())*12 + int(g.DateTo.Month()-today.Month()) + 1
+		if months < 1 {
+			months = 1
+		}
+		if e.Current < e.Target {
+			e.Monthly = (e.Target - e.Current) / float64(months)
+		}
+		expected := e.Target
+		if today.Before(g.DateTo) {
+			expected = e.Target * today.Sub(g.DateFrom).Hours() / g.DateTo.Sub(g.DateFrom).Hours()
+		}
+		e.OnTrack = e.Current >= expected-0.005
+		entries = append(entries, e)
 	}
 
 	// Create closure
-	f = func() *IncomeVsCostReportEntry {
-		if rows.Next() {
-			e := IncomeVsCostReportEntryNew()
-			rows.Scan(&e.Period.Year, &e.Period.Month, &e.Income, &e.Cost)
-			return e
+	i := 0
+	f = func() *GoalsReportEntry {
+		if i == len(entries) {
+			return nil
 		}
-		rows.Close()
-		stmt.Close()
-
-		return nil
+		e := entries[i]
+		i++
+		return e
 	}
 
 	return f, nil
 	//TODO: add test
 }
 
-func ReportIncomeVsCostYearly(db *gsqlitehandler.SqliteDB, currency string, dateFrom, dateTo time.Time) (f func() *IncomeVsCostReportEntry, err error) {
-	var stmt *sql.Stmt
-	var rows *sql.Rows
+// reportPeriodValues sums up rows of date and n values (ordered by date) in periods of granularity g.
+// All the periods between days from and to are returned, the ones without rows with zeros, so that gaps are seen.
+// Days which are not set are replaced by dates of the first and the last row.
+func reportPeriodValues(db *gsqlitehandler.SqliteDB, rows *sql.Rows, g Granularity, n int, from, to time.Time) (periods []*Period, values [][]float64, err error) {
+	defer rows.Close()
 
-	// Check input parameters
-	if s, err := missingCurrenciesForTransactions(db, currency); err == nil {
-		if s != nil {
-			return nil, errors.New(errReportMissingCurrencies + strings.Join(s, ", "))
+	sums := make(map[time.Time][]float64)
+	var first, last time.Time
+	for rows.Next() {
+		var tmpDate string
+		v := make([]float64, n)
+		dest := []interface{}{&tmpDate}
+		for j := range v {
+			dest = append(dest, &v[j])
+		}
+		if err = rows.Scan(dest...); err != nil {
+			return nil, nil, errors.New(errReadingFromFile)
+		}
+		var d time.Time
+		if d, err = time.Parse(DateFormat, tmpDate); err != nil {
+			return nil, nil, errors.New(errReadingFromFile)
+		}
+		if first.IsZero() {
+			first = d
+		}
+		last = d
+		s := PeriodFor(db, g, d).Start
+		if sums[s] == nil {
+			sums[s] = make([]float64, n)
+		}
+		for j := range v {
+			sums[s][j] += v[j]
 		}
-	} else {
-		return nil, err
-	}
-	df := noStringParamForSQL
-	if !dateFrom.IsZero() {
-		df = dateFrom.Format(DateFormat)
 	}
-	dt := noStringParamForSQL
-	if !dateTo.IsZero() {
-		dt = dateTo.Format(DateFormat)
+	if err = rows.Err(); err != nil {
+		return nil, nil, errors.New(errReadingFromFile)
 	}
 
-	// Execute main query
-	if stmt, err = db.Handler.Prepare(sqlReportIncomeAndCostYearly); err != nil {
-		return nil, errors.New(errReadingFromFile)
+	if from.IsZero() {
+		from = first
 	}
-	if rows, err = stmt.Query(df, df, noStringParamForSQL, dt, dt, noStringParamForSQL, currency, currency, MCTIncome, currency, currency, MCTCost); err != nil {
-		return nil, errors.New(errReadingFromFile)
+	if to.IsZero() {
+		to = last
 	}
-
-	// Create closure
-	f = func() *IncomeVsCostReportEntry {
-		if rows.Next() {
-			e := IncomeVsCostReportEntryNew()
-			rows.Scan(&e.Period.Year, &e.Income, &e.Cost)
-			return e
+	if from.IsZero() || to.IsZero() {
+		return nil, nil, nil
+	}
+	getNextPeriod := PeriodList(db, g, from, to)
+	for p := getNextPeriod(); p != nil; p = getNextPeriod() {
+		v := sums[p.Start]
+		if v == nil {
+			v = make([]float64, n)
 		}
-		rows.Close()
-		stmt.Close()
-
-		return nil
+		periods, values = append(periods, p), append(values, v)
 	}
 
-	return f, nil
-	//TODO: add test
+	return periods, values, nil
 }
 
 // missingCurrenciesForTransactions returns list of missing currency exchange rates for transactions
@@ -877,7 +1055,7 @@ func missingCurrenciesForTransactions(db *gsqlitehandler.SqliteDB, c string) (l
 	var stmt *sql.Stmt
 	var rows *sql.Rows
 
-	if stmt, err = db.Handler.Prepare(sqlReportMissingCurrenciesForTransactions); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlReportMissingCurrenciesForTransactions); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 	defer stmt.Close()
@@ -902,7 +1080,7 @@ func missingCurrenciesForBudgets(db *gsqlitehandler.SqliteDB, c string) (l []str
 	var stmt *sql.Stmt
 	var rows *sql.Rows
 
-	if stmt, err = db.Handler.Prepare(sqlReportMissingCurrenciesForBudgets); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlReportMissingCurrenciesForBudgets); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 	defer stmt.Close()
@@ -0,0 +1,64 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Periods without transactions are reported with zeros
+func TestReportCategoriesBalanceTimeGaps(t *testing.T) {
+	db := newTestDataFile(t)
+	a := &Account{Name: "Bank", Currency: "SEK", AType: ATTransactional, Status: ISOpen}
+	if err := AccountAdd(db, a); err != nil {
+		t.Fatalf("adding account: %v", err)
+	}
+	food := newTestCategory(t, db, "Food", nil)
+	for _, tr := range []struct {
+		date  string
+		value float64
+	}{{"2016-03-01", 10}, {"2016-03-03", 20}, {"2016-03-03", 5}} {
+		nt := TransactionNew()
+		nt.Date, nt.Account, nt.Category, nt.Value = testDate(t, tr.date), a, food, tr.value
+		if err := TransactionAdd(db, nt); err != nil {
+			t.Fatalf("adding transaction: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		g        Granularity
+		from, to string
+		want     []string
+	}{
+		{name: "days with transactions", g: GranularityDay, want: []string{"2016-03-01 -10.00", "2016-03-02 0.00", "2016-03-03 -25.00"}},
+		{name: "given days", g: GranularityDay, from: "2016-02-29", to: "2016-03-04",
+			want: []string{"2016-02-29 0.00", "2016-03-01 -10.00", "2016-03-02 0.00", "2016-03-03 -25.00", "2016-03-04 0.00"}},
+		{name: "weeks", g: GranularityWeek, from: "2016-03-01", to: "2016-03-14", want: []string{"2016-W09 -35.00", "2016-W10 0.00", "2016-W11 0.00"}},
+		{name: "no transactions", g: GranularityMonth, from: "2016-04-01", to: "2016-05-31", want: []string{"2016-04 0.00", "2016-05 0.00"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var from, to time.Time
+			if tt.from != NotSetStringValue {
+				from, to = testDate(t, tt.from), testDate(t, tt.to)
+			}
+			getNextEntry, err := ReportCategoriesBalanceTime(db, "SEK", food, tt.g, from, to)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for e := getNextEntry(); e != nil; e = getNextEntry() {
+				got = append(got, fmt.Sprintf("%s %.2f", e.Period, e.Value))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("report = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
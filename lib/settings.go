@@ -14,6 +14,8 @@ const (
 
 	DateFormat    = "2006-01-02"
 	DateSeparator = "-"
+
+	CategoryPathSeparator = ":"
 )
 
 // Special objects
@@ -36,6 +38,7 @@ const (
 	errCategoryWithNameNone      = "no category with given name"
 	errCategoryWithNameAmbiguous = "given category name is ambiguous"
 	errCategoryMissing           = "category missing"
+	errCategoryParentIncorrect   = "category cannot be moved under itself or its subcategory"
 
 	errExchangeRateNone          = "no exchange rate for given currencies"
 	errExchangeRateAlreadyExists = "exchange rate for given currencies already exists"
@@ -44,13 +47,61 @@ const (
 	errAccountForNameNone   = "no account with given name"
 	errAccountNameAmbiguous = "given account name is ambiguous"
 
-	errTransactionWithIDNone = "no transaction with given ID"
+	errTransactionWithIDNone    = "no transaction with given ID"
+	errDuplicateSameTransaction = "transaction cannot be duplicate of itself"
+	errDuplicateAccount         = "transactions of different accounts cannot be merged"
+	errDuplicateValue           = "transactions with different values cannot be merged"
+	errDuplicateTransfer        = "transfer cannot be merged into transaction which is not a transfer"
 
 	errBudgetNone = "no budget"
 
+	errRecurringWithIDNone = "no recurring transaction with given ID"
+	errRecurringEvery      = "recurring transaction must be repeated every 1 or more periods"
+	errRecurringDates      = "last date of recurring transaction cannot be before its first date"
+	errForecastUntil       = "forecast must end after today"
+
+	errGoalWithIDNone = "no goal with given ID"
+	errGoalLink       = "goal must be saved either in accounts or in category"
+	errGoalValue      = "target amount of goal must be greater than zero"
+	errGoalDate       = "last day of goal must be after today"
+
+	errStatementFormat          = "incorrect format of statement file"
+	errStatementDate            = "incorrect date in statement file: "
+	errStatementDateMissing     = "missing date of transaction in statement file"
+	errStatementAmount          = "incorrect amount in statement file: "
+	errStatementAccountMissing  = "missing account for transactions without account in statement file"
+	errStatementAccountNone     = "no account with name given in statement file: "
+	errStatementCategoryMissing = "missing category for transactions without category in statement file"
+	errStatementCategoryFactor  = "category for imported transactions must be of main category type with factor 1 or -1"
+	errStatementCurrency        = "statement currency differs from currency of account: "
+	errJournalFormat            = "incorrect format of journal file"
+	errJournalAmount            = "incorrect amount in journal file: "
+	errDumpFormat               = "incorrect format of dump file"
+	errDumpVersion              = "dump file comes from newer version of data file: "
+	errDumpReference            = "incorrect dump file: "
+	errBackupSettings           = "incorrect setting in config file: "
+	errBackupNone               = "no backup of data file with given name: "
+	errBackupCopy               = "error copying data file: "
+	errDataFileEncrypted        = "data file is already encrypted"
+	errDataFileNotEncrypted     = "data file is not encrypted"
+	errDataFileNotSQLite        = "file is not a data file"
+	errDataFileInUse            = "data file is in use by PID "
+	errAttachmentWithIDNone     = "no attachment with given ID"
+	errAttachmentExists         = "the file is already attached"
+	errAttachmentHash           = "attachment is damaged (its contents does not match its hash)"
+	errAttachmentObject         = "files cannot be attached to "
+	errDateIncorrect            = "incorrect date: "
+	errGranularityIncorrect     = "incorrect granularity (day, week, month, quarter or year): "
+	errFiscalYearStartIncorrect = "incorrect first month of fiscal year (1-12 or name of month): "
+	errPassphraseMissing        = "missing passphrase of encrypted data file"
+	errPassphraseWrong          = "wrong passphrase of encrypted data file (or the file is damaged)"
+
 	errWritingToFile   = "error writing to file"
 	errReadingFromFile = "error reading from file"
 
+	errDataFileTransactionOpen = "changes are already grouped (begin was used before)"
+	errDataFileTransactionNone = "there are no grouped changes (use begin first)"
+
 	errReportMissingCurrencies string = "missing currency exchange rate(s) for: "
 
 	errSystemObject = "this is system object and cannot be changed or removed"
@@ -4,6 +4,108 @@
 
 package lib
 
+// sqlCategoryList is SQL string to get categories ordered by main category and their path in the categories tree.
+//
+// Parameters
+// 1 - parent_id of categories placed directly under main category (NotSetIntValue)
+// 2 - CategoryPathSeparator (string)
+// 3 - main_category_id (int)
+// 4 - main_category_id (int)
+// 5 - noIntParamForSQL
+// 6 - category name (string)
+// 7 - category name (string)
+// 8 - noStringParamForSQL
+// 9 - status (int)
+// 10 - status (int)
+// 11 - ISUnset
+const sqlCategoryList string = `
+with recursive tree(id, path) as (
+    select
+        id
+        ,name
+    from
+        categories
+    where
+        parent_id=?
+    union all
+    select
+        c.id
+        ,tree.path || ? || c.name
+    from
+        categories c
+        inner join tree on c.parent_id=tree.id
+)
+select
+    c.id
+    ,c.name
+    ,c.status
+    ,c.parent_id
+    ,m.id
+    ,m.name
+    ,m.status
+    ,t.id
+    ,t.name
+    ,t.factor
+from
+    categories c
+    inner join main_categories m on c.main_category_id=m.id
+    inner join main_categories_types t on m.type_id=t.id
+    left join tree on c.id=tree.id
+where 1=1
+    and (m.id=? or ?=?)
+    and (c.name like ? or ?=?)
+    and (c.status=? or ?=?)
+order by
+    m.type_id
+    ,m.name
+    ,tree.path
+;
+`
+
+// sqlCategoryDescendantsMainCategoryUpdate is SQL string to move all subcategories of given category to new main category.
+//
+// Parameters
+// 1 - main_category_id (int)
+// 2 - category_id (int)
+const sqlCategoryDescendantsMainCategoryUpdate string = `
+update categories
+set
+    main_category_id=?
+where
+    id in (
+        with recursive sub(id) as (
+            select id from categories where parent_id=?
+            union
+            select c.id from categories c inner join sub on c.parent_id=sub.id
+        )
+        select id from sub
+    )
+;
+`
+
+// sqlCategoryDescendantsClose is SQL string to close all subcategories of given category having given status.
+//
+// Parameters
+// 1 - new status (int)
+// 2 - category_id (int)
+// 3 - status of subcategories to close (int)
+const sqlCategoryDescendantsClose string = `
+update categories
+set
+    status=?
+where
+    id in (
+        with recursive sub(id) as (
+            select id from categories where parent_id=?
+            union
+            select c.id from categories c inner join sub on c.parent_id=sub.id
+        )
+        select id from sub
+    )
+    and status=?
+;
+`
+
 // sqlReportTransactionsBalance is SQL string to get transactions values recalculated to one currency.
 //
 // Parameters
@@ -18,7 +120,7 @@ package lib
 // 9 - account_id (int)
 // 10 - account_id (int)
 // 11 - noIntParamForSQL
-// 12 - category_id (int)
+// 12 - category_id (int), including its subcategories
 // 13 - category_id (int)
 // 14 - noIntParamForSQL
 // 15 - main_category_id (int)
@@ -61,7 +163,7 @@ where 1=1
     and (t.date>=? or ?=?)
     and (t.date<=? or ?=?)
     and (a.id=? or ?=?)
-    and (c.id=? or ?=?)
+    and (c.id in (with recursive sub(id) as (select ? union select cs.id from categories cs inner join sub on cs.parent_id=sub.id) select id from sub) or ?=?)
     and (m.id=? or ?=?)
     and (t.description like ? or ?=?)
 order by
@@ -72,24 +174,23 @@ order by
 // sqlReportCategoriesBalance is SQL string to get categories values recalculated to one currency.
 //
 // Parameters
-// 1 - MCTypeTransfer
+// 1 - currency_to (string)
 // 2 - currency_to (string)
-// 3 - currency_to (string)
+// 3 - date_from (string)
 // 4 - date_from (string)
-// 5 - date_from (string)
-// 6 - NoStringParamForSQL
+// 5 - NoStringParamForSQL
+// 6 - date_to (string)
 // 7 - date_to (string)
-// 8 - date_to (string)
-// 9 - NoStringParamForSQL
+// 8 - NoStringParamForSQL
+// 9 - account_id (int)
 // 10 - account_id (int)
-// 11 - account_id (int)
-// 12 - NoIntParamForSQL
+// 11 - NoIntParamForSQL
+// 12 - category_id (int), including its subcategories
 // 13 - category_id (int)
-// 14 - category_id (int)
-// 15 - NoIntParamForSQL
+// 14 - NoIntParamForSQL
+// 15 - main_category_id (int)
 // 16 - main_category_id (int)
-// 17 - main_category_id (int)
-// 18 - NoIntParamForSQL
+// 17 - NoIntParamForSQL
 const sqlReportCategoriesBalance string = `
 select
     m.id
@@ -107,13 +208,13 @@ from
     inner join accounts a on t.account_id=a.id
     inner join categories c on t.category_id=c.id
     inner join main_categories m on c.main_category_id=m.id
-    inner join (select * from main_categories_types where id<>?) mt on m.type_id=mt.id
+    inner join (select * from main_categories_types where in_income_cost=1) mt on m.type_id=mt.id
     inner join (select currency_from, exchange_rate from currencies where currency_to=upper(?) union select upper(?), 1) cur on a.currency=cur.currency_from
 where 1=1
     and (t.date>=? or ?=?)
     and (t.date<=? or ?=?)
     and (a.id=? or ?=?)
-    and (c.id=? or ?=?)
+    and (c.id in (with recursive sub(id) as (select ? union select cs.id from categories cs inner join sub on cs.parent_id=sub.id) select id from sub) or ?=?)
     and (m.id=? or ?=?)
 group by
     m.id
@@ -135,21 +236,20 @@ order by
 // sqlReportCategoriesBalance is SQL string to get categories values recalculated to one currency.
 //
 // Parameters
-// 1 - MCTypeTransfer (int)
+// 1 - Currency_to (string)
 // 2 - Currency_to (string)
-// 3 - Currency_to (string)
+// 3 - date_from (string)
 // 4 - date_from (string)
-// 5 - date_from (string)
-// 6 - NoStringParamForSQL
+// 5 - NoStringParamForSQL
+// 6 - date_to (string)
 // 7 - date_to (string)
-// 8 - date_to (string)
-// 9 - NoStringParamForSQL
+// 8 - NoStringParamForSQL
+// 9 - account_id (int)
 // 10 - account_id (int)
-// 11 - account_id (int)
-// 12 - NoIntParamForSQL
+// 11 - NoIntParamForSQL
+// 12 - main_category_id (int)
 // 13 - main_category_id (int)
-// 14 - main_category_id (int)
-// 15 - NoIntParamForSQL
+// 14 - NoIntParamForSQL
 const sqlReportMainCategoriesBalance string = `
 select
     m.id
@@ -164,7 +264,7 @@ from
     inner join accounts a on t.account_id=a.id
     inner join categories c on t.category_id=c.id
     inner join main_categories m on c.main_category_id=m.id
-    inner join (select * from main_categories_types where id<>?) mt on m.type_id=mt.id
+    inner join (select * from main_categories_types where in_income_cost=1) mt on m.type_id=mt.id
     inner join (select currency_from, exchange_rate from currencies where currency_to=upper(?) union select upper(?), 1) cur on a.currency=cur.currency_from
 where 1=1
     and (t.date>=? or ?=?)
@@ -233,15 +333,14 @@ order by
 // 2 - month (string)
 // 3 - year (int)
 // 4 - month (int)
-// 5 - Main Category Type Transfer (int)
+// 5 - reporting currency (string)
 // 6 - reporting currency (string)
-// 7 - reporting currency (string)
-// 8 - year (int)
-// 9 - month (int)
+// 7 - year (int)
+// 8 - month (int)
+// 9 - reporting currency (string)
 // 10 - reporting currency (string)
-// 11 - reporting currency (string)
-// 12 - year (string)
-// 13 - month (string)
+// 11 - year (string)
+// 12 - month (string)
 const sqlReportBudgetCategoriesMonthly string = `
 select
     m.id
@@ -282,7 +381,7 @@ from
     inner join main_categories m on c.main_category_id=m.id
 
     -- main categories types
-    inner join (select * from main_categories_types where id<>?) mct on m.type_id=mct.id
+    inner join (select * from main_categories_types where in_budget=1) mct on m.type_id=mct.id
 
     -- budget details
     left join (
@@ -326,18 +425,21 @@ order by
 `
 
 // sqlReportBudgetCategoriesYearly is SQL string to get budget values vs actual transactions value on category granularity
-// for given year.
+// for given (fiscal) year.
 //
 // Parameters
-// 1 - year (string)
-// 2 - year (int)
-// 3 - Main Category Type Transfer (int)
-// 4 - reporting currency (string)
+// 1 - date_from (string)
+// 2 - date_to (string)
+// 3 - year*100+month of the first month (int)
+// 4 - year*100+month of the last month (int)
 // 5 - reporting currency (string)
-// 6 - year (int)
-// 7 - reporting currency (string)
-// 8 - reporting currency (string)
-// 9 - year (string)
+// 6 - reporting currency (string)
+// 7 - year*100+month of the first month (int)
+// 8 - year*100+month of the last month (int)
+// 9 - reporting currency (string)
+// 10 - reporting currency (string)
+// 11 - date_from (string)
+// 12 - date_to (string)
 const sqlReportBudgetCategoriesYearly string = `
 select
     m.id
@@ -359,14 +461,14 @@ from
     from
         transactions
     where
-        strftime('%Y',date)=?
+        date between ? and ?
     union
     select
         category_id
     from
         budgets
     where
-        year=?
+        year*100+month between ? and ?
 ) lc
 
     -- categories details
@@ -376,7 +478,7 @@ from
     inner join main_categories m on c.main_category_id=m.id
 
     -- main categories types
-    inner join (select * from main_categories_types where id<>?) mct on m.type_id=mct.id
+    inner join (select * from main_categories_types where in_budget=1) mct on m.type_id=mct.id
 
     -- budget details
     left join (
@@ -390,7 +492,7 @@ from
             inner join main_categories m on c.main_category_id=m.id
             inner join main_categories_types mt on m.type_id=mt.id
         where
-            year=?
+            year*100+month between ? and ?
         group by
             category_id
     ) b on lc.id=b.category_id
@@ -408,7 +510,7 @@ from
             inner join main_categories m on c.main_category_id=m.id
             inner join main_categories_types mt on m.type_id=mt.id
         where
-            strftime('%Y',t.date)=?
+            t.date between ? and ?
         group by
             category_id
     ) ta on lc.id=ta.category_id
@@ -427,15 +529,14 @@ order by
 // 2 - month (string)
 // 3 - year (int)
 // 4 - month (int)
-// 5 - main category transfer id (int)
+// 5 - currency_to (string)
 // 6 - currency_to (string)
-// 7 - currency_to (string)
-// 8 - year (int)
-// 9 - month (int)
-// 10 - year (string)
-// 11 - month (string)
+// 7 - year (int)
+// 8 - month (int)
+// 9 - year (string)
+// 10 - month (string)
+// 11 - currency_to (string)
 // 12 - currency_to (string)
-// 13 - currency_to (string)
 const sqlReportBudgetMainCategoriesMonthly string = `
 select
     lmc.id
@@ -461,7 +562,7 @@ from
 ) lmc
 
     -- main categories types
-    inner join (select * from main_categories_types where id<>?) mct on lmc.type_id=mct.id
+    inner join (select * from main_categories_types where in_budget=1) mct on lmc.type_id=mct.id
 
     -- budget details
     left join (
@@ -503,18 +604,21 @@ order by
 `
 
 // sqlReportBudgetMainCategoriesYearly is SQL string to get budget values vs actual transactions value on main category granularity
-// for given year.
+// for given (fiscal) year.
 //
 // Parameters
-// 1 - year (string)
-// 2 - year (int)
-// 3 - main category type transfer (int)
-// 4 - currency_to (string)
+// 1 - date_from (string)
+// 2 - date_to (string)
+// 3 - year*100+month of the first month (int)
+// 4 - year*100+month of the last month (int)
 // 5 - currency_to (string)
-// 6 - year (int)
-// 7 - year (string)
-// 8 - currency_to (string)
-// 9 - currency_to (string)
+// 6 - currency_to (string)
+// 7 - year*100+month of the first month (int)
+// 8 - year*100+month of the last month (int)
+// 9 - date_from (string)
+// 10 - date_to (string)
+// 11 - currency_to (string)
+// 12 - currency_to (string)
 const sqlReportBudgetMainCategoriesYearly string = `
 select
     lmc.id
@@ -531,16 +635,16 @@ from
     (select
     	mc.*
     from
-        main_categories mc inner join categories c on mc.id=c.main_category_id inner join (select * from transactions where strftime('%Y',date)=?) t on c.id=t.category_id
+        main_categories mc inner join categories c on mc.id=c.main_category_id inner join (select * from transactions where date between ? and ?) t on c.id=t.category_id
     union
     select
         mb.*
     from
-        main_categories mb inner join categories c on mb.id=c.main_category_id inner join (select * from budgets where year=?) b on c.id=b.category_id
+        main_categories mb inner join categories c on mb.id=c.main_category_id inner join (select * from budgets where year*100+month between ? and ?) b on c.id=b.category_id
 ) lmc
 
     -- main categories types
-    inner join (select * from main_categories_types where id<>?) mct on lmc.type_id=mct.id
+    inner join (select * from main_categories_types where in_budget=1) mct on lmc.type_id=mct.id
 
     -- budget details
     left join (
@@ -554,7 +658,7 @@ from
             inner join main_categories m on c.main_category_id=m.id
             inner join main_categories_types mt on m.type_id=mt.id
         where
-            year=?
+            year*100+month between ? and ?
         group by
             m.id
     ) b on lmc.id=b.id
@@ -565,7 +669,7 @@ from
             m.id
             ,sum(t.value * mt.factor * cur.exchange_rate) as actual
         from
-           (select * from transactions where strftime('%Y',date)=?) t
+           (select * from transactions where date between ? and ?) t
             inner join accounts a on t.account_id=a.id
             inner join (select currency_from, exchange_rate from currencies where currency_to=upper(?) union select upper(?), 1) cur on a.currency=cur.currency_from
             inner join categories c on t.category_id=c.id
@@ -596,7 +700,7 @@ ac.currency_from is null
 ;
 `
 
-// sqlReportNetValueMonthly is SQL string to get monthly balance of all transactions in order to build net value.
+// sqlReportNetValue is SQL string to get daily balance of transactions taken into net value in order to build net value.
 //
 // Paramters:
 // 1 - currency (string)
@@ -607,10 +711,9 @@ ac.currency_from is null
 // 6 - date_to (string)
 // 7 - date_to (string)
 // 8 - NoStringParamForSQL
-const sqlReportNetValueMonthly string = `
+const sqlReportNetValue string = `
 select
-    strftime('%Y',date) as y
-    ,strftime('%m',date) as m
+    t.date
     ,sum(mt.factor * t.value * cur.exchange_rate) as balance
 from
     transactions t
@@ -620,14 +723,13 @@ from
     inner join accounts a on t.account_id=a.id
     inner join (select currency_from, exchange_rate from currencies where currency_to=upper(?) union select upper(?), 1) cur on a.currency=cur.currency_from
 where 1=1
+    and mt.in_net_value=1
     and (t.date>=? or ?=?)
     and (t.date<=? or ?=?)
 group by
-    y
-    ,m
+    t.date
 order by
-    y
-    ,m
+    t.date
 ;
 `
 
@@ -647,58 +749,21 @@ where
 ;
 `
 
-// sqlReportCategoriesBalanceMonthly is SQL string to get selected category balance over time (monthly).
-//
-// Parameters
-// 1 - currency (string)
-// 2 - currency (string)
-// 3 - category_id (int)
-// 4 - date_from (string)
-// 5 - date_from (string)
-// 6 - NoStringParamForSQL
-// 7 - date_to (string)
-// 8 - date_to (string)
-// 9 - NoStringParamForSQL
-const sqlReportCategoriesBalanceMonthly string = `
-select
-	strftime('%Y',date) as y
-    	,strftime('%m',date) as m
-	,sum(t.value * mt.factor * cur.exchange_rate) as balance
-from
-	transactions t
-	inner join accounts a on t.account_id=a.id
-	inner join (select currency_from, exchange_rate from currencies where currency_to=upper(?) union select upper(?), 1) cur on a.currency=cur.currency_from
-	inner join categories c on t.category_id=c.id
-	inner join main_categories m on c.main_category_id=m.id
-	inner join main_categories_types mt on m.type_id=mt.id
-where 1=1
-	and c.id=?
-	and (t.date>=? or ?=?)
-	and (t.date<=? or ?=?)
-group by
-	1
-	,2
-order by
-	1
-	,2
-;
-`
-
-// sqlReportCategoriesBalanceYearly is SQL string to get selected category balance over time (yearly).
+// sqlReportCategoriesBalanceTime is SQL string to get selected category balance daily (to be summed up in periods).
 //
 // Parameters
 // 1 - currency (string)
 // 2 - currency (string)
-// 3 - category_id (int)
+// 3 - category_id (int), including its subcategories
 // 4 - date_from (string)
 // 5 - date_from (string)
 // 6 - NoStringParamForSQL
 // 7 - date_to (string)
 // 8 - date_to (string)
 // 9 - NoStringParamForSQL
-const sqlReportCategoriesBalanceYearly string = `
+const sqlReportCategoriesBalanceTime string = `
 select
-	strftime('%Y',date) as y
+	t.date
 	,sum(t.value * mt.factor * cur.exchange_rate) as balance
 from
 	transactions t
@@ -708,17 +773,17 @@ from
 	inner join main_categories m on c.main_category_id=m.id
 	inner join main_categories_types mt on m.type_id=mt.id
 where 1=1
-	and c.id=?
+	and c.id in (with recursive sub(id) as (select ? union select cs.id from categories cs inner join sub on cs.parent_id=sub.id) select id from sub)
 	and (t.date>=? or ?=?)
 	and (t.date<=? or ?=?)
 group by
-	1
+	t.date
 order by
-	1
+	t.date
 ;
 `
 
-// sqlReportMainCategoriesBalanceMonthly is SQL string to get selected main category balance over time (monthly).
+// sqlReportMainCategoriesBalanceTime is SQL string to get selected main category balance daily (to be summed up in periods).
 //
 // Parameters
 // 1 - currency (string)
@@ -730,10 +795,9 @@ order by
 // 7 - date_to (string)
 // 8 - date_to (string)
 // 9 - NoStringParamForSQL
-const sqlReportMainCategoriesBalanceMonthly string = `
+const sqlReportMainCategoriesBalanceTime string = `
 select
-	strftime('%Y',date) as y
-    	,strftime('%m',date) as m
+	t.date
 	,sum(t.value * mt.factor * cur.exchange_rate) as balance
 from
 	transactions t
@@ -747,30 +811,30 @@ where 1=1
 	and (t.date>=? or ?=?)
 	and (t.date<=? or ?=?)
 group by
-	1
-	,2
+	t.date
 order by
-	1
-	,2
+	t.date
 ;
 `
 
-// sqlReportMainCategoriesBalanceYearly is SQL string to get selected main category balance over time (yearly).
+// sqlReportIncomeAndCost is SQL string to get sum of income and cost per day (to be summed up in periods)
+// for main category types taken into income vs cost (income for positive factor, cost for negative one).
+// Days with other transactions only are listed with zeros.
 //
 // Parameters
 // 1 - currency (string)
 // 2 - currency (string)
-// 3 - main_category_id (int)
+// 3 - date_from (string)
 // 4 - date_from (string)
-// 5 - date_from (string)
-// 6 - NoStringParamForSQL
-// 7 - date_to (string)
-// 8 - date_to (string)
-// 9 - NoStringParamForSQL
-const sqlReportMainCategoriesBalanceYearly string = `
+// 5 - NoStringParamForSQL
+// 6 - date_to (string)
+// 7 - date_to (string
+// 8 - NoStringParamForSQL
+const sqlReportIncomeAndCost string = `
 select
-	strftime('%Y',date) as y
-	,sum(t.value * mt.factor * cur.exchange_rate) as balance
+	t.date
+	,coalesce(sum(case when mt.in_income_cost=1 and mt.factor>0 then mt.factor * t.value * cur.exchange_rate end), 0.0) as income
+	,coalesce(sum(case when mt.in_income_cost=1 and mt.factor<0 then mt.factor * t.value * cur.exchange_rate end), 0.0) as cost
 from
 	transactions t
 	inner join accounts a on t.account_id=a.id
@@ -779,150 +843,11 @@ from
 	inner join main_categories m on c.main_category_id=m.id
 	inner join main_categories_types mt on m.type_id=mt.id
 where 1=1
-	and m.id=?
 	and (t.date>=? or ?=?)
 	and (t.date<=? or ?=?)
 group by
-	1
+	t.date
 order by
-	1
-;
-`
-
-// sqlReportIncomeAndCostMonthly is SQL string to get sum of income and cost per month
-//
-// Parameters
-// 1 - date_from (string)
-// 2 - date_from (string)
-// 3 - NoStringParamForSQL
-// 4 - date_to (string)
-// 5 - date_to (string
-// 6 - NoStringParamForSQL
-// 7 - currency (string)
-// 8 - currency (string)
-// 9 - MainCategoryTypeIncome (int)
-// 10 - currency (string)
-// 11 - currency (string)
-// 12 - MainCategoryTypeCost (int)
-const sqlReportIncomeAndCostMonthly string = `
--- PERIODS
-select
-	periods.year
-	,periods.month
-	,coalesce(income.balance, 0.0) as income
-	,coalesce(cost.balance, 0.0) as cost
-from
-	(select
-		strftime('%Y', t.date) as year
-		,strftime('%m', t.date) as month
-	from
-		transactions t
-	where 1=1
-		and (t.date>=? or ?=?)
-		and (t.date<=? or ?=?)
-	group by 1,2
-	order by 1,2) periods
-	left join
-	-- INCOME
-	(select
-		strftime('%Y',t.date) as year
-		,strftime('%m',t.date) as month
-		,sum(mt.factor * t.value * cur.exchange_rate) as balance
-	from
-		transactions t
-		inner join accounts a on t.account_id=a.id
-		inner join (select currency_from, exchange_rate from currencies where currency_to=upper(?) union select upper(?), 1) cur on a.currency=cur.currency_from
-		inner join categories c on t.category_id=c.id
-		inner join main_categories m on c.main_category_id=m.id
-		inner join main_categories_types mt on m.type_id=mt.id
-	where
-		mt.id=?
-	group by
-		1
-		,2) income on periods.year=income.year and periods.month=income.month
-	left join
-	-- COST
-	(select
-		strftime('%Y',t.date) as year
-		,strftime('%m',t.date) as month
-		,sum(mt.factor * t.value * cur.exchange_rate) as balance
-	from
-		transactions t
-		inner join accounts a on t.account_id=a.id
-		inner join (select currency_from, exchange_rate from currencies where currency_to=upper(?) union select upper(?), 1) cur on a.currency=cur.currency_from
-		inner join categories c on t.category_id=c.id
-		inner join main_categories m on c.main_category_id=m.id
-		inner join main_categories_types mt on m.type_id=mt.id
-	where
-		mt.id=?
-	group by
-		1
-		,2) cost on periods.year=cost.year and periods.month=cost.month
-;
-`
-
-// sqlReportIncomeAndCostYearly is SQL string to get sum of income and cost per year
-//
-// Parameters
-// 1 - date_from (string)
-// 2 - date_from (string)
-// 3 - NoStringParamForSQL
-// 4 - date_to (string)
-// 5 - date_to (string
-// 6 - NoStringParamForSQL
-// 7 - currency (string)
-// 8 - currency (string)
-// 9 - MainCategoryTypeIncome (int)
-// 10 - currency (string)
-// 11 - currency (string)
-// 12 - MainCategoryTypeCost (int)
-const sqlReportIncomeAndCostYearly string = `
--- PERIODS
-select
-	periods.year
-	,coalesce(income.balance, 0.0) as income
-	,coalesce(cost.balance, 0.0) as cost
-from
-	(select
-		strftime('%Y', t.date) as year
-	from
-		transactions t
-	where 1=1
-		and (t.date>=? or ?=?)
-		and (t.date<=? or ?=?)
-	group by 1
-	order by 1) periods
-	left join
-	-- INCOME
-	(select
-		strftime('%Y',t.date) as year
-		,sum(mt.factor * t.value * cur.exchange_rate) as balance
-	from
-		transactions t
-		inner join accounts a on t.account_id=a.id
-		inner join (select currency_from, exchange_rate from currencies where currency_to=upper(?) union select upper(?), 1) cur on a.currency=cur.currency_from
-		inner join categories c on t.category_id=c.id
-		inner join main_categories m on c.main_category_id=m.id
-		inner join main_categories_types mt on m.type_id=mt.id
-	where
-		mt.id=?
-	group by
-		1) income on periods.year=income.year
-	left join
-	-- COST
-	(select
-		strftime('%Y',t.date) as year
-		,sum(mt.factor * t.value * cur.exchange_rate) as balance
-	from
-		transactions t
-		inner join accounts a on t.account_id=a.id
-		inner join (select currency_from, exchange_rate from currencies where currency_to=upper(?) union select upper(?), 1) cur on a.currency=cur.currency_from
-		inner join categories c on t.category_id=c.id
-		inner join main_categories m on c.main_category_id=m.id
-		inner join main_categories_types mt on m.type_id=mt.id
-	where
-		mt.id=?
-	group by
-		1) cost on periods.year=cost.year
+	t.date
 ;
 `
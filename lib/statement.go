@@ -0,0 +1,541 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/zbroju/gsqlitehandler"
+	"math"
+	"strings"
+	"time"
+)
+
+// Statement represents account statement downloaded from bank or exported from another application
+type Statement struct {
+	Currency     string
+	Transactions []*StatementTransaction
+
+	// Balance of the account according to the bank at BalanceDate (zero date if the statement has no balance)
+	Balance     float64
+	BalanceDate time.Time
+
+	// Balance of the account before the statement, i.e. at the end of OpeningBalanceDate (zero date if not given)
+	OpeningBalance     float64
+	OpeningBalanceDate time.Time
+}
+
+// StatementTransaction represents one transaction of statement
+type StatementTransaction struct {
+	// ImportID identifies transaction in statements of the account (e.g. FITID of OFX),
+	// so that importing the same transaction again can be detected
+	ImportID    string
+	Date        time.Time
+	Description string
+
+	// Value is negative for debits and positive for credits of the account
+	Value float64
+
+	// Account is the name of account given in statement containing many accounts (empty for the account of import)
+	Account string
+
+	// Category is the path of category given in statement, starting with main category (e.g. 'Food:Groceries'),
+	// or empty if the category of import is to be used
+	Category string
+
+	// TransferAccount is the name of the other account if the transaction is a transfer between accounts
+	TransferAccount string
+
+	// TransferValue is the value of transfer for the other account, in its currency
+	// (zero if it is to be calculated with exchange rate)
+	TransferValue float64
+
+	// Splits are parts of split transaction, each one with its own value and category (or transfer account)
+	Splits []*StatementTransaction
+
+	// Details given by bank (empty if not given): reference of transaction, value date and the other party
+	Reference        string
+	ValueDate        time.Time
+	Counterparty     string
+	CounterpartyIBAN string
+}
+
+// openingBalanceDate returns the day which ends with opening balance of statement s, dated d by the bank.
+// Banks date it with the last day of the previous statement (as MT940 standard says) or with the first day
+// of the statement (as camt.053 and many MT940 files do). The balance does not change until the first transaction,
+// so the day before it is used, or the day of closing balance if the statement has no transactions.
+func (s *Statement) openingBalanceDate(d time.Time) time.Time {
+	if d.IsZero() {
+		return d
+	}
+	var first time.Time
+	for _, st := range s.Transactions {
+		if first.IsZero() || st.Date.Before(first) {
+			first = st.Date
+		}
+	}
+	switch {
+	case !first.IsZero():
+		return first.AddDate(0, 0, -1)
+	case !s.BalanceDate.IsZero():
+		return s.BalanceDate
+	}
+
+	return d
+}
+
+// statementDescription returns description made of payee name and memo (skipped if it only repeats the name)
+func statementDescription(name, memo string) string {
+	switch {
+	case memo == NotSetStringValue || strings.Contains(name, memo):
+		return name
+	case name == NotSetStringValue || strings.Contains(memo, name):
+		return memo
+	}
+
+	return name + " - " + memo
+}
+
+// statementImport keeps objects of data file used while importing statement,
+// so that the names given in statement are resolved only once
+type statementImport struct {
+	tx             *change
+	accounts       map[string]*Account
+	mainCategories map[string]*MainCategory
+	categories     map[string]*Category
+	categoriesName map[string][]*Category
+	types          map[int]*MainCategoryType
+	rates          map[string]float64
+	transfers      map[string]int // number of identical transfers read before for account ID and transfer ID
+
+	stmtExists, stmtAdd, stmtLink, stmtAddMainCategory, stmtAddCategory *sql.Stmt
+}
+
+// StatementImport adds transactions of statement s to account a (or to accounts named in the statement, a can be nil then).
+// Transactions imported before (with the same import ID) are skipped.
+// Transactions without category given in statement get category c, the missing categories are created
+// (with main category of cost or income type depending on the value).
+// Transfers become pairs of transactions with category transfer and splits become separate transactions.
+// Values are saved so that the transactions change account balance the same way as in the statement,
+// so the statement must be in the currency of the accounts.
+func StatementImport(db *gsqlitehandler.SqliteDB, s *Statement, a *Account, c *Category) (added, skipped int, err error) {
+	var si *statementImport
+
+	if c != nil && c.Main.MType.Factor == 0 {
+		return 0, 0, errors.New(errStatementCategoryFactor)
+	}
+	if si, err = statementImportNew(db, s, a, c); err != nil {
+		return 0, 0, err
+	}
+
+	if si.tx, err = beginChange(db); err != nil {
+		return 0, 0, errors.New(errWritingToFile)
+	}
+	defer si.tx.Rollback()
+	if err = si.prepare(); err != nil {
+		return 0, 0, err
+	}
+	defer si.close()
+
+	if added, skipped, err = si.importTransactions(s, a, c); err != nil {
+		return 0, 0, err
+	}
+
+	if err = si.tx.Commit(); err != nil {
+		return 0, 0, errors.New(errWritingToFile)
+	}
+
+	return added, skipped, nil
+	//TODO: add test
+}
+
+// importTransactions saves transactions of statement s, the same way as StatementImport
+func (si *statementImport) importTransactions(s *Statement, a *Account, c *Category) (added, skipped int, err error) {
+	for _, st := range s.Transactions {
+		acc := a
+		if st.Account != NotSetStringValue {
+			acc = si.accounts[strings.ToLower(st.Account)]
+		}
+		parts := st.Splits
+		if len(parts) == 0 {
+			parts = []*StatementTransaction{st}
+		}
+
+		n := 0
+		for _, p := range parts {
+			var ok bool
+			if other := si.transferAccount(acc, p); other != nil {
+				ok, err = si.addTransfer(acc, other, p)
+			} else {
+				ok, err = si.addTransaction(acc, p, c)
+			}
+			if err != nil {
+				return 0, 0, err
+			}
+			if ok {
+				n++
+			}
+		}
+		if n > 0 {
+			added++
+		} else {
+			skipped++
+		}
+	}
+
+	return added, skipped, nil
+}
+
+// statementImportNew reads accounts, categories and exchange rates used by statement s
+// and checks that all the names given in the statement can be resolved
+func statementImportNew(db *gsqlitehandler.SqliteDB, s *Statement, a *Account, c *Category) (si *statementImport, err error) {
+	si = &statementImport{accounts: make(map[string]*Account), mainCategories: make(map[string]*MainCategory),
+		categories: make(map[string]*Category), categoriesName: make(map[string][]*Category),
+		types: make(map[int]*MainCategoryType), rates: make(map[string]float64), transfers: make(map[string]int)}
+
+	var getNextAccount func() *Account
+	if getNextAccount, err = AccountList(db, NotSetStringValue, NotSetStringValue, NotSetStringValue, NotSetStringValue, ATUnset, ISOpen); err != nil {
+		return nil, err
+	}
+	for acc := getNextAccount(); acc != nil; acc = getNextAccount() {
+		si.accounts[strings.ToLower(acc.Name)] = acc
+	}
+
+	var getNextMainCategory func() *MainCategory
+	if getNextMainCategory, err = MainCategoryList(db, nil, NotSetStringValue, ISUnset); err != nil {
+		return nil, err
+	}
+	for m := getNextMainCategory(); m != nil; m = getNextMainCategory() {
+		if m.Status != ISClose {
+			si.mainCategories[strings.ToLower(m.Name)] = m
+		}
+	}
+
+	var tree *CategoryTree
+	if tree, err = CategoryTreeGet(db); err != nil {
+		return nil, err
+	}
+	for _, cc := range tree.categories {
+		if cc.Status != ISClose {
+			si.categories[strings.ToLower(tree.FullPath(cc))] = cc
+			si.categoriesName[strings.ToLower(cc.Name)] = append(si.categoriesName[strings.ToLower(cc.Name)], cc)
+		}
+	}
+
+	for _, id := range []int{MCTCost, MCTIncome} {
+		if si.types[id], err = MainCategoryTypeForID(db, id); err != nil {
+			return nil, err
+		}
+	}
+
+	// Check names of accounts and get exchange rates for transfers
+	for _, st := range s.Transactions {
+		acc := a
+		if st.Account != NotSetStringValue {
+			if acc = si.accounts[strings.ToLower(st.Account)]; acc == nil {
+				return nil, errors.New(errStatementAccountNone + st.Account)
+			}
+		}
+		if acc == nil {
+			return nil, errors.New(errStatementAccountMissing)
+		}
+		if s.Currency != NotSetStringValue && !strings.EqualFold(s.Currency, acc.Currency) {
+			return nil, errors.New(errStatementCurrency + acc.Name + " (" + acc.Currency + ", statement: " + strings.ToUpper(s.Currency) + ")")
+		}
+		parts := st.Splits
+		if len(parts) == 0 {
+			parts = []*StatementTransaction{st}
+		}
+		for _, p := range parts {
+			if p.TransferAccount != NotSetStringValue {
+				other := si.accounts[strings.ToLower(p.TransferAccount)]
+				if other == nil {
+					return nil, errors.New(errStatementAccountNone + p.TransferAccount)
+				}
+				if other.Id != acc.Id {
+					if p.TransferValue != NotSetFloatValue {
+						continue
+					}
+					k := acc.Currency + other.Currency
+					if _, ok := si.rates[k]; !ok {
+						var e *ExchangeRate
+						if e, err = ExchangeRateForCurrencies(db, acc.Currency, other.Currency); err != nil {
+							return nil, err
+						}
+						si.rates[k] = e.Rate
+					}
+					continue
+				}
+			}
+			if p.Category == NotSetStringValue && c == nil {
+				return nil, errors.New(errStatementCategoryMissing)
+			}
+		}
+	}
+
+	return si, nil
+}
+
+// prepare creates statements used to save transactions and categories
+func (si *statementImport) prepare() (err error) {
+	if si.stmtExists, err = si.tx.Prepare("SELECT count(*) FROM imported_transactions WHERE account_id=? AND import_id=?;"); err != nil {
+		return errors.New(errReadingFromFile)
+	}
+	if si.stmtAdd, err = si.tx.Prepare(sqlTransactionAdd); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	if si.stmtLink, err = si.tx.Prepare("INSERT OR IGNORE INTO imported_transactions (account_id, import_id, transaction_id, reference, value_date, counterparty, counterparty_iban) VALUES (?, ?, ?, ?, ?, ?, ?);"); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	if si.stmtAddMainCategory, err = si.tx.Prepare("INSERT INTO main_categories VALUES (NULL, ?, ?, ?);"); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	if si.stmtAddCategory, err = si.tx.Prepare("INSERT INTO categories VALUES (NULL, ?, ?, ?, ?);"); err != nil {
+		return errors.New(errWritingToFile)
+	}
+
+	return nil
+}
+
+// close closes prepared statements
+func (si *statementImport) close() {
+	for _, stmt := range []*sql.Stmt{si.stmtExists, si.stmtAdd, si.stmtLink, si.stmtAddMainCategory, si.stmtAddCategory} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+}
+
+// transferAccount returns the other account of transfer, or nil if p is not a transfer
+// (transfers to the same account, e.g. opening balances, are treated as regular transactions)
+func (si *statementImport) transferAccount(acc *Account, p *StatementTransaction) *Account {
+	if p.TransferAccount == NotSetStringValue {
+		return nil
+	}
+	if other := si.accounts[strings.ToLower(p.TransferAccount)]; other.Id != acc.Id {
+		return other
+	}
+
+	return nil
+}
+
+// imported checks if transaction with given import ID has been already imported to account acc
+func (si *statementImport) imported(acc *Account, id string) (bool, error) {
+	var n int
+	if err := si.stmtExists.QueryRow(acc.Id, id).Scan(&n); err != nil {
+		return false, errors.New(errReadingFromFile)
+	}
+
+	return n > 0, nil
+}
+
+// add saves transaction with value v and category c for transaction p of statement,
+// and links it with import ID (if given) keeping details given by bank
+func (si *statementImport) add(acc *Account, id string, p *StatementTransaction, v float64, c int64) error {
+	res, err := si.stmtAdd.Exec(p.Date.Format(DateFormat), acc.Id, p.Description, v, c)
+	if err != nil {
+		return errors.New(errWritingToFile)
+	}
+	if id == NotSetStringValue {
+		return nil
+	}
+	var tId int64
+	if tId, err = res.LastInsertId(); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	vd := NotSetStringValue
+	if !p.ValueDate.IsZero() {
+		vd = p.ValueDate.Format(DateFormat)
+	}
+	if _, err = si.stmtLink.Exec(acc.Id, id, tId, p.Reference, vd, p.Counterparty, p.CounterpartyIBAN); err != nil {
+		return errors.New(errWritingToFile)
+	}
+
+	return nil
+}
+
+// addTransaction saves transaction p of account acc, unless it has been imported before
+func (si *statementImport) addTransaction(acc *Account, p *StatementTransaction, c *Category) (bool, error) {
+	if p.ImportID != NotSetStringValue {
+		if done, err := si.imported(acc, p.ImportID); err != nil || done {
+			return false, err
+		}
+	}
+	if p.Category != NotSetStringValue {
+		var err error
+		if c, err = si.category(p.Category, p.Value); err != nil {
+			return false, err
+		}
+		if c.Main.MType.Factor == 0 {
+			return false, errors.New(errStatementCategoryFactor)
+		}
+	}
+
+	return true, si.add(acc, p.ImportID, p, p.Value*float64(c.Main.MType.Factor), c.Id)
+}
+
+// addTransfer saves transfer p between accounts acc and other, unless it has been imported before
+// to any of them. The same transfer is identified in statements of both accounts by its date, accounts and value,
+// and the number of identical transfers read before from the statement of the account.
+func (si *statementImport) addTransfer(acc, other *Account, p *StatementTransaction) (bool, error) {
+	vOther := -p.Value * si.rates[acc.Currency+other.Currency]
+	if p.TransferValue != NotSetFloatValue {
+		vOther = p.TransferValue
+	}
+	vFrom := math.Abs(p.Value)
+	if p.Value > 0 {
+		vFrom = math.Abs(vOther)
+	}
+	idFrom, idTo := acc.Id, other.Id
+	if idFrom > idTo {
+		idFrom, idTo = idTo, idFrom
+	}
+	id := fmt.Sprintf("transfer:%s:%d:%d:%.2f", p.Date.Format(DateFormat), idFrom, idTo, vFrom)
+	k := fmt.Sprintf("%d:%s", acc.Id, id)
+	si.transfers[k]++
+	if n := si.transfers[k]; n > 1 {
+		id = fmt.Sprintf("%s#%d", id, n)
+	}
+
+	if done, err := si.imported(acc, id); err != nil || done {
+		return false, err
+	}
+	if err := si.add(acc, id, p, p.Value, SOCategoryTransferID); err != nil {
+		return false, err
+	}
+	if err := si.add(other, id, p, vOther, SOCategoryTransferID); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// category returns category for given path (main category first), creating it if missing.
+// Path with one name only means category with this name (if there is exactly one) or main category and its category of the same name.
+func (si *statementImport) category(path string, value float64) (c *Category, err error) {
+	if c = si.categories[strings.ToLower(path)]; c != nil {
+		return c, nil
+	}
+
+	var names []string
+	for _, n := range strings.Split(path, CategoryPathSeparator) {
+		if n = strings.TrimSpace(n); n != NotSetStringValue {
+			names = append(names, n)
+		}
+	}
+	if len(names) == 1 {
+		if cs := si.categoriesName[strings.ToLower(names[0])]; len(cs) == 1 {
+			si.categories[strings.ToLower(path)] = cs[0]
+			return cs[0], nil
+		}
+		names = append(names, names[0])
+	}
+
+	m := si.mainCategories[strings.ToLower(names[0])]
+	if m == nil {
+		m = MainCategoryNew()
+		m.Name, m.Status, m.MType = names[0], ISOpen, si.types[MCTIncome]
+		if value < 0 {
+			m.MType = si.types[MCTCost]
+		}
+		var res sql.Result
+		if res, err = si.stmtAddMainCategory.Exec(m.MType.Id, m.Name, m.Status); err != nil {
+			return nil, errors.New(errWritingToFile)
+		}
+		if m.Id, err = res.LastInsertId(); err != nil {
+			return nil, errors.New(errWritingToFile)
+		}
+		si.mainCategories[strings.ToLower(m.Name)] = m
+	}
+
+	key, parentId := m.Name, int64(NotSetIntValue)
+	for _, n := range names[1:] {
+		key += CategoryPathSeparator + n
+		if c = si.categories[strings.ToLower(key)]; c == nil {
+			c = CategoryNew()
+			c.Main, c.Name, c.Status, c.ParentId = m, n, ISOpen, parentId
+			var res sql.Result
+			if res, err = si.stmtAddCategory.Exec(m.Id, c.Name, c.Status, c.ParentId); err != nil {
+				return nil, errors.New(errWritingToFile)
+			}
+			if c.Id, err = res.LastInsertId(); err != nil {
+				return nil, errors.New(errWritingToFile)
+			}
+			si.categories[strings.ToLower(key)] = c
+		}
+		parentId = c.Id
+	}
+	si.categories[strings.ToLower(path)] = c
+
+	return c, nil
+}
+
+// StatementForAccount returns statement of account a with transactions between dates df and dt (zero dates mean no limit).
+// Transfers get the name of the other account if its transaction can be found (the same date and description, opposite value),
+// other transactions get the paths of their categories.
+func StatementForAccount(db *gsqlitehandler.SqliteDB, a *Account, df, dt time.Time) (s *Statement, err error) {
+	var tree *CategoryTree
+	if tree, err = CategoryTreeGet(db); err != nil {
+		return nil, err
+	}
+
+	// Transfers of other accounts, to find the other side of transfers
+	var ct *Category
+	if ct, err = CategoryForID(db, int(SOCategoryTransferID)); err != nil {
+		return nil, err
+	}
+	var getNextTransaction func() *Transaction
+	if getNextTransaction, err = TransactionList(db, df, dt, nil, NotSetStringValue, ct, nil); err != nil {
+		return nil, err
+	}
+	var transfers []*Transaction
+	for t := getNextTransaction(); t != nil; t = getNextTransaction() {
+		if t.Account.Id != a.Id {
+			transfers = append(transfers, t)
+		}
+	}
+
+	s = &Statement{Currency: a.Currency}
+	if getNextTransaction, err = TransactionList(db, df, dt, a, NotSetStringValue, nil, nil); err != nil {
+		return nil, err
+	}
+	for t := getNextTransaction(); t != nil; t = getNextTransaction() {
+		st := &StatementTransaction{ImportID: fmt.Sprintf("%d", t.Id), Date: t.Date, Description: t.Description, Value: t.GetSValue()}
+		if t.Category.Id == SOCategoryTransferID {
+			for i, o := range transfers {
+				if o != nil && o.Date.Equal(t.Date) && o.Description == t.Description && o.Value*t.Value < 0 {
+					st.TransferAccount, transfers[i] = o.Account.Name, nil
+					break
+				}
+			}
+		}
+		if st.TransferAccount == NotSetStringValue {
+			st.Category = tree.FullPath(t.Category)
+		}
+		s.Transactions = append(s.Transactions, st)
+	}
+
+	return s, nil
+	//TODO: add test
+}
+
+// StatementAccountBalance returns balance of account a at date d (in account currency),
+// to be compared with the balance given by the bank
+func StatementAccountBalance(db *gsqlitehandler.SqliteDB, a *Account, d time.Time) (balance float64, err error) {
+	var getNextEntry func() *AccountBalanceReportEntry
+
+	if getNextEntry, err = ReportAccountBalance(db, d); err != nil {
+		return NotSetFloatValue, err
+	}
+	for e := getNextEntry(); e != nil; e = getNextEntry() {
+		if e.Account.Id == a.Id {
+			balance = e.Value
+		}
+	}
+
+	return balance, nil
+	//TODO: add test
+}
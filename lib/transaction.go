@@ -9,6 +9,7 @@ import (
 	"errors"
 	"github.com/zbroju/gsqlitehandler"
 	"math"
+	"strings"
 	"time"
 )
 
@@ -32,6 +33,9 @@ type Transaction struct {
 	// categories and/or main categories change.
 	Value       float64
 	Description string
+
+	// Attachments is the number of files attached to the transaction
+	Attachments int
 }
 
 func TransactionNew() *Transaction {
@@ -52,14 +56,22 @@ func TransactionAdd(db *gsqlitehandler.SqliteDB, t *Transaction) error {
 	var err error
 	var stmt *sql.Stmt
 
-	if stmt, err = db.Handler.Prepare(sqlTransactionAdd); err != nil {
+	if err = beforeChange(db); err != nil {
+		return err
+	}
+
+	if stmt, err = dbHandler(db).Prepare(sqlTransactionAdd); err != nil {
 		return errors.New(errWritingToFile)
 	}
 	defer stmt.Close()
 
-	if _, err = stmt.Exec(t.Date.Format(DateFormat), t.Account.Id, t.Description, t.Value, t.Category.Id); err != nil {
+	var res sql.Result
+	if res, err = stmt.Exec(t.Date.Format(DateFormat), t.Account.Id, t.Description, t.Value, t.Category.Id); err != nil {
 		return errors.New(errWritingToFile)
 	}
+	if id, err := res.LastInsertId(); err == nil {
+		t.Id = id
+	}
 
 	return nil
 
@@ -70,17 +82,18 @@ func TransactionAdd(db *gsqlitehandler.SqliteDB, t *Transaction) error {
 func TransactionForID(db *gsqlitehandler.SqliteDB, i int) (t *Transaction, err error) {
 	var stmt *sql.Stmt
 
-	sqlQuery := "SELECT t.id, t.date, t.description, t.value, a.id, a.name, a.description, a.institution, a.currency, a.type, a.status, c.id, c.name, c.status, m.id, m.name, m.status, mt.id, mt.name, mt.factor " +
+	sqlQuery := "SELECT t.id, t.date, t.description, t.value, a.id, a.name, a.description, a.institution, a.currency, a.type, a.status, c.id, c.name, c.status, m.id, m.name, m.status, mt.id, mt.name, mt.factor, " +
+		"(SELECT COUNT(*) FROM attachments at WHERE at.object='" + AttachmentTransaction + "' AND at.object_id=t.id) " +
 		"FROM transactions t INNER JOIN accounts a ON t.account_id=a.id INNER JOIN categories c ON t.category_id=c.id INNER JOIN main_categories m ON c.main_category_id=m.id INNER JOIN main_categories_types mt on m.type_id=mt.id " +
 		"WHERE t.id=?;"
-	if stmt, err = db.Handler.Prepare(sqlQuery); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 	defer stmt.Close()
 
 	t = TransactionNew()
 	var tmpDate string
-	if err = stmt.QueryRow(i).Scan(&t.Id, &tmpDate, &t.Description, &t.Value, &t.Account.Id, &t.Account.Name, &t.Account.Description, &t.Account.Institution, &t.Account.Currency, &t.Account.AType, &t.Account.Status, &t.Category.Id, &t.Category.Name, &t.Category.Status, &t.Category.Main.Id, &t.Category.Main.Name, &t.Category.Main.Status, &t.Category.Main.MType.Id, &t.Category.Main.MType.Name, &t.Category.Main.MType.Factor); err != nil {
+	if err = stmt.QueryRow(i).Scan(&t.Id, &tmpDate, &t.Description, &t.Value, &t.Account.Id, &t.Account.Name, &t.Account.Description, &t.Account.Institution, &t.Account.Currency, &t.Account.AType, &t.Account.Status, &t.Category.Id, &t.Category.Name, &t.Category.Status, &t.Category.Main.Id, &t.Category.Main.Name, &t.Category.Main.Status, &t.Category.Main.MType.Id, &t.Category.Main.MType.Name, &t.Category.Main.MType.Factor, &t.Attachments); err != nil {
 		return nil, errors.New(errTransactionWithIDNone)
 	}
 	if t.Date, err = time.Parse(DateFormat, tmpDate); err != nil {
@@ -91,19 +104,13 @@ func TransactionForID(db *gsqlitehandler.SqliteDB, i int) (t *Transaction, err e
 	//TODO: add test
 }
 
-// TransactionList returns all transactions from file as closure
-func TransactionList(db *gsqlitehandler.SqliteDB, dateF, dateT time.Time, a *Account, description string, c *Category, m *MainCategory) (f func() *Transaction, err error) {
-	var stmt *sql.Stmt
-	var rows *sql.Rows
+// sqlTransactionFilter is condition of transactions of account, with description, of category (or its subcategories)
+// and of main category, with parameters given by transactionFilterParams
+const sqlTransactionFilter = "(a.id=? OR ?=?) AND (t.description LIKE ? OR ?=?) " +
+	"AND (c.id IN (WITH RECURSIVE sub(id) AS (SELECT ? UNION SELECT cs.id FROM categories cs INNER JOIN sub ON cs.parent_id=sub.id) SELECT id FROM sub) OR ?=?) AND (m.id=? OR ?=?)"
 
-	// Prepare filtering parameters
-	df, dt := noStringParamForSQL, noStringParamForSQL
-	if !dateF.IsZero() {
-		df = dateF.Format(DateFormat)
-	}
-	if !dateT.IsZero() {
-		dt = dateT.Format(DateFormat)
-	}
+// transactionFilterParams returns parameters of sqlTransactionFilter (nil or not set values do not filter transactions)
+func transactionFilterParams(a *Account, description string, c *Category, m *MainCategory) []interface{} {
 	if description == NotSetStringValue {
 		description = noStringParamForSQL
 	} else {
@@ -128,16 +135,36 @@ func TransactionList(db *gsqlitehandler.SqliteDB, dateF, dateT time.Time, a *Acc
 		mId = m.Id
 	}
 
+	return []interface{}{aId, aId, noIntParamForSQL, description, description, noStringParamForSQL, cId, cId, noIntParamForSQL, mId, mId, noIntParamForSQL}
+}
+
+// TransactionList returns all transactions from file as closure.
+// Filtering by category c includes also transactions of all its subcategories.
+func TransactionList(db *gsqlitehandler.SqliteDB, dateF, dateT time.Time, a *Account, description string, c *Category, m *MainCategory) (f func() *Transaction, err error) {
+	var stmt *sql.Stmt
+	var rows *sql.Rows
+
+	// Prepare filtering parameters
+	df, dt := noStringParamForSQL, noStringParamForSQL
+	if !dateF.IsZero() {
+		df = dateF.Format(DateFormat)
+	}
+	if !dateT.IsZero() {
+		dt = dateT.Format(DateFormat)
+	}
+
 	// Prepare query
-	sqlQuery := "SELECT t.id, t.date, t.description, t.value, a.id, a.name, a.description, a.institution, a.currency, a.type, a.status, c.id, c.name, c.status, m.id, m.name, m.status, mt.id, mt.name, mt.factor " +
+	sqlQuery := "SELECT t.id, t.date, t.description, t.value, a.id, a.name, a.description, a.institution, a.currency, a.type, a.status, c.id, c.name, c.status, m.id, m.name, m.status, mt.id, mt.name, mt.factor, " +
+		"(SELECT COUNT(*) FROM attachments at WHERE at.object='" + AttachmentTransaction + "' AND at.object_id=t.id) " +
 		"FROM transactions t INNER JOIN accounts a ON t.account_id=a.id INNER JOIN categories c ON t.category_id=c.id INNER JOIN main_categories m ON c.main_category_id=m.id INNER JOIN main_categories_types mt on m.type_id=mt.id " +
-		"WHERE (t.date>=? OR ?=?) AND (t.date<=? OR ?=?) AND (a.id=? OR ?=?) AND (t.description LIKE ? OR ?=?) AND (c.id=? OR ?=?) AND (m.id=? OR ?=?) " +
+		"WHERE (t.date>=? OR ?=?) AND (t.date<=? OR ?=?) AND " + sqlTransactionFilter + " " +
 		"ORDER BY t.date, t.id;"
-	if stmt, err = db.Handler.Prepare(sqlQuery); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 
-	if rows, err = stmt.Query(df, df, noStringParamForSQL, dt, dt, noStringParamForSQL, aId, aId, NotSetIntValue, description, description, noStringParamForSQL, cId, cId, NotSetIntValue, mId, mId, NotSetIntValue); err != nil {
+	params := append([]interface{}{df, df, noStringParamForSQL, dt, dt, noStringParamForSQL}, transactionFilterParams(a, description, c, m)...)
+	if rows, err = stmt.Query(params...); err != nil {
 		return nil, errors.New(errReadingFromFile)
 	}
 
@@ -146,7 +173,7 @@ func TransactionList(db *gsqlitehandler.SqliteDB, dateF, dateT time.Time, a *Acc
 		if rows.Next() {
 			t := TransactionNew()
 			var tmpDate string
-			rows.Scan(&t.Id, &tmpDate, &t.Description, &t.Value, &t.Account.Id, &t.Account.Name, &t.Account.Description, &t.Account.Institution, &t.Account.Currency, &t.Account.AType, &t.Account.Status, &t.Category.Id, &t.Category.Name, &t.Category.Status, &t.Category.Main.Id, &t.Category.Main.Name, &t.Category.Main.Status, &t.Category.Main.MType.Id, &t.Category.Main.MType.Name, &t.Category.Main.MType.Factor)
+			rows.Scan(&t.Id, &tmpDate, &t.Description, &t.Value, &t.Account.Id, &t.Account.Name, &t.Account.Description, &t.Account.Institution, &t.Account.Currency, &t.Account.AType, &t.Account.Status, &t.Category.Id, &t.Category.Name, &t.Category.Status, &t.Category.Main.Id, &t.Category.Main.Name, &t.Category.Main.Status, &t.Category.Main.MType.Id, &t.Category.Main.MType.Name, &t.Category.Main.MType.Factor, &t.Attachments)
 			if t.Date, err = time.Parse(DateFormat, tmpDate); err != nil {
 				t.Date = time.Time{}
 			}
@@ -162,16 +189,54 @@ func TransactionList(db *gsqlitehandler.SqliteDB, dateF, dateT time.Time, a *Acc
 	//TODO: add test
 }
 
+// TransactionBalanceBefore returns balance of transactions dated before day d (0 if d is not set)
+// of account a, or of all accounts if a is nil, filtered the same way as by TransactionList.
+// The balance is given in currency, or in currency of account a if currency is not set.
+func TransactionBalanceBefore(db *gsqlitehandler.SqliteDB, d time.Time, a *Account, description string, c *Category, m *MainCategory, currency string) (b float64, err error) {
+	if currency != NotSetStringValue {
+		if s, err := missingCurrenciesForTransactions(db, currency); err == nil {
+			if s != nil {
+				return 0, errors.New(errReportMissingCurrencies + strings.Join(s, ", "))
+			}
+		} else {
+			return 0, err
+		}
+	}
+	if d.IsZero() {
+		return 0, nil
+	}
+	if a != nil && currency == NotSetStringValue {
+		currency = a.Currency
+	}
+
+	sqlQuery := "SELECT ifnull(sum(mt.factor * t.value * cur.exchange_rate), 0) FROM transactions t " +
+		"INNER JOIN accounts a ON t.account_id=a.id INNER JOIN categories c ON t.category_id=c.id " +
+		"INNER JOIN main_categories m ON c.main_category_id=m.id INNER JOIN main_categories_types mt ON m.type_id=mt.id " +
+		"INNER JOIN (SELECT currency_from, exchange_rate FROM currencies WHERE currency_to=upper(?) UNION SELECT upper(?), 1) cur ON a.currency=cur.currency_from " +
+		"WHERE t.date<? AND " + sqlTransactionFilter + ";"
+	params := append([]interface{}{currency, currency, d.Format(DateFormat)}, transactionFilterParams(a, description, c, m)...)
+	if err = dbHandler(db).QueryRow(sqlQuery, params...).Scan(&b); err != nil {
+		return 0, errors.New(errReadingFromFile)
+	}
+
+	return b, nil
+	//TODO: add test
+}
+
 // TransactionEdit updates transaction with new values.
 // All fields are updated, so make sure you pass old values in argument t.
 func TransactionEdit(db *gsqlitehandler.SqliteDB, t *Transaction) error {
 	var err error
 	var stmt *sql.Stmt
 
+	if err = beforeChange(db); err != nil {
+		return err
+	}
+
 	sqlQuery := "UPDATE transactions " +
 		"SET date=?, account_id=?, description=?, value=?, category_id=? " +
 		"WHERE id=?;"
-	if stmt, err = db.Handler.Prepare(sqlQuery); err != nil {
+	if stmt, err = dbHandler(db).Prepare(sqlQuery); err != nil {
 		return errors.New(errWritingToFile)
 	}
 	defer stmt.Close()
@@ -184,14 +249,21 @@ func TransactionEdit(db *gsqlitehandler.SqliteDB, t *Transaction) error {
 	//TODO: add test
 }
 
-// TransactionRemove removes given transaction completely from data file
+// TransactionRemove removes given transaction completely from data file.
+// If it was imported from bank statement, it will be imported again with the statement.
 func TransactionRemove(db *gsqlitehandler.SqliteDB, t *Transaction) error {
 	var err error
+	var tx *change
 	var stmt *sql.Stmt
 
+	if tx, err = beginChange(db); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	defer tx.Rollback()
+
 	// Remove transaction
 	sqlQuery := "DELETE FROM transactions WHERE id=?;"
-	if stmt, err = db.Handler.Prepare(sqlQuery); err != nil {
+	if stmt, err = tx.Prepare(sqlQuery); err != nil {
 		return errors.New(errWritingToFile)
 	}
 	defer stmt.Close()
@@ -200,6 +272,43 @@ func TransactionRemove(db *gsqlitehandler.SqliteDB, t *Transaction) error {
 		return errors.New(errWritingToFile)
 	}
 
+	// Remove its link with bank statement
+	sqlQuery = "DELETE FROM imported_transactions WHERE transaction_id=?;"
+	if stmt, err = tx.Prepare(sqlQuery); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.Exec(t.Id); err != nil {
+		return errors.New(errWritingToFile)
+	}
+
+	// Remove pairs with it dismissed as duplicates
+	sqlQuery = "DELETE FROM dismissed_duplicates WHERE transaction_id=? OR other_id=?;"
+	if stmt, err = tx.Prepare(sqlQuery); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.Exec(t.Id, t.Id); err != nil {
+		return errors.New(errWritingToFile)
+	}
+
+	// Remove its attachments
+	sqlQuery = "DELETE FROM attachments WHERE object=? AND object_id=?;"
+	if stmt, err = tx.Prepare(sqlQuery); err != nil {
+		return errors.New(errWritingToFile)
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.Exec(AttachmentTransaction, t.Id); err != nil {
+		return errors.New(errWritingToFile)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.New(errWritingToFile)
+	}
+
 	return nil
 	//TODO: add test
 }
@@ -208,7 +317,7 @@ func TransactionRemove(db *gsqlitehandler.SqliteDB, t *Transaction) error {
 // It should be used to transfer money between accounts.
 func CompoundTransferAdd(db *gsqlitehandler.SqliteDB, date time.Time, accFrom, accTo *Account, value float64, description string, e *ExchangeRate) error {
 	var err error
-	var tx *sql.Tx
+	var tx *change
 	var stmt *sql.Stmt
 
 	// Create two separate transactions
@@ -221,9 +330,10 @@ func CompoundTransferAdd(db *gsqlitehandler.SqliteDB, date time.Time, accFrom, a
 	tMinus.Description, tPlus.Description = description, description
 
 	// Save transactions to DB
-	if tx, err = db.Handler.Begin(); err != nil {
+	if tx, err = beginChange(db); err != nil {
 		return errors.New(errWritingToFile)
 	}
+	defer tx.Rollback()
 
 	if stmt, err = tx.Prepare(sqlTransactionAdd); err != nil {
 		return errors.New(errWritingToFile)
@@ -237,7 +347,9 @@ func CompoundTransferAdd(db *gsqlitehandler.SqliteDB, date time.Time, accFrom, a
 		return errors.New(errWritingToFile)
 	}
 
-	tx.Commit()
+	if err = tx.Commit(); err != nil {
+		return errors.New(errWritingToFile)
+	}
 
 	return nil
 
@@ -248,7 +360,7 @@ func CompoundTransferAdd(db *gsqlitehandler.SqliteDB, date time.Time, accFrom, a
 // It should be used to transfer money between accounts.
 func CompoundInternalCostAdd(db *gsqlitehandler.SqliteDB, date time.Time, c *Category, accCost, accTransfer *Account, value float64, description string, e *ExchangeRate) error {
 	var err error
-	var tx *sql.Tx
+	var tx *change
 	var stmt *sql.Stmt
 
 	// Create two separate transactions
@@ -261,9 +373,10 @@ func CompoundInternalCostAdd(db *gsqlitehandler.SqliteDB, date time.Time, c *Cat
 	tCost.Description, tTransfer.Description = description, description
 
 	// Save transactions to DB
-	if tx, err = db.Handler.Begin(); err != nil {
+	if tx, err = beginChange(db); err != nil {
 		return errors.New(errWritingToFile)
 	}
+	defer tx.Rollback()
 
 	if stmt, err = tx.Prepare(sqlTransactionAdd); err != nil {
 		return errors.New(errWritingToFile)
@@ -277,7 +390,9 @@ func CompoundInternalCostAdd(db *gsqlitehandler.SqliteDB, date time.Time, c *Cat
 		return errors.New(errWritingToFile)
 	}
 
-	tx.Commit()
+	if err = tx.Commit(); err != nil {
+		return errors.New(errWritingToFile)
+	}
 
 	return nil
 
@@ -287,7 +402,7 @@ func CompoundInternalCostAdd(db *gsqlitehandler.SqliteDB, date time.Time, c *Cat
 // CompountSplitAdd adds two transactions for two different categories with half of the value each.
 func CompoundSplitAdd(db *gsqlitehandler.SqliteDB, d time.Time, a *Account, value float64, description string, c1, c2 *Category) error {
 	var err error
-	var tx *sql.Tx
+	var tx *change
 	var stmt *sql.Stmt
 
 	// Create two separate transactions
@@ -301,9 +416,10 @@ func CompoundSplitAdd(db *gsqlitehandler.SqliteDB, d time.Time, a *Account, valu
 	t1.Category, t2.Category = c1, c2
 
 	// Save transactions to DB
-	if tx, err = db.Handler.Begin(); err != nil {
+	if tx, err = beginChange(db); err != nil {
 		return errors.New(errWritingToFile)
 	}
+	defer tx.Rollback()
 	if stmt, err = tx.Prepare(sqlTransactionAdd); err != nil {
 		return errors.New(errWritingToFile)
 	}
@@ -315,7 +431,9 @@ func CompoundSplitAdd(db *gsqlitehandler.SqliteDB, d time.Time, a *Account, valu
 	if _, err = stmt.Exec(t2.Date.Format(DateFormat), t2.Account.Id, t2.Description, t2.Value, t2.Category.Id); err != nil {
 		return errors.New(errWritingToFile)
 	}
-	tx.Commit()
+	if err = tx.Commit(); err != nil {
+		return errors.New(errWritingToFile)
+	}
 
 	return nil
 
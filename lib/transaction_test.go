@@ -0,0 +1,113 @@
+// Written 2016 by Marcin 'Zbroju' Zbroinski.
+// Use of this source code is governed by a GNU General Public License
+// that can be found in the LICENSE file.
+
+package lib
+
+import (
+	"github.com/zbroju/gsqlitehandler"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestDataFile returns new data file opened in temporary directory, closed at the end of the test
+func newTestDataFile(t *testing.T) *gsqlitehandler.SqliteDB {
+	t.Helper()
+	db := GetDataFileHandler(filepath.Join(t.TempDir(), "test.db"))
+	if err := CreateNewDataFile(db); err != nil {
+		t.Fatalf("creating data file: %v", err)
+	}
+	if err := OpenDataFile(db); err != nil {
+		t.Fatalf("opening data file: %v", err)
+	}
+	t.Cleanup(func() { CloseDataFile(db) })
+
+	return db
+}
+
+// newTestCategory adds category with given parent (or directly under new cost main category if parent is nil)
+func newTestCategory(t *testing.T, db *gsqlitehandler.SqliteDB, name string, parent *Category) *Category {
+	t.Helper()
+	c := CategoryNew()
+	c.Name, c.Status, c.ParentId = name, ISOpen, int64(NotSetIntValue)
+	if parent == nil {
+		c.Main.Name, c.Main.Status, c.Main.MType.Id = name, ISOpen, MCTCost
+		if err := MainCategoryAdd(db, c.Main); err != nil {
+			t.Fatalf("adding main category: %v", err)
+		}
+	} else {
+		c.ParentId = parent.Id
+	}
+	if err := CategoryAdd(db, c); err != nil {
+		t.Fatalf("adding category: %v", err)
+	}
+	c, err := CategoryForID(db, int(c.Id))
+	if err != nil {
+		t.Fatalf("reading category: %v", err)
+	}
+
+	return c
+}
+
+func TestTransactionBalanceBefore(t *testing.T) {
+	db := newTestDataFile(t)
+	a := &Account{Name: "Bank", Currency: "SEK", AType: ATTransactional, Status: ISOpen}
+	if err := AccountAdd(db, a); err != nil {
+		t.Fatalf("adding account: %v", err)
+	}
+	food := newTestCategory(t, db, "Food", nil)
+	fruit := newTestCategory(t, db, "Fruit", food)
+	rent := newTestCategory(t, db, "Rent", nil)
+
+	for _, tr := range []struct {
+		date, description string
+		c                 *Category
+		value             float64
+	}{
+		{"2016-03-01", "ICA", food, 10},
+		{"2016-03-01", "ICA", fruit, 5},
+		{"2016-03-02", "Landlord", rent, 100},
+		{"2016-03-05", "ICA", food, 20},
+	} {
+		nt := TransactionNew()
+		nt.Date, _ = time.Parse(DateFormat, tr.date)
+		nt.Account, nt.Category, nt.Description, nt.Value = a, tr.c, tr.description, tr.value
+		if err := TransactionAdd(db, nt); err != nil {
+			t.Fatalf("adding transaction: %v", err)
+		}
+	}
+
+	day, _ := time.Parse(DateFormat, "2016-03-05")
+	tests := []struct {
+		name        string
+		d           time.Time
+		a           *Account
+		description string
+		c           *Category
+		m           *MainCategory
+		currency    string
+		want        float64
+	}{
+		{name: "date not set", a: a, want: 0},
+		{name: "account", d: day, a: a, want: -115},
+		{name: "all accounts in currency", d: day, currency: "SEK", want: -115},
+		{name: "description", d: day, a: a, description: "lord", want: -100},
+		{name: "category with subcategories", d: day, a: a, c: food, want: -15},
+		{name: "subcategory", d: day, a: a, c: fruit, want: -5},
+		{name: "main category", d: day, a: a, m: rent.Main, want: -100},
+		{name: "no transactions left", d: day, a: a, description: "ICA", m: rent.Main, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := TransactionBalanceBefore(db, tt.d, tt.a, tt.description, tt.c, tt.m, tt.currency)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if b != tt.want {
+				t.Errorf("balance = %.2f, want %.2f", b, tt.want)
+			}
+		})
+	}
+}
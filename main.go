@@ -9,6 +9,8 @@ import (
 	. "github.com/zbroju/financoj/cmd/cli"
 	. "github.com/zbroju/financoj/lib"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
@@ -21,6 +23,17 @@ func main() {
 		printError.Fatalln(err)
 	}
 
+	// Encrypted data files are decrypted with passphrase asked for (unless it is given otherwise)
+	// and saved when the application is interrupted
+	PassphrasePrompt = AskPassphrase
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupted
+		CloseDataFiles()
+		os.Exit(1)
+	}()
+
 	// Parse user commands and flags
 	cli.CommandHelpTemplate = `
 NAME:
@@ -56,16 +69,36 @@ SUBCOMMANDS:
 	flagCategory := cli.StringFlag{Name: ObjCategory + "," + ObjCategoryAlias, Value: NotSetStringValue, Usage: "category name"}
 	flagCategorySplit := cli.StringFlag{Name: OptCategorySplit + "," + OptCategorySplitAlias, Value: NotSetStringValue, Usage: "second category name for split transaction"}
 	flagMainCategory := cli.StringFlag{Name: ObjMainCategory + "," + ObjMainCategoryAlias, Value: NotSetStringValue, Usage: "main category name"}
-	flagMainCategoryType := cli.StringFlag{Name: OptMainCategoryType + "," + OptMainCategoryTypeAlias, Value: NotSetStringValue, Usage: "main category type (cost, transfer, income)"}
+	flagMainCategoryType := cli.StringFlag{Name: OptMainCategoryType + "," + OptMainCategoryTypeAlias, Value: NotSetStringValue, Usage: "main category type name (cost, transfer, income or user defined)"}
 	flagCurrency := cli.StringFlag{Name: OptCurrency + "," + OptCurrencyAlias, Value: NotSetStringValue, Usage: "currency"}
 	flagCurrencyWithDefault := cli.StringFlag{Name: OptCurrency + "," + OptCurrencyAlias, Value: defaultCurrency, Usage: "currency"}
 	flagCurrencyTo := cli.StringFlag{Name: OptCurrencyTo + "," + OptCurrencyToAlias, Value: NotSetStringValue, Usage: "currency to"}
 	flagExchangeRate := cli.Float64Flag{Name: ObjExchangeRate + "," + ObjExchangeRateAlias, Value: NotSetFloatValue, Usage: "currency exchange rate"}
 	flagValue := cli.Float64Flag{Name: OptValue + "," + OptValueAlias, Value: NotSetFloatValue, Usage: "value"}
-	flagDate := cli.StringFlag{Name: OptDate + "," + OptDateAlias, Value: NotSetStringValue, Usage: "date"}
+	flagDate := cli.StringFlag{Name: OptDate + "," + OptDateAlias, Value: NotSetStringValue, Usage: "date, e.g. 2016-05-31, yesterday, -3d, last-monday"}
 	flagDateFrom := cli.StringFlag{Name: OptDateFrom, Value: NotSetStringValue, Usage: "date from"}
 	flagDateTo := cli.StringFlag{Name: OptDateTo, Value: NotSetStringValue, Usage: "date to"}
+	flagRange := cli.StringFlag{Name: OptRange, Value: NotSetStringValue, Usage: "dates range, e.g. last-month, this-quarter, ytd, 2016-05 (overridden by date from and to)"}
 	flagPeriod := cli.StringFlag{Name: OptPeriod + "," + OptPeriodAlias, Value: NotSetStringValue, Usage: "year-month period (yyyy-mm)"}
+	flagCategoryParent := cli.StringFlag{Name: OptCategoryParent + "," + OptCategoryParentAlias, Value: NotSetStringValue, Usage: "parent category name (path like 'Main:Category' allowed)"}
+	flagDepth := cli.IntFlag{Name: OptDepth, Value: NotSetIntValue, Usage: "roll up categories to given level of the tree"}
+	flagFactor := cli.IntFlag{Name: OptFactor, Value: NotSetIntValue, Usage: "factor of main category type: 1 (like income) or -1 (like cost, default)"}
+	flagInteractive := cli.BoolFlag{Name: OptInteractive, Usage: "ask for all values (missing values are asked for anyway when standard input is a terminal)"}
+	flagGranularity := cli.StringFlag{Name: OptGranularity, Value: GranularityMonth.String(), Usage: "length of report periods: day, week, month, quarter or year"}
+	flagGranularityYear := flagGranularity
+	flagGranularityYear.Value = GranularityYear.String()
+	flagAccounts := cli.StringSliceFlag{Name: ObjAccount + "," + ObjAccountAlias, Usage: "account name (may be given many times)"}
+	flagGoal := cli.StringFlag{Name: ObjGoal + "," + ObjGoalAlias, Value: NotSetStringValue, Usage: "goal name"}
+	flagRecurringGranularity := cli.StringFlag{Name: OptGranularity, Value: GranularityMonth.String(), Usage: "period of repeating: day, week, month, quarter or year"}
+	flagEvery := cli.IntFlag{Name: OptEvery, Value: 1, Usage: "number of periods between repeats"}
+	flagUntil := cli.StringFlag{Name: OptUntil, Value: NotSetStringValue, Usage: "last day, e.g. 2017-06-30, 2017-06 (its last day), +3m"}
+	flagFiscalYearStart := cli.StringFlag{Name: OptFiscalYearStart, Value: NotSetStringValue, Usage: "first month of fiscal year (1-12 or name of month)"}
+	flagChart := cli.BoolFlag{Name: OptChart, Usage: "show report as bar chart scaled to the terminal width"}
+	flagFormat := cli.StringFlag{Name: OptFormat, Value: NotSetStringValue, Usage: "format of file: ofx (or qfx), qif, camt053, mt940, beancount, ledger"}
+	flagListen := cli.StringFlag{Name: OptListen, Value: "127.0.0.1:8080", Usage: "address (host:port) to listen on"}
+	flagDays := cli.IntFlag{Name: OptDays, Value: DuplicateDays, Usage: "the greatest number of days between duplicate transactions"}
+	flagSimilarity := cli.Float64Flag{Name: OptSimilarity, Value: DuplicateSimilarity, Usage: "the least similarity of descriptions of duplicate transactions (0-1)"}
+	flagReports := cli.StringFlag{Name: OptReports, Value: NotSetStringValue, Usage: "comma separated reports taking main category type into account: income-cost/ic, budget/b, net-value/nv or none (all by default)"}
 
 	app.Commands = []cli.Command{
 		{Name: CmdInit,
@@ -73,51 +106,151 @@ SUBCOMMANDS:
 			Flags:   []cli.Flag{flagFile},
 			Usage:   "Init a new data file specified by the user",
 			Action:  CmdCreateNewDataFile},
+		{Name: CmdTui,
+			Flags:  []cli.Flag{flagFile, flagCurrencyWithDefault},
+			Usage:  "Browse and edit transactions and see main reports in full screen terminal interface.",
+			Action: CmdTerminalUI},
+		{Name: CmdShell,
+			Flags:  []cli.Flag{flagFile},
+			Usage:  "Run commands in interactive session keeping the data file open. Use begin, commit and rollback to group changes.",
+			Action: CmdInteractiveShell},
+		{Name: CmdServe,
+			Flags:  []cli.Flag{flagFile, flagListen, flagCurrencyWithDefault},
+			Usage:  "Serve HTTP API with JSON requests and responses for objects and reports, and web dashboard with charts.",
+			Action: CmdHTTPServer},
+		{Name: CmdImport,
+			Flags:     []cli.Flag{flagFile, flagFormat, flagAccount, flagCategory},
+			Usage:     "Import transactions from bank statement file to the account (or the whole beancount or ledger journal), skipping the ones imported before.",
+			ArgsUsage: "STATEMENT_FILE",
+			Action:    CmdStatementImport},
+		{Name: CmdExport,
+			Flags:     []cli.Flag{flagFile, flagFormat, flagAccount, flagRange, flagDateFrom, flagDateTo},
+			Usage:     "Export transactions of the account (or the whole data file as beancount or ledger journal) to file (or to standard output).",
+			ArgsUsage: "[OUTPUT_FILE]",
+			Action:    CmdStatementExport},
+		{Name: CmdDuplicates,
+			Flags:     []cli.Flag{flagFile, flagAccount, flagRange, flagDateFrom, flagDateTo, flagDays, flagSimilarity, flagInteractive},
+			Usage:     "List candidate duplicate transactions (the same account and value, close dates, similar descriptions) to merge or dismiss them.",
+			ArgsUsage: "[merge|dismiss ID ID]",
+			Action:    CmdTransactionDuplicates},
+		{Name: CmdDump,
+			Flags:     []cli.Flag{flagFile},
+			Usage:     "Dump the whole data file as JSON to file (or to standard output).",
+			ArgsUsage: "[OUTPUT_FILE]",
+			Action:    CmdDataFileDump},
+		{Name: CmdRestore,
+			Flags:     []cli.Flag{flagFile},
+			Usage:     "Restore JSON dump to new data file.",
+			ArgsUsage: "DUMP_FILE",
+			Action:    CmdDataFileRestore},
+		{Name: CmdEncrypt,
+			Flags:  []cli.Flag{flagFile},
+			Usage:  "Encrypt data file with passphrase (asked for or given in FINANCOJ_PASSPHRASE or key file).",
+			Action: CmdDataFileEncrypt},
+		{Name: CmdDecrypt,
+			Flags:  []cli.Flag{flagFile},
+			Usage:  "Decrypt encrypted data file.",
+			Action: CmdDataFileDecrypt},
+		{Name: CmdSettings,
+			Flags:  []cli.Flag{flagFile, flagFiscalYearStart},
+			Usage:  "Change settings of data file given with flags (first month of fiscal year used by yearly and quarterly periods), or show them.",
+			Action: CmdDataFileSettings},
+		{Name: CmdBackup, Usage: "Manage backups of data file made before changing it.",
+			Subcommands: []cli.Command{
+				{Name: CmdList,
+					Flags:  []cli.Flag{flagFile},
+					Usage:  "List backups of data file, from the newest one.",
+					Action: CmdBackupList},
+				{Name: CmdRestore,
+					Flags:     []cli.Flag{flagFile},
+					Usage:     "Replace contents of data file with backup (the current contents is backed up first).",
+					ArgsUsage: "BACKUP",
+					Action:    CmdBackupRestore},
+			},
+		},
+		{Name: CmdAttachment, Usage: "Manage files (receipts, invoices) attached to transactions and accounts and kept in data file.",
+			Subcommands: []cli.Command{
+				{Name: CmdAdd,
+					Flags:     []cli.Flag{flagFile, flagID, flagAccount},
+					Usage:     "Attach files to transaction given by ID or to account.",
+					ArgsUsage: "FILE...",
+					Action:    CmdAttachmentAdd},
+				{Name: CmdList,
+					Flags:  []cli.Flag{flagFile, flagID, flagAccount},
+					Usage:  "List attachments of transaction given by ID, of account or all of them.",
+					Action: CmdAttachmentList},
+				{Name: CmdExtract,
+					Flags:     []cli.Flag{flagFile},
+					Usage:     "Write attachment to file (by default with its original name, - for standard output).",
+					ArgsUsage: "ATTACHMENT_ID [OUTPUT_FILE]",
+					Action:    CmdAttachmentExtract},
+				{Name: CmdRemove,
+					Flags:     []cli.Flag{flagFile},
+					Usage:     "Remove attachment.",
+					ArgsUsage: "ATTACHMENT_ID",
+					Action:    CmdAttachmentRemove},
+			},
+		},
 		{Name: CmdAdd, Aliases: []string{CmdAddAlias}, Usage: "Add an object.",
 			Subcommands: []cli.Command{
 				{Name: ObjCategory,
 					Aliases: []string{ObjCategoryAlias},
-					Flags:   []cli.Flag{flagFile, flagCategory, flagMainCategory},
-					Usage:   "Add new category.",
+					Flags:   []cli.Flag{flagFile, flagCategory, flagMainCategory, flagCategoryParent, flagInteractive},
+					Usage:   "Add new category (under main category or under parent category).",
 					Action:  CmdCategoryAdd},
 				{Name: ObjMainCategory,
 					Aliases: []string{ObjMainCategoryAlias},
-					Flags:   []cli.Flag{flagFile, flagMainCategory, flagMainCategoryType},
+					Flags:   []cli.Flag{flagFile, flagMainCategory, flagMainCategoryType, flagInteractive},
 					Usage:   "Add new main category.",
 					Action:  CmdMainCategoryAdd},
+				{Name: ObjMainCategoryType,
+					Aliases: []string{ObjMainCategoryTypeAlias},
+					Flags:   []cli.Flag{flagFile, flagMainCategoryType, flagFactor, flagReports, flagInteractive},
+					Usage:   "Add new main category type.",
+					Action:  CmdMainCategoryTypeAdd},
 				{Name: ObjExchangeRate,
 					Aliases: []string{ObjExchangeRateAlias},
-					Flags:   []cli.Flag{flagFile, flagCurrencyWithDefault, flagCurrencyTo, flagExchangeRate},
+					Flags:   []cli.Flag{flagFile, flagCurrencyWithDefault, flagCurrencyTo, flagExchangeRate, flagInteractive},
 					Usage:   "Add new currency exchange rate.",
 					Action:  CmdExchangeRateAdd},
 				{Name: ObjAccount,
 					Aliases: []string{ObjAccountAlias},
-					Flags:   []cli.Flag{flagFile, flagAccount, flagDescription, flagInstitution, flagCurrencyWithDefault, flagAccountType},
+					Flags:   []cli.Flag{flagFile, flagAccount, flagDescription, flagInstitution, flagCurrencyWithDefault, flagAccountType, flagInteractive},
 					Usage:   "Add new account",
 					Action:  CmdAccountAdd},
 				{Name: ObjTransaction,
 					Aliases: []string{ObjTransactionAlias},
-					Flags:   []cli.Flag{flagFile, flagDescription, flagValue, flagAccount, flagCategory, flagDate},
+					Flags:   []cli.Flag{flagFile, flagDescription, flagValue, flagAccount, flagCategory, flagDate, flagInteractive},
 					Usage:   "Add new transaction.",
 					Action:  CmdTransactionAdd},
 				{Name: ObjBudget,
 					Aliases: []string{ObjBudgetAlias},
-					Flags:   []cli.Flag{flagFile, flagPeriod, flagCategory, flagValue, flagCurrencyWithDefault},
+					Flags:   []cli.Flag{flagFile, flagPeriod, flagCategory, flagValue, flagCurrencyWithDefault, flagInteractive},
 					Usage:   "Add new budget.",
 					Action:  CmdBudgetAdd},
+				{Name: ObjRecurring,
+					Aliases: []string{ObjRecurringAlias},
+					Flags:   []cli.Flag{flagFile, flagAccount, flagCategory, flagValue, flagDescription, flagDate, flagUntil, flagRecurringGranularity, flagEvery, flagInteractive},
+					Usage:   "Add new recurring transaction (repeated from date until given day, or with no end).",
+					Action:  CmdRecurringAdd},
+				{Name: ObjGoal,
+					Aliases: []string{ObjGoalAlias},
+					Flags:   []cli.Flag{flagFile, flagGoal, flagValue, flagCurrencyWithDefault, flagUntil, flagAccounts, flagCategory, flagInteractive},
+					Usage:   "Add new savings goal (saved on balance of accounts or as transactions of category).",
+					Action:  CmdGoalAdd},
 				{Name: ObjCompoundTransfer,
 					Aliases: []string{ObjCompoundTransferAlias},
-					Flags:   []cli.Flag{flagFile, flagAccount, flagAccountTo, flagValue, flagDescription, flagDate, flagExchangeRate},
+					Flags:   []cli.Flag{flagFile, flagAccount, flagAccountTo, flagValue, flagDescription, flagDate, flagExchangeRate, flagInteractive},
 					Usage:   "Add transfer between accounts.",
 					Action:  CmdCompoundTransferAdd},
 				{Name: ObjCompoundInternalCost,
 					Aliases: []string{ObjCompoundInternalCostAlias},
-					Flags:   []cli.Flag{flagFile, flagCategory, flagAccount, flagAccountTo, flagValue, flagDescription, flagDate, flagExchangeRate},
+					Flags:   []cli.Flag{flagFile, flagCategory, flagAccount, flagAccountTo, flagValue, flagDescription, flagDate, flagExchangeRate, flagInteractive},
 					Usage:   "Add two transactions: budgetable on first account and non-budgetable on the other.",
 					Action:  CmdCompoundInternalCostAdd},
 				{Name: ObjCompoundTransactionSplit,
 					Aliases: []string{ObjCompoundTransactionSplitAlias},
-					Flags:   []cli.Flag{flagFile, flagAccount, flagValue, flagDescription, flagCategory, flagCategorySplit, flagDate},
+					Flags:   []cli.Flag{flagFile, flagAccount, flagValue, flagDescription, flagCategory, flagCategorySplit, flagDate, flagInteractive},
 					Usage:   "Add transaction split between two categories.",
 					Action:  CmdCompoundTransactionSplit},
 			},
@@ -126,32 +259,37 @@ SUBCOMMANDS:
 			Subcommands: []cli.Command{
 				{Name: ObjCategory,
 					Aliases: []string{ObjCategoryAlias},
-					Flags:   []cli.Flag{flagFile, flagID, flagCategory, flagMainCategory},
-					Usage:   "Edit category.",
+					Flags:   []cli.Flag{flagFile, flagID, flagCategory, flagMainCategory, flagCategoryParent, flagInteractive},
+					Usage:   "Edit category (main category flag moves it to the top level of the main category).",
 					Action:  CmdCategoryEdit},
 				{Name: ObjMainCategory,
 					Aliases: []string{ObjMainCategoryAlias},
-					Flags:   []cli.Flag{flagFile, flagID, flagMainCategory, flagMainCategoryType},
+					Flags:   []cli.Flag{flagFile, flagID, flagMainCategory, flagMainCategoryType, flagInteractive},
 					Usage:   "Edit main category.",
 					Action:  CmdMainCategoryEdit},
+				{Name: ObjMainCategoryType,
+					Aliases: []string{ObjMainCategoryTypeAlias},
+					Flags:   []cli.Flag{flagFile, flagID, flagMainCategoryType, flagFactor, flagReports, flagInteractive},
+					Usage:   "Edit main category type.",
+					Action:  CmdMainCategoryTypeEdit},
 				{Name: ObjExchangeRate,
 					Aliases: []string{ObjExchangeRateAlias},
-					Flags:   []cli.Flag{flagFile, flagCurrencyWithDefault, flagCurrencyTo, flagExchangeRate},
+					Flags:   []cli.Flag{flagFile, flagCurrencyWithDefault, flagCurrencyTo, flagExchangeRate, flagInteractive},
 					Usage:   "Edit currency exchange rate.",
 					Action:  CmdExchangeRateEdit},
 				{Name: ObjAccount,
 					Aliases: []string{ObjAccountAlias},
-					Flags:   []cli.Flag{flagFile, flagID, flagAccount, flagDescription, flagInstitution, flagCurrency, flagAccountType},
+					Flags:   []cli.Flag{flagFile, flagID, flagAccount, flagDescription, flagInstitution, flagCurrency, flagAccountType, flagInteractive},
 					Usage:   "Edit account.",
 					Action:  CmdAccountEdit},
 				{Name: ObjTransaction,
 					Aliases: []string{ObjTransactionAlias},
-					Flags:   []cli.Flag{flagFile, flagID, flagDate, flagCategory, flagAccount, flagValue, flagDescription},
+					Flags:   []cli.Flag{flagFile, flagID, flagDate, flagCategory, flagAccount, flagValue, flagDescription, flagInteractive},
 					Usage:   "Edit transaction.",
 					Action:  CmdTransactionEdit},
 				{Name: ObjBudget,
 					Aliases: []string{ObjBudgetAlias},
-					Flags:   []cli.Flag{flagFile, flagPeriod, flagCategory, flagValue, flagCurrency},
+					Flags:   []cli.Flag{flagFile, flagPeriod, flagCategory, flagValue, flagCurrency, flagInteractive},
 					Usage:   "Edit budget.",
 					Action:  CmdBudgetEdit},
 			},
@@ -168,6 +306,11 @@ SUBCOMMANDS:
 					Flags:   []cli.Flag{flagFile, flagID},
 					Usage:   "Remove main category.",
 					Action:  CmdMainCategoryRemove},
+				{Name: ObjMainCategoryType,
+					Aliases: []string{ObjMainCategoryTypeAlias},
+					Flags:   []cli.Flag{flagFile, flagID},
+					Usage:   "Remove main category type.",
+					Action:  CmdMainCategoryTypeRemove},
 				{Name: ObjExchangeRate,
 					Aliases: []string{ObjExchangeRateAlias},
 					Flags:   []cli.Flag{flagFile, flagCurrencyWithDefault, flagCurrencyTo},
@@ -188,6 +331,16 @@ SUBCOMMANDS:
 					Flags:   []cli.Flag{flagFile, flagPeriod, flagCategory},
 					Usage:   "Remove budget.",
 					Action:  CmdBudgetRemove},
+				{Name: ObjRecurring,
+					Aliases: []string{ObjRecurringAlias},
+					Flags:   []cli.Flag{flagFile, flagID},
+					Usage:   "Remove recurring transaction.",
+					Action:  CmdRecurringRemove},
+				{Name: ObjGoal,
+					Aliases: []string{ObjGoalAlias},
+					Flags:   []cli.Flag{flagFile, flagID},
+					Usage:   "Remove savings goal.",
+					Action:  CmdGoalRemove},
 			},
 		},
 		{Name: CmdList, Aliases: []string{CmdListAlias}, Usage: "List objects on standard output.",
@@ -197,9 +350,14 @@ SUBCOMMANDS:
 					Flags:   []cli.Flag{flagFile, flagMainCategory, flagMainCategoryType, flagAll},
 					Usage:   "List main categories.",
 					Action:  CmdMainCategoryList},
+				{Name: ObjMainCategoryType,
+					Aliases: []string{ObjMainCategoryTypeAlias},
+					Flags:   []cli.Flag{flagFile, flagAll},
+					Usage:   "List main category types.",
+					Action:  CmdMainCategoryTypeList},
 				{Name: ObjCategory,
 					Aliases: []string{ObjCategoryAlias},
-					Flags:   []cli.Flag{flagFile, flagCategory, flagMainCategory, flagAll},
+					Flags:   []cli.Flag{flagFile, flagCategory, flagMainCategory, flagDepth, flagAll},
 					Usage:   "List categories.",
 					Action:  CmdCategoryList},
 				{Name: ObjExchangeRate,
@@ -214,14 +372,24 @@ SUBCOMMANDS:
 					Action:  CmdAccountList},
 				{Name: ObjTransaction,
 					Aliases: []string{ObjTransactionAlias},
-					Flags:   []cli.Flag{flagFile, flagDateFrom, flagDateTo, flagAccount, flagDescription, flagCategory, flagMainCategory},
-					Usage:   "List transactions.",
+					Flags:   []cli.Flag{flagFile, flagRange, flagDateFrom, flagDateTo, flagAccount, flagDescription, flagCategory, flagMainCategory, flagCurrency},
+					Usage:   "List transactions (with running balance for one account, or converted to currency if it is given).",
 					Action:  CmdTransactionList},
 				{Name: ObjBudget,
 					Aliases: []string{ObjBudgetAlias},
 					Flags:   []cli.Flag{flagFile, flagPeriod, flagCategory},
 					Usage:   "List budgets.",
 					Action:  CmdBudgetList},
+				{Name: ObjRecurring,
+					Aliases: []string{ObjRecurringAlias},
+					Flags:   []cli.Flag{flagFile, flagAccount},
+					Usage:   "List recurring transactions.",
+					Action:  CmdRecurringList},
+				{Name: ObjGoal,
+					Aliases: []string{ObjGoalAlias},
+					Flags:   []cli.Flag{flagFile},
+					Usage:   "List savings goals.",
+					Action:  CmdGoalList},
 			},
 		},
 		{Name: CmdReport, Aliases: []string{CmdReportAlias}, Usage: "Prints report.",
@@ -238,42 +406,42 @@ SUBCOMMANDS:
 					Action:  RepAssetsSummary},
 				{Name: ObjReportTransactionBalance,
 					Aliases: []string{ObjReportTransactionBalanceAlias},
-					Flags:   []cli.Flag{flagFile, flagCurrencyWithDefault, flagDateFrom, flagDateTo, flagAccount, flagCategory, flagMainCategory, flagDescription},
+					Flags:   []cli.Flag{flagFile, flagCurrencyWithDefault, flagRange, flagDateFrom, flagDateTo, flagAccount, flagCategory, flagMainCategory, flagDescription},
 					Usage:   "Transactions balance for given criteria.",
 					Action:  RepTransactionBalance},
 				{Name: ObjReportCategoryBalance,
 					Aliases: []string{ObjReportCategoryBalanceAlias},
-					Flags:   []cli.Flag{flagFile, flagCurrencyWithDefault, flagDateFrom, flagDateTo, flagAccount, flagCategory, flagMainCategory},
+					Flags:   []cli.Flag{flagFile, flagCurrencyWithDefault, flagRange, flagDateFrom, flagDateTo, flagAccount, flagCategory, flagMainCategory, flagDepth},
 					Usage:   "Categories balance for given criteria.",
 					Action:  RepCategoryBalance},
 				{Name: ObjReportCategoryBalanceMonthly,
 					Aliases: []string{ObjReportCategoryBalanceMonthlyAlias},
-					Flags:   []cli.Flag{flagFile, flagCurrencyWithDefault, flagCategory, flagDateFrom, flagDateTo},
-					Usage:   "Categories balance monthly.",
-					Action:  RepCategoryBalanceMonthly},
+					Flags:   []cli.Flag{flagFile, flagCurrencyWithDefault, flagCategory, flagDepth, flagGranularity, flagRange, flagDateFrom, flagDateTo, flagChart},
+					Usage:   "Categories balance over time (monthly unless granularity is given).",
+					Action:  RepCategoryBalanceTime},
 				{Name: ObjReportCategoryBalanceYearly,
 					Aliases: []string{ObjReportCategoryBalanceYearlyAlias},
-					Flags:   []cli.Flag{flagFile, flagCurrencyWithDefault, flagCategory, flagDateFrom, flagDateTo},
-					Usage:   "Categories balance yearly.",
-					Action:  RepCategoryBalanceYearly},
+					Flags:   []cli.Flag{flagFile, flagCurrencyWithDefault, flagCategory, flagDepth, flagGranularityYear, flagRange, flagDateFrom, flagDateTo, flagChart},
+					Usage:   "Categories balance over time (yearly unless granularity is given).",
+					Action:  RepCategoryBalanceTime},
 				{Name: ObjReportMainCategoryBalance,
 					Aliases: []string{ObjReportMainCategoryBalanceAlias},
-					Flags:   []cli.Flag{flagFile, flagCurrencyWithDefault, flagDateFrom, flagDateTo, flagAccount, flagMainCategory},
+					Flags:   []cli.Flag{flagFile, flagCurrencyWithDefault, flagRange, flagDateFrom, flagDateTo, flagAccount, flagMainCategory},
 					Usage:   "Main categories balance for given criteria.",
 					Action:  RepMainCategoryBalance},
 				{Name: ObjReportMainCategoryBalanceMonthly,
 					Aliases: []string{ObjReportMainCategoryBalanceMonthlyAlias},
-					Flags:   []cli.Flag{flagFile, flagCurrencyWithDefault, flagMainCategory, flagDateFrom, flagDateTo},
-					Usage:   "Main categories balance monthly.",
-					Action:  RepMainCategoryBalanceMonthly},
+					Flags:   []cli.Flag{flagFile, flagCurrencyWithDefault, flagMainCategory, flagGranularity, flagRange, flagDateFrom, flagDateTo, flagChart},
+					Usage:   "Main categories balance over time (monthly unless granularity is given).",
+					Action:  RepMainCategoryBalanceTime},
 				{Name: ObjReportMainCategoryBalanceYearly,
 					Aliases: []string{ObjReportMainCategoryBalanceYearlyAlias},
-					Flags:   []cli.Flag{flagFile, flagCurrencyWithDefault, flagMainCategory, flagDateFrom, flagDateTo},
-					Usage:   "Main categories balance yearly.",
-					Action:  RepMainCategoryBalanceYearly},
+					Flags:   []cli.Flag{flagFile, flagCurrencyWithDefault, flagMainCategory, flagGranularityYear, flagRange, flagDateFrom, flagDateTo, flagChart},
+					Usage:   "Main categories balance over time (yearly unless granularity is given).",
+					Action:  RepMainCategoryBalanceTime},
 				{Name: ObjReportBudgetCategories,
 					Aliases: []string{ObjReportBudgetCategoriesAlias},
-					Flags:   []cli.Flag{flagFile, flagPeriod, flagCurrencyWithDefault},
+					Flags:   []cli.Flag{flagFile, flagPeriod, flagCurrencyWithDefault, flagDepth},
 					Usage:   "Budget categories for given year or year-month (or current month if period flag is missing).",
 					Action:  RepBudgetCategories},
 				{Name: ObjReportBudgetMainCategories,
@@ -283,24 +451,37 @@ SUBCOMMANDS:
 					Action:  RepBudgetMainCategories},
 				{Name: ObjReportNetValueMonthly,
 					Aliases: []string{ObjReportNetValueMonthlyAlias},
-					Flags:   []cli.Flag{flagFile, flagCurrencyWithDefault, flagDateFrom, flagDateTo},
-					Usage:   "Net value over time.",
-					Action:  RepNetValueMonthly},
+					Flags:   []cli.Flag{flagFile, flagCurrencyWithDefault, flagGranularity, flagRange, flagDateFrom, flagDateTo, flagChart},
+					Usage:   "Net value over time (monthly unless granularity is given).",
+					Action:  RepNetValue},
 				{Name: ObjReportIncomeVsCostMonthly,
 					Aliases: []string{ObjReportIncomeVsCostMonthlyAlias},
-					Flags:   []cli.Flag{flagFile, flagCurrencyWithDefault, flagDateFrom, flagDateTo},
-					Usage:   "Income, cost and difference (monthly)",
-					Action:  RepIncomeVsCostMonthly},
+					Flags:   []cli.Flag{flagFile, flagCurrencyWithDefault, flagGranularity, flagRange, flagDateFrom, flagDateTo, flagChart},
+					Usage:   "Income, cost and difference (monthly unless granularity is given)",
+					Action:  RepIncomeVsCost},
 				{Name: ObjReportIncomeVsCostYearly,
 					Aliases: []string{ObjReportIncomeVsCostYearlyAlias},
-					Flags:   []cli.Flag{flagFile, flagCurrencyWithDefault, flagDateFrom, flagDateTo},
-					Usage:   "Income, cost and difference (yearly)",
-					Action:  RepIncomeVsCostYearly},
+					Flags:   []cli.Flag{flagFile, flagCurrencyWithDefault, flagGranularityYear, flagRange, flagDateFrom, flagDateTo, flagChart},
+					Usage:   "Income, cost and difference (yearly unless granularity is given)",
+					Action:  RepIncomeVsCost},
+				{Name: ObjReportForecast,
+					Aliases: []string{ObjReportForecastAlias},
+					Flags:   []cli.Flag{flagFile, flagUntil, flagAccount},
+					Usage:   "Balances of accounts forecast day by day until given day, from recurring transactions and budgets left to spend (on given account, or the first operational one).",
+					Action:  RepForecast},
+				{Name: ObjReportGoals,
+					Aliases: []string{ObjReportGoalsAlias},
+					Flags:   []cli.Flag{flagFile, flagCurrencyWithDefault},
+					Usage:   "Savings goals: amount saved, percentage done, monthly contribution required and whether they are on track.",
+					Action:  RepGoals},
 			},
 		},
 	}
 
-	app.Run(os.Args)
+	if err = app.Run(os.Args); err != nil {
+		CloseDataFiles()
+		os.Exit(1)
+	}
 }
 
 //TODO: add condition to mainCategoryRemove checking if there are any transactions/categories connected and if not, remove it completely